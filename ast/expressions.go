@@ -197,6 +197,32 @@ func (t *Ternary) String() string {
 	return out.String()
 }
 
+// Propagate is an expression node that unwraps a result, as in "value?". If the
+// result holds an error, the enclosing function returns the result instead.
+type Propagate struct {
+	token token.Token // the '?' token
+
+	// value is the expression that evaluates to a result
+	value Expression
+}
+
+// NewPropagate creates a new Propagate node.
+func NewPropagate(token token.Token, value Expression) *Propagate {
+	return &Propagate{token: token, value: value}
+}
+
+func (p *Propagate) ExpressionNode() {}
+
+func (p *Propagate) IsExpression() bool { return true }
+
+func (p *Propagate) Token() token.Token { return p.token }
+
+func (p *Propagate) Literal() string { return p.token.Literal }
+
+func (p *Propagate) Value() Expression { return p.value }
+
+func (p *Propagate) String() string { return p.value.String() + "?" }
+
 // Call is an expression node that describes the invocation of a function.
 type Call struct {
 	token     token.Token // the '(' token
@@ -263,10 +289,17 @@ func (e *GetAttr) Object() Expression { return e.object }
 
 func (e *GetAttr) Name() string { return e.attribute.value }
 
+// IsOptional returns true if the attribute access is nil-safe, as in "a?.b".
+func (e *GetAttr) IsOptional() bool { return e.token.Type == token.QUESTION_PERIOD }
+
 func (e *GetAttr) String() string {
 	var out bytes.Buffer
 	out.WriteString(e.object.String())
-	out.WriteString(".")
+	if e.IsOptional() {
+		out.WriteString("?.")
+	} else {
+		out.WriteString(".")
+	}
 	out.WriteString(e.attribute.value)
 	return out.String()
 }
@@ -332,10 +365,17 @@ func (c *ObjectCall) Object() Expression { return c.object }
 
 func (c *ObjectCall) Call() Expression { return c.call }
 
+// IsOptional returns true if the method call is nil-safe, as in "a?.b()".
+func (c *ObjectCall) IsOptional() bool { return c.token.Type == token.QUESTION_PERIOD }
+
 func (c *ObjectCall) String() string {
 	var out bytes.Buffer
 	out.WriteString(c.object.String())
-	out.WriteString(".")
+	if c.IsOptional() {
+		out.WriteString("?.")
+	} else {
+		out.WriteString(".")
+	}
 	out.WriteString(c.call.String())
 	return out.String()
 }
@@ -368,16 +408,49 @@ func (i *Index) Left() Expression { return i.left }
 
 func (i *Index) Index() Expression { return i.index }
 
+// IsOptional returns true if the index operation is nil-safe, as in "a?.[k]".
+func (i *Index) IsOptional() bool { return i.token.Type == token.QUESTION_PERIOD }
+
 func (i *Index) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(i.left.String())
+	if i.IsOptional() {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(i.index.String())
 	out.WriteString("])")
 	return out.String()
 }
 
+// Group is an expression node that describes an optional chain enclosed in
+// parentheses, as in "(a?.b).c". The parentheses end the chain, so when a is
+// nil, only the part of the chain within them is skipped.
+type Group struct {
+	token token.Token // the '(' token
+
+	// expr is the expression within the parentheses
+	expr Expression
+}
+
+// NewGroup creates a new Group node.
+func NewGroup(token token.Token, expr Expression) *Group {
+	return &Group{token: token, expr: expr}
+}
+
+func (g *Group) ExpressionNode() {}
+
+func (g *Group) IsExpression() bool { return true }
+
+func (g *Group) Token() token.Token { return g.token }
+
+func (g *Group) Literal() string { return g.token.Literal }
+
+func (g *Group) Expression() Expression { return g.expr }
+
+func (g *Group) String() string { return "(" + g.expr.String() + ")" }
+
 // Slice is an expression node that describes a slicing operation on an object.
 type Slice struct {
 	token token.Token
@@ -390,6 +463,9 @@ type Slice struct {
 
 	// Optional "to" index for [from:to] style expressions
 	toIndex Expression
+
+	// Optional step for [from:to:step] style expressions
+	step Expression
 }
 
 // NewSlice creates a new Slice node.
@@ -397,6 +473,11 @@ func NewSlice(token token.Token, left Expression, fromIndex Expression, toIndex
 	return &Slice{token: token, left: left, fromIndex: fromIndex, toIndex: toIndex}
 }
 
+// NewStepSlice creates a new Slice node with a step, e.g. x[1:10:2].
+func NewStepSlice(token token.Token, left Expression, fromIndex Expression, toIndex Expression, step Expression) *Slice {
+	return &Slice{token: token, left: left, fromIndex: fromIndex, toIndex: toIndex, step: step}
+}
+
 func (s *Slice) ExpressionNode() {}
 
 func (s *Slice) IsExpression() bool { return true }
@@ -411,6 +492,8 @@ func (s *Slice) FromIndex() Expression { return s.fromIndex }
 
 func (s *Slice) ToIndex() Expression { return s.toIndex }
 
+func (s *Slice) Step() Expression { return s.step }
+
 func (s *Slice) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -419,7 +502,14 @@ func (s *Slice) String() string {
 	if s.fromIndex != nil {
 		out.WriteString(s.fromIndex.String())
 	}
-	if s.toIndex != nil {
+	if s.step != nil {
+		out.WriteString(":")
+		if s.toIndex != nil {
+			out.WriteString(s.toIndex.String())
+		}
+		out.WriteString(":")
+		out.WriteString(s.step.String())
+	} else if s.toIndex != nil {
 		out.WriteString(":")
 		out.WriteString(s.toIndex.String())
 	}
@@ -434,9 +524,12 @@ type Case struct {
 	// Default branch?
 	isDefault bool
 
-	// The thing we match
+	// The thing we match. Each may be a value to compare with or a Pattern.
 	expr []Expression
 
+	// An optional condition that must also be true for the case to match
+	guard Expression
+
 	// The code to execute if there is a match
 	block *Block
 }
@@ -446,6 +539,12 @@ func NewCase(token token.Token, expressions []Expression, block *Block) *Case {
 	return &Case{token: token, expr: expressions, block: block}
 }
 
+// NewGuardedCase creates a new Case node that only matches if the guard
+// expression is also true, as in "case x if x > 10:".
+func NewGuardedCase(token token.Token, expressions []Expression, guard Expression, block *Block) *Case {
+	return &Case{token: token, expr: expressions, guard: guard, block: block}
+}
+
 // NewDefaultCase represents the default case within a switch expression.
 func NewDefaultCase(token token.Token, block *Block) *Case {
 	return &Case{token: token, isDefault: true, block: block}
@@ -463,6 +562,9 @@ func (c *Case) IsDefault() bool { return c.isDefault }
 
 func (c *Case) Expressions() []Expression { return c.expr }
 
+// Guard returns the case's guard expression, or nil if it has none.
+func (c *Case) Guard() Expression { return c.guard }
+
 func (c *Case) Block() *Block { return c.block }
 
 func (c *Case) String() string {
@@ -476,6 +578,9 @@ func (c *Case) String() string {
 			tmp = append(tmp, exp.String())
 		}
 		out.WriteString(strings.Join(tmp, ","))
+		if c.guard != nil {
+			out.WriteString(" if " + c.guard.String())
+		}
 	}
 	out.WriteString(":\n")
 	for i, exp := range c.block.statements {
@@ -563,6 +668,38 @@ func (i *In) String() string {
 	return out.String()
 }
 
+// LoopTargets is an expression node that describes the two loop variables
+// of a for-in loop, as in "for k, [a, b] in m". Each target is a name or a
+// list or map pattern.
+type LoopTargets struct {
+	token token.Token // the first token of the first target
+
+	targets []Expression
+}
+
+// NewLoopTargets creates a new LoopTargets node.
+func NewLoopTargets(token token.Token, targets []Expression) *LoopTargets {
+	return &LoopTargets{token: token, targets: targets}
+}
+
+func (t *LoopTargets) ExpressionNode() {}
+
+func (t *LoopTargets) IsExpression() bool { return true }
+
+func (t *LoopTargets) Token() token.Token { return t.token }
+
+func (t *LoopTargets) Literal() string { return t.token.Literal }
+
+func (t *LoopTargets) Targets() []Expression { return t.targets }
+
+func (t *LoopTargets) String() string {
+	targets := make([]string, 0, len(t.targets))
+	for _, target := range t.targets {
+		targets = append(targets, target.String())
+	}
+	return strings.Join(targets, ", ")
+}
+
 // Range is an expression node that describes iterating over a container.
 type Range struct {
 	// the "range" token
@@ -594,3 +731,228 @@ func (r *Range) String() string {
 	out.WriteString(r.container.String())
 	return out.String()
 }
+
+// Receive is an expression node that receives a value from a channel, as in
+// "<-ch".
+type Receive struct {
+	// the "<-" token
+	token token.Token
+
+	// the channel to receive from
+	channel Expression
+}
+
+// NewReceive creates a new Receive node.
+func NewReceive(token token.Token, channel Expression) *Receive {
+	return &Receive{token: token, channel: channel}
+}
+
+func (r *Receive) ExpressionNode() {}
+
+func (r *Receive) IsExpression() bool { return true }
+
+func (r *Receive) Token() token.Token { return r.token }
+
+func (r *Receive) Literal() string { return r.token.Literal }
+
+func (r *Receive) Channel() Expression { return r.channel }
+
+func (r *Receive) String() string { return "<-" + r.channel.String() }
+
+// Send is an expression node that sends a value on a channel, as in
+// "ch <- value". It evaluates to nil.
+type Send struct {
+	// the "<-" token
+	token token.Token
+
+	// the channel to send on
+	channel Expression
+
+	// the value to send
+	value Expression
+}
+
+// NewSend creates a new Send node.
+func NewSend(token token.Token, channel Expression, value Expression) *Send {
+	return &Send{token: token, channel: channel, value: value}
+}
+
+func (s *Send) ExpressionNode() {}
+
+func (s *Send) IsExpression() bool { return true }
+
+func (s *Send) Token() token.Token { return s.token }
+
+func (s *Send) Literal() string { return s.token.Literal }
+
+func (s *Send) Channel() Expression { return s.channel }
+
+func (s *Send) Value() Expression { return s.value }
+
+func (s *Send) String() string {
+	return s.channel.String() + " <- " + s.value.String()
+}
+
+// SelectCase is a case within a select expression. Each case other than the
+// default case holds either a Send or a Receive operation.
+type SelectCase struct {
+	token token.Token
+
+	// Default branch?
+	isDefault bool
+
+	// The channel operation, either a *Send or a *Receive
+	comm Expression
+
+	// Optional variable that is assigned the received value
+	ident *Ident
+
+	// The code to execute if this case is selected
+	block *Block
+}
+
+// NewSelectCase creates a new SelectCase node.
+func NewSelectCase(token token.Token, comm Expression, ident *Ident, block *Block) *SelectCase {
+	return &SelectCase{token: token, comm: comm, ident: ident, block: block}
+}
+
+// NewDefaultSelectCase represents the default case within a select expression.
+func NewDefaultSelectCase(token token.Token, block *Block) *SelectCase {
+	return &SelectCase{token: token, isDefault: true, block: block}
+}
+
+func (c *SelectCase) ExpressionNode() {}
+
+func (c *SelectCase) IsExpression() bool { return true }
+
+func (c *SelectCase) Token() token.Token { return c.token }
+
+func (c *SelectCase) Literal() string { return c.token.Literal }
+
+func (c *SelectCase) IsDefault() bool { return c.isDefault }
+
+func (c *SelectCase) Comm() Expression { return c.comm }
+
+func (c *SelectCase) Ident() *Ident { return c.ident }
+
+func (c *SelectCase) Block() *Block { return c.block }
+
+func (c *SelectCase) String() string {
+	var out bytes.Buffer
+	if c.isDefault {
+		out.WriteString("default")
+	} else {
+		out.WriteString("case ")
+		if c.ident != nil {
+			out.WriteString(c.ident.String() + " := ")
+		}
+		out.WriteString(c.comm.String())
+	}
+	out.WriteString(":\n")
+	if c.block != nil {
+		for i, exp := range c.block.statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString("\t" + exp.String())
+		}
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Select is an expression node that waits on multiple channel operations and
+// runs the case of the first one that proceeds.
+type Select struct {
+	// token containing "select"
+	token token.Token
+
+	// select cases
+	cases []*SelectCase
+}
+
+// NewSelect creates a new Select node.
+func NewSelect(token token.Token, cases []*SelectCase) *Select {
+	return &Select{token: token, cases: cases}
+}
+
+func (s *Select) ExpressionNode() {}
+
+func (s *Select) IsExpression() bool { return true }
+
+func (s *Select) Token() token.Token { return s.token }
+
+func (s *Select) Literal() string { return s.token.Literal }
+
+func (s *Select) Cases() []*SelectCase { return s.cases }
+
+func (s *Select) String() string {
+	var out bytes.Buffer
+	out.WriteString("\nselect {\n")
+	for _, c := range s.cases {
+		out.WriteString(c.String())
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// KeywordArg is a node that describes a keyword argument given in a function
+// call, as in "timeout=5".
+type KeywordArg struct {
+	// the identifier token
+	token token.Token
+
+	// the name of the argument
+	name *Ident
+
+	// the argument value
+	value Expression
+}
+
+// NewKeywordArg creates a new KeywordArg node.
+func NewKeywordArg(name *Ident, value Expression) *KeywordArg {
+	return &KeywordArg{token: name.token, name: name, value: value}
+}
+
+func (k *KeywordArg) ExpressionNode() {}
+
+func (k *KeywordArg) IsExpression() bool { return true }
+
+func (k *KeywordArg) Token() token.Token { return k.token }
+
+func (k *KeywordArg) Literal() string { return k.token.Literal }
+
+func (k *KeywordArg) Name() string { return k.name.value }
+
+func (k *KeywordArg) Value() Expression { return k.value }
+
+func (k *KeywordArg) String() string {
+	return k.name.value + "=" + k.value.String()
+}
+
+// Spread is a node that expands a list into individual positional arguments
+// in a function call, as in "...args".
+type Spread struct {
+	// the "..." token
+	token token.Token
+
+	// the list to expand
+	value Expression
+}
+
+// NewSpread creates a new Spread node.
+func NewSpread(token token.Token, value Expression) *Spread {
+	return &Spread{token: token, value: value}
+}
+
+func (s *Spread) ExpressionNode() {}
+
+func (s *Spread) IsExpression() bool { return true }
+
+func (s *Spread) Token() token.Token { return s.token }
+
+func (s *Spread) Literal() string { return s.token.Literal }
+
+func (s *Spread) Value() Expression { return s.value }
+
+func (s *Spread) String() string { return "..." + s.value.String() }
@@ -55,6 +55,31 @@ func (f *Float) Value() float64 { return f.value }
 
 func (f *Float) String() string { return f.token.Literal }
 
+// Imaginary is an expression node that holds an imaginary number literal,
+// such as 2i or 1.5i.
+type Imaginary struct {
+	token token.Token // the token containing the number
+	value float64     // the value of the imaginary part
+}
+
+// NewImaginary creates a new Imaginary node.
+func NewImaginary(token token.Token, value float64) *Imaginary {
+	return &Imaginary{token: token, value: value}
+}
+
+func (i *Imaginary) ExpressionNode() {}
+
+func (i *Imaginary) IsExpression() bool { return true }
+
+func (i *Imaginary) Token() token.Token { return i.token }
+
+func (i *Imaginary) Literal() string { return i.token.Literal }
+
+// Value returns the imaginary part of the number.
+func (i *Imaginary) Value() float64 { return i.value }
+
+func (i *Imaginary) String() string { return i.token.Literal }
+
 // Nil is an expression node that holds a nil literal.
 type Nil struct {
 	token token.Token // token containing "nil"
@@ -113,17 +138,34 @@ type Func struct {
 	// defaults holds any default values for arguments which aren't specified.
 	defaults map[string]Expression
 
+	// rest optionally receives extra positional arguments, as in "*rest".
+	rest *Ident
+
+	// kwargs optionally receives extra keyword arguments, as in "**kwargs".
+	kwargs *Ident
+
 	// body contains the set of statements within the function.
 	body *Block
 }
 
-// NewFunc creates a new Func node.
-func NewFunc(token token.Token, name *Ident, parameters []*Ident, defaults map[string]Expression, body *Block) *Func {
+// NewFunc creates a new Func node. The rest and kwargs parameters are optional
+// and may be nil.
+func NewFunc(
+	token token.Token,
+	name *Ident,
+	parameters []*Ident,
+	defaults map[string]Expression,
+	rest *Ident,
+	kwargs *Ident,
+	body *Block,
+) *Func {
 	return &Func{
 		token:      token,
 		name:       name,
 		parameters: parameters,
 		defaults:   defaults,
+		rest:       rest,
+		kwargs:     kwargs,
 		body:       body,
 	}
 }
@@ -150,6 +192,10 @@ func (f *Func) ParameterNames() []string {
 
 func (f *Func) Defaults() map[string]Expression { return f.defaults }
 
+func (f *Func) RestParameter() *Ident { return f.rest }
+
+func (f *Func) KwargsParameter() *Ident { return f.kwargs }
+
 func (f *Func) Body() *Block { return f.body }
 
 func (f *Func) String() string {
@@ -158,6 +204,12 @@ func (f *Func) String() string {
 	for _, p := range f.parameters {
 		params = append(params, p.value)
 	}
+	if f.rest != nil {
+		params = append(params, "*"+f.rest.value)
+	}
+	if f.kwargs != nil {
+		params = append(params, "**"+f.kwargs.value)
+	}
 	out.WriteString(f.Literal())
 	if f.name != nil {
 		out.WriteString(" " + f.name.value)
@@ -246,15 +298,51 @@ func (l *List) String() string {
 	return out.String()
 }
 
+// Tuple is an expression node that builds a tuple, e.g. "(1, 2)".
+type Tuple struct {
+	// the '(' token
+	token token.Token
+
+	// items holds the members of the tuple.
+	items []Expression
+}
+
+// NewTuple creates a new Tuple node.
+func NewTuple(tok token.Token, items []Expression) *Tuple {
+	return &Tuple{token: tok, items: items}
+}
+
+func (t *Tuple) ExpressionNode() {}
+
+func (t *Tuple) IsExpression() bool { return true }
+
+func (t *Tuple) Token() token.Token { return t.token }
+
+func (t *Tuple) Literal() string { return t.token.Literal }
+
+func (t *Tuple) Items() []Expression { return t.items }
+
+func (t *Tuple) String() string {
+	elements := make([]string, 0, len(t.items))
+	for _, el := range t.items {
+		elements = append(elements, el.String())
+	}
+	if len(elements) == 1 {
+		return "(" + elements[0] + ",)"
+	}
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
 // Map is an expression node that builds a map data structure.
 type Map struct {
-	token token.Token               // the '{' token
-	items map[Expression]Expression // items in the map
+	token  token.Token  // the '{' token
+	keys   []Expression // keys in the map, in source order
+	values []Expression // values in the map, in source order
 }
 
-// NewMap creates a new Map node.
-func NewMap(token token.Token, items map[Expression]Expression) *Map {
-	return &Map{token: token, items: items}
+// NewMap creates a new Map node. The keys and values are paired by position.
+func NewMap(token token.Token, keys, values []Expression) *Map {
+	return &Map{token: token, keys: keys, values: values}
 }
 
 func (m *Map) ExpressionNode() {}
@@ -265,13 +353,26 @@ func (m *Map) Token() token.Token { return m.token }
 
 func (m *Map) Literal() string { return m.token.Literal }
 
-func (m *Map) Items() map[Expression]Expression { return m.items }
+// Keys returns the keys of the map in source order.
+func (m *Map) Keys() []Expression { return m.keys }
+
+// Values returns the values of the map in source order.
+func (m *Map) Values() []Expression { return m.values }
+
+// Items returns the key-value pairs of the map.
+func (m *Map) Items() map[Expression]Expression {
+	items := make(map[Expression]Expression, len(m.keys))
+	for i, key := range m.keys {
+		items[key] = m.values[i]
+	}
+	return items
+}
 
 func (m *Map) String() string {
 	var out bytes.Buffer
-	pairs := make([]string, 0)
-	for key, value := range m.items {
-		pairs = append(pairs, key.String()+":"+value.String())
+	pairs := make([]string, 0, len(m.keys))
+	for i, key := range m.keys {
+		pairs = append(pairs, key.String()+":"+m.values[i].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -279,6 +380,46 @@ func (m *Map) String() string {
 	return out.String()
 }
 
+// StructLiteral is an expression node that creates an instance of a struct
+// type, as in `Point{x: 1, y: 2}` or `geo.Point{x: 1, y: 2}`.
+type StructLiteral struct {
+	token  token.Token  // the struct name token
+	typ    Expression   // the struct type, an identifier or attribute
+	fields []*Ident     // names of the given fields, in source order
+	values []Expression // values of the given fields, in source order
+}
+
+// NewStructLiteral creates a new StructLiteral node. The fields and values are
+// paired by position.
+func NewStructLiteral(token token.Token, typ Expression, fields []*Ident, values []Expression) *StructLiteral {
+	return &StructLiteral{token: token, typ: typ, fields: fields, values: values}
+}
+
+func (s *StructLiteral) ExpressionNode() {}
+
+func (s *StructLiteral) IsExpression() bool { return true }
+
+func (s *StructLiteral) Token() token.Token { return s.token }
+
+func (s *StructLiteral) Literal() string { return s.token.Literal }
+
+// Type returns the expression that evaluates to the struct type.
+func (s *StructLiteral) Type() Expression { return s.typ }
+
+// Fields returns the names of the given fields in source order.
+func (s *StructLiteral) Fields() []*Ident { return s.fields }
+
+// Values returns the values of the given fields in source order.
+func (s *StructLiteral) Values() []Expression { return s.values }
+
+func (s *StructLiteral) String() string {
+	pairs := make([]string, 0, len(s.fields))
+	for i, field := range s.fields {
+		pairs = append(pairs, field.value+": "+s.values[i].String())
+	}
+	return s.typ.String() + "{" + strings.Join(pairs, ", ") + "}"
+}
+
 // Set is an expression node that builds a set data structure.
 type Set struct {
 	token token.Token  // the '{' token
@@ -311,3 +452,169 @@ func (s *Set) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// ComprehensionClause is one "for names in iterable if condition" clause of a
+// list, map, or set comprehension. The condition is optional.
+type ComprehensionClause struct {
+	// the "for" token
+	token token.Token
+
+	// the loop variables, of which there may be one or two
+	names []*Ident
+
+	// the expression being iterated over
+	iterable Expression
+
+	// optional condition that filters the iterated values
+	condition Expression
+}
+
+// NewComprehensionClause creates a new ComprehensionClause.
+func NewComprehensionClause(
+	token token.Token,
+	names []*Ident,
+	iterable Expression,
+	condition Expression,
+) *ComprehensionClause {
+	return &ComprehensionClause{
+		token:     token,
+		names:     names,
+		iterable:  iterable,
+		condition: condition,
+	}
+}
+
+func (c *ComprehensionClause) Token() token.Token { return c.token }
+
+func (c *ComprehensionClause) Names() []*Ident { return c.names }
+
+func (c *ComprehensionClause) Iterable() Expression { return c.iterable }
+
+func (c *ComprehensionClause) Condition() Expression { return c.condition }
+
+func (c *ComprehensionClause) String() string {
+	names := make([]string, 0, len(c.names))
+	for _, name := range c.names {
+		names = append(names, name.value)
+	}
+	result := "for " + strings.Join(names, ", ") + " in " + c.iterable.String()
+	if c.condition != nil {
+		result += " if " + c.condition.String()
+	}
+	return result
+}
+
+func clausesString(clauses []*ComprehensionClause) string {
+	items := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		items = append(items, clause.String())
+	}
+	return strings.Join(items, " ")
+}
+
+// ListComprehension is an expression node that builds a list from the values
+// of an element expression, as in "[x * 2 for x in items if x > 0]".
+type ListComprehension struct {
+	// the '[' token
+	token token.Token
+
+	// the expression evaluated for each iteration
+	element Expression
+
+	// the for clauses, from outermost to innermost
+	clauses []*ComprehensionClause
+}
+
+// NewListComprehension creates a new ListComprehension node.
+func NewListComprehension(tok token.Token, element Expression, clauses []*ComprehensionClause) *ListComprehension {
+	return &ListComprehension{token: tok, element: element, clauses: clauses}
+}
+
+func (l *ListComprehension) ExpressionNode() {}
+
+func (l *ListComprehension) IsExpression() bool { return true }
+
+func (l *ListComprehension) Token() token.Token { return l.token }
+
+func (l *ListComprehension) Literal() string { return l.token.Literal }
+
+func (l *ListComprehension) Element() Expression { return l.element }
+
+func (l *ListComprehension) Clauses() []*ComprehensionClause { return l.clauses }
+
+func (l *ListComprehension) String() string {
+	return "[" + l.element.String() + " " + clausesString(l.clauses) + "]"
+}
+
+// MapComprehension is an expression node that builds a map from key and value
+// expressions, as in "{k: v * 2 for k, v in m}".
+type MapComprehension struct {
+	// the '{' token
+	token token.Token
+
+	// the key expression evaluated for each iteration
+	key Expression
+
+	// the value expression evaluated for each iteration
+	value Expression
+
+	// the for clauses, from outermost to innermost
+	clauses []*ComprehensionClause
+}
+
+// NewMapComprehension creates a new MapComprehension node.
+func NewMapComprehension(tok token.Token, key, value Expression, clauses []*ComprehensionClause) *MapComprehension {
+	return &MapComprehension{token: tok, key: key, value: value, clauses: clauses}
+}
+
+func (m *MapComprehension) ExpressionNode() {}
+
+func (m *MapComprehension) IsExpression() bool { return true }
+
+func (m *MapComprehension) Token() token.Token { return m.token }
+
+func (m *MapComprehension) Literal() string { return m.token.Literal }
+
+func (m *MapComprehension) Key() Expression { return m.key }
+
+func (m *MapComprehension) Value() Expression { return m.value }
+
+func (m *MapComprehension) Clauses() []*ComprehensionClause { return m.clauses }
+
+func (m *MapComprehension) String() string {
+	return "{" + m.key.String() + ": " + m.value.String() + " " + clausesString(m.clauses) + "}"
+}
+
+// SetComprehension is an expression node that builds a set from the values of
+// an element expression, as in "{x for x in items}".
+type SetComprehension struct {
+	// the '{' token
+	token token.Token
+
+	// the expression evaluated for each iteration
+	element Expression
+
+	// the for clauses, from outermost to innermost
+	clauses []*ComprehensionClause
+}
+
+// NewSetComprehension creates a new SetComprehension node.
+func NewSetComprehension(tok token.Token, element Expression, clauses []*ComprehensionClause) *SetComprehension {
+	return &SetComprehension{token: tok, element: element, clauses: clauses}
+}
+
+func (s *SetComprehension) ExpressionNode() {}
+
+func (s *SetComprehension) IsExpression() bool { return true }
+
+func (s *SetComprehension) Token() token.Token { return s.token }
+
+func (s *SetComprehension) Literal() string { return s.token.Literal }
+
+func (s *SetComprehension) Element() Expression { return s.element }
+
+func (s *SetComprehension) Clauses() []*ComprehensionClause { return s.clauses }
+
+func (s *SetComprehension) String() string {
+	return "{" + s.element.String() + " " + clausesString(s.clauses) + "}"
+}
@@ -0,0 +1,182 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/risor-io/risor/token"
+)
+
+// Pattern is implemented by nodes that describe the structure of a value in
+// a switch case. Matching a value against a pattern may bind parts of the
+// value to names. Within a pattern, literal values such as ints and strings
+// match values that are equal to them.
+type Pattern interface {
+	Expression
+
+	// PatternNode signals that this Node is a pattern.
+	PatternNode()
+}
+
+// CapturePattern matches any value and binds it to a name. The name "_" is a
+// wildcard that matches any value without binding it.
+type CapturePattern struct {
+	// the identifier token
+	token token.Token
+}
+
+// NewCapturePattern creates a new CapturePattern node.
+func NewCapturePattern(token token.Token) *CapturePattern {
+	return &CapturePattern{token: token}
+}
+
+func (p *CapturePattern) ExpressionNode() {}
+
+func (p *CapturePattern) PatternNode() {}
+
+func (p *CapturePattern) IsExpression() bool { return true }
+
+func (p *CapturePattern) Token() token.Token { return p.token }
+
+func (p *CapturePattern) Literal() string { return p.token.Literal }
+
+// Name returns the name the matched value is bound to.
+func (p *CapturePattern) Name() string { return p.token.Literal }
+
+// IsWildcard returns true if this is the "_" pattern, which binds nothing.
+func (p *CapturePattern) IsWildcard() bool { return p.token.Literal == "_" }
+
+func (p *CapturePattern) String() string { return p.token.Literal }
+
+// ListPattern matches a list whose items match the given patterns. If the
+// pattern has a rest name, as in "[first, *rest]", any number of additional
+// items are accepted at that position and bound to the name as a list.
+type ListPattern struct {
+	// the "[" token
+	token token.Token
+
+	// the patterns for each item, not including the rest name
+	items []Expression
+
+	// the optional rest name
+	rest *Ident
+
+	// the position of the rest name within the items
+	restIndex int
+}
+
+// NewListPattern creates a new ListPattern node. The rest name may be nil.
+func NewListPattern(token token.Token, items []Expression, rest *Ident, restIndex int) *ListPattern {
+	return &ListPattern{token: token, items: items, rest: rest, restIndex: restIndex}
+}
+
+func (p *ListPattern) ExpressionNode() {}
+
+func (p *ListPattern) PatternNode() {}
+
+func (p *ListPattern) IsExpression() bool { return true }
+
+func (p *ListPattern) Token() token.Token { return p.token }
+
+func (p *ListPattern) Literal() string { return p.token.Literal }
+
+func (p *ListPattern) Items() []Expression { return p.items }
+
+// Rest returns the rest name, or nil if the pattern has none.
+func (p *ListPattern) Rest() *Ident { return p.rest }
+
+// RestIndex returns the position of the rest name within the items.
+func (p *ListPattern) RestIndex() int { return p.restIndex }
+
+func (p *ListPattern) String() string {
+	items := make([]string, 0, len(p.items)+1)
+	for i, item := range p.items {
+		if p.rest != nil && i == p.restIndex {
+			items = append(items, "*"+p.rest.String())
+		}
+		items = append(items, item.String())
+	}
+	if p.rest != nil && p.restIndex == len(p.items) {
+		items = append(items, "*"+p.rest.String())
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// MapPattern matches a map that contains the given keys, with values that
+// match the corresponding patterns. Other keys in the map are ignored.
+type MapPattern struct {
+	// the "{" token
+	token token.Token
+
+	// the keys to look up
+	keys []string
+
+	// the patterns for the value of each key
+	values []Expression
+}
+
+// NewMapPattern creates a new MapPattern node.
+func NewMapPattern(token token.Token, keys []string, values []Expression) *MapPattern {
+	return &MapPattern{token: token, keys: keys, values: values}
+}
+
+func (p *MapPattern) ExpressionNode() {}
+
+func (p *MapPattern) PatternNode() {}
+
+func (p *MapPattern) IsExpression() bool { return true }
+
+func (p *MapPattern) Token() token.Token { return p.token }
+
+func (p *MapPattern) Literal() string { return p.token.Literal }
+
+func (p *MapPattern) Keys() []string { return p.keys }
+
+func (p *MapPattern) Values() []Expression { return p.values }
+
+func (p *MapPattern) String() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, key := range p.keys {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(`"` + key + `": ` + p.values[i].String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// TypePattern matches a value of the named type, as in "int(n)", where the
+// value must also match the inner pattern.
+type TypePattern struct {
+	// the type name token
+	token token.Token
+
+	// the pattern for the value
+	value Expression
+}
+
+// NewTypePattern creates a new TypePattern node.
+func NewTypePattern(token token.Token, value Expression) *TypePattern {
+	return &TypePattern{token: token, value: value}
+}
+
+func (p *TypePattern) ExpressionNode() {}
+
+func (p *TypePattern) PatternNode() {}
+
+func (p *TypePattern) IsExpression() bool { return true }
+
+func (p *TypePattern) Token() token.Token { return p.token }
+
+func (p *TypePattern) Literal() string { return p.token.Literal }
+
+// TypeName returns the name of the type to match.
+func (p *TypePattern) TypeName() string { return p.token.Literal }
+
+func (p *TypePattern) Value() Expression { return p.value }
+
+func (p *TypePattern) String() string {
+	return p.token.Literal + "(" + p.value.String() + ")"
+}
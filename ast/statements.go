@@ -108,6 +108,64 @@ func (s *MultiVar) String() string {
 	return out.String()
 }
 
+// Destructure is a declaration statement that unpacks a value into variables
+// using patterns, as in "a, [b, c] := x", "{name, age} := person", or
+// "head, *tail := items".
+type Destructure struct {
+	// the first token of the statement
+	token token.Token
+
+	// the patterns being assigned, not including the rest name
+	targets []Expression
+
+	// the optional rest name
+	rest *Ident
+
+	// the position of the rest name within the targets
+	restIndex int
+
+	// the value being unpacked
+	value Expression
+}
+
+// NewDestructure creates a new Destructure node. The rest name may be nil.
+func NewDestructure(token token.Token, targets []Expression, rest *Ident, restIndex int, value Expression) *Destructure {
+	return &Destructure{token: token, targets: targets, rest: rest, restIndex: restIndex, value: value}
+}
+
+func (s *Destructure) StatementNode() {}
+
+func (s *Destructure) IsExpression() bool { return false }
+
+func (s *Destructure) Token() token.Token { return s.token }
+
+func (s *Destructure) Literal() string { return s.token.Literal }
+
+// Targets returns the patterns being assigned, not including the rest name.
+func (s *Destructure) Targets() []Expression { return s.targets }
+
+// Rest returns the rest name, or nil if the statement has none.
+func (s *Destructure) Rest() *Ident { return s.rest }
+
+// RestIndex returns the position of the rest name within the targets.
+func (s *Destructure) RestIndex() int { return s.restIndex }
+
+func (s *Destructure) Value() Expression { return s.value }
+
+func (s *Destructure) String() string {
+	targets := make([]string, 0, len(s.targets)+1)
+	for i, target := range s.targets {
+		if s.rest != nil && i == s.restIndex {
+			targets = append(targets, "*"+s.rest.String())
+		}
+		targets = append(targets, target.String())
+	}
+	if s.rest != nil && s.restIndex == len(s.targets) {
+		targets = append(targets, "*"+s.rest.String())
+	}
+	return strings.Join(targets, ", ") + " := " + s.value.String()
+}
+
 // Const is a statement that defines a named constant.
 type Const struct {
 	// the "const" token
@@ -328,6 +386,7 @@ type Assign struct {
 	token    token.Token
 	name     *Ident // this may be nil, e.g. `[0, 1, 2][0] = 3`
 	index    *Index
+	attr     *GetAttr
 	operator string
 	value    Expression
 }
@@ -342,6 +401,11 @@ func NewAssignIndex(operator token.Token, index *Index, value Expression) *Assig
 	return &Assign{token: operator, index: index, operator: operator.Literal, value: value}
 }
 
+// NewAssignAttr creates a new Assign node for an attribute assignment.
+func NewAssignAttr(operator token.Token, attr *GetAttr, value Expression) *Assign {
+	return &Assign{token: operator, attr: attr, operator: operator.Literal, value: value}
+}
+
 func (a *Assign) StatementNode() {}
 
 func (a *Assign) IsExpression() bool { return false }
@@ -354,6 +418,10 @@ func (a *Assign) Name() string { return a.name.value }
 
 func (a *Assign) Index() *Index { return a.index }
 
+// Attr returns the attribute being assigned to, if this is an attribute
+// assignment like `point.x = 1`.
+func (a *Assign) Attr() *GetAttr { return a.attr }
+
 func (a *Assign) Operator() string { return a.operator }
 
 func (a *Assign) Value() Expression { return a.value }
@@ -362,6 +430,8 @@ func (a *Assign) String() string {
 	var out bytes.Buffer
 	if a.index != nil {
 		out.WriteString(a.index.String())
+	} else if a.attr != nil {
+		out.WriteString(a.attr.String())
 	} else {
 		out.WriteString(a.name.value)
 	}
@@ -429,3 +499,289 @@ func (p *Postfix) String() string {
 	out.WriteString(")")
 	return out.String()
 }
+
+// Try is a statement node that describes a try/catch/finally statement.
+type Try struct {
+	// the "try" token
+	token token.Token
+
+	// the block of code that may raise an error
+	body *Block
+
+	// optional name bound to the error within the catch block
+	catchIdent *Ident
+
+	// optional block to execute if an error is raised in the body
+	catchBlock *Block
+
+	// optional block that always executes after the body and catch block
+	finallyBlock *Block
+}
+
+// NewTry creates a new Try node.
+func NewTry(token token.Token, body *Block, catchIdent *Ident, catchBlock *Block, finallyBlock *Block) *Try {
+	return &Try{
+		token:        token,
+		body:         body,
+		catchIdent:   catchIdent,
+		catchBlock:   catchBlock,
+		finallyBlock: finallyBlock,
+	}
+}
+
+func (t *Try) StatementNode() {}
+
+func (t *Try) IsExpression() bool { return false }
+
+func (t *Try) Token() token.Token { return t.token }
+
+func (t *Try) Literal() string { return t.token.Literal }
+
+func (t *Try) Body() *Block { return t.body }
+
+func (t *Try) CatchIdent() *Ident { return t.catchIdent }
+
+func (t *Try) CatchBlock() *Block { return t.catchBlock }
+
+func (t *Try) FinallyBlock() *Block { return t.finallyBlock }
+
+func (t *Try) String() string {
+	var out bytes.Buffer
+	out.WriteString("try { ")
+	out.WriteString(t.body.String())
+	out.WriteString(" }")
+	if t.catchBlock != nil {
+		out.WriteString(" catch ")
+		if t.catchIdent != nil {
+			out.WriteString(t.catchIdent.Literal() + " ")
+		}
+		out.WriteString("{ ")
+		out.WriteString(t.catchBlock.String())
+		out.WriteString(" }")
+	}
+	if t.finallyBlock != nil {
+		out.WriteString(" finally { ")
+		out.WriteString(t.finallyBlock.String())
+		out.WriteString(" }")
+	}
+	return out.String()
+}
+
+// Throw is a statement node that raises an error.
+type Throw struct {
+	// the "throw" token
+	token token.Token
+
+	// the error or message to raise
+	value Expression
+}
+
+// NewThrow creates a new Throw node.
+func NewThrow(token token.Token, value Expression) *Throw {
+	return &Throw{token: token, value: value}
+}
+
+func (t *Throw) StatementNode() {}
+
+func (t *Throw) IsExpression() bool { return false }
+
+func (t *Throw) Token() token.Token { return t.token }
+
+func (t *Throw) Literal() string { return t.token.Literal }
+
+func (t *Throw) Value() Expression { return t.value }
+
+func (t *Throw) String() string {
+	var out bytes.Buffer
+	out.WriteString(t.Literal())
+	if t.value != nil {
+		out.WriteString(" " + t.value.String())
+	}
+	return out.String()
+}
+
+// Yield is a statement node that suspends a generator function, producing a
+// value for the code iterating over the generator.
+type Yield struct {
+	// the "yield" token
+	token token.Token
+
+	// the value to produce (optional)
+	value Expression
+}
+
+// NewYield creates a new Yield node.
+func NewYield(token token.Token, value Expression) *Yield {
+	return &Yield{token: token, value: value}
+}
+
+func (y *Yield) StatementNode() {}
+
+func (y *Yield) IsExpression() bool { return false }
+
+func (y *Yield) Token() token.Token { return y.token }
+
+func (y *Yield) Literal() string { return y.token.Literal }
+
+func (y *Yield) Value() Expression { return y.value }
+
+func (y *Yield) String() string {
+	var out bytes.Buffer
+	out.WriteString(y.Literal())
+	if y.value != nil {
+		out.WriteString(" " + y.value.String())
+	}
+	return out.String()
+}
+
+// Defer is a statement node that defers a function call until the enclosing
+// function returns.
+type Defer struct {
+	// the "defer" token
+	token token.Token
+
+	// the function call to defer
+	call Expression
+}
+
+// NewDefer creates a new Defer node.
+func NewDefer(token token.Token, call Expression) *Defer {
+	return &Defer{token: token, call: call}
+}
+
+func (d *Defer) StatementNode() {}
+
+func (d *Defer) IsExpression() bool { return false }
+
+func (d *Defer) Token() token.Token { return d.token }
+
+func (d *Defer) Literal() string { return d.token.Literal }
+
+func (d *Defer) Call() Expression { return d.call }
+
+func (d *Defer) String() string {
+	return d.Literal() + " " + d.call.String()
+}
+
+// Go is a statement node that calls a function in a new goroutine.
+type Go struct {
+	// the "go" token
+	token token.Token
+
+	// the function call to run in the goroutine
+	call Expression
+}
+
+// NewGo creates a new Go node.
+func NewGo(token token.Token, call Expression) *Go {
+	return &Go{token: token, call: call}
+}
+
+func (g *Go) StatementNode() {}
+
+func (g *Go) IsExpression() bool { return false }
+
+func (g *Go) Token() token.Token { return g.token }
+
+func (g *Go) Literal() string { return g.token.Literal }
+
+func (g *Go) Call() Expression { return g.call }
+
+func (g *Go) String() string {
+	return g.Literal() + " " + g.call.String()
+}
+
+// Struct is a statement that declares a struct type with a set of named
+// fields, which may have default values.
+type Struct struct {
+	// the "struct" token
+	token token.Token
+
+	// name of the struct type
+	name *Ident
+
+	// fields of the struct, in declaration order
+	fields []*Ident
+
+	// defaults holds the default values of fields that have one
+	defaults map[string]Expression
+}
+
+// NewStruct creates a new Struct node.
+func NewStruct(token token.Token, name *Ident, fields []*Ident, defaults map[string]Expression) *Struct {
+	return &Struct{token: token, name: name, fields: fields, defaults: defaults}
+}
+
+func (s *Struct) StatementNode() {}
+
+func (s *Struct) IsExpression() bool { return false }
+
+func (s *Struct) Token() token.Token { return s.token }
+
+func (s *Struct) Literal() string { return s.token.Literal }
+
+func (s *Struct) Name() *Ident { return s.name }
+
+func (s *Struct) Fields() []*Ident { return s.fields }
+
+func (s *Struct) Defaults() map[string]Expression { return s.defaults }
+
+func (s *Struct) String() string {
+	fields := make([]string, 0, len(s.fields))
+	for _, field := range s.fields {
+		if def, ok := s.defaults[field.value]; ok {
+			fields = append(fields, field.value+" = "+def.String())
+		} else {
+			fields = append(fields, field.value)
+		}
+	}
+	return s.Literal() + " " + s.name.value + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// Method is a statement that declares a method on a struct type, as in
+// `func (p Point) norm() { ... }`. The function receives the instance as its
+// first parameter.
+type Method struct {
+	// the "func" token
+	token token.Token
+
+	// name of the struct type that owns the method
+	structName *Ident
+
+	// name of the method
+	name *Ident
+
+	// the method function, with the receiver as its first parameter
+	fn *Func
+}
+
+// NewMethod creates a new Method node.
+func NewMethod(token token.Token, structName *Ident, name *Ident, fn *Func) *Method {
+	return &Method{token: token, structName: structName, name: name, fn: fn}
+}
+
+func (m *Method) StatementNode() {}
+
+func (m *Method) IsExpression() bool { return false }
+
+func (m *Method) Token() token.Token { return m.token }
+
+func (m *Method) Literal() string { return m.token.Literal }
+
+func (m *Method) StructName() *Ident { return m.structName }
+
+func (m *Method) Name() *Ident { return m.name }
+
+func (m *Method) Func() *Func { return m.fn }
+
+func (m *Method) String() string {
+	params := m.fn.ParameterNames()
+	var out bytes.Buffer
+	out.WriteString(m.Literal())
+	out.WriteString(" (" + params[0] + " " + m.structName.value + ") ")
+	out.WriteString(m.name.value)
+	out.WriteString("(" + strings.Join(params[1:], ", ") + ") { ")
+	out.WriteString(m.fn.Body().String())
+	out.WriteString(" }")
+	return out.String()
+}
@@ -3,8 +3,11 @@ package builtins
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"strconv"
 	"unicode"
 
@@ -17,11 +20,21 @@ func Len(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("len", 1, args); err != nil {
 		return err
 	}
-	container, ok := args[0].(object.Container)
-	if !ok {
-		return object.Errorf("type error: len() unsupported argument (%s given)", args[0].Type())
+	switch arg := args[0].(type) {
+	case object.Container:
+		return arg.Len()
+	case *object.Chan:
+		return arg.Len()
+	}
+	// Objects like structs may implement the length with a method
+	result, found, err := object.LenOperator(ctx, args[0])
+	if err != nil {
+		return object.NewError(err)
+	}
+	if found {
+		return result
 	}
-	return container.Len()
+	return object.Errorf("type error: len() unsupported argument (%s given)", args[0].Type())
 }
 
 func Sprintf(ctx context.Context, args ...object.Object) object.Object {
@@ -82,6 +95,9 @@ func Set(ctx context.Context, args ...object.Object) object.Object {
 			return res
 		}
 	}
+	if err := object.IteratorError(iter); err != nil {
+		return object.NewError(err)
+	}
 	return set
 }
 
@@ -108,9 +124,89 @@ func List(ctx context.Context, args ...object.Object) object.Object {
 		}
 		items = append(items, val)
 	}
+	if err := object.IteratorError(iter); err != nil {
+		return object.NewError(err)
+	}
 	return object.NewList(items)
 }
 
+func Range(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("range", 1, 3, args); err != nil {
+		return err
+	}
+	// A single non-integer argument is ranged over directly, which keeps
+	// "range(xs)" working the same as "range xs"
+	if len(args) == 1 {
+		switch arg := args[0].(type) {
+		case object.Iterator:
+			return arg
+		case object.Iterable:
+			return arg.Iter()
+		}
+	}
+	values := make([]int64, len(args))
+	for i, obj := range args {
+		value, ok := obj.(*object.Int)
+		if !ok {
+			return object.Errorf("type error: range() expected an int (%s given)", obj.Type())
+		}
+		values[i] = value.Value()
+	}
+	start, stop, step := int64(0), values[0], int64(1)
+	if len(values) > 1 {
+		start, stop = values[0], values[1]
+	}
+	if len(values) > 2 {
+		step = values[2]
+	}
+	if err := object.CheckRange(start, stop, step); err != nil {
+		return err
+	}
+	return object.NewRange(start, stop, step)
+}
+
+func Tuple(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("tuple", 0, 1, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return object.NewTuple(nil)
+	}
+	arg := args[0]
+	if tuple, ok := arg.(*object.Tuple); ok {
+		return tuple
+	}
+	if err := limits.TrackCost(ctx, arg.Cost()); err != nil {
+		return object.NewError(err)
+	}
+	iter, err := object.AsIterator(arg)
+	if err != nil {
+		return err
+	}
+	var items []object.Object
+	for {
+		val, ok := iter.Next()
+		if !ok {
+			break
+		}
+		items = append(items, val)
+	}
+	if err := object.IteratorError(iter); err != nil {
+		return object.NewError(err)
+	}
+	return object.NewTuple(items)
+}
+
+func Freeze(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("freeze", 1, args); err != nil {
+		return err
+	}
+	if err := limits.TrackCost(ctx, args[0].Cost()); err != nil {
+		return object.NewError(err)
+	}
+	return object.Freeze(args[0])
+}
+
 func Map(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.RequireRange("map", 0, 1, args); err != nil {
 		return err
@@ -156,6 +252,9 @@ func Map(ctx context.Context, args ...object.Object) object.Object {
 			result.Set(k.Inspect(), v)
 		}
 	}
+	if err := object.IteratorError(iter); err != nil {
+		return object.NewError(err)
+	}
 	return result
 }
 
@@ -244,6 +343,51 @@ func FloatSlice(ctx context.Context, args ...object.Object) object.Object {
 	}
 }
 
+func ComplexSlice(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("complex_slice", 0, 1, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return object.NewComplexSlice(nil)
+	}
+	arg := args[0]
+	argCost := arg.Cost()
+	if err := limits.TrackCost(ctx, argCost); err != nil {
+		return object.NewError(err)
+	}
+	switch arg := arg.(type) {
+	case *object.ComplexSlice:
+		return arg.Clone()
+	case *object.Int:
+		val := arg.Value()
+		if err := limits.TrackCost(ctx, int(val)-argCost); err != nil {
+			return object.NewError(err)
+		}
+		return object.NewComplexSlice(make([]complex128, val))
+	case *object.FloatSlice:
+		floats := arg.Value()
+		values := make([]complex128, len(floats))
+		for i, f := range floats {
+			values[i] = complex(f, 0)
+		}
+		return object.NewComplexSlice(values)
+	case *object.List:
+		items := arg.Value()
+		values := make([]complex128, len(items))
+		for i, item := range items {
+			switch item := item.(type) {
+			case *object.Byte, *object.Int, *object.Float, *object.Complex:
+				values[i], _ = object.AsComplex(item)
+			default:
+				return object.Errorf("type error: complex_slice() list item unsupported (%s given)", item.Type())
+			}
+		}
+		return object.NewComplexSlice(values)
+	default:
+		return object.Errorf("type error: complex_slice() unsupported argument (%s given)", args[0].Type())
+	}
+}
+
 func ByteSlice(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.RequireRange("byte_slice", 0, 1, args); err != nil {
 		return err
@@ -409,6 +553,9 @@ func Any(ctx context.Context, args ...object.Object) object.Object {
 				return object.True
 			}
 		}
+		if err := object.IteratorError(arg); err != nil {
+			return object.NewError(err)
+		}
 	default:
 		return object.Errorf("type error: any() argument must be a container (%s given)", args[0].Type())
 	}
@@ -465,6 +612,9 @@ func All(ctx context.Context, args ...object.Object) object.Object {
 				return object.False
 			}
 		}
+		if err := object.IteratorError(arg); err != nil {
+			return object.NewError(err)
+		}
 	default:
 		return object.Errorf("type error: all() argument must be a container (%s given)", args[0].Type())
 	}
@@ -500,6 +650,8 @@ func Sorted(ctx context.Context, args ...object.Object) object.Object {
 		items = arg.Keys().Value()
 	case *object.Set:
 		items = arg.List().Value()
+	case *object.Tuple:
+		items = arg.Value()
 	case *object.String:
 		items = arg.Runes()
 	case *object.ByteSlice:
@@ -509,7 +661,7 @@ func Sorted(ctx context.Context, args ...object.Object) object.Object {
 	}
 	resultItems := make([]object.Object, len(items))
 	copy(resultItems, items)
-	if err := object.Sort(resultItems); err != nil {
+	if err := object.SortContext(ctx, resultItems); err != nil {
 		return err
 	}
 	return object.NewList(resultItems)
@@ -526,6 +678,13 @@ func Reversed(ctx context.Context, args ...object.Object) object.Object {
 	switch arg := arg.(type) {
 	case *object.List:
 		return arg.Reversed()
+	case *object.Tuple:
+		items := arg.Value()
+		reversed := make([]object.Object, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		return object.NewTuple(reversed)
 	case *object.String:
 		return arg.Reversed()
 	case *object.ByteSlice:
@@ -567,7 +726,7 @@ func Call(ctx context.Context, args ...object.Object) object.Object {
 		}
 		result, err := callFunc(ctx, fn, args[1:])
 		if err != nil {
-			return object.Errorf(err.Error())
+			return object.NewError(err)
 		}
 		return result
 	default:
@@ -651,16 +810,145 @@ func Int(ctx context.Context, args ...object.Object) object.Object {
 		return object.NewInt(int64(obj.Value()))
 	case *object.Duration:
 		return object.NewInt(int64(obj.Value()))
+	case *object.BigInt:
+		if !obj.IsInt64() {
+			return object.Errorf("value error: int() argument out of range: %s", obj)
+		}
+		return object.NewInt(obj.Value().Int64())
+	case *object.Decimal:
+		value := obj.Integer()
+		if !value.IsInt64() {
+			return object.Errorf("value error: int() argument out of range: %s", obj)
+		}
+		return object.NewInt(value.Int64())
 	case *object.String:
-		if i, err := strconv.ParseInt(obj.Value(), 0, 64); err == nil {
+		i, err := strconv.ParseInt(obj.Value(), 0, 64)
+		if err == nil {
 			return object.NewInt(i)
 		}
+		// Integers too large for an int64 are promoted to a bigint
+		if errors.Is(err, strconv.ErrRange) {
+			if value, err := object.ParseBigInt(obj.Value(), 0); err == nil {
+				return value
+			}
+		}
 		return object.Errorf("value error: invalid literal for int(): %q", obj.Value())
 	default:
 		return object.Errorf("type error: int() unsupported argument (%s given)", args[0].Type())
 	}
 }
 
+func BigInt(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("bigint", 0, 2, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return object.NewBigInt(big.NewInt(0))
+	}
+	if len(args) == 2 {
+		s, err := object.AsString(args[0])
+		if err != nil {
+			return err
+		}
+		base, err := object.AsInt(args[1])
+		if err != nil {
+			return err
+		}
+		if base != 0 && (base < 2 || base > 62) {
+			return object.Errorf("value error: bigint() base must be 0 or between 2 and 62 (got %d)", base)
+		}
+		value, err := object.ParseBigInt(s, int(base))
+		if err != nil {
+			return err
+		}
+		return value
+	}
+	switch obj := args[0].(type) {
+	case *object.BigInt:
+		return obj
+	case *object.Int:
+		return object.NewBigInt(big.NewInt(obj.Value()))
+	case *object.Byte:
+		return object.NewBigInt(big.NewInt(int64(obj.Value())))
+	case *object.Float:
+		value := obj.Value()
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return object.Errorf("value error: bigint() cannot convert %s", obj)
+		}
+		i, _ := big.NewFloat(value).Int(nil)
+		return object.NewBigInt(i)
+	case *object.Decimal:
+		return object.NewBigInt(obj.Integer())
+	case *object.String:
+		value, err := object.ParseBigInt(obj.Value(), 0)
+		if err != nil {
+			return err
+		}
+		return value
+	default:
+		return object.Errorf("type error: bigint() unsupported argument (%s given)", args[0].Type())
+	}
+}
+
+func Decimal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("decimal", 0, 3, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return object.NewDecimalFromInt(0)
+	}
+	var value *object.Decimal
+	switch obj := args[0].(type) {
+	case *object.Decimal:
+		value = obj
+	case *object.Int:
+		value = object.NewDecimalFromInt(obj.Value())
+	case *object.Byte:
+		value = object.NewDecimalFromInt(int64(obj.Value()))
+	case *object.BigInt:
+		value = object.NewDecimalFromBigInt(obj.Value())
+	case *object.Float:
+		// Use the shortest representation of the float, so that 0.1 becomes
+		// exactly 0.1 rather than its binary approximation
+		f := obj.Value()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return object.Errorf("value error: decimal() cannot convert %s", obj)
+		}
+		var err *object.Error
+		if value, err = object.ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64)); err != nil {
+			return err
+		}
+	case *object.String:
+		var err *object.Error
+		if value, err = object.ParseDecimal(obj.Value()); err != nil {
+			return err
+		}
+	default:
+		return object.Errorf("type error: decimal() unsupported argument (%s given)", args[0].Type())
+	}
+	if len(args) == 1 {
+		return value
+	}
+	places, err := object.AsInt(args[1])
+	if err != nil {
+		return err
+	}
+	if places < 0 {
+		return object.Errorf("value error: decimal() places must be non-negative (got %d)", places)
+	}
+	mode := object.RoundHalfEven
+	if len(args) == 3 {
+		name, err := object.AsString(args[2])
+		if err != nil {
+			return err
+		}
+		if mode, err = object.ParseRoundingMode(name); err != nil {
+			return err
+		}
+	}
+	return value.Round(int(places), mode)
+}
+
 func Float(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.RequireRange("float", 0, 1, args); err != nil {
 		return err
@@ -675,6 +963,11 @@ func Float(ctx context.Context, args ...object.Object) object.Object {
 		return object.NewFloat(float64(obj.Value()))
 	case *object.Float:
 		return obj
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value()).Float64()
+		return object.NewFloat(f)
+	case *object.Decimal:
+		return object.NewFloat(obj.Float64())
 	case *object.String:
 		if f, err := strconv.ParseFloat(obj.Value(), 64); err == nil {
 			return object.NewFloat(f)
@@ -685,6 +978,40 @@ func Float(ctx context.Context, args ...object.Object) object.Object {
 	}
 }
 
+func Complex(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("complex", 0, 2, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return object.NewComplex(0)
+	}
+	if len(args) == 2 {
+		re, err := object.AsFloat(args[0])
+		if err != nil {
+			return err
+		}
+		im, err := object.AsFloat(args[1])
+		if err != nil {
+			return err
+		}
+		return object.NewComplex(complex(re, im))
+	}
+	switch obj := args[0].(type) {
+	case *object.Complex:
+		return obj
+	case *object.Int, *object.Byte, *object.Float:
+		value, _ := object.AsComplex(obj)
+		return object.NewComplex(value)
+	case *object.String:
+		if c, err := strconv.ParseComplex(obj.Value(), 128); err == nil {
+			return object.NewComplex(c)
+		}
+		return object.Errorf("value error: invalid literal for complex(): %q", obj.Value())
+	default:
+		return object.Errorf("type error: complex() unsupported argument (%s given)", args[0].Type())
+	}
+}
+
 func Ord(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("ord", 1, args); err != nil {
 		return err
@@ -700,6 +1027,33 @@ func Ord(ctx context.Context, args ...object.Object) object.Object {
 	return object.Errorf("type error: ord() expected a string of length 1 (%s given)", args[0].Type())
 }
 
+func Chan(ctx context.Context, kwargs map[string]object.Object, args ...object.Object) object.Object {
+	if err := arg.RequireRange("chan", 0, 1, args); err != nil {
+		return err
+	}
+	sizeArg, hasSize := kwargs["size"]
+	if len(kwargs) > 1 || (len(kwargs) == 1 && !hasSize) {
+		return object.Errorf("type error: chan() accepts only the size keyword argument")
+	}
+	if hasSize && len(args) == 1 {
+		return object.Errorf("type error: chan() got multiple values for argument: size")
+	}
+	if len(args) == 1 {
+		sizeArg = args[0]
+	}
+	var size int64
+	if sizeArg != nil {
+		var err *object.Error
+		if size, err = object.AsInt(sizeArg); err != nil {
+			return err
+		}
+		if size < 0 {
+			return object.Errorf("value error: chan() size must be non-negative (%d given)", size)
+		}
+	}
+	return object.NewChan(ctx, int(size))
+}
+
 func Chr(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("chr", 1, args); err != nil {
 		return err
@@ -748,22 +1102,11 @@ func Try(ctx context.Context, args ...object.Object) object.Object {
 			if err != nil {
 				return nil, err
 			}
-			switch result := result.(type) {
-			case *object.Error:
-				return nil, result.Value()
-			default:
-				return result, nil
-			}
+			return tryResult(result)
 		case *object.Builtin:
-			result := obj.Call(ctx)
-			switch result := result.(type) {
-			case *object.Error:
-				return nil, result.Value()
-			default:
-				return result, nil
-			}
+			return tryResult(obj.Call(ctx))
 		default:
-			return obj, nil
+			return tryResult(obj)
 		}
 	}
 	for _, arg := range args {
@@ -775,48 +1118,128 @@ func Try(ctx context.Context, args ...object.Object) object.Object {
 	return object.Nil
 }
 
+// tryResult returns the error held by the given error or err result, if any.
+// Ok results are unwrapped.
+func tryResult(obj object.Object) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Error:
+		return nil, obj.Value()
+	case *object.Result:
+		if obj.IsErr() {
+			return nil, obj.Err().Value()
+		}
+		return obj.Unwrap(), nil
+	}
+	return obj, nil
+}
+
+func Ok(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("ok", 1, args); err != nil {
+		return err
+	}
+	return object.NewOkResult(args[0])
+}
+
+func Err(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("err", 1, 64, args); err != nil {
+		return err
+	}
+	switch value := args[0].(type) {
+	case *object.Error:
+		if len(args) > 1 {
+			return object.NewArgsError("err", 1, len(args))
+		}
+		return object.NewErrResult(value)
+	case *object.String:
+		var msgArgs []interface{}
+		for _, arg := range args[1:] {
+			msgArgs = append(msgArgs, arg.Interface())
+		}
+		return object.NewErrResult(object.Errorf(value.Value(), msgArgs...))
+	default:
+		return object.Errorf("type error: err() expected a string or error (%s given)", args[0].Type())
+	}
+}
+
+func Unwrap(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("unwrap", 1, args); err != nil {
+		return err
+	}
+	result, ok := args[0].(*object.Result)
+	if !ok {
+		return object.Errorf("type error: unwrap() expected a result (%s given)", args[0].Type())
+	}
+	return result.Unwrap()
+}
+
+func UnwrapOr(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("unwrap_or", 2, args); err != nil {
+		return err
+	}
+	result, ok := args[0].(*object.Result)
+	if !ok {
+		return object.Errorf("type error: unwrap_or() expected a result (%s given)", args[0].Type())
+	}
+	return result.UnwrapOr(args[1])
+}
+
 func Iter(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("iter", 1, args); err != nil {
 		return err
 	}
-	container, ok := args[0].(object.Iterable)
-	if !ok {
+	switch arg := args[0].(type) {
+	case object.Iterable:
+		return arg.Iter()
+	case object.Iterator:
+		return arg
+	default:
 		return object.Errorf("type error: iter() expected an iterable (%s given)", args[0].Type())
 	}
-	return container.Iter()
 }
 
 func Builtins() map[string]object.Object {
 	return map[string]object.Object{
-		"all":         object.NewBuiltin("all", All),
-		"any":         object.NewBuiltin("any", Any),
-		"assert":      object.NewBuiltin("assert", Assert),
-		"bool":        object.NewBuiltin("bool", Bool),
-		"buffer":      object.NewBuiltin("buffer", Buffer),
-		"byte_slice":  object.NewBuiltin("byte_slice", ByteSlice),
-		"byte":        object.NewBuiltin("byte", Byte),
-		"call":        object.NewBuiltin("call", Call),
-		"chr":         object.NewBuiltin("chr", Chr),
-		"decode":      object.NewBuiltin("decode", Decode),
-		"delete":      object.NewBuiltin("delete", Delete),
-		"encode":      object.NewBuiltin("encode", Encode),
-		"error":       object.NewBuiltin("error", Error),
-		"float_slice": object.NewBuiltin("float_slice", FloatSlice),
-		"float":       object.NewBuiltin("float", Float),
-		"getattr":     object.NewBuiltin("getattr", GetAttr),
-		"int":         object.NewBuiltin("int", Int),
-		"iter":        object.NewBuiltin("iter", Iter),
-		"keys":        object.NewBuiltin("keys", Keys),
-		"len":         object.NewBuiltin("len", Len),
-		"list":        object.NewBuiltin("list", List),
-		"map":         object.NewBuiltin("map", Map),
-		"ord":         object.NewBuiltin("ord", Ord),
-		"reversed":    object.NewBuiltin("reversed", Reversed),
-		"set":         object.NewBuiltin("set", Set),
-		"sorted":      object.NewBuiltin("sorted", Sorted),
-		"sprintf":     object.NewBuiltin("sprintf", Sprintf),
-		"string":      object.NewBuiltin("string", String),
-		"try":         object.NewBuiltin("try", Try),
-		"type":        object.NewBuiltin("type", Type),
+		"all":           object.NewBuiltin("all", All),
+		"any":           object.NewBuiltin("any", Any),
+		"assert":        object.NewBuiltin("assert", Assert),
+		"bigint":        object.NewBuiltin("bigint", BigInt),
+		"bool":          object.NewBuiltin("bool", Bool),
+		"buffer":        object.NewBuiltin("buffer", Buffer),
+		"byte_slice":    object.NewBuiltin("byte_slice", ByteSlice),
+		"byte":          object.NewBuiltin("byte", Byte),
+		"call":          object.NewBuiltin("call", Call),
+		"chan":          object.NewBuiltin("chan", nil).WithKeywords(Chan),
+		"chr":           object.NewBuiltin("chr", Chr),
+		"complex_slice": object.NewBuiltin("complex_slice", ComplexSlice),
+		"complex":       object.NewBuiltin("complex", Complex),
+		"decimal":       object.NewBuiltin("decimal", Decimal),
+		"decode":        object.NewBuiltin("decode", Decode),
+		"delete":        object.NewBuiltin("delete", Delete),
+		"encode":        object.NewBuiltin("encode", Encode),
+		"err":           object.NewBuiltin("err", Err),
+		"error":         object.NewBuiltin("error", Error),
+		"float_slice":   object.NewBuiltin("float_slice", FloatSlice),
+		"float":         object.NewBuiltin("float", Float),
+		"freeze":        object.NewBuiltin("freeze", Freeze),
+		"getattr":       object.NewBuiltin("getattr", GetAttr),
+		"int":           object.NewBuiltin("int", Int),
+		"iter":          object.NewBuiltin("iter", Iter),
+		"keys":          object.NewBuiltin("keys", Keys),
+		"len":           object.NewBuiltin("len", Len),
+		"list":          object.NewBuiltin("list", List),
+		"map":           object.NewBuiltin("map", Map),
+		"ok":            object.NewBuiltin("ok", Ok),
+		"ord":           object.NewBuiltin("ord", Ord),
+		"range":         object.NewBuiltin("range", Range),
+		"reversed":      object.NewBuiltin("reversed", Reversed),
+		"set":           object.NewBuiltin("set", Set),
+		"sorted":        object.NewBuiltin("sorted", Sorted),
+		"sprintf":       object.NewBuiltin("sprintf", Sprintf),
+		"string":        object.NewBuiltin("string", String),
+		"try":           object.NewBuiltin("try", Try),
+		"tuple":         object.NewBuiltin("tuple", Tuple),
+		"type":          object.NewBuiltin("type", Type),
+		"unwrap_or":     object.NewBuiltin("unwrap_or", UnwrapOr),
+		"unwrap":        object.NewBuiltin("unwrap", Unwrap),
 	}
 }
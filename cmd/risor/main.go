@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/risor-io/risor"
+	"github.com/risor-io/risor/disasm"
+	"github.com/risor-io/risor/errz"
+	"github.com/risor-io/risor/importer"
 	"github.com/spf13/cobra"
 )
 
@@ -51,8 +57,61 @@ func main() {
 
 	cmdVersion.Flags().StringP("output", "o", "", "Set the output format")
 
+	cmdCompile := &cobra.Command{
+		Use:   "compile [file...]",
+		Short: "Compile Risor source files to bytecode",
+		Long: `Compile each source file to a .rsc file in the same directory. Imports
+use a compiled module in place of its source when the source is unchanged.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			opts := getRisorOptions()
+			for _, path := range args {
+				source, err := os.ReadFile(path)
+				if err != nil {
+					fatal(red(err.Error()))
+				}
+				code, err := risor.Compile(ctx, string(source), opts...)
+				if err != nil {
+					if friendlyErr, ok := err.(errz.FriendlyError); ok {
+						fatal(red(friendlyErr.FriendlyErrorMessage()))
+					}
+					fatal(red(err.Error()))
+				}
+				outPath := strings.TrimSuffix(path, filepath.Ext(path)) + importer.CompiledExtension
+				if err := importer.WriteCompiled(outPath, string(source), code); err != nil {
+					fatal(red(err.Error()))
+				}
+			}
+		},
+	}
+
+	cmdDisasm := &cobra.Command{
+		Use:   "disasm [file]",
+		Short: "Show the bytecode compiled from a Risor source file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			source, err := os.ReadFile(args[0])
+			if err != nil {
+				fatal(red(err.Error()))
+			}
+			code, err := risor.Compile(context.Background(), string(source), getRisorOptions()...)
+			if err != nil {
+				if friendlyErr, ok := err.(errz.FriendlyError); ok {
+					fatal(red(friendlyErr.FriendlyErrorMessage()))
+				}
+				fatal(red(err.Error()))
+			}
+			if err := disasm.Fprint(os.Stdout, code); err != nil {
+				fatal(red(err.Error()))
+			}
+		},
+	}
+
 	rootCmd.AddCommand(cmdServe)
 	rootCmd.AddCommand(cmdVersion)
+	rootCmd.AddCommand(cmdCompile)
+	rootCmd.AddCommand(cmdDisasm)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -136,16 +136,7 @@ var rootCmd = &cobra.Command{
 		}
 
 		// Build up a list of options to pass to the VM
-		var opts []risor.Option
-		if !viper.GetBool("no-default-modules") {
-			opts = append(opts, risor.WithDefaultModules())
-		}
-		if !viper.GetBool("no-default-builtins") {
-			opts = append(opts, risor.WithDefaultBuiltins())
-		}
-		if modulesDir := viper.GetString("modules"); modulesDir != "" {
-			opts = append(opts, risor.WithLocalImporter(modulesDir))
-		}
+		opts := getRisorOptions()
 
 		// Determine what code is to be executed. The code may be supplied
 		// via the --code option, a path supplied as an arg, or stdin.
@@ -208,6 +199,22 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// getRisorOptions returns the options that configure the builtins, modules,
+// and importer, according to the global flags.
+func getRisorOptions() []risor.Option {
+	var opts []risor.Option
+	if !viper.GetBool("no-default-modules") {
+		opts = append(opts, risor.WithDefaultModules())
+	}
+	if !viper.GetBool("no-default-builtins") {
+		opts = append(opts, risor.WithDefaultBuiltins())
+	}
+	if modulesDir := viper.GetString("modules"); modulesDir != "" {
+		opts = append(opts, risor.WithLocalImporter(modulesDir))
+	}
+	return opts
+}
+
 func getOutput(result object.Object, format string) (string, error) {
 	switch strings.ToLower(format) {
 	case "":
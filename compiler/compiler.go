@@ -39,6 +39,38 @@ type Compiler struct {
 
 	// Built in objects available to the code being compiled
 	builtins map[string]object.Object
+
+	// Try statements that are currently being compiled, innermost last
+	tries []*tryBlock
+
+	// Source position of the AST node currently being compiled
+	position token.Position
+
+	// Set when the code is to be optimized
+	optimize bool
+
+	// Indexes of the constants of each code object, used to share duplicate
+	// constants when optimizing
+	constants map[*object.Code]map[constantKey]uint16
+
+	// Nil check jumps of the attribute, call and index chain whose receiver
+	// is being compiled. These jump to the end of the whole chain.
+	nilChain *[]int
+}
+
+// tryBlock tracks a try statement whose exception handler is active at the
+// current compilation position. Statements that exit the try statement early,
+// such as return or break, use this to pop the handler and run the finally
+// block before leaving.
+type tryBlock struct {
+	// The code object the try statement belongs to
+	code *object.Code
+
+	// Number of enclosing loops at the start of the try statement
+	loopDepth int
+
+	// Optional finally block to run when leaving the try statement
+	finally *ast.Block
 }
 
 // Option is a configuration function for a Compiler.
@@ -58,6 +90,16 @@ func WithCode(code *object.Code) Option {
 	}
 }
 
+// WithOptimizations configures the compiler to optimize the code it compiles.
+// Constant expressions are folded, duplicate constants are shared, jumps to
+// jumps are threaded, unreachable code is removed, and common sequences of
+// instructions are combined into superinstructions.
+func WithOptimizations() Option {
+	return func(c *Compiler) {
+		c.optimize = true
+	}
+}
+
 // Compile the given AST node and return the compiled code object. This is a
 // shorthand for compiler.New(options).Compile(node).
 func Compile(node ast.Node, options ...Option) (*object.Code, error) {
@@ -71,7 +113,7 @@ func Compile(node ast.Node, options ...Option) (*object.Code, error) {
 // New creates and returns a new Compiler. Any supplied options are used to
 // configure the compilation process.
 func New(options ...Option) (*Compiler, error) {
-	c := &Compiler{}
+	c := &Compiler{constants: map[*object.Code]map[constantKey]uint16{}}
 	for _, opt := range options {
 		opt(c)
 	}
@@ -108,6 +150,7 @@ func (c *Compiler) CurrentInstructions() []op.Code {
 // Compile the given AST node and return the compiled code object.
 func (c *Compiler) Compile(node ast.Node) (*object.Code, error) {
 	c.failure = nil
+	start := len(c.main.Instructions)
 	if err := c.compile(node); err != nil {
 		return nil, err
 	}
@@ -116,11 +159,25 @@ func (c *Compiler) Compile(node ast.Node) (*object.Code, error) {
 	if c.failure != nil {
 		return nil, c.failure
 	}
+	// Only the newly compiled instructions are optimized, since code that
+	// was compiled previously may already be running, as in the REPL
+	if c.optimize {
+		if err := optimizeCode(c.main, start); err != nil {
+			return nil, err
+		}
+	}
 	return c.main, nil
 }
 
 // compile the given AST node and all its children.
 func (c *Compiler) compile(node ast.Node) error {
+	// Attribute instructions emitted for this node to its source position,
+	// then restore the position of the enclosing node when done.
+	if tok := node.Token(); tok.Type != "" {
+		prevPosition := c.position
+		c.position = tok.StartPosition
+		defer func() { c.position = prevPosition }()
+	}
 	switch node := node.(type) {
 	case *ast.Nil:
 		if err := c.compileNil(node); err != nil {
@@ -134,6 +191,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileFloat(node); err != nil {
 			return err
 		}
+	case *ast.Imaginary:
+		if err := c.compileImaginary(node); err != nil {
+			return err
+		}
 	case *ast.String:
 		if err := c.compileString(node); err != nil {
 			return err
@@ -190,6 +251,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileList(node); err != nil {
 			return err
 		}
+	case *ast.Tuple:
+		if err := c.compileTuple(node); err != nil {
+			return err
+		}
 	case *ast.Map:
 		if err := c.compileMap(node); err != nil {
 			return err
@@ -198,6 +263,18 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileSet(node); err != nil {
 			return err
 		}
+	case *ast.ListComprehension:
+		if err := c.compileListComprehension(node); err != nil {
+			return err
+		}
+	case *ast.MapComprehension:
+		if err := c.compileMapComprehension(node); err != nil {
+			return err
+		}
+	case *ast.SetComprehension:
+		if err := c.compileSetComprehension(node); err != nil {
+			return err
+		}
 	case *ast.Index:
 		if err := c.compileIndex(node); err != nil {
 			return err
@@ -210,6 +287,12 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileObjectCall(node); err != nil {
 			return err
 		}
+	case *ast.Group:
+		// The group starts a new nil-safe chain, as it isn't a receiver
+		// that continues the chain outside of it
+		if err := c.compile(node.Expression()); err != nil {
+			return err
+		}
 	case *ast.Prefix:
 		if err := c.compilePrefix(node); err != nil {
 			return err
@@ -234,6 +317,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileTernary(node); err != nil {
 			return err
 		}
+	case *ast.Propagate:
+		if err := c.compilePropagate(node); err != nil {
+			return err
+		}
 	case *ast.Range:
 		if err := c.compileRange(node); err != nil {
 			return err
@@ -254,6 +341,54 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileMultiVar(node); err != nil {
 			return err
 		}
+	case *ast.Destructure:
+		if err := c.compileDestructure(node); err != nil {
+			return err
+		}
+	case *ast.Try:
+		if err := c.compileTry(node); err != nil {
+			return err
+		}
+	case *ast.Throw:
+		if err := c.compileThrow(node); err != nil {
+			return err
+		}
+	case *ast.Yield:
+		if err := c.compileYield(node); err != nil {
+			return err
+		}
+	case *ast.Defer:
+		if err := c.compileDefer(node); err != nil {
+			return err
+		}
+	case *ast.Go:
+		if err := c.compileGo(node); err != nil {
+			return err
+		}
+	case *ast.Receive:
+		if err := c.compileReceive(node); err != nil {
+			return err
+		}
+	case *ast.Send:
+		if err := c.compileSend(node); err != nil {
+			return err
+		}
+	case *ast.Select:
+		if err := c.compileSelect(node); err != nil {
+			return err
+		}
+	case *ast.Struct:
+		if err := c.compileStruct(node); err != nil {
+			return err
+		}
+	case *ast.Method:
+		if err := c.compileMethod(node); err != nil {
+			return err
+		}
+	case *ast.StructLiteral:
+		if err := c.compileStructLiteral(node); err != nil {
+			return err
+		}
 	default:
 		panic(fmt.Sprintf("unknown ast node type: %T", node))
 	}
@@ -284,6 +419,70 @@ func (c *Compiler) currentLoop() *object.Loop {
 	return loops[len(loops)-1]
 }
 
+// startTry should be called when starting to compile a region of code that is
+// protected by an exception handler.
+func (c *Compiler) startTry(finally *ast.Block) {
+	c.tries = append(c.tries, &tryBlock{
+		code:      c.current,
+		loopDepth: len(c.current.Loops),
+		finally:   finally,
+	})
+}
+
+// endTry should be called when the compilation of a protected region is done.
+func (c *Compiler) endTry() {
+	c.tries = c.tries[:len(c.tries)-1]
+}
+
+// exitTries emits code to leave the try statements in the current code object
+// that were started within the given loop depth. For each one, the exception
+// handler is popped and then the finally block, if any, is run.
+func (c *Compiler) exitTries(loopDepth int) error {
+	tries := c.tries
+	defer func() { c.tries = tries }()
+	for i := len(tries) - 1; i >= 0; i-- {
+		t := tries[i]
+		if t.code != c.current || t.loopDepth < loopDepth {
+			break
+		}
+		c.emit(op.PopTry)
+		if t.finally == nil {
+			continue
+		}
+		// The finally block runs outside of this try statement
+		c.tries = append([]*tryBlock(nil), tries[:i]...)
+		if err := c.compile(t.finally); err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+	}
+	return nil
+}
+
+// handler adds an exception handler to the exception table of the current
+// code object and returns its index.
+func (c *Compiler) handler() (*object.ExceptionHandler, uint16) {
+	code := c.current
+	if len(code.Handlers) >= math.MaxUint16 {
+		c.failure = fmt.Errorf("number of exception handlers exceeded limits")
+		return &object.ExceptionHandler{}, 0
+	}
+	h := &object.ExceptionHandler{}
+	code.Handlers = append(code.Handlers, h)
+	return h, uint16(len(code.Handlers) - 1)
+}
+
+// pattern adds a pattern to the current code object and returns its index,
+// which is the operand of the MatchPattern instruction.
+func (c *Compiler) pattern(p *object.Pattern) (uint16, error) {
+	code := c.current
+	if len(code.Patterns) >= math.MaxUint16 {
+		return 0, fmt.Errorf("number of patterns exceeded limits")
+	}
+	code.Patterns = append(code.Patterns, p)
+	return uint16(len(code.Patterns) - 1), nil
+}
+
 func (c *Compiler) currentPosition() int {
 	return len(c.CurrentInstructions())
 }
@@ -303,6 +502,11 @@ func (c *Compiler) compileFloat(node *ast.Float) error {
 	return nil
 }
 
+func (c *Compiler) compileImaginary(node *ast.Imaginary) error {
+	c.emit(op.LoadConst, c.constant(object.NewComplex(complex(0, node.Value()))))
+	return nil
+}
+
 func (c *Compiler) compileBool(node *ast.Bool) error {
 	if node.Value() {
 		c.emit(op.True)
@@ -444,81 +648,134 @@ func (c *Compiler) compileMultiVar(node *ast.MultiVar) error {
 		case object.ScopeLocal:
 			c.emit(op.StoreFast, sym.Index)
 		case object.ScopeFree:
-			c.emit(op.StoreFree, sym.Index)
+			c.emit(op.StoreFree, uint16(resolution.FreeIndex))
 		}
 	}
 	return nil
 }
 
-func (c *Compiler) compileSwitch(node *ast.Switch) error {
-	// Compile the switch expression
+func (c *Compiler) compileDestructure(node *ast.Destructure) error {
 	if err := c.compile(node.Value()); err != nil {
 		return err
 	}
+	targets := node.Targets()
+	var pattern *object.Pattern
+	var err error
+	if node.Rest() == nil && len(targets) == 1 {
+		pattern, err = c.buildTarget(targets[0])
+	} else {
+		pattern, err = c.buildTarget(ast.NewListPattern(node.Token(), targets, node.Rest(), node.RestIndex()))
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkPatternNames(pattern); err != nil {
+		return err
+	}
+	return c.storePattern(pattern)
+}
 
-	choices := node.Choices()
-
-	// Emit jump positions for each case
-	var caseJumpPositions []int
-	defaultJumpPos := -1
-
-	for i, choice := range choices {
-		if choice.IsDefault() {
-			defaultJumpPos = i
-			continue
+// buildTarget converts a pattern node on the left side of a declaration or
+// for loop to the pattern object used by the VM. Only names, the "_" wildcard,
+// and list and map patterns may be used as targets.
+func (c *Compiler) buildTarget(node ast.Expression) (*object.Pattern, error) {
+	var check func(node ast.Expression) error
+	check = func(node ast.Expression) error {
+		var items []ast.Expression
+		switch node := node.(type) {
+		case *ast.CapturePattern:
+			return nil
+		case *ast.ListPattern:
+			items = node.Items()
+		case *ast.MapPattern:
+			items = node.Values()
+		default:
+			return fmt.Errorf("invalid assignment target: %s", node)
 		}
-		for _, expr := range choice.Expressions() {
-			// Duplicate the switch value for each case comparison
-			c.emit(op.Copy, 0)
-			// Compile the case expression
-			if err := c.compile(expr); err != nil {
+		for _, item := range items {
+			if err := check(item); err != nil {
 				return err
 			}
-			// Emit the CompareOp equal comparison
-			c.emit(op.CompareOp, uint16(op.Equal))
-			// Emit PopJumpForwardIfTrue and store its position
-			jumpPos := c.emit(op.PopJumpForwardIfTrue, Placeholder)
-			caseJumpPositions = append(caseJumpPositions, jumpPos)
 		}
+		return nil
 	}
+	if err := check(node); err != nil {
+		return nil, err
+	}
+	return c.buildPattern(node)
+}
 
-	jumpDefaultPos := c.emit(op.JumpForward, Placeholder)
-
-	// Update case jump positions and compile case blocks
-	var offset int
-	var endBlockPosits []int
-	for i, choice := range choices {
-		if i == defaultJumpPos {
-			continue
+// storePattern unpacks the value on the top of the stack using the given
+// pattern, consuming the value. A new variable is declared in the current
+// scope for each name in the pattern.
+func (c *Compiler) storePattern(pattern *object.Pattern) error {
+	switch pattern.Kind {
+	case object.PatternWildcard:
+		c.emit(op.PopTop)
+		return nil
+	case object.PatternList, object.PatternMap:
+		index, err := c.pattern(pattern)
+		if err != nil {
+			return err
 		}
-		for range choice.Expressions() {
-			delta, err := c.calculateDelta(caseJumpPositions[offset])
-			if err != nil {
-				return err
-			}
-			c.changeOperand(caseJumpPositions[offset], delta)
-			offset++
+		c.emit(op.UnpackPattern, index)
+	}
+	code := c.current
+	names := pattern.Names()
+	for i := len(names) - 1; i >= 0; i-- {
+		sym, err := code.Symbols.InsertVariable(names[i])
+		if err != nil {
+			return err
 		}
-		if choice.Block() == nil {
-			// Empty case block
-			c.emit(op.Nil)
+		if code.Symbols.IsGlobal() {
+			c.emit(op.StoreGlobal, sym.Index)
 		} else {
-			if err := c.compile(choice.Block()); err != nil {
-				return err
+			c.emit(op.StoreFast, sym.Index)
+		}
+	}
+	return nil
+}
+
+// checkPatternNames returns an error if a name is bound more than once by the
+// given patterns.
+func checkPatternNames(patterns ...*object.Pattern) error {
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		for _, name := range pattern.Names() {
+			if seen[name] {
+				return fmt.Errorf("duplicate name in pattern: %s", name)
 			}
+			seen[name] = true
 		}
-		endBlockPosits = append(endBlockPosits, c.emit(op.JumpForward, Placeholder))
 	}
+	return nil
+}
 
-	delta, err := c.calculateDelta(jumpDefaultPos)
-	if err != nil {
+func (c *Compiler) compileSwitch(node *ast.Switch) error {
+	// Compile the switch expression
+	if err := c.compile(node.Value()); err != nil {
 		return err
 	}
-	c.changeOperand(jumpDefaultPos, delta)
+
+	// Try each case in order, jumping to the end after running the block of
+	// the first case that matches
+	var defaultCase *ast.Case
+	var endBlockPosits []int
+	for _, choice := range node.Choices() {
+		if choice.IsDefault() {
+			defaultCase = choice
+			continue
+		}
+		endPos, err := c.compileCase(choice)
+		if err != nil {
+			return err
+		}
+		endBlockPosits = append(endBlockPosits, endPos)
+	}
 
 	// Compile the default case block if it exists
-	if defaultJumpPos != -1 {
-		if err := c.compile(choices[defaultJumpPos].Block()); err != nil {
+	if defaultCase != nil && defaultCase.Block() != nil {
+		if err := c.compile(defaultCase.Block()); err != nil {
 			return err
 		}
 	} else {
@@ -526,12 +783,8 @@ func (c *Compiler) compileSwitch(node *ast.Switch) error {
 	}
 
 	// Update end block jump positions
-	for _, pos := range endBlockPosits {
-		delta, err := c.calculateDelta(pos)
-		if err != nil {
-			return err
-		}
-		c.changeOperand(pos, delta)
+	if err := c.patchJumps(endBlockPosits); err != nil {
+		return err
 	}
 
 	c.emit(op.Swap, 1)
@@ -541,74 +794,681 @@ func (c *Compiler) compileSwitch(node *ast.Switch) error {
 	return nil
 }
 
-func (c *Compiler) compileImport(node *ast.Import) error {
-	name := node.Module().String()
-	c.emit(op.LoadConst, c.constant(object.NewString(name)))
-	c.emit(op.Import)
-	sym, err := c.current.Symbols.InsertConstant(name)
-	if err != nil {
-		return err
+// compileCase compiles one case of a switch statement. The switch value must
+// be on the top of the stack, and it is left there. If the case matches, its
+// block runs and then execution jumps forward. Returns the position of that
+// jump, so that the caller can patch it to the end of the switch. Otherwise
+// execution continues with the code following the case.
+func (c *Compiler) compileCase(choice *ast.Case) (int, error) {
+	// Names bound by patterns are visible in the guard and the case block
+	code := c.current
+	code.Symbols = code.Symbols.NewBlock()
+	defer func() {
+		code.Symbols = code.Symbols.Parent()
+	}()
+
+	var matchJumps []int
+	for _, expr := range choice.Expressions() {
+		// Duplicate the switch value for each case comparison
+		c.emit(op.Copy, 0)
+		pattern, ok := expr.(ast.Pattern)
+		if !ok {
+			// Compare the switch value with the case expression
+			if err := c.compile(expr); err != nil {
+				return 0, err
+			}
+			c.emit(op.CompareOp, uint16(op.Equal))
+			matchJumps = append(matchJumps, c.emit(op.PopJumpForwardIfTrue, Placeholder))
+			continue
+		}
+		// Match the switch value against the pattern. On success, the
+		// captured values are pushed, which are then stored in reverse order.
+		failPos, err := c.compileMatch(pattern)
+		if err != nil {
+			return 0, err
+		}
+		matchJumps = append(matchJumps, c.emit(op.JumpForward, Placeholder))
+		if err := c.patchJumps([]int{failPos}); err != nil {
+			return 0, err
+		}
 	}
-	if c.current.Parent == nil {
-		c.emit(op.StoreGlobal, sym.Index)
-	} else {
-		c.emit(op.StoreFast, sym.Index)
+	nextCaseJumps := []int{c.emit(op.JumpForward, Placeholder)}
+	if err := c.patchJumps(matchJumps); err != nil {
+		return 0, err
 	}
-	return nil
-}
 
-func (c *Compiler) compileSlice(node *ast.Slice) error {
-	if err := c.compile(node.Left()); err != nil {
-		return err
-	}
-	to := node.ToIndex()
-	if to == nil {
-		c.emit(op.Copy, 0)
-		c.emit(op.Length)
-	} else {
-		if err := c.compile(to); err != nil {
-			return err
+	// The case only matches if the guard is also true
+	if guard := choice.Guard(); guard != nil {
+		if err := c.compile(guard); err != nil {
+			return 0, err
 		}
+		nextCaseJumps = append(nextCaseJumps, c.emit(op.PopJumpForwardIfFalse, Placeholder))
 	}
-	from := node.FromIndex()
-	if from == nil {
-		c.emit(op.LoadConst, c.constant(object.NewInt(0)))
+
+	if choice.Block() == nil {
+		// Empty case block
+		c.emit(op.Nil)
 	} else {
-		if err := c.compile(from); err != nil {
-			return err
+		if err := c.compile(choice.Block()); err != nil {
+			return 0, err
 		}
 	}
-	c.emit(op.Slice)
-	return nil
+	endPos := c.emit(op.JumpForward, Placeholder)
+	if err := c.patchJumps(nextCaseJumps); err != nil {
+		return 0, err
+	}
+	return endPos, nil
 }
 
-func (c *Compiler) compileRange(node *ast.Range) error {
-	if err := c.compile(node.Container()); err != nil {
-		return err
+// compileMatch matches the value on the top of the stack against the given
+// pattern, consuming the value. If it matches, the captured values are stored
+// in the variables named by the pattern. Otherwise, execution jumps forward
+// from the returned position, which the caller must patch.
+func (c *Compiler) compileMatch(node ast.Pattern) (int, error) {
+	pattern, err := c.buildPattern(node)
+	if err != nil {
+		return 0, err
 	}
-	c.emit(op.Range)
-	return nil
+	if err := checkPatternNames(pattern); err != nil {
+		return 0, err
+	}
+	names := pattern.Names()
+	index, err := c.pattern(pattern)
+	if err != nil {
+		return 0, err
+	}
+	c.emit(op.MatchPattern, index)
+	failPos := c.emit(op.PopJumpForwardIfFalse, Placeholder)
+	code := c.current
+	for i := len(names) - 1; i >= 0; i-- {
+		// Alternative patterns in the same case may bind the same name
+		sym, ok := code.Symbols.Get(names[i])
+		if !ok || !code.Symbols.IsVariable(names[i]) {
+			if sym, err = code.Symbols.InsertVariable(names[i]); err != nil {
+				return 0, err
+			}
+		}
+		if code.Symbols.IsGlobal() {
+			c.emit(op.StoreGlobal, sym.Index)
+		} else {
+			c.emit(op.StoreFast, sym.Index)
+		}
+	}
+	return failPos, nil
 }
 
-func (c *Compiler) compileTernary(node *ast.Ternary) error {
-	// evaluate the condition and then conditionally jump to the false case
-	if err := c.compile(node.Condition()); err != nil {
-		return err
+// buildPattern converts a pattern node to the pattern object used by the VM.
+// Literal values within the pattern become value patterns.
+func (c *Compiler) buildPattern(node ast.Expression) (*object.Pattern, error) {
+	switch node := node.(type) {
+	case *ast.CapturePattern:
+		if node.IsWildcard() {
+			return &object.Pattern{Kind: object.PatternWildcard}, nil
+		}
+		return &object.Pattern{Kind: object.PatternCapture, Name: node.Name()}, nil
+	case *ast.ListPattern:
+		items, err := c.buildPatterns(node.Items())
+		if err != nil {
+			return nil, err
+		}
+		pattern := &object.Pattern{Kind: object.PatternList, Items: items, RestIndex: -1}
+		if rest := node.Rest(); rest != nil {
+			pattern.RestIndex = node.RestIndex()
+			if name := rest.Literal(); name != "_" {
+				pattern.RestName = name
+			}
+		}
+		return pattern, nil
+	case *ast.MapPattern:
+		items, err := c.buildPatterns(node.Values())
+		if err != nil {
+			return nil, err
+		}
+		return &object.Pattern{Kind: object.PatternMap, Keys: node.Keys(), Items: items}, nil
+	case *ast.TypePattern:
+		item, err := c.buildPattern(node.Value())
+		if err != nil {
+			return nil, err
+		}
+		return &object.Pattern{
+			Kind:  object.PatternType,
+			Type:  object.Type(node.TypeName()),
+			Items: []*object.Pattern{item},
+		}, nil
+	case *ast.Int:
+		return &object.Pattern{Kind: object.PatternValue, Value: object.NewInt(node.Value())}, nil
+	case *ast.Float:
+		return &object.Pattern{Kind: object.PatternValue, Value: object.NewFloat(node.Value())}, nil
+	case *ast.String:
+		return &object.Pattern{Kind: object.PatternValue, Value: object.NewString(node.Value())}, nil
+	case *ast.Bool:
+		return &object.Pattern{Kind: object.PatternValue, Value: object.NewBool(node.Value())}, nil
+	case *ast.Nil:
+		return &object.Pattern{Kind: object.PatternValue, Value: object.Nil}, nil
+	case *ast.Prefix:
+		switch right := node.Right().(type) {
+		case *ast.Int:
+			return &object.Pattern{Kind: object.PatternValue, Value: object.NewInt(-right.Value())}, nil
+		case *ast.Float:
+			return &object.Pattern{Kind: object.PatternValue, Value: object.NewFloat(-right.Value())}, nil
+		}
 	}
-	jumpIfFalsePos := c.emit(op.PopJumpForwardIfFalse, Placeholder)
+	return nil, fmt.Errorf("invalid pattern: %s", node)
+}
 
-	// true case execution, then jump over false case
-	if err := c.compile(node.IfTrue()); err != nil {
-		return err
+func (c *Compiler) buildPatterns(nodes []ast.Expression) ([]*object.Pattern, error) {
+	patterns := make([]*object.Pattern, 0, len(nodes))
+	for _, node := range nodes {
+		pattern, err := c.buildPattern(node)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
 	}
-	trueCaseEndPos := c.emit(op.JumpForward, Placeholder)
+	return patterns, nil
+}
 
-	// set the jump amount to reach the beginning of the false case
-	falseCaseDelta, err := c.calculateDelta(jumpIfFalsePos)
-	if err != nil {
+func (c *Compiler) compileTry(node *ast.Try) error {
+	code := c.current
+	catchBlock := node.CatchBlock()
+	finallyBlock := node.FinallyBlock()
+
+	// Jumps to the end of the try statement, patched when the end is known
+	var endJumps []int
+
+	// Compile the body, protected by an exception handler. If the body
+	// completes without error, run the finally block and jump to the end.
+	bodyHandler, bodyHandlerIndex := c.handler()
+	bodyHandler.Start = c.emit(op.SetupTry, bodyHandlerIndex)
+	c.startTry(finallyBlock)
+	if err := c.compile(node.Body()); err != nil {
 		return err
 	}
-	c.changeOperand(jumpIfFalsePos, falseCaseDelta)
+	c.emit(op.PopTop)
+	c.endTry()
+	c.emit(op.PopTry)
+	bodyHandler.End = c.currentPosition()
+	if finallyBlock != nil {
+		if err := c.compile(finallyBlock); err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+	}
+	endJumps = append(endJumps, c.emit(op.JumpForward, Placeholder))
+
+	// This handler is used to run the finally block when an error is raised
+	// that isn't caught. This is the body handler if there is no catch block.
+	rethrowHandler := bodyHandler
+
+	// Compile the catch block. When it is entered, the error is TOS.
+	if catchBlock != nil {
+		bodyHandler.Target = c.currentPosition()
+		code.Symbols = code.Symbols.NewBlock()
+		if ident := node.CatchIdent(); ident != nil {
+			sym, err := code.Symbols.InsertVariable(ident.Literal())
+			if err != nil {
+				code.Symbols = code.Symbols.Parent()
+				return err
+			}
+			if code.Symbols.IsGlobal() {
+				c.emit(op.StoreGlobal, sym.Index)
+			} else {
+				c.emit(op.StoreFast, sym.Index)
+			}
+		} else {
+			c.emit(op.PopTop)
+		}
+		var catchHandler *object.ExceptionHandler
+		if finallyBlock != nil {
+			var catchHandlerIndex uint16
+			catchHandler, catchHandlerIndex = c.handler()
+			catchHandler.Start = c.emit(op.SetupTry, catchHandlerIndex)
+			c.startTry(finallyBlock)
+		}
+		err := c.compile(catchBlock)
+		code.Symbols = code.Symbols.Parent()
+		if err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+		if catchHandler != nil {
+			c.endTry()
+			c.emit(op.PopTry)
+			catchHandler.End = c.currentPosition()
+			if err := c.compile(finallyBlock); err != nil {
+				return err
+			}
+			c.emit(op.PopTop)
+			endJumps = append(endJumps, c.emit(op.JumpForward, Placeholder))
+		}
+		rethrowHandler = catchHandler
+	}
+
+	// Run the finally block for an uncaught error and then raise it again
+	if finallyBlock != nil {
+		rethrowHandler.Target = c.currentPosition()
+		if err := c.compile(finallyBlock); err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+		c.emit(op.Throw)
+	}
+
+	for _, pos := range endJumps {
+		delta, err := c.calculateDelta(pos)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(pos, delta)
+	}
+	return nil
+}
+
+func (c *Compiler) compileThrow(node *ast.Throw) error {
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	c.emit(op.Throw)
+	return nil
+}
+
+func (c *Compiler) compileYield(node *ast.Yield) error {
+	if c.current.Parent == nil {
+		return fmt.Errorf("yield outside of function")
+	}
+	// Any function containing a yield statement is a generator function
+	c.current.IsGenerator = true
+	if value := node.Value(); value != nil {
+		if err := c.compile(value); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.Nil)
+	}
+	c.emit(op.Yield)
+	return nil
+}
+
+func (c *Compiler) compileDefer(node *ast.Defer) error {
+	// The function and its arguments are evaluated now, while the call itself
+	// is deferred until the current frame exits.
+	argc, err := c.compileCallOperands(node.Call())
+	if err != nil {
+		return err
+	}
+	c.emit(op.Defer, argc)
+	return nil
+}
+
+func (c *Compiler) compileGo(node *ast.Go) error {
+	// The function and its arguments are evaluated now, then the function is
+	// called in a new goroutine.
+	argc, err := c.compileCallOperands(node.Call())
+	if err != nil {
+		return err
+	}
+	c.emit(op.Go, argc)
+	return nil
+}
+
+// compileCallOperands compiles the function and arguments of the given call
+// expression, without emitting the call itself. The function is pushed onto
+// the stack first, followed by each argument. Returns the argument count.
+// If the call uses keyword arguments or spreads, the function and arguments
+// are combined into a partial, which then takes no further arguments.
+func (c *Compiler) compileCallOperands(node ast.Expression) (uint16, error) {
+	var args []ast.Node
+	switch call := node.(type) {
+	case *ast.Call:
+		if err := c.compile(call.Function()); err != nil {
+			return 0, err
+		}
+		args = call.Arguments()
+	case *ast.ObjectCall:
+		method, ok := call.Call().(*ast.Call)
+		if !ok {
+			return 0, fmt.Errorf("invalid call expression")
+		}
+		if call.IsOptional() {
+			return 0, fmt.Errorf("optional method calls are not supported here: %s", call)
+		}
+		if err := c.compile(call.Object()); err != nil {
+			return 0, err
+		}
+		c.emit(op.LoadAttr, c.current.AddName(method.Function().String()))
+		args = method.Arguments()
+	default:
+		return 0, fmt.Errorf("invalid call expression")
+	}
+	argc, extended, err := c.compileArguments(args)
+	if err != nil {
+		return 0, err
+	}
+	if extended {
+		c.emit(op.PartialEx)
+		return 0, nil
+	}
+	return argc, nil
+}
+
+// compileArguments compiles the arguments of a function call. When all the
+// arguments are plain positional arguments, each is pushed onto the stack and
+// the argument count is returned. Otherwise, a list of the positional
+// arguments (with any spreads expanded) and a map of the keyword arguments
+// are pushed, and extended is true. Extended calls use CallEx or PartialEx.
+func (c *Compiler) compileArguments(args []ast.Node) (argc uint16, extended bool, err error) {
+	var kwargs []*ast.KeywordArg
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *ast.KeywordArg:
+			kwargs = append(kwargs, arg)
+			extended = true
+		case *ast.Spread:
+			extended = true
+		}
+	}
+	if !extended {
+		if len(args) > MaxArgs {
+			return 0, false, fmt.Errorf("max arguments limit of %d exceeded (got %d)", MaxArgs, len(args))
+		}
+		for _, arg := range args {
+			if err := c.compile(arg); err != nil {
+				return 0, false, err
+			}
+		}
+		return uint16(len(args)), false, nil
+	}
+	// Build the list of positional arguments. Consecutive plain arguments are
+	// collected with BuildList, and each list or spread after the first is
+	// appended to the first using ListExtend.
+	var pending uint16
+	started := false
+	flush := func() {
+		if pending == 0 && started {
+			return
+		}
+		c.emit(op.BuildList, pending)
+		if started {
+			c.emit(op.ListExtend)
+		}
+		started = true
+		pending = 0
+	}
+	for _, arg := range args[:len(args)-len(kwargs)] {
+		if spread, ok := arg.(*ast.Spread); ok {
+			flush()
+			if err := c.compile(spread.Value()); err != nil {
+				return 0, false, err
+			}
+			c.emit(op.ListExtend)
+			continue
+		}
+		if err := c.compile(arg); err != nil {
+			return 0, false, err
+		}
+		pending++
+	}
+	flush()
+	// Build the map of keyword arguments
+	for _, kwarg := range kwargs {
+		c.emit(op.LoadConst, c.constant(object.NewString(kwarg.Name())))
+		if err := c.compile(kwarg.Value()); err != nil {
+			return 0, false, err
+		}
+	}
+	c.emit(op.BuildMap, uint16(len(kwargs)))
+	return 0, true, nil
+}
+
+func (c *Compiler) compileReceive(node *ast.Receive) error {
+	if err := c.compile(node.Channel()); err != nil {
+		return err
+	}
+	c.emit(op.Receive)
+	return nil
+}
+
+func (c *Compiler) compileSend(node *ast.Send) error {
+	if err := c.compile(node.Channel()); err != nil {
+		return err
+	}
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	c.emit(op.Send)
+	// A send expression evaluates to nil
+	c.emit(op.Nil)
+	return nil
+}
+
+func (c *Compiler) compileSelect(node *ast.Select) error {
+	// Push the operands of each channel operation onto the stack. Each case
+	// is described by three values: the channel, the value to send (nil when
+	// receiving), and a boolean that is true for send operations.
+	var cases []*ast.SelectCase
+	var defaultCase *ast.SelectCase
+	for _, choice := range node.Cases() {
+		if choice.IsDefault() {
+			defaultCase = choice
+			continue
+		}
+		switch comm := choice.Comm().(type) {
+		case *ast.Receive:
+			if err := c.compile(comm.Channel()); err != nil {
+				return err
+			}
+			c.emit(op.Nil)
+			c.emit(op.False)
+		case *ast.Send:
+			if err := c.compile(comm.Channel()); err != nil {
+				return err
+			}
+			if err := c.compile(comm.Value()); err != nil {
+				return err
+			}
+			c.emit(op.True)
+		default:
+			return fmt.Errorf("invalid select case")
+		}
+		cases = append(cases, choice)
+	}
+	if len(cases) > math.MaxUint16 {
+		return fmt.Errorf("select case limit exceeded")
+	}
+	var hasDefault uint16
+	if defaultCase != nil {
+		hasDefault = 1
+	}
+
+	// The select instruction leaves the received value (or nil) and the index
+	// of the chosen case on the stack. The index is -1 for the default case.
+	c.emit(op.Select, uint16(len(cases)), hasDefault)
+
+	// Emit a jump to the block of each case, based on the chosen index
+	caseJumpPositions := make([]int, len(cases))
+	for i := range cases {
+		c.emit(op.Copy, 0)
+		c.emit(op.LoadConst, c.constant(object.NewInt(int64(i))))
+		c.emit(op.CompareOp, uint16(op.Equal))
+		caseJumpPositions[i] = c.emit(op.PopJumpForwardIfTrue, Placeholder)
+	}
+
+	// With no case matched, fall through to the default case
+	c.emit(op.PopTop)
+	c.emit(op.PopTop)
+	if defaultCase != nil && defaultCase.Block() != nil {
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.Nil)
+	}
+	endBlockPosits := []int{c.emit(op.JumpForward, Placeholder)}
+
+	// Compile the block of each case
+	for i, choice := range cases {
+		delta, err := c.calculateDelta(caseJumpPositions[i])
+		if err != nil {
+			return err
+		}
+		c.changeOperand(caseJumpPositions[i], delta)
+		if err := c.compileSelectCase(choice); err != nil {
+			return err
+		}
+		endBlockPosits = append(endBlockPosits, c.emit(op.JumpForward, Placeholder))
+	}
+
+	// Update end block jump positions
+	for _, pos := range endBlockPosits {
+		delta, err := c.calculateDelta(pos)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(pos, delta)
+	}
+	return nil
+}
+
+func (c *Compiler) compileSelectCase(choice *ast.SelectCase) error {
+	// Pop the case index, leaving the received value on the stack
+	c.emit(op.PopTop)
+	ident := choice.Ident()
+	if ident == nil {
+		c.emit(op.PopTop)
+		if choice.Block() == nil {
+			c.emit(op.Nil)
+			return nil
+		}
+		return c.compile(choice.Block())
+	}
+	// Bind the received value to the variable in a new block scope
+	code := c.current
+	code.Symbols = code.Symbols.NewBlock()
+	defer func() {
+		code.Symbols = code.Symbols.Parent()
+	}()
+	sym, err := code.Symbols.InsertVariable(ident.Literal())
+	if err != nil {
+		return err
+	}
+	if code.Symbols.IsGlobal() {
+		c.emit(op.StoreGlobal, sym.Index)
+	} else {
+		c.emit(op.StoreFast, sym.Index)
+	}
+	if choice.Block() == nil {
+		c.emit(op.Nil)
+		return nil
+	}
+	return c.compile(choice.Block())
+}
+
+func (c *Compiler) compileImport(node *ast.Import) error {
+	name := node.Module().String()
+	c.emit(op.LoadConst, c.constant(object.NewString(name)))
+	c.emit(op.Import)
+	sym, err := c.current.Symbols.InsertConstant(name)
+	if err != nil {
+		return err
+	}
+	if c.current.Parent == nil {
+		c.emit(op.StoreGlobal, sym.Index)
+	} else {
+		c.emit(op.StoreFast, sym.Index)
+	}
+	return nil
+}
+
+func (c *Compiler) compileSlice(node *ast.Slice) error {
+	if err := c.compile(node.Left()); err != nil {
+		return err
+	}
+	if node.Step() != nil {
+		return c.compileStepSlice(node)
+	}
+	to := node.ToIndex()
+	if to == nil {
+		c.emit(op.Copy, 0)
+		c.emit(op.Length)
+	} else {
+		if err := c.compile(to); err != nil {
+			return err
+		}
+	}
+	from := node.FromIndex()
+	if from == nil {
+		c.emit(op.LoadConst, c.constant(object.NewInt(0)))
+	} else {
+		if err := c.compile(from); err != nil {
+			return err
+		}
+	}
+	c.emit(op.Slice, 0)
+	return nil
+}
+
+// compileStepSlice compiles a slice with a step, e.g. x[::-1]. The default
+// start and stop indices depend on the sign of the step, so any that are
+// missing are passed to the Slice opcode as nil.
+func (c *Compiler) compileStepSlice(node *ast.Slice) error {
+	for _, index := range []ast.Expression{node.ToIndex(), node.FromIndex(), node.Step()} {
+		if index == nil {
+			c.emit(op.Nil)
+			continue
+		}
+		if err := c.compile(index); err != nil {
+			return err
+		}
+	}
+	c.emit(op.Slice, 1)
+	return nil
+}
+
+func (c *Compiler) compileRange(node *ast.Range) error {
+	if err := c.compile(node.Container()); err != nil {
+		return err
+	}
+	c.emit(op.Range)
+	return nil
+}
+
+// compilePropagate compiles the postfix "?" operator. An ok result is replaced
+// by its value, while an err result is returned from the current function.
+func (c *Compiler) compilePropagate(node *ast.Propagate) error {
+	if c.current.Parent == nil {
+		return fmt.Errorf("? operator outside of function")
+	}
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	// Jump past the return if the result is ok
+	okPos := c.emit(op.UnwrapResult, Placeholder)
+	if err := c.exitTries(0); err != nil {
+		return err
+	}
+	c.emit(op.ReturnValue)
+	return c.patchJumps([]int{okPos})
+}
+
+func (c *Compiler) compileTernary(node *ast.Ternary) error {
+	// evaluate the condition and then conditionally jump to the false case
+	if err := c.compile(node.Condition()); err != nil {
+		return err
+	}
+	jumpIfFalsePos := c.emit(op.PopJumpForwardIfFalse, Placeholder)
+
+	// true case execution, then jump over false case
+	if err := c.compile(node.IfTrue()); err != nil {
+		return err
+	}
+	trueCaseEndPos := c.emit(op.JumpForward, Placeholder)
+
+	// set the jump amount to reach the beginning of the false case
+	falseCaseDelta, err := c.calculateDelta(jumpIfFalsePos)
+	if err != nil {
+		return err
+	}
+	c.changeOperand(jumpIfFalsePos, falseCaseDelta)
 
 	// false case execution
 	if err := c.compile(node.IfFalse()); err != nil {
@@ -660,6 +1520,8 @@ func (c *Compiler) compileString(node *ast.String) error {
 				nil, // no name
 				nil, // no params
 				nil, // no defaults
+				nil, // no rest parameter
+				nil, // no kwargs parameter
 				ast.NewBlock(token.Token{}, []ast.Node{expr}),
 			)
 			// Emit code to push the compiled function as TOS
@@ -695,17 +1557,17 @@ func (c *Compiler) compilePipe(node *ast.Pipe) error {
 	defer func() {
 		c.current.PipeActive = false
 	}()
-	// Iterate over the remaining expressions. Each should eval to a function.
+	// Iterate over the remaining expressions. Each should eval to a function,
+	// or to the right operand of a bitwise or operation.
 	// TODO: may need to compile to a partial as well.
 	for i := 1; i < len(exprs); i++ {
 		// Compile the current expression, pushing a function as TOS
 		if err := c.compile(exprs[i]); err != nil {
 			return err
 		}
-		// Swap the function (TOS) with the argument below it on the stack
-		// and then call the function with one argument
-		c.emit(op.Swap, 1)
-		c.emit(op.Call, 1)
+		// Since "|" is also the bitwise or operator, each step is a binary op
+		// which the VM runs as a call when the right operand is callable
+		c.emit(op.BinaryOp, uint16(op.BitwiseOr))
 	}
 	return nil
 }
@@ -744,7 +1606,7 @@ func (c *Compiler) compilePostfix(node *ast.Postfix) error {
 	case object.ScopeLocal:
 		c.emit(op.StoreFast, sym.Index)
 	case object.ScopeFree:
-		c.emit(op.StoreFree, sym.Index)
+		c.emit(op.StoreFree, uint16(resolution.FreeIndex))
 	}
 	return nil
 }
@@ -778,6 +1640,12 @@ func (c *Compiler) compileIn(node *ast.In) error {
 }
 
 func (c *Compiler) compilePrefix(node *ast.Prefix) error {
+	if c.optimize {
+		if value, ok := foldConstant(node); ok {
+			c.emitConstant(value)
+			return nil
+		}
+	}
 	if err := c.compile(node.Right()); err != nil {
 		return err
 	}
@@ -791,29 +1659,36 @@ func (c *Compiler) compilePrefix(node *ast.Prefix) error {
 }
 
 func (c *Compiler) compileCall(node *ast.Call) error {
-	args := node.Arguments()
-	argc := len(args)
-	if argc > MaxArgs {
-		return fmt.Errorf("max arguments limit of %d exceeded (got %d)", MaxArgs, argc)
-	}
 	if err := c.compile(node.Function()); err != nil {
 		return err
 	}
-	for _, arg := range args {
-		if err := c.compile(arg); err != nil {
-			return err
-		}
-	}
-	if c.current.PipeActive {
-		c.emit(op.Partial, uint16(argc))
-	} else {
-		c.emit(op.Call, uint16(argc))
+	argc, extended, err := c.compileArguments(node.Arguments())
+	if err != nil {
+		return err
 	}
+	c.emitCall(argc, extended)
 	return nil
 }
 
+// emitCall emits the instruction that calls the function on the stack with
+// the arguments pushed by compileArguments. If a pipe expression is being
+// compiled, a partial is created instead.
+func (c *Compiler) emitCall(argc uint16, extended bool) {
+	switch {
+	case c.current.PipeActive && extended:
+		c.emit(op.PartialEx)
+	case c.current.PipeActive:
+		c.emit(op.Partial, argc)
+	case extended:
+		c.emit(op.CallEx)
+	default:
+		c.emit(op.Call, argc)
+	}
+}
+
 func (c *Compiler) compileObjectCall(node *ast.ObjectCall) error {
-	if err := c.compile(node.Object()); err != nil {
+	jumps, outer := c.takeNilChain()
+	if err := c.compileReceiver(node.Object(), jumps); err != nil {
 		return err
 	}
 	expr := node.Call()
@@ -822,95 +1697,329 @@ func (c *Compiler) compileObjectCall(node *ast.ObjectCall) error {
 		return fmt.Errorf("invalid call expression")
 	}
 	name := method.Function().String()
-	c.emit(op.LoadAttr, c.current.AddName(name))
-	args := method.Arguments()
-	argc := len(args)
-	if argc > MaxArgs {
-		return fmt.Errorf("max arguments limit of %d exceeded (got %d)", MaxArgs, argc)
+	if !node.IsOptional() {
+		c.emit(op.LoadAttr, c.current.AddName(name))
+	} else {
+		// For "a?.b()", the result is nil without evaluating the arguments
+		// if either the object or its method is nil or missing
+		*jumps = append(*jumps, c.emitNilCheck())
+		c.emit(op.LoadAttrOrNil, c.current.AddName(name))
+		*jumps = append(*jumps, c.emitNilCheck())
 	}
-	for _, arg := range args {
-		if err := c.compile(arg); err != nil {
+	argc, extended, err := c.compileArguments(method.Arguments())
+	if err != nil {
+		return err
+	}
+	c.emitCall(argc, extended)
+	return c.endNilChain(jumps, outer)
+}
+
+func (c *Compiler) compileGetAttr(node *ast.GetAttr) error {
+	jumps, outer := c.takeNilChain()
+	if err := c.compileReceiver(node.Object(), jumps); err != nil {
+		return err
+	}
+	idx := c.current.AddName(node.Name())
+	if !node.IsOptional() {
+		c.emit(op.LoadAttr, idx)
+	} else {
+		*jumps = append(*jumps, c.emitNilCheck())
+		c.emit(op.LoadAttrOrNil, idx)
+	}
+	return c.endNilChain(jumps, outer)
+}
+
+func (c *Compiler) compileIndex(node *ast.Index) error {
+	jumps, outer := c.takeNilChain()
+	if err := c.compileReceiver(node.Left(), jumps); err != nil {
+		return err
+	}
+	if node.IsOptional() {
+		*jumps = append(*jumps, c.emitNilCheck())
+	}
+	if err := c.compile(node.Index()); err != nil {
+		return err
+	}
+	c.emit(op.BinarySubscr)
+	return c.endNilChain(jumps, outer)
+}
+
+// takeNilChain returns the nil check jumps of the chain that the current
+// attribute access, method call or index operation belongs to. If it is not
+// the receiver of another one, it starts a new chain and outer is true.
+// Nil-safe operators anywhere in a chain skip the rest of the chain, so
+// "a?.b.c" and "a?.b()" are nil if a is nil.
+func (c *Compiler) takeNilChain() (jumps *[]int, outer bool) {
+	jumps = c.nilChain
+	c.nilChain = nil
+	if jumps == nil {
+		return &[]int{}, true
+	}
+	return jumps, false
+}
+
+// compileReceiver compiles the receiver of an attribute access, method call
+// or index operation. A receiver that is itself one of these continues the
+// same chain.
+func (c *Compiler) compileReceiver(node ast.Node, jumps *[]int) error {
+	switch node.(type) {
+	case *ast.GetAttr, *ast.ObjectCall, *ast.Index:
+		c.nilChain = jumps
+	}
+	err := c.compile(node)
+	c.nilChain = nil
+	return err
+}
+
+// endNilChain patches the nil check jumps of a chain to jump "here", once
+// the outermost operation of the chain has been compiled.
+func (c *Compiler) endNilChain(jumps *[]int, outer bool) error {
+	if !outer {
+		return nil
+	}
+	return c.patchJumps(*jumps)
+}
+
+// emitNilCheck emits code that jumps forward if the value at TOS is nil,
+// leaving the nil on the stack as the result of the expression. Returns the
+// position of the jump instruction, which is updated using patchJumps.
+func (c *Compiler) emitNilCheck() int {
+	c.emit(op.Copy, 0)
+	return c.emit(op.PopJumpForwardIfNil, 0)
+}
+
+// patchJumps updates the given forward jump instructions to jump "here".
+func (c *Compiler) patchJumps(positions []int) error {
+	for _, pos := range positions {
+		delta, err := c.calculateDelta(pos)
+		if err != nil {
 			return err
 		}
+		c.changeOperand(pos, delta)
 	}
-	if c.current.PipeActive {
-		c.emit(op.Partial, uint16(len(args)))
-	} else {
-		c.emit(op.Call, uint16(len(args)))
+	return nil
+}
+
+func (c *Compiler) compileList(node *ast.List) error {
+	items := node.Items()
+	count := len(items)
+	if count > math.MaxUint16 {
+		return fmt.Errorf("list literal exceeds max size")
 	}
+	for _, expr := range items {
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(op.BuildList, uint16(count))
 	return nil
 }
 
-func (c *Compiler) compileGetAttr(node *ast.GetAttr) error {
-	if err := c.compile(node.Object()); err != nil {
-		return err
-	}
-	idx := c.current.AddName(node.Name())
-	c.emit(op.LoadAttr, idx)
-	return nil
+func (c *Compiler) compileTuple(node *ast.Tuple) error {
+	items := node.Items()
+	count := len(items)
+	if count > math.MaxUint16 {
+		return fmt.Errorf("tuple literal exceeds max size")
+	}
+	if c.optimize {
+		if value, ok := foldConstant(node); ok {
+			c.emitConstant(value)
+			return nil
+		}
+	}
+	for _, expr := range items {
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(op.BuildTuple, uint16(count))
+	return nil
+}
+
+func (c *Compiler) compileMap(node *ast.Map) error {
+	keys := node.Keys()
+	values := node.Values()
+	count := len(keys)
+	if count > math.MaxUint16 {
+		return fmt.Errorf("map literal exceeds max size")
+	}
+	for i, k := range keys {
+		// A bare name is shorthand for a string key
+		if ident, ok := k.(*ast.Ident); ok {
+			c.emit(op.LoadConst, c.constant(object.NewString(ident.String())))
+		} else if err := c.compile(k); err != nil {
+			return err
+		}
+		if err := c.compile(values[i]); err != nil {
+			return err
+		}
+	}
+	c.emit(op.BuildMap, uint16(count))
+	return nil
+}
+
+func (c *Compiler) compileSet(node *ast.Set) error {
+	items := node.Items()
+	count := len(items)
+	for _, expr := range items {
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(op.BuildSet, uint16(count))
+	return nil
+}
+
+func (c *Compiler) compileListComprehension(node *ast.ListComprehension) error {
+	c.emit(op.BuildList, 0)
+	return c.compileComprehension(node.Clauses(), func(depth uint16) error {
+		if err := c.compile(node.Element()); err != nil {
+			return err
+		}
+		c.emit(op.ListAppend, depth)
+		return nil
+	})
+}
+
+func (c *Compiler) compileMapComprehension(node *ast.MapComprehension) error {
+	c.emit(op.BuildMap, 0)
+	return c.compileComprehension(node.Clauses(), func(depth uint16) error {
+		if err := c.compile(node.Key()); err != nil {
+			return err
+		}
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		c.emit(op.MapAdd, depth)
+		return nil
+	})
+}
+
+func (c *Compiler) compileSetComprehension(node *ast.SetComprehension) error {
+	c.emit(op.BuildSet, 0)
+	return c.compileComprehension(node.Clauses(), func(depth uint16) error {
+		if err := c.compile(node.Element()); err != nil {
+			return err
+		}
+		c.emit(op.SetAdd, depth)
+		return nil
+	})
+}
+
+// compileComprehension compiles the loops of a comprehension inline. The
+// collection being built must already be on the stack. The addItem callback
+// emits the code that adds one item to the collection, given the number of
+// iterators that are on the stack above the collection at that point. Loop
+// variables are scoped to the comprehension.
+func (c *Compiler) compileComprehension(clauses []*ast.ComprehensionClause, addItem func(depth uint16) error) error {
+	code := c.current
+	code.Symbols = code.Symbols.NewBlock()
+	defer func() {
+		code.Symbols = code.Symbols.Parent()
+	}()
+	return c.compileComprehensionClause(clauses, 0, addItem)
 }
 
-func (c *Compiler) compileIndex(node *ast.Index) error {
-	if err := c.compile(node.Left()); err != nil {
-		return err
+func (c *Compiler) compileComprehensionClause(
+	clauses []*ast.ComprehensionClause,
+	index int,
+	addItem func(depth uint16) error,
+) error {
+	if index == len(clauses) {
+		return addItem(uint16(index))
 	}
-	if err := c.compile(node.Index()); err != nil {
+	clause := clauses[index]
+	if err := c.compile(clause.Iterable()); err != nil {
 		return err
 	}
-	c.emit(op.BinarySubscr)
-	return nil
-}
+	c.emit(op.GetIter)
 
-func (c *Compiler) compileList(node *ast.List) error {
-	items := node.Items()
-	count := len(items)
-	if count > math.MaxUint16 {
-		return fmt.Errorf("list literal exceeds max size")
+	// With a single loop variable, it receives the primary value of each
+	// entry, e.g. the items of a list rather than their indexes
+	names := clause.Names()
+	nameCount := uint16(len(names))
+	if nameCount == 1 {
+		nameCount = op.ForIterPrimary
 	}
-	for _, expr := range items {
-		if err := c.compile(expr); err != nil {
+	iterPos := c.emit(op.ForIter, 0, nameCount)
+	code := c.current
+	for _, name := range names {
+		sym, err := code.Symbols.InsertVariable(name.Literal())
+		if err != nil {
 			return err
 		}
+		if code.Symbols.IsGlobal() {
+			c.emit(op.StoreGlobal, sym.Index)
+		} else {
+			c.emit(op.StoreFast, sym.Index)
+		}
 	}
-	c.emit(op.BuildList, uint16(count))
-	return nil
-}
 
-func (c *Compiler) compileMap(node *ast.Map) error {
-	items := node.Items()
-	count := len(items)
-	for k, v := range items {
-		switch k := k.(type) {
-		case *ast.String:
-			if err := c.compile(k); err != nil {
-				return err
-			}
-		case *ast.Ident:
-			c.emit(op.LoadConst, c.constant(object.NewString(k.String())))
-		default:
-			return fmt.Errorf("invalid map key type: %v", k)
+	// If the condition is false, skip ahead to the next iteration
+	skipPos := -1
+	if cond := clause.Condition(); cond != nil {
+		if err := c.compile(cond); err != nil {
+			return err
 		}
-		if err := c.compile(v); err != nil {
+		skipPos = c.emit(op.PopJumpForwardIfFalse, 0)
+	}
+	if err := c.compileComprehensionClause(clauses, index+1, addItem); err != nil {
+		return err
+	}
+	if skipPos >= 0 {
+		delta, err := c.calculateDelta(skipPos)
+		if err != nil {
 			return err
 		}
+		c.changeOperand(skipPos, delta)
 	}
-	c.emit(op.BuildMap, uint16(count))
+
+	// Jump back to the start of the loop, and update the ForIter instruction
+	// to jump past this point when the iterator is exhausted
+	delta, err := c.calculateDelta(iterPos)
+	if err != nil {
+		return err
+	}
+	c.emit(op.JumpBackward, delta)
+	delta, err = c.calculateDelta(iterPos)
+	if err != nil {
+		return err
+	}
+	c.changeOperand(iterPos, delta)
 	return nil
 }
 
-func (c *Compiler) compileSet(node *ast.Set) error {
-	items := node.Items()
-	count := len(items)
-	for _, expr := range items {
-		if err := c.compile(expr); err != nil {
+func (c *Compiler) compileFunc(node *ast.Func) error {
+	// The function has an optional name. If it is named, the name will be
+	// stored in the function's own symbol table to support recursive calls.
+	var functionName string
+	if ident := node.Name(); ident != nil {
+		functionName = ident.Literal()
+	}
+	if err := c.emitFunc(node, functionName); err != nil {
+		return err
+	}
+
+	// If the function was named, we store it as a named variable in the current
+	// code. Otherwise, we just leave it on the stack.
+	if functionName != "" {
+		funcSymbol, err := c.current.Symbols.InsertConstant(functionName)
+		if err != nil {
 			return err
 		}
+		if c.current.Parent == nil {
+			c.emit(op.StoreGlobal, funcSymbol.Index)
+		} else {
+			c.emit(op.StoreFast, funcSymbol.Index)
+		}
 	}
-	c.emit(op.BuildSet, uint16(count))
 	return nil
 }
 
-func (c *Compiler) compileFunc(node *ast.Func) error {
+// emitFunc compiles the given function and emits the code that pushes the
+// function object onto the stack. The name is used for the function object,
+// while only functions with a name in the AST may call themselves by name.
+func (c *Compiler) emitFunc(node *ast.Func, functionName string) error {
 
 	// Python cell variables:
 	// https://stackoverflow.com/questions/23757143/what-is-a-cell-in-the-context-of-an-interpreter-or-compiler
@@ -919,17 +2028,10 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 		return fmt.Errorf("function exceeded parameter limit of 255")
 	}
 
-	// The function has an optional name. If it is named, the name will be
-	// stored in the function's own symbol table to support recursive calls.
-	var functionName string
-	if ident := node.Name(); ident != nil {
-		functionName = ident.Literal()
-	}
-
 	// This new code object will store the compiled code for this function
 	code := &object.Code{
 		Name:    functionName,
-		IsNamed: functionName != "",
+		IsNamed: node.Name() != nil,
 		Parent:  c.current,
 		Symbols: c.current.Symbols.NewChild(),
 		Source:  node.Body().String(),
@@ -950,28 +2052,28 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 	// the basic types of int, string, bool, float, and nil.
 	defaults := make([]object.Object, len(params))
 	for name, expr := range node.Defaults() {
-		var value object.Object
-		switch expr := expr.(type) {
-		case *ast.Int:
-			value = object.NewInt(expr.Value())
-		case *ast.String:
-			value = object.NewString(expr.Value())
-		case *ast.Bool:
-			value = object.NewBool(expr.Value())
-		case *ast.Float:
-			value = object.NewFloat(expr.Value())
-		case *ast.Nil:
-			value = object.Nil
-		default:
-			return fmt.Errorf("unsupported default value: %s", expr)
+		value, err := defaultValue(expr)
+		if err != nil {
+			return err
 		}
 		defaults[paramsIdx[name]] = value
 	}
 
-	// Add the parameter names to the symbol table.
+	// Add the parameter names to the symbol table, followed by the optional
+	// rest and kwargs parameters. The VM assembles the function's locals in
+	// this same order when it is called.
 	for _, arg := range node.Parameters() {
 		code.Symbols.InsertVariable(arg.Literal())
 	}
+	var restName, kwargsName string
+	if rest := node.RestParameter(); rest != nil {
+		restName = rest.Literal()
+		code.Symbols.InsertVariable(restName)
+	}
+	if kwargs := node.KwargsParameter(); kwargs != nil {
+		kwargsName = kwargs.Literal()
+		code.Symbols.InsertVariable(kwargsName)
+	}
 	// Add the function's own name to its symbol table. This supports recursive
 	// calls to the function. Later when we create the function object, we'll
 	// add the object value to the table.
@@ -990,13 +2092,20 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 
 	// We're done compiling the function, so switch back to compiling the parent
 	c.current = c.current.Parent
+	if c.optimize {
+		if err := optimizeCode(code, 0); err != nil {
+			return err
+		}
+	}
 
 	// Create the function object that contains the compiled code
 	fn := object.NewFunction(object.FunctionOpts{
-		Name:           functionName,
-		ParameterNames: params,
-		Defaults:       defaults,
-		Code:           code,
+		Name:            functionName,
+		ParameterNames:  params,
+		Defaults:        defaults,
+		RestParameter:   restName,
+		KwargsParameter: kwargsName,
+		Code:            code,
 	})
 	if code.IsNamed {
 		code.Symbols.SetValue(functionName, fn)
@@ -1013,20 +2122,92 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 	} else {
 		c.emit(op.LoadConst, c.constant(fn))
 	}
+	return nil
+}
 
-	// If the function was named, we store it as a named variable in the current
-	// code. Otherwise, we just leave it on the stack.
-	if functionName != "" {
-		funcSymbol, err := c.current.Symbols.InsertConstant(functionName)
+// defaultValue returns the value of a default parameter or field expression,
+// supporting only the basic types of int, string, bool, float, and nil.
+func defaultValue(expr ast.Expression) (object.Object, error) {
+	switch expr := expr.(type) {
+	case *ast.Int:
+		return object.NewInt(expr.Value()), nil
+	case *ast.String:
+		return object.NewString(expr.Value()), nil
+	case *ast.Bool:
+		return object.NewBool(expr.Value()), nil
+	case *ast.Float:
+		return object.NewFloat(expr.Value()), nil
+	case *ast.Nil:
+		return object.Nil, nil
+	}
+	return nil, fmt.Errorf("unsupported default value: %s", expr)
+}
+
+func (c *Compiler) compileStruct(node *ast.Struct) error {
+	// BuildStruct expects the struct name on the stack, followed by the name
+	// and default value of each field
+	fields := node.Fields()
+	if len(fields) > math.MaxUint16 {
+		return fmt.Errorf("struct exceeds max field count")
+	}
+	name := node.Name().Literal()
+	c.emit(op.LoadConst, c.constant(object.NewString(name)))
+	for _, field := range fields {
+		c.emit(op.LoadConst, c.constant(object.NewString(field.Literal())))
+		expr, ok := node.Defaults()[field.Literal()]
+		if !ok {
+			c.emit(op.Nil)
+			continue
+		}
+		value, err := defaultValue(expr)
 		if err != nil {
 			return err
 		}
-		if c.current.Parent == nil {
-			c.emit(op.StoreGlobal, funcSymbol.Index)
-		} else {
-			c.emit(op.StoreFast, funcSymbol.Index)
+		c.emit(op.LoadConst, c.constant(value))
+	}
+	c.emit(op.BuildStruct, uint16(len(fields)))
+	symbol, err := c.current.Symbols.InsertConstant(name)
+	if err != nil {
+		return err
+	}
+	if c.current.Parent == nil {
+		c.emit(op.StoreGlobal, symbol.Index)
+	} else {
+		c.emit(op.StoreFast, symbol.Index)
+	}
+	return nil
+}
+
+func (c *Compiler) compileMethod(node *ast.Method) error {
+	// Methods are stored as attributes on the struct type
+	structName := node.StructName().Literal()
+	name := node.Name().Literal()
+	if err := c.emitFunc(node.Func(), structName+"."+name); err != nil {
+		return err
+	}
+	if err := c.compile(node.StructName()); err != nil {
+		return err
+	}
+	c.emit(op.StoreAttr, c.current.AddName(name))
+	return nil
+}
+
+func (c *Compiler) compileStructLiteral(node *ast.StructLiteral) error {
+	// The type is pushed followed by the name and value of each field
+	fields := node.Fields()
+	if len(fields) > math.MaxUint16 {
+		return fmt.Errorf("struct literal exceeds max field count")
+	}
+	if err := c.compile(node.Type()); err != nil {
+		return err
+	}
+	for i, field := range fields {
+		c.emit(op.LoadConst, c.constant(object.NewString(field.Literal())))
+		if err := c.compile(node.Values()[i]); err != nil {
+			return err
 		}
 	}
+	c.emit(op.NewStruct, uint16(len(fields)))
 	return nil
 }
 
@@ -1037,6 +2218,9 @@ func (c *Compiler) compileControl(node *ast.Control) error {
 			return fmt.Errorf("return outside of function")
 		}
 		value := node.Value()
+		if call, ok := value.(*ast.Call); ok && !c.inTry() {
+			return c.compileTailCall(call)
+		}
 		if value == nil {
 			c.emit(op.Nil)
 		} else {
@@ -1044,6 +2228,9 @@ func (c *Compiler) compileControl(node *ast.Control) error {
 				return err
 			}
 		}
+		if err := c.exitTries(0); err != nil {
+			return err
+		}
 		c.emit(op.ReturnValue)
 		return nil
 	}
@@ -1054,6 +2241,9 @@ func (c *Compiler) compileControl(node *ast.Control) error {
 		}
 		return fmt.Errorf("continue outside of loop")
 	}
+	if err := c.exitTries(len(c.current.Loops)); err != nil {
+		return err
+	}
 	if literal == "break" {
 		position := c.emit(op.JumpForward, Placeholder)
 		loop.BreakPos = append(loop.BreakPos, position)
@@ -1064,6 +2254,36 @@ func (c *Compiler) compileControl(node *ast.Control) error {
 	return nil
 }
 
+// compileTailCall compiles a return statement whose value is the result of
+// a call. The VM may run the called function in the frame of the current
+// function, so that tail recursion doesn't consume additional frames.
+func (c *Compiler) compileTailCall(node *ast.Call) error {
+	if err := c.compile(node.Function()); err != nil {
+		return err
+	}
+	argc, extended, err := c.compileArguments(node.Arguments())
+	if err != nil {
+		return err
+	}
+	if extended {
+		c.emit(op.CallEx)
+	} else {
+		c.emit(op.TailCall, argc)
+	}
+	c.emit(op.ReturnValue)
+	return nil
+}
+
+// inTry returns true if a try statement in the current function is active.
+func (c *Compiler) inTry() bool {
+	for _, t := range c.tries {
+		if t.code == c.current {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Compiler) compileSetItem(node *ast.Assign) error {
 	// StoreSubscr / STORE_SUBSCR
 	// Implements TOS1[TOS] = TOS2.
@@ -1086,10 +2306,59 @@ func (c *Compiler) compileSetItem(node *ast.Assign) error {
 	return nil
 }
 
+func (c *Compiler) compileSetAttr(node *ast.Assign) error {
+	// StoreAttr / STORE_ATTR
+	// Implements TOS.name = TOS1.
+	//
+	// p.x += 1
+	// 1. Push attr.Object() (p)
+	// 2. Copy it and load the current value (p.x)
+	// 3. Push node.Value() and apply the operator
+	// 4. Swap so the object is TOS again
+	attr := node.Attr()
+	idx := c.current.AddName(attr.Name())
+	if node.Operator() == "=" {
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		if err := c.compile(attr.Object()); err != nil {
+			return err
+		}
+		c.emit(op.StoreAttr, idx)
+		return nil
+	}
+	if err := c.compile(attr.Object()); err != nil {
+		return err
+	}
+	c.emit(op.Copy, 0)
+	c.emit(op.LoadAttr, idx)
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	switch node.Operator() {
+	case "+=":
+		c.emit(op.BinaryOp, uint16(op.Add))
+	case "-=":
+		c.emit(op.BinaryOp, uint16(op.Subtract))
+	case "*=":
+		c.emit(op.BinaryOp, uint16(op.Multiply))
+	case "/=":
+		c.emit(op.BinaryOp, uint16(op.Divide))
+	default:
+		return fmt.Errorf("unsupported operator for assignment: %s", node.Operator())
+	}
+	c.emit(op.Swap, 1)
+	c.emit(op.StoreAttr, idx)
+	return nil
+}
+
 func (c *Compiler) compileAssign(node *ast.Assign) error {
 	if node.Index() != nil {
 		return c.compileSetItem(node)
 	}
+	if node.Attr() != nil {
+		return c.compileSetAttr(node)
+	}
 	name := node.Name()
 	resolution, found := c.current.Symbols.Lookup(name)
 	if !found {
@@ -1109,7 +2378,7 @@ func (c *Compiler) compileAssign(node *ast.Assign) error {
 		case object.ScopeLocal:
 			c.emit(op.StoreFast, sym.Index)
 		case object.ScopeFree:
-			c.emit(op.StoreFree, sym.Index)
+			c.emit(op.StoreFree, uint16(resolution.FreeIndex))
 		}
 		return nil
 	}
@@ -1144,12 +2413,32 @@ func (c *Compiler) compileAssign(node *ast.Assign) error {
 	case object.ScopeLocal:
 		c.emit(op.StoreFast, sym.Index)
 	case object.ScopeFree:
-		c.emit(op.StoreFree, sym.Index)
+		c.emit(op.StoreFree, uint16(resolution.FreeIndex))
 	}
 	return nil
 }
 
-func (c *Compiler) compileForRange(forNode *ast.For, names []string, container ast.Node) error {
+func (c *Compiler) compileForRange(forNode *ast.For, targets []*object.Pattern, container ast.Node) error {
+	return c.compileForLoop(forNode, targets, uint16(len(targets)), container)
+}
+
+// compileForIn compiles a loop that assigns the primary value of each entry
+// in the container to the target, like a comprehension does. This is the
+// item of a list or set, the key of a map, or the value of an iterator.
+func (c *Compiler) compileForIn(forNode *ast.For, target *object.Pattern, container ast.Node) error {
+	return c.compileForLoop(forNode, []*object.Pattern{target}, op.ForIterPrimary, container)
+}
+
+// capturePatterns returns patterns that store values in the given names.
+func capturePatterns(names []string) []*object.Pattern {
+	patterns := make([]*object.Pattern, 0, len(names))
+	for _, name := range names {
+		patterns = append(patterns, &object.Pattern{Kind: object.PatternCapture, Name: name})
+	}
+	return patterns
+}
+
+func (c *Compiler) compileForLoop(forNode *ast.For, targets []*object.Pattern, nameCount uint16, container ast.Node) error {
 
 	if err := c.compile(container); err != nil {
 		return err
@@ -1165,19 +2454,13 @@ func (c *Compiler) compileForRange(forNode *ast.For, names []string, container a
 		code.Symbols = code.Symbols.Parent()
 	}()
 
-	iterPos := c.emit(op.ForIter, 0, uint16(len(names)))
+	iterPos := c.emit(op.ForIter, 0, nameCount)
 
-	// assign the current value of the iterator to the loop variable
-	for _, name := range names {
-		sym, err := code.Symbols.InsertVariable(name)
-		if err != nil {
+	// assign the current value of the iterator to the loop variables
+	for _, target := range targets {
+		if err := c.storePattern(target); err != nil {
 			return err
 		}
-		if code.Symbols.IsGlobal() {
-			c.emit(op.StoreGlobal, sym.Index)
-		} else {
-			c.emit(op.StoreFast, sym.Index)
-		}
 	}
 
 	// compile the body of the loop
@@ -1233,23 +2516,84 @@ func (c *Compiler) compileFor(node *ast.For) error {
 		switch cond := cond.(type) {
 		case *ast.Var:
 			name, rhs := cond.Value()
+			targets := capturePatterns([]string{name})
 			if rangeNode, ok := rhs.(*ast.Range); ok {
-				return c.compileForRange(node, []string{name}, rangeNode.Container())
+				return c.compileForRange(node, targets, rangeNode.Container())
 			} else {
-				return c.compileForRange(node, []string{name}, rhs)
+				return c.compileForRange(node, targets, rhs)
 			}
 		case *ast.MultiVar:
 			names, rhs := cond.Value()
 			if len(names) != 2 {
 				return fmt.Errorf("invalid for loop")
 			}
+			targets := capturePatterns(names)
+			if rangeNode, ok := rhs.(*ast.Range); ok {
+				return c.compileForRange(node, targets, rangeNode.Container())
+			} else {
+				return c.compileForRange(node, targets, rhs)
+			}
+		case *ast.Destructure:
+			// Destructuring loop e.g. `for i, [a, b] := range pairs { ... }`
+			if cond.Rest() != nil || len(cond.Targets()) > 2 {
+				return fmt.Errorf("invalid for loop")
+			}
+			var targets []*object.Pattern
+			for _, target := range cond.Targets() {
+				pattern, err := c.buildTarget(target)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, pattern)
+			}
+			if err := checkPatternNames(targets...); err != nil {
+				return err
+			}
+			rhs := cond.Value()
 			if rangeNode, ok := rhs.(*ast.Range); ok {
-				return c.compileForRange(node, names, rangeNode.Container())
+				return c.compileForRange(node, targets, rangeNode.Container())
 			} else {
-				return c.compileForRange(node, names, rhs)
+				return c.compileForRange(node, targets, rhs)
 			}
 		case *ast.Range:
 			return c.compileForRange(node, nil, cond.Container())
+		case *ast.In:
+			// For-In loop e.g. `for item in container { ... }`
+			switch left := cond.Left().(type) {
+			case *ast.Ident:
+				return c.compileForIn(node, capturePatterns([]string{left.Literal()})[0], cond.Right())
+			case *ast.ListPattern, *ast.MapPattern:
+				target, err := c.buildTarget(left)
+				if err != nil {
+					return err
+				}
+				if err := checkPatternNames(target); err != nil {
+					return err
+				}
+				return c.compileForIn(node, target, cond.Right())
+			case *ast.LoopTargets:
+				// Two loop variables e.g. `for k, [a, b] in m { ... }`
+				var targets []*object.Pattern
+				for _, item := range left.Targets() {
+					if ident, ok := item.(*ast.Ident); ok {
+						targets = append(targets, capturePatterns([]string{ident.Literal()})...)
+						continue
+					}
+					pattern, err := c.buildTarget(item)
+					if err != nil {
+						return err
+					}
+					targets = append(targets, pattern)
+				}
+				if len(targets) != 2 {
+					return fmt.Errorf("invalid for loop")
+				}
+				if err := checkPatternNames(targets...); err != nil {
+					return err
+				}
+				return c.compileForRange(node, targets, cond.Right())
+			}
+			return c.compileForRange(node, nil, cond)
 		default:
 			return c.compileForRange(node, nil, cond)
 		}
@@ -1441,59 +2785,69 @@ func (c *Compiler) changeOperand(instructionIndex int, operand uint16) {
 }
 
 func (c *Compiler) compileInfix(node *ast.Infix) error {
+	if node.Operator() == "??" {
+		return c.compileNilCoalesce(node)
+	}
+	if c.optimize {
+		if value, ok := foldConstant(node); ok {
+			c.emitConstant(value)
+			return nil
+		}
+	}
 	if err := c.compile(node.Left()); err != nil {
 		return err
 	}
 	if err := c.compile(node.Right()); err != nil {
 		return err
 	}
-	switch node.Operator() {
-	case "&&":
-		c.emit(op.BinaryOp, uint16(op.And))
-	case "||":
-		c.emit(op.BinaryOp, uint16(op.Or))
-	case "+":
-		c.emit(op.BinaryOp, uint16(op.Add))
-	case "-":
-		c.emit(op.BinaryOp, uint16(op.Subtract))
-	case "*":
-		c.emit(op.BinaryOp, uint16(op.Multiply))
-	case "/":
-		c.emit(op.BinaryOp, uint16(op.Divide))
-	case "%":
-		c.emit(op.BinaryOp, uint16(op.Modulo))
-	case "**":
-		c.emit(op.BinaryOp, uint16(op.Power))
-	case "<<":
-		c.emit(op.BinaryOp, uint16(op.LShift))
-	case ">>":
-		c.emit(op.BinaryOp, uint16(op.RShift))
-	case ">":
-		c.emit(op.CompareOp, uint16(op.GreaterThan))
-	case ">=":
-		c.emit(op.CompareOp, uint16(op.GreaterThanOrEqual))
-	case "<":
-		c.emit(op.CompareOp, uint16(op.LessThan))
-	case "<=":
-		c.emit(op.CompareOp, uint16(op.LessThanOrEqual))
-	case "==":
-		c.emit(op.CompareOp, uint16(op.Equal))
-	case "!=":
-		c.emit(op.CompareOp, uint16(op.NotEqual))
-	default:
+	if opType, ok := binaryOperators[node.Operator()]; ok {
+		c.emit(op.BinaryOp, uint16(opType))
+	} else if opType, ok := compareOperators[node.Operator()]; ok {
+		c.emit(op.CompareOp, uint16(opType))
+	} else {
 		return fmt.Errorf("unknown operator: %s", node.Operator())
 	}
 	return nil
 }
 
+// compileNilCoalesce compiles "a ?? b", which evaluates to a unless it is nil.
+// The right operand is only evaluated when a is nil.
+func (c *Compiler) compileNilCoalesce(node *ast.Infix) error {
+	if err := c.compile(node.Left()); err != nil {
+		return err
+	}
+	c.emit(op.Copy, 0)
+	jump := c.emit(op.PopJumpForwardIfNotNil, 0)
+	c.emit(op.PopTop)
+	if err := c.compile(node.Right()); err != nil {
+		return err
+	}
+	return c.patchJumps([]int{jump})
+}
+
 func (c *Compiler) constant(obj object.Object) uint16 {
 	code := c.current
+	// Share the existing constant if this is a duplicate
+	key, shareable := getConstantKey(obj)
+	shareable = shareable && c.optimize
+	if shareable {
+		if index, ok := c.constants[code][key]; ok {
+			return index
+		}
+	}
 	if len(code.Constants) >= math.MaxUint16 {
 		c.failure = fmt.Errorf("number of constants exceeded limits")
 		return 0
 	}
 	code.Constants = append(code.Constants, obj)
-	return uint16(len(code.Constants) - 1)
+	index := uint16(len(code.Constants) - 1)
+	if shareable {
+		if c.constants[code] == nil {
+			c.constants[code] = map[constantKey]uint16{}
+		}
+		c.constants[code][key] = index
+	}
+	return index
 }
 
 func (c *Compiler) emit(opcode op.Code, operands ...uint16) int {
@@ -1501,6 +2855,7 @@ func (c *Compiler) emit(opcode op.Code, operands ...uint16) int {
 	code := c.current
 	pos := len(code.Instructions)
 	// fmt.Println("EMIT", len(code.Instructions), op.GetInfo(opcode).Name, operands)
+	code.AddLocation(pos, c.position)
 	code.Instructions = append(code.Instructions, inst...)
 	return pos
 }
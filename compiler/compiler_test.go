@@ -1,10 +1,13 @@
 package compiler
 
 import (
+	"context"
 	"testing"
 
 	"github.com/risor-io/risor/ast"
+	"github.com/risor-io/risor/object"
 	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/parser"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,6 +21,58 @@ func TestNil(t *testing.T) {
 	require.Equal(t, op.Nil, op.Code(instr))
 }
 
+func TestSourceLocations(t *testing.T) {
+	program, err := parser.Parse(context.Background(), "x := 1\n\ny := x + 2")
+	require.Nil(t, err)
+	code, err := Compile(program)
+	require.Nil(t, err)
+	pos, ok := code.LocationAt(0)
+	require.True(t, ok)
+	require.Equal(t, 1, pos.LineNumber())
+	// The addition is attributed to the position of the + operator
+	addPos := -1
+	for i := 0; i < len(code.Instructions); i++ {
+		opcode := code.Instructions[i]
+		if opcode == op.BinaryOp {
+			addPos = i
+		}
+		i += op.OperandCount[opcode].OperandCount
+	}
+	pos, ok = code.LocationAt(addPos)
+	require.True(t, ok)
+	require.Equal(t, 3, pos.LineNumber())
+	require.Equal(t, 8, pos.ColumnNumber())
+	// Only changes in position are recorded in the line table
+	for i := 1; i < len(code.Locations); i++ {
+		require.NotEqual(t, code.Locations[i-1].Position, code.Locations[i].Position)
+		require.Greater(t, code.Locations[i].Offset, code.Locations[i-1].Offset)
+	}
+}
+
+func TestTailCall(t *testing.T) {
+	program, err := parser.Parse(context.Background(), `
+	func f(n) {
+		try {
+			return f(n)
+		} catch e {
+			return f(n - 1)
+		}
+	}`)
+	require.Nil(t, err)
+	code, err := Compile(program)
+	require.Nil(t, err)
+	fn, ok := code.Constants[0].(*object.Function)
+	require.True(t, ok)
+	// Only the call outside of the try block is a tail call
+	var calls []op.Code
+	for _, opcode := range opcodes(fn.Code()) {
+		if opcode == op.Call || opcode == op.TailCall {
+			calls = append(calls, opcode)
+		}
+	}
+	require.Equal(t, []op.Code{op.Call, op.TailCall}, calls)
+}
+
 // func TestAdd(t *testing.T) {
 // 	program, err := parser.Parse(`
 // 	x := 1
@@ -0,0 +1,577 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/risor-io/risor/ast"
+	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/token"
+)
+
+// Optimizations are enabled with the WithOptimizations option. Constant
+// expressions are folded as they are compiled and duplicate constants are
+// shared. Once the code for a function or program is complete, its
+// instructions are rewritten to thread jumps, remove unreachable code, and
+// combine common sequences of instructions into superinstructions.
+
+// binaryOperators maps infix operators to the binary operations they compile to.
+var binaryOperators = map[string]op.BinaryOpType{
+	"&&": op.And,
+	"||": op.Or,
+	"+":  op.Add,
+	"-":  op.Subtract,
+	"*":  op.Multiply,
+	"/":  op.Divide,
+	"%":  op.Modulo,
+	"**": op.Power,
+	"<<": op.LShift,
+	">>": op.RShift,
+	"&":  op.BitwiseAnd,
+	"^":  op.Xor,
+}
+
+// compareOperators maps infix operators to the comparisons they compile to.
+var compareOperators = map[string]op.CompareOpType{
+	">":  op.GreaterThan,
+	">=": op.GreaterThanOrEqual,
+	"<":  op.LessThan,
+	"<=": op.LessThanOrEqual,
+	"==": op.Equal,
+	"!=": op.NotEqual,
+}
+
+// maxFoldedExponent limits the exponents that are folded, since the result
+// of raising an int to a large power may be very large.
+const maxFoldedExponent = 64
+
+// foldConstant evaluates the expression at compile time, if it consists only
+// of literals combined with operators. The boolean result is false if the
+// expression can't be folded, including when evaluating it would fail, so
+// that the error is raised at runtime as usual.
+func foldConstant(node ast.Node) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.Nil:
+		return object.Nil, true
+	case *ast.Bool:
+		return object.NewBool(node.Value()), true
+	case *ast.Int:
+		return object.NewInt(node.Value()), true
+	case *ast.Float:
+		return object.NewFloat(node.Value()), true
+	case *ast.Imaginary:
+		return object.NewComplex(complex(0, node.Value())), true
+	case *ast.String:
+		if node.Template() != nil {
+			return nil, false
+		}
+		return object.NewString(node.Value()), true
+	case *ast.Tuple:
+		items := make([]object.Object, 0, len(node.Items()))
+		for _, expr := range node.Items() {
+			item, ok := foldConstant(expr)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return object.NewTuple(items), true
+	case *ast.Prefix:
+		right, ok := foldConstant(node.Right())
+		if !ok {
+			return nil, false
+		}
+		return foldPrefix(node.Operator(), right)
+	case *ast.Infix:
+		left, ok := foldConstant(node.Left())
+		if !ok {
+			return nil, false
+		}
+		right, ok := foldConstant(node.Right())
+		if !ok {
+			return nil, false
+		}
+		return foldInfix(node.Operator(), left, right)
+	}
+	return nil, false
+}
+
+// foldPrefix applies a prefix operator in the same way as the VM.
+func foldPrefix(operator string, right object.Object) (object.Object, bool) {
+	switch operator {
+	case "!":
+		return object.NewBool(!right.IsTruthy()), true
+	case "-":
+		switch right := right.(type) {
+		case *object.Int:
+			if right.Value() == math.MinInt64 {
+				return foldedResult(object.NewInt(0).RunOperation(op.Subtract, right))
+			}
+			return object.NewInt(-right.Value()), true
+		case *object.Float:
+			return object.NewFloat(-right.Value()), true
+		case *object.Complex:
+			return object.NewComplex(-right.Value()), true
+		case *object.BigInt:
+			return right.Neg(), true
+		case *object.Decimal:
+			return right.Neg(), true
+		}
+	}
+	return nil, false
+}
+
+// foldInfix applies an infix operator in the same way as the VM.
+func foldInfix(operator string, left, right object.Object) (object.Object, bool) {
+	if opType, ok := compareOperators[operator]; ok {
+		return foldedResult(object.Compare(opType, left, right))
+	}
+	opType, ok := binaryOperators[operator]
+	if !ok {
+		return nil, false
+	}
+	switch opType {
+	case op.Multiply:
+		// Repeating a string or tuple may produce a very large constant
+		if isSequence(left) || isSequence(right) {
+			return nil, false
+		}
+	case op.Divide, op.Modulo:
+		// Leave division by zero to fail at runtime
+		if !right.IsTruthy() {
+			return nil, false
+		}
+	case op.Power, op.LShift:
+		exponent, ok := right.(*object.Int)
+		if !ok || exponent.Value() < 0 || exponent.Value() > maxFoldedExponent {
+			return nil, false
+		}
+	}
+	return foldedResult(object.BinaryOp(opType, left, right))
+}
+
+func isSequence(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.String, *object.Tuple:
+		return true
+	}
+	return false
+}
+
+// foldedResult accepts the result of a folded operation if it is a constant
+// value that may be stored in the code.
+func foldedResult(result object.Object) (object.Object, bool) {
+	switch result.(type) {
+	case *object.NilType, *object.Bool, *object.Int, *object.Float,
+		*object.Complex, *object.BigInt, *object.Decimal, *object.String,
+		*object.Tuple:
+		return result, true
+	}
+	return nil, false
+}
+
+// emitConstant emits the instruction that loads the given constant value.
+func (c *Compiler) emitConstant(value object.Object) {
+	switch value {
+	case object.Nil:
+		c.emit(op.Nil)
+	case object.True:
+		c.emit(op.True)
+	case object.False:
+		c.emit(op.False)
+	default:
+		c.emit(op.LoadConst, c.constant(value))
+	}
+}
+
+// constantKey identifies a constant value, for sharing duplicate constants.
+type constantKey struct {
+	typ   object.Type
+	value interface{}
+}
+
+// getConstantKey returns the key for the constant. Only simple values may be
+// shared; the boolean result is false for any other type of constant.
+func getConstantKey(obj object.Object) (constantKey, bool) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return constantKey{object.INT, obj.Value()}, true
+	case *object.Float:
+		// Compare the bits so that 0.0 and -0.0 are distinct constants
+		return constantKey{object.FLOAT, math.Float64bits(obj.Value())}, true
+	case *object.String:
+		return constantKey{object.STRING, obj.Value()}, true
+	case *object.Byte:
+		return constantKey{object.BYTE, obj.Value()}, true
+	}
+	return constantKey{}, false
+}
+
+// instruction is a decoded instruction that is being optimized. Jumps refer
+// to their target instruction, so that instructions may be added, removed,
+// and resized before the jump distances are calculated again.
+type instruction struct {
+	opcode   op.Code
+	operands []op.Code
+	target   *instruction
+	position token.Position
+	index    int
+	removed  bool
+}
+
+// jumpOperand returns the index of the operand that holds the distance to the
+// jump target, or -1 if the instruction doesn't jump.
+func jumpOperand(opcode op.Code) int {
+	switch opcode {
+	case op.JumpForward, op.JumpBackward,
+		op.PopJumpForwardIfFalse, op.PopJumpForwardIfTrue,
+		op.PopJumpForwardIfNil, op.PopJumpForwardIfNotNil,
+		op.PopJumpBackwardIfFalse, op.PopJumpBackwardIfTrue,
+		op.ForIter, op.UnwrapResult:
+		return 0
+	case op.ComparePopJumpForwardIfFalse:
+		return 1
+	}
+	return -1
+}
+
+func isBackwardJump(opcode op.Code) bool {
+	switch opcode {
+	case op.JumpBackward, op.PopJumpBackwardIfFalse, op.PopJumpBackwardIfTrue:
+		return true
+	}
+	return false
+}
+
+// reversedJumps maps jumps to the equivalent jump in the other direction.
+// Jumps that are missing may only jump forward.
+var reversedJumps = map[op.Code]op.Code{
+	op.JumpForward:            op.JumpBackward,
+	op.JumpBackward:           op.JumpForward,
+	op.PopJumpForwardIfFalse:  op.PopJumpBackwardIfFalse,
+	op.PopJumpBackwardIfFalse: op.PopJumpForwardIfFalse,
+	op.PopJumpForwardIfTrue:   op.PopJumpBackwardIfTrue,
+	op.PopJumpBackwardIfTrue:  op.PopJumpForwardIfTrue,
+}
+
+// endsFlow returns true if execution never continues to the instruction that
+// follows the given one.
+func endsFlow(opcode op.Code) bool {
+	switch opcode {
+	case op.JumpForward, op.JumpBackward, op.ReturnValue, op.Throw, op.Halt:
+		return true
+	}
+	return false
+}
+
+// optimizer rewrites the instructions of a code object, beginning at the
+// given offset. Earlier instructions were optimized by a previous call, as
+// happens when a REPL compiles its input incrementally.
+type optimizer struct {
+	code   *object.Code
+	start  int
+	instrs []*instruction
+
+	// end is a placeholder for the position just past the last instruction.
+	// It is the target of jumps that leave the code.
+	end *instruction
+
+	// Instructions referenced by the exception handlers of the code, as
+	// the start and end of each handler's range and its target
+	handlers [][3]*instruction
+}
+
+// optimizeCode optimizes the instructions of the code from the given offset.
+func optimizeCode(code *object.Code, start int) error {
+	if start >= len(code.Instructions) {
+		return nil
+	}
+	o := &optimizer{code: code, start: start}
+	if err := o.decode(); err != nil {
+		return err
+	}
+	for {
+		changed := o.threadJumps()
+		changed = o.removeUnreachable() || changed
+		changed = o.removeJumpsToNext() || changed
+		if !changed {
+			break
+		}
+	}
+	o.fuse()
+	return o.assemble()
+}
+
+func (o *optimizer) decode() error {
+	code := o.code
+	byOffset := map[int]*instruction{}
+	for offset := o.start; offset < len(code.Instructions); {
+		opcode := code.Instructions[offset]
+		info := op.GetInfo(opcode)
+		end := offset + 1 + info.OperandCount
+		if end > len(code.Instructions) {
+			return fmt.Errorf("optimizer error: truncated instruction at offset %d", offset)
+		}
+		instr := &instruction{
+			opcode:   opcode,
+			operands: append([]op.Code{}, code.Instructions[offset+1:end]...),
+			index:    len(o.instrs),
+		}
+		instr.position, _ = code.LocationAt(offset)
+		byOffset[offset] = instr
+		o.instrs = append(o.instrs, instr)
+		offset = end
+	}
+	o.end = &instruction{index: len(o.instrs)}
+	byOffset[len(code.Instructions)] = o.end
+
+	offset := o.start
+	for _, instr := range o.instrs {
+		if i := jumpOperand(instr.opcode); i >= 0 {
+			targetOffset := offset + int(instr.operands[i])
+			if isBackwardJump(instr.opcode) {
+				targetOffset = offset - int(instr.operands[i])
+			}
+			target, ok := byOffset[targetOffset]
+			if !ok {
+				return fmt.Errorf("optimizer error: invalid jump target %d at offset %d",
+					targetOffset, offset)
+			}
+			instr.target = target
+		}
+		offset += 1 + len(instr.operands)
+	}
+
+	for _, h := range code.Handlers {
+		if h.Target < o.start {
+			continue
+		}
+		start, startOk := byOffset[h.Start]
+		end, endOk := byOffset[h.End]
+		target, targetOk := byOffset[h.Target]
+		if !startOk || !endOk || !targetOk {
+			return fmt.Errorf("optimizer error: invalid exception handler at offset %d", h.Start)
+		}
+		o.handlers = append(o.handlers, [3]*instruction{start, end, target})
+	}
+	return nil
+}
+
+// resolve returns the given instruction, or if it was removed, the next
+// instruction that remains.
+func (o *optimizer) resolve(instr *instruction) *instruction {
+	for i := instr.index; i < len(o.instrs); i++ {
+		if !o.instrs[i].removed {
+			return o.instrs[i]
+		}
+	}
+	return o.end
+}
+
+// next returns the instruction that follows the given one.
+func (o *optimizer) next(instr *instruction) *instruction {
+	if instr.index+1 >= len(o.instrs) {
+		return o.end
+	}
+	return o.resolve(o.instrs[instr.index+1])
+}
+
+// threadJumps changes jumps that target an unconditional jump to go directly
+// to the final destination.
+func (o *optimizer) threadJumps() bool {
+	var changed bool
+	for _, instr := range o.instrs {
+		if instr.removed || instr.target == nil {
+			continue
+		}
+		target := o.resolve(instr.target)
+		seen := map[*instruction]bool{instr: true}
+		for (target.opcode == op.JumpForward || target.opcode == op.JumpBackward) &&
+			target != o.end && !seen[target] {
+			seen[target] = true
+			target = o.resolve(target.target)
+		}
+		// Some jumps can only go forward
+		if _, ok := reversedJumps[instr.opcode]; !ok && target.index <= instr.index {
+			continue
+		}
+		if target != o.resolve(instr.target) {
+			instr.target = target
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeUnreachable removes instructions that can't be reached from the start
+// of the code or from an exception handler.
+func (o *optimizer) removeUnreachable() bool {
+	reachable := make([]bool, len(o.instrs))
+	var work []*instruction
+	visit := func(instr *instruction) {
+		instr = o.resolve(instr)
+		if instr != o.end && !reachable[instr.index] {
+			reachable[instr.index] = true
+			work = append(work, instr)
+		}
+	}
+	visit(o.instrs[0])
+	for _, h := range o.handlers {
+		visit(h[2])
+	}
+	for len(work) > 0 {
+		instr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if instr.target != nil {
+			visit(instr.target)
+		}
+		if !endsFlow(instr.opcode) {
+			visit(o.next(instr))
+		}
+	}
+	var changed bool
+	for i, instr := range o.instrs {
+		if !instr.removed && !reachable[i] {
+			instr.removed = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeJumpsToNext removes jumps to the instruction that follows them. A
+// conditional jump still pops its operand, so it is replaced with a PopTop.
+func (o *optimizer) removeJumpsToNext() bool {
+	var changed bool
+	for _, instr := range o.instrs {
+		if instr.removed || instr.target == nil || o.resolve(instr.target) != o.next(instr) {
+			continue
+		}
+		switch instr.opcode {
+		case op.JumpForward, op.JumpBackward:
+			instr.removed = true
+		case op.PopJumpForwardIfFalse, op.PopJumpForwardIfTrue,
+			op.PopJumpForwardIfNil, op.PopJumpForwardIfNotNil,
+			op.PopJumpBackwardIfFalse, op.PopJumpBackwardIfTrue:
+			instr.opcode = op.PopTop
+			instr.operands = nil
+			instr.target = nil
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+// fuse combines common pairs of instructions into superinstructions. The
+// second instruction of a pair must not be the target of a jump.
+func (o *optimizer) fuse() {
+	referenced := map[*instruction]bool{}
+	for _, instr := range o.instrs {
+		if !instr.removed && instr.target != nil {
+			referenced[o.resolve(instr.target)] = true
+		}
+	}
+	for _, h := range o.handlers {
+		for _, instr := range h {
+			referenced[o.resolve(instr)] = true
+		}
+	}
+	for _, first := range o.instrs {
+		if first.removed {
+			continue
+		}
+		second := o.next(first)
+		if second == o.end || referenced[second] {
+			continue
+		}
+		switch {
+		case first.opcode == op.LoadFast && second.opcode == op.LoadFast:
+			first.opcode = op.LoadFastLoadFast
+			first.operands = []op.Code{first.operands[0], second.operands[0]}
+		case first.opcode == op.LoadConst && second.opcode == op.BinaryOp:
+			// Errors are attributed to the position of the operation
+			first.opcode = op.BinaryOpConst
+			first.operands = []op.Code{second.operands[0], first.operands[0]}
+			first.position = second.position
+		case first.opcode == op.CompareOp && second.opcode == op.PopJumpForwardIfFalse &&
+			o.resolve(second.target).index > second.index:
+			// Threading may have made the jump go backward, which the
+			// superinstruction doesn't support
+			first.opcode = op.ComparePopJumpForwardIfFalse
+			first.operands = []op.Code{first.operands[0], 0}
+			first.target = second.target
+		default:
+			continue
+		}
+		second.removed = true
+	}
+}
+
+// assemble writes the optimized instructions back to the code, updating the
+// jump distances, exception handlers, and line table.
+func (o *optimizer) assemble() error {
+	offsets := make([]int, len(o.instrs)+1)
+	offset := o.start
+	for i, instr := range o.instrs {
+		offsets[i] = offset
+		if !instr.removed {
+			offset += 1 + len(instr.operands)
+		}
+	}
+	offsets[len(o.instrs)] = offset
+	offsetOf := func(instr *instruction) int {
+		return offsets[o.resolve(instr).index]
+	}
+
+	code := o.code
+	instructions := code.Instructions[:o.start:o.start]
+	locations := code.Locations[:0:0]
+	for _, loc := range code.Locations {
+		if loc.Offset < o.start {
+			locations = append(locations, loc)
+		}
+	}
+	code.Locations = locations
+	for _, instr := range o.instrs {
+		if instr.removed {
+			continue
+		}
+		pos := offsets[instr.index]
+		if i := jumpOperand(instr.opcode); i >= 0 {
+			delta := offsetOf(instr.target) - pos
+			backward := isBackwardJump(instr.opcode)
+			if (delta < 0 && !backward) || (delta > 0 && backward) {
+				reversed, ok := reversedJumps[instr.opcode]
+				if !ok {
+					return fmt.Errorf("optimizer error: invalid jump at offset %d", pos)
+				}
+				instr.opcode = reversed
+			}
+			if delta < 0 {
+				delta = -delta
+			}
+			if delta > math.MaxUint16 {
+				return fmt.Errorf("jump destination too far away")
+			}
+			instr.operands[i] = op.Code(delta)
+		}
+		code.AddLocation(pos, instr.position)
+		instructions = append(instructions, instr.opcode)
+		instructions = append(instructions, instr.operands...)
+	}
+	code.Instructions = instructions
+
+	i := 0
+	for _, h := range code.Handlers {
+		if h.Target < o.start {
+			continue
+		}
+		refs := o.handlers[i]
+		h.Start, h.End, h.Target = offsetOf(refs[0]), offsetOf(refs[1]), offsetOf(refs[2])
+		i++
+	}
+	return nil
+}
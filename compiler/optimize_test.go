@@ -0,0 +1,148 @@
+package compiler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func compileOptimized(t *testing.T, input string) *object.Code {
+	t.Helper()
+	program, err := parser.Parse(context.Background(), input)
+	require.Nil(t, err)
+	code, err := Compile(program, WithOptimizations())
+	require.Nil(t, err)
+	return code
+}
+
+// opcodes returns the opcodes of the instructions, without their operands.
+func opcodes(code *object.Code) []op.Code {
+	var result []op.Code
+	for i := 0; i < len(code.Instructions); i++ {
+		opcode := code.Instructions[i]
+		result = append(result, opcode)
+		i += op.OperandCount[opcode].OperandCount
+	}
+	return result
+}
+
+func functionCode(t *testing.T, code *object.Code, index int) *object.Code {
+	t.Helper()
+	fn, ok := code.Constants[index].(*object.Function)
+	require.True(t, ok)
+	return fn.Code()
+}
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{"1 + 2 * 3", object.NewInt(7)},
+		{"-(2 ** 3)", object.NewInt(-8)},
+		{"1.5 * 2", object.NewFloat(3)},
+		{`"a" + "b"`, object.NewString("ab")},
+		{"1 < 2 && 3 == 3", object.True},
+		{"!(1 > 2)", object.True},
+		{"(1 << 4) + 1", object.NewInt(17)},
+		{"(1, 2 + 3)", object.NewTuple([]object.Object{object.NewInt(1), object.NewInt(5)})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			code := compileOptimized(t, tt.input)
+			ops := opcodes(code)
+			require.Len(t, ops, 1)
+			switch ops[0] {
+			case op.True:
+				require.Equal(t, tt.expected, object.True)
+			case op.LoadConst:
+				require.Len(t, code.Constants, 1)
+				require.Equal(t, tt.expected, code.Constants[0])
+			default:
+				t.Fatalf("unexpected opcode: %s", op.GetInfo(ops[0]).Name)
+			}
+			// Folded constants may be encoded like any other
+			_, err := object.MarshalCode(code)
+			require.Nil(t, err)
+		})
+	}
+}
+
+func TestFoldConstantsSkipped(t *testing.T) {
+	// These fail at runtime or are expensive, so they aren't folded
+	for _, input := range []string{"1 / 0", "5 % 0", "2 ** 100", `"x" * 1000`, `1 + "a"`} {
+		t.Run(input, func(t *testing.T) {
+			code := compileOptimized(t, input)
+			require.Greater(t, len(opcodes(code)), 1)
+		})
+	}
+}
+
+func TestDeduplicateConstants(t *testing.T) {
+	input := `x := 1; y := 1; z := "a"; w := "a"; f := 1.0`
+	program, err := parser.Parse(context.Background(), input)
+	require.Nil(t, err)
+	code, err := Compile(program)
+	require.Nil(t, err)
+	require.Len(t, code.Constants, 5)
+
+	code = compileOptimized(t, input)
+	require.Equal(t, []object.Object{
+		object.NewInt(1),
+		object.NewString("a"),
+		object.NewFloat(1),
+	}, code.Constants)
+}
+
+func TestThreadJumps(t *testing.T) {
+	code := compileOptimized(t, `
+	a := 1; b := 2
+	if a { if b { 3 } else { 4 } }`)
+	instructions := code.Instructions
+	for i := 0; i < len(instructions); i++ {
+		opcode := instructions[i]
+		if opcode == op.JumpForward {
+			target := i + int(instructions[i+1])
+			if target < len(instructions) {
+				require.NotEqual(t, op.JumpForward, instructions[target])
+			}
+		}
+		i += op.OperandCount[opcode].OperandCount
+	}
+}
+
+func TestRemoveUnreachable(t *testing.T) {
+	code := compileOptimized(t, `
+	func f(x) {
+		return x
+		x = 2
+		x++
+	}`)
+	require.Equal(t, []op.Code{op.LoadFast, op.ReturnValue}, opcodes(functionCode(t, code, 0)))
+}
+
+func TestSuperinstructions(t *testing.T) {
+	code := compileOptimized(t, `
+	func f(a, b) {
+		if a < b {
+			return a + 1
+		}
+		return a * b
+	}`)
+	require.Equal(t, []op.Code{
+		op.LoadFastLoadFast,
+		op.ComparePopJumpForwardIfFalse,
+		op.LoadFast,
+		op.BinaryOpConst,
+		op.ReturnValue,
+		op.Nil,
+		op.PopTop,
+		op.LoadFastLoadFast,
+		op.BinaryOp,
+		op.ReturnValue,
+	}, opcodes(functionCode(t, code, 0)))
+}
@@ -0,0 +1,318 @@
+// Package disasm renders compiled Risor code as human readable listings of
+// bytecode instructions.
+package disasm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
+)
+
+// Instruction is a single decoded bytecode instruction.
+type Instruction struct {
+	// Offset of the opcode in the code instructions
+	Offset int
+
+	// Opcode and its name, e.g. LOAD_CONST
+	Opcode op.Code
+	Name   string
+
+	// Operands that follow the opcode
+	Operands []op.Code
+
+	// Annotation describes the operands, for example the value of a constant,
+	// the name of a variable, or the target of a jump. It may be empty.
+	Annotation string
+
+	// Line is the 1-indexed source line the instruction was compiled from,
+	// or 0 if it is unknown.
+	Line int
+}
+
+// Disassemble decodes the instructions of the given code, using the operand
+// counts found in the op package. An error is returned if the instructions
+// contain an unknown opcode or end partway through an instruction.
+func Disassemble(code *object.Code) ([]Instruction, error) {
+	var locals, globals map[int]string
+	if code.Symbols != nil {
+		locals = symbolNames(code.Symbols)
+		globals = symbolNames(code.Symbols.Root())
+	}
+	var result []Instruction
+	instructions := code.Instructions
+	for offset := 0; offset < len(instructions); {
+		opcode := instructions[offset]
+		var info op.Info
+		if int(opcode) < len(op.OperandCount) {
+			info = op.GetInfo(opcode)
+		}
+		if info.Name == "" {
+			return nil, fmt.Errorf("disasm error: unknown opcode %d at offset %d", opcode, offset)
+		}
+		end := offset + 1 + info.OperandCount
+		if end > len(instructions) {
+			return nil, fmt.Errorf("disasm error: truncated %s instruction at offset %d", info.Name, offset)
+		}
+		instr := Instruction{
+			Offset:   offset,
+			Opcode:   opcode,
+			Name:     info.Name,
+			Operands: instructions[offset+1 : end],
+		}
+		if pos, ok := code.LocationAt(offset); ok {
+			instr.Line = pos.LineNumber()
+		}
+		instr.Annotation = annotate(code, locals, globals, instr)
+		result = append(result, instr)
+		offset = end
+	}
+	return result, nil
+}
+
+// Fprint writes a listing of the code to w, followed by listings of the
+// functions it defines.
+func Fprint(w io.Writer, code *object.Code) error {
+	instructions, err := Disassemble(code)
+	if err != nil {
+		return err
+	}
+	return FprintInstructions(w, code, instructions)
+}
+
+// FprintFunction writes a listing of the function body to w, followed by
+// listings of the functions defined within it.
+func FprintFunction(w io.Writer, fn *object.Function) error {
+	instructions, err := Disassemble(fn.Code())
+	if err != nil {
+		return err
+	}
+	return fprintCode(w, signature(fn), fn.Code(), instructions)
+}
+
+// FprintInstructions writes a listing of the given instructions to w, which
+// must have been disassembled from the given code. It is followed by listings
+// of the functions loaded by the instructions, including nested functions.
+func FprintInstructions(w io.Writer, code *object.Code, instructions []Instruction) error {
+	name := code.Name
+	if name == "" {
+		name = "main"
+	}
+	return fprintCode(w, name, code, instructions)
+}
+
+// fprintCode writes the listing of the code, followed by the listings of
+// the functions it loads, recursively.
+func fprintCode(w io.Writer, heading string, code *object.Code, instructions []Instruction) error {
+	if err := fprintListing(w, heading, instructions); err != nil {
+		return err
+	}
+	for _, fn := range loadedFunctions(code, instructions) {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+		fnInstructions, err := Disassemble(fn.Code())
+		if err != nil {
+			return err
+		}
+		if err := fprintCode(w, signature(fn), fn.Code(), fnInstructions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fprintListing writes a heading followed by one line per instruction. The
+// source line number is shown on the first instruction of each line.
+func fprintListing(w io.Writer, heading string, instructions []Instruction) error {
+	if _, err := fmt.Fprintf(w, "%s:\n", heading); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	lastLine := 0
+	for _, instr := range instructions {
+		line := ""
+		if instr.Line != 0 && instr.Line != lastLine {
+			line = fmt.Sprint(instr.Line)
+			lastLine = instr.Line
+		}
+		operands := make([]string, 0, len(instr.Operands))
+		for _, operand := range instr.Operands {
+			operands = append(operands, fmt.Sprint(operand))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", line, instr.Offset, instr.Name,
+			strings.Join(operands, " "), instr.Annotation)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Drop the padding that is left after the last non-empty column
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(w, strings.TrimRight(line, " \n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadedFunctions returns the function constants that are loaded by the
+// given instructions, in the order they are first loaded.
+func loadedFunctions(code *object.Code, instructions []Instruction) []*object.Function {
+	var result []*object.Function
+	seen := map[int]bool{}
+	for _, instr := range instructions {
+		if instr.Opcode != op.LoadConst && instr.Opcode != op.LoadClosure {
+			continue
+		}
+		index := int(instr.Operands[0])
+		if seen[index] || index >= len(code.Constants) {
+			continue
+		}
+		seen[index] = true
+		if fn, ok := code.Constants[index].(*object.Function); ok {
+			result = append(result, fn)
+		}
+	}
+	return result
+}
+
+// symbolNames returns the names of the symbols in the table, indexed by
+// their symbol index. Variables declared in blocks are not included, since
+// the compiler discards block symbol tables once they are compiled.
+func symbolNames(table *object.SymbolTable) map[int]string {
+	names := map[int]string{}
+	for _, name := range table.InsertedNames() {
+		if symbol, ok := table.Get(name); ok {
+			names[int(symbol.Index)] = name
+		}
+	}
+	return names
+}
+
+// annotate returns a description of the operands of the instruction.
+func annotate(code *object.Code, locals, globals map[int]string, instr Instruction) string {
+	if len(instr.Operands) == 0 {
+		return ""
+	}
+	operand := int(instr.Operands[0])
+	switch instr.Opcode {
+	case op.LoadConst, op.LoadClosure:
+		if operand < len(code.Constants) {
+			return describeConstant(code.Constants[operand])
+		}
+	case op.LoadAttr, op.LoadAttrOrNil, op.StoreAttr, op.LoadName, op.StoreName:
+		if operand < len(code.Names) {
+			return code.Names[operand]
+		}
+	case op.LoadFast, op.StoreFast:
+		return locals[operand]
+	case op.LoadGlobal, op.StoreGlobal:
+		return globals[operand]
+	case op.LoadFree, op.StoreFree:
+		if code.Symbols != nil {
+			free := code.Symbols.Free()
+			if operand < len(free) && free[operand] != nil {
+				return free[operand].Symbol.Name
+			}
+		}
+	case op.BinaryOp:
+		return binaryOps[op.BinaryOpType(operand)]
+	case op.BinaryOpConst:
+		if index := int(instr.Operands[1]); index < len(code.Constants) {
+			return binaryOps[op.BinaryOpType(operand)] + " " + describeConstant(code.Constants[index])
+		}
+	case op.CompareOp:
+		return compareOps[op.CompareOpType(operand)]
+	case op.ComparePopJumpForwardIfFalse:
+		return fmt.Sprintf("%s, to %d", compareOps[op.CompareOpType(operand)],
+			instr.Offset+int(instr.Operands[1]))
+	case op.LoadFastLoadFast:
+		return locals[operand] + ", " + locals[int(instr.Operands[1])]
+	case op.ContainsOp:
+		if operand == 1 {
+			return "not in"
+		}
+		return "in"
+	case op.JumpForward, op.PopJumpForwardIfFalse, op.PopJumpForwardIfTrue,
+		op.PopJumpForwardIfNil, op.PopJumpForwardIfNotNil, op.ForIter, op.UnwrapResult:
+		return fmt.Sprintf("to %d", instr.Offset+operand)
+	case op.JumpBackward, op.PopJumpBackwardIfFalse, op.PopJumpBackwardIfTrue:
+		return fmt.Sprintf("to %d", instr.Offset-operand)
+	case op.SetupTry:
+		if operand < len(code.Handlers) {
+			return fmt.Sprintf("handler at %d", code.Handlers[operand].Target)
+		}
+	}
+	return ""
+}
+
+// maxConstantLength limits the length of constants shown in annotations.
+const maxConstantLength = 40
+
+func describeConstant(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return signature(fn)
+	}
+	s := obj.Inspect()
+	if len(s) > maxConstantLength {
+		s = s[:maxConstantLength-3] + "..."
+	}
+	return s
+}
+
+// signature returns the function name and parameters, e.g.
+// "func add(a, b=1)".
+func signature(fn *object.Function) string {
+	defaults := fn.Defaults()
+	params := make([]string, 0, len(fn.Parameters()))
+	for i, name := range fn.Parameters() {
+		if i < len(defaults) && defaults[i] != nil {
+			name += "=" + defaults[i].Inspect()
+		}
+		params = append(params, name)
+	}
+	if rest := fn.RestParameter(); rest != "" {
+		params = append(params, "*"+rest)
+	}
+	if kwargs := fn.KwargsParameter(); kwargs != "" {
+		params = append(params, "**"+kwargs)
+	}
+	name := "func"
+	if fn.Name() != "" {
+		name += " " + fn.Name()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+}
+
+var binaryOps = map[op.BinaryOpType]string{
+	op.Add:        "+",
+	op.Subtract:   "-",
+	op.Multiply:   "*",
+	op.Divide:     "/",
+	op.Modulo:     "%",
+	op.And:        "&&",
+	op.Or:         "||",
+	op.Xor:        "^",
+	op.Power:      "**",
+	op.LShift:     "<<",
+	op.RShift:     ">>",
+	op.BitwiseAnd: "&",
+	op.BitwiseOr:  "|",
+}
+
+var compareOps = map[op.CompareOpType]string{
+	op.LessThan:           "<",
+	op.LessThanOrEqual:    "<=",
+	op.Equal:              "==",
+	op.NotEqual:           "!=",
+	op.GreaterThan:        ">",
+	op.GreaterThanOrEqual: ">=",
+}
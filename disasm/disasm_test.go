@@ -0,0 +1,112 @@
+package disasm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/risor-io/risor/compiler"
+	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func compile(t *testing.T, source string, options ...compiler.Option) *object.Code {
+	t.Helper()
+	ast, err := parser.Parse(context.Background(), source)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, options...)
+	require.Nil(t, err)
+	return code
+}
+
+func TestDisassemble(t *testing.T) {
+	code := compile(t, `x := 2; y := x * 3; y == 6`)
+	instructions, err := Disassemble(code)
+	require.Nil(t, err)
+	require.Len(t, instructions, 9)
+
+	require.Equal(t, Instruction{
+		Offset:     4,
+		Opcode:     op.LoadGlobal,
+		Name:       "LOAD_GLOBAL",
+		Operands:   []op.Code{0},
+		Annotation: "x",
+		Line:       1,
+	}, instructions[2])
+	require.Equal(t, "BINARY_OP", instructions[4].Name)
+	require.Equal(t, "*", instructions[4].Annotation)
+	require.Equal(t, "COMPARE_OP", instructions[8].Name)
+	require.Equal(t, "==", instructions[8].Annotation)
+}
+
+func TestFprint(t *testing.T) {
+	code := compile(t, "x := 2\nfunc add(a, b=1) {\n  return a + b\n}\nif x > 1 { add(x) }")
+	var buf bytes.Buffer
+	require.Nil(t, Fprint(&buf, code))
+	expected := `main:
+1  0   LOAD_CONST                 0   2
+   2   STORE_GLOBAL               0   x
+2  4   LOAD_CONST                 1   func add(a, b=1)
+   6   STORE_GLOBAL               1   add
+5  8   LOAD_GLOBAL                0   x
+   10  LOAD_CONST                 2   1
+   12  COMPARE_OP                 4   >
+   14  POP_JUMP_FORWARD_IF_FALSE  10  to 24
+   16  LOAD_GLOBAL                1   add
+   18  LOAD_GLOBAL                0   x
+   20  CALL                       1
+   22  JUMP_FORWARD               3   to 25
+   24  NIL
+
+func add(a, b=1):
+3  0  LOAD_FAST     0  a
+   2  LOAD_FAST     1  b
+   4  BINARY_OP     0  +
+   6  RETURN_VALUE
+2  7  NIL
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestFprintOptimized(t *testing.T) {
+	code := compile(t, "func f(a, b) {\n  if a < b { return a + 1 }\n  return a * b\n}",
+		compiler.WithOptimizations())
+	var buf bytes.Buffer
+	require.Nil(t, FprintFunction(&buf, code.Constants[0].(*object.Function)))
+	expected := `func f(a, b):
+2  0   LOAD_FAST_LOAD_FAST                0 1  a, b
+   3   COMPARE_POP_JUMP_FORWARD_IF_FALSE  0 9  <, to 12
+   6   LOAD_FAST                          0    a
+   8   BINARY_OP_CONST                    0 0  + 1
+   11  RETURN_VALUE
+   12  NIL
+1  13  POP_TOP
+3  14  LOAD_FAST_LOAD_FAST                0 1  a, b
+   17  BINARY_OP                          2    *
+   19  RETURN_VALUE
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestFprintNested(t *testing.T) {
+	code := compile(t, `func outer() { return func(y) { y + 1 } }; for i := range [1] { i }`)
+	var buf bytes.Buffer
+	require.Nil(t, Fprint(&buf, code))
+	listing := buf.String()
+	require.Contains(t, listing, "\nfunc outer():\n")
+	require.Contains(t, listing, "\nfunc(y):\n")
+	require.Contains(t, listing, "JUMP_BACKWARD")
+	require.Contains(t, listing, "FOR_ITER")
+}
+
+func TestDisassembleErrors(t *testing.T) {
+	_, err := Disassemble(&object.Code{Instructions: []op.Code{op.Nop, op.LoadConst}})
+	require.NotNil(t, err)
+	require.Equal(t, "disasm error: truncated LOAD_CONST instruction at offset 1", err.Error())
+
+	_, err = Disassemble(&object.Code{Instructions: []op.Code{op.Nop, 999}})
+	require.NotNil(t, err)
+	require.Equal(t, "disasm error: unknown opcode 999 at offset 1", err.Error())
+}
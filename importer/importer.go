@@ -1,7 +1,10 @@
 package importer
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,17 +21,26 @@ type Importer interface {
 	Import(ctx context.Context, name string) (*object.Module, error)
 }
 
+// CompiledExtension is the file extension of compiled Risor modules.
+const CompiledExtension = ".rsc"
+
 type LocalImporter struct {
 	builtins   map[string]object.Object
 	modules    map[string]*object.Module
 	sourceDir  string
 	extensions []string
+	writeCache bool
 }
 
 type LocalImporterOptions struct {
 	Builtins   map[string]object.Object
 	SourceDir  string
 	Extensions []string
+
+	// WriteCache enables writing a compiled ".rsc" file alongside each
+	// module that is compiled from source. Compiled files are always used,
+	// when present, if they match the module source.
+	WriteCache bool
 }
 
 func NewLocalImporter(opts LocalImporterOptions) *LocalImporter {
@@ -43,6 +55,7 @@ func NewLocalImporter(opts LocalImporterOptions) *LocalImporter {
 		modules:    map[string]*object.Module{},
 		sourceDir:  opts.SourceDir,
 		extensions: opts.Extensions,
+		writeCache: opts.WriteCache,
 	}
 }
 
@@ -54,6 +67,13 @@ func (i *LocalImporter) Import(ctx context.Context, name string) (*object.Module
 	if !found {
 		return nil, fmt.Errorf("module not found: %s", name)
 	}
+	// Use the compiled module if it was compiled from the same source. If it
+	// is stale or unreadable, the module is compiled from source instead.
+	compiledPath := filepath.Join(i.sourceDir, name+CompiledExtension)
+	if code, err := ReadCompiled(compiledPath, source, i.builtins); err == nil {
+		code.Name = fmt.Sprintf("module: %s", name)
+		return object.NewModule(name, code), nil
+	}
 	cmp, err := compiler.New(compiler.WithBuiltins(i.builtins))
 	if err != nil {
 		return nil, err
@@ -66,10 +86,46 @@ func (i *LocalImporter) Import(ctx context.Context, name string) (*object.Module
 	if err != nil {
 		return nil, err
 	}
+	if i.writeCache {
+		// The cache is an optimization, so failing to write it is not an error
+		WriteCompiled(compiledPath, source, code)
+	}
 	code.Name = fmt.Sprintf("module: %s", name)
 	return object.NewModule(name, code), nil
 }
 
+// ErrStaleCompiled is returned by ReadCompiled when a compiled file was
+// compiled from different source code.
+var ErrStaleCompiled = errors.New("import error: compiled code does not match source")
+
+// WriteCompiled writes the compiled code to a ".rsc" file at the given path.
+// The file begins with the SHA-256 hash of the source code, followed by the
+// code as encoded by object.MarshalCode.
+func WriteCompiled(path, source string, code *object.Code) error {
+	data, err := object.MarshalCode(code)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(source))
+	return os.WriteFile(path, append(hash[:], data...), 0o644)
+}
+
+// ReadCompiled reads compiled code from a ".rsc" file at the given path, as
+// written by WriteCompiled. ErrStaleCompiled is returned if the code was not
+// compiled from the given source. The builtins must include those that were
+// available when the code was compiled.
+func ReadCompiled(path, source string, builtins map[string]object.Object) (*object.Code, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(source))
+	if len(data) < len(hash) || !bytes.Equal(data[:len(hash)], hash[:]) {
+		return nil, ErrStaleCompiled
+	}
+	return object.UnmarshalCode(data[len(hash):], builtins)
+}
+
 func readFileWithExtensions(dir, name string, extensions []string) (string, bool) {
 	for _, ext := range extensions {
 		fullPath := filepath.Join(dir, name+ext)
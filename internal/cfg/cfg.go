@@ -13,4 +13,7 @@ type RisorConfig struct {
 	Importer        importer.Importer
 	LocalImportPath string
 	Offset          int
+	Optimize        bool
+	MaxFrameDepth   int
+	MaxStackDepth   int
 }
@@ -130,7 +130,11 @@ func (l *Lexer) Next() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.AND, string(ch)+string(l.ch))
+		} else {
+			tok = l.newToken(token.AMPERSAND, string(l.ch))
 		}
+	case rune('^'):
+		tok = l.newToken(token.CARET, string(l.ch))
 	case rune('|'):
 		if l.peekChar() == rune('|') {
 			ch := l.ch
@@ -150,7 +154,17 @@ func (l *Lexer) Next() (token.Token, error) {
 	case rune(';'):
 		tok = l.newToken(token.SEMICOLON, string(l.ch))
 	case rune('?'):
-		tok = l.newToken(token.QUESTION, string(l.ch))
+		if l.peekChar() == rune('?') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.QUESTION_QUESTION, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('.') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.QUESTION_PERIOD, string(ch)+string(l.ch))
+		} else {
+			tok = l.newToken(token.QUESTION, string(l.ch))
+		}
 	case rune('('):
 		tok = l.newToken(token.LPAREN, string(l.ch))
 	case rune(')'):
@@ -158,7 +172,13 @@ func (l *Lexer) Next() (token.Token, error) {
 	case rune(','):
 		tok = l.newToken(token.COMMA, string(l.ch))
 	case rune('.'):
-		tok = l.newToken(token.PERIOD, string(l.ch))
+		if l.peekChar() == rune('.') && l.peekCharAt(2) == rune('.') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.ELLIPSIS, "...")
+		} else {
+			tok = l.newToken(token.PERIOD, string(l.ch))
+		}
 	case rune('+'):
 		if l.peekChar() == rune('+') {
 			ch := l.ch
@@ -218,6 +238,10 @@ func (l *Lexer) Next() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.LT_EQUALS, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('-') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.ARROW, string(ch)+string(l.ch))
 		} else {
 			tok = l.newToken(token.LT, string(l.ch))
 		}
@@ -442,19 +466,39 @@ func (l *Lexer) readNumber(onlyDecimal bool) (NumberType, string, error) {
 		str += string(l.ch)
 	}
 	trailing := l.peekChar()
+	if l.peekImaginarySuffix() {
+		return numberType, str, nil
+	}
 	if unicode.IsLetter(trailing) || unicode.IsNumber(trailing) {
 		return NumberTypeInvalid, "", fmt.Errorf("invalid decimal literal: %s%c", str, trailing)
 	}
 	return numberType, str, nil
 }
 
-// Read an integer or floating point number
+// peekImaginarySuffix returns true if the next character is an "i" that ends
+// the number, making it an imaginary literal like "2i".
+func (l *Lexer) peekImaginarySuffix() bool {
+	if l.peekChar() != 'i' {
+		return false
+	}
+	next := l.peekCharAt(2)
+	return !unicode.IsLetter(next) && !unicode.IsNumber(next) && next != '_'
+}
+
+// Read an integer, floating point, or imaginary number
 func (l *Lexer) readDecimal() (token.Token, error) {
 	// Read an integer
 	numberType, integer, err := l.readNumber(false)
 	if err != nil {
 		return token.Token{}, err
 	}
+	if l.peekImaginarySuffix() {
+		if numberType == NumberTypeHex {
+			return token.Token{}, fmt.Errorf("invalid imaginary literal: %si", integer)
+		}
+		l.readChar()
+		return l.newToken(token.IMAGINARY, integer+"i"), nil
+	}
 	hasDot := l.peekChar() == rune('.')
 	if !hasDot {
 		return l.newToken(token.INT, integer), nil
@@ -473,6 +517,10 @@ func (l *Lexer) readDecimal() (token.Token, error) {
 		if numberType != NumberTypeDecimal {
 			return token.Token{}, fmt.Errorf("invalid decimal literal: %s.%s", integer, fraction)
 		}
+		if l.peekImaginarySuffix() {
+			l.readChar()
+			return l.newToken(token.IMAGINARY, integer+"."+fraction+"i"), nil
+		}
 		return l.newToken(token.FLOAT, integer+"."+fraction), nil
 	}
 	// We reach this point with something like "42.foo"
@@ -536,6 +584,16 @@ func (l *Lexer) peekChar() rune {
 	return l.characters[l.nextPosition]
 }
 
+// peekCharAt returns the character the given distance ahead of the current
+// character, without advancing.
+func (l *Lexer) peekCharAt(distance int) rune {
+	pos := l.position + distance
+	if pos >= len(l.characters) {
+		return rune(0)
+	}
+	return l.characters[pos]
+}
+
 // GetLineText returns the text of the line containing the given token.
 func (l *Lexer) GetLineText(t token.Token) string {
 
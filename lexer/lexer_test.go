@@ -720,6 +720,8 @@ func TestInvalids(t *testing.T) {
 		{"4a.f", "invalid decimal literal: 4a"},
 		{"0x.1", "invalid decimal literal: 0x."},
 		{"0b.1", "invalid decimal literal: 0b"},
+		{"0x1i", "invalid imaginary literal: 0x1i"},
+		{"2in", "invalid decimal literal: 2i"},
 		{`"foo`, "unterminated string literal"},
 		{"`foo", "unterminated string literal"},
 		{"'foo", "unterminated string literal"},
@@ -734,3 +736,157 @@ func TestInvalids(t *testing.T) {
 		})
 	}
 }
+
+func TestImaginary(t *testing.T) {
+	input := `2i + 1.5i; 3 in x`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IMAGINARY, "2i"},
+		{token.PLUS, "+"},
+		{token.IMAGINARY, "1.5i"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "3"},
+		{token.IN, "in"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestArrow(t *testing.T) {
+	input := `ch <- x; y := <-ch; a < -1`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "ch"},
+		{token.ARROW, "<-"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "y"},
+		{token.DECLARE, ":="},
+		{token.ARROW, "<-"},
+		{token.IDENT, "ch"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.LT, "<"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `f(...args); a.b`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "args"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PERIOD, "."},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestQuestionOperators(t *testing.T) {
+	input := `a?.b ?? c ? d : e`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.QUESTION_PERIOD, "?."},
+		{token.IDENT, "b"},
+		{token.QUESTION_QUESTION, "??"},
+		{token.IDENT, "c"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "d"},
+		{token.COLON, ":"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	input := `a & b ^ c | d && e`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.CARET, "^"},
+		{token.IDENT, "c"},
+		{token.PIPE, "|"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	input := `struct Point { x }`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.STRUCT, "struct"},
+		{token.IDENT, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type)
+		require.Equal(t, tt.expectedLiteral, tok.Literal)
+	}
+}
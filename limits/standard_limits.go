@@ -3,10 +3,14 @@ package limits
 import (
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// StandardLimits is the standard implementation of Limits. It is safe for
+// concurrent use, so it may be shared by goroutines.
 type StandardLimits struct {
+	mutex sync.Mutex
 	// Configuration
 	ioTimeout           time.Duration
 	maxBufferSize       int64
@@ -26,6 +30,8 @@ func (l *StandardLimits) MaxBufferSize() int64 {
 }
 
 func (l *StandardLimits) TrackHTTPRequest(req *http.Request) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.httpRequestsCount++
 	if l.maxHttpRequestCount > NoLimit && l.httpRequestsCount > l.maxHttpRequestCount {
 		return NewLimitsError("limit error: reached maximum number of http requests (%d)", l.maxHttpRequestCount)
@@ -42,6 +48,8 @@ func (l *StandardLimits) TrackHTTPResponse(resp *http.Response) error {
 }
 
 func (l *StandardLimits) TrackCost(cost int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.cost += int64(cost)
 	if l.maxCost > NoLimit && l.cost > l.maxCost {
 		return NewLimitsError("limit error: reached maximum processing cost (%d)", l.maxCost)
@@ -53,7 +61,9 @@ func (l *StandardLimits) ReadAll(reader io.Reader) ([]byte, error) {
 	if l.maxCost <= NoLimit {
 		return io.ReadAll(reader)
 	}
+	l.mutex.Lock()
 	remainingCost := l.maxCost - l.cost
+	l.mutex.Unlock()
 	if remainingCost <= 0 {
 		return nil, NewLimitsError("limit error: reached maximum processing cost (%d)", l.maxCost)
 	}
@@ -61,8 +71,7 @@ func (l *StandardLimits) ReadAll(reader io.Reader) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	l.cost += int64(len(bytes))
-	return bytes, nil
+	return bytes, l.TrackCost(len(bytes))
 }
 
 // Option is a function that configures a Limits instance.
@@ -5,6 +5,7 @@ import (
 	modAws "github.com/risor-io/risor/modules/aws"
 	modBase64 "github.com/risor-io/risor/modules/base64"
 	modBytes "github.com/risor-io/risor/modules/bytes"
+	modCmath "github.com/risor-io/risor/modules/cmath"
 	modFetch "github.com/risor-io/risor/modules/fetch"
 	modFmt "github.com/risor-io/risor/modules/fmt"
 	modHash "github.com/risor-io/risor/modules/hash"
@@ -14,6 +15,7 @@ import (
 	modOs "github.com/risor-io/risor/modules/os"
 	modPgx "github.com/risor-io/risor/modules/pgx"
 	modRand "github.com/risor-io/risor/modules/rand"
+	modRegexp "github.com/risor-io/risor/modules/regexp"
 	modStrconv "github.com/risor-io/risor/modules/strconv"
 	modStrings "github.com/risor-io/risor/modules/strings"
 	modTime "github.com/risor-io/risor/modules/time"
@@ -25,10 +27,12 @@ func Builtins() map[string]object.Object {
 	result := map[string]object.Object{
 		"aws":     modAws.Module(),
 		"math":    modMath.Module(),
+		"cmath":   modCmath.Module(),
 		"json":    modJson.Module(),
 		"strings": modStrings.Module(),
 		"time":    modTime.Module(),
 		"rand":    modRand.Module(),
+		"regexp":  modRegexp.Module(),
 		"strconv": modStrconv.Module(),
 		"pgx":     modPgx.Module(),
 		"uuid":    modUuid.Module(),
@@ -0,0 +1,139 @@
+package cmath
+
+import (
+	"context"
+	"math/cmplx"
+
+	"github.com/risor-io/risor/internal/arg"
+	"github.com/risor-io/risor/object"
+)
+
+func Abs(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.abs", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewFloat(cmplx.Abs(x))
+}
+
+func Conj(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.conj", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewComplex(cmplx.Conj(x))
+}
+
+func Exp(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.exp", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewComplex(cmplx.Exp(x))
+}
+
+func IsInf(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.is_inf", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(cmplx.IsInf(x))
+}
+
+func IsNaN(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.is_nan", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(cmplx.IsNaN(x))
+}
+
+func Log(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.log", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewComplex(cmplx.Log(x))
+}
+
+func Phase(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.phase", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewFloat(cmplx.Phase(x))
+}
+
+func Polar(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.polar", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	r, theta := cmplx.Polar(x)
+	return object.NewList([]object.Object{object.NewFloat(r), object.NewFloat(theta)})
+}
+
+func Rect(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.rect", 2, args); err != nil {
+		return err
+	}
+	r, err := object.AsFloat(args[0])
+	if err != nil {
+		return err
+	}
+	theta, err := object.AsFloat(args[1])
+	if err != nil {
+		return err
+	}
+	return object.NewComplex(cmplx.Rect(r, theta))
+}
+
+func Sqrt(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("cmath.sqrt", 1, args); err != nil {
+		return err
+	}
+	x, err := object.AsComplex(args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewComplex(cmplx.Sqrt(x))
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("cmath", map[string]object.Object{
+		"abs":    object.NewBuiltin("abs", Abs),
+		"conj":   object.NewBuiltin("conj", Conj),
+		"exp":    object.NewBuiltin("exp", Exp),
+		"is_inf": object.NewBuiltin("is_inf", IsInf),
+		"is_nan": object.NewBuiltin("is_nan", IsNaN),
+		"log":    object.NewBuiltin("log", Log),
+		"phase":  object.NewBuiltin("phase", Phase),
+		"polar":  object.NewBuiltin("polar", Polar),
+		"rect":   object.NewBuiltin("rect", Rect),
+		"sqrt":   object.NewBuiltin("sqrt", Sqrt),
+	})
+}
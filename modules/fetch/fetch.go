@@ -55,6 +55,7 @@ func Fetch(ctx context.Context, args ...object.Object) object.Object {
 
 func Builtins() map[string]object.Object {
 	return map[string]object.Object{
-		"fetch": object.NewBuiltin("fetch", Fetch),
+		"fetch":     object.NewBuiltin("fetch", Fetch),
+		"try_fetch": object.NewResultBuiltin("try_fetch", Fetch),
 	}
 }
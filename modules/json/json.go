@@ -62,8 +62,9 @@ func Valid(ctx context.Context, args ...object.Object) object.Object {
 
 func Module() *object.Module {
 	return object.NewBuiltinsModule("json", map[string]object.Object{
-		"unmarshal": object.NewBuiltin("unmarshal", Unmarshal),
-		"marshal":   object.NewBuiltin("marshal", Marshal),
-		"valid":     object.NewBuiltin("valid", Valid),
+		"unmarshal":     object.NewBuiltin("unmarshal", Unmarshal),
+		"marshal":       object.NewBuiltin("marshal", Marshal),
+		"valid":         object.NewBuiltin("valid", Valid),
+		"try_unmarshal": object.NewResultBuiltin("try_unmarshal", Unmarshal),
 	})
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/risor-io/risor/internal/arg"
 	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
 )
 
 func Abs(ctx context.Context, args ...object.Object) object.Object {
@@ -25,6 +26,16 @@ func Abs(ctx context.Context, args ...object.Object) object.Object {
 			v = v * -1
 		}
 		return object.NewFloat(v)
+	case *object.BigInt:
+		if arg.Value().Sign() < 0 {
+			return arg.Neg()
+		}
+		return arg
+	case *object.Decimal:
+		if arg.Unscaled().Sign() < 0 {
+			return arg.Neg()
+		}
+		return arg
 	default:
 		return object.Errorf("type error: argument to math.abs not supported, got=%s", args[0].Type())
 	}
@@ -157,6 +168,12 @@ func Sum(ctx context.Context, args ...object.Object) object.Object {
 	if len(array) == 0 {
 		return object.NewFloat(0)
 	}
+	for _, value := range array {
+		switch value.(type) {
+		case *object.BigInt, *object.Decimal:
+			return sumExact(array)
+		}
+	}
 	var sum float64
 	for _, value := range array {
 		switch val := value.(type) {
@@ -171,6 +188,24 @@ func Sum(ctx context.Context, args ...object.Object) object.Object {
 	return object.NewFloat(sum)
 }
 
+// sumExact sums values without converting them to floats, which is used when
+// any of the values is a bigint or decimal so that no precision is lost.
+func sumExact(array []object.Object) object.Object {
+	var sum object.Object = object.NewInt(0)
+	for _, value := range array {
+		switch value.(type) {
+		case *object.Int, *object.BigInt, *object.Decimal:
+			sum = sum.RunOperation(op.Add, value)
+		default:
+			return object.Errorf("value error: invalid input for math.sum: %s", value.Type())
+		}
+		if object.IsError(sum) {
+			return sum
+		}
+	}
+	return sum
+}
+
 func Ceil(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("math.ceil", 1, args); err != nil {
 		return err
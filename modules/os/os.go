@@ -514,6 +514,7 @@ func Module() *object.Module {
 		"stat":            object.NewBuiltin("stat", Stat),
 		"symlink":         object.NewBuiltin("symlink", Symlink),
 		"temp_dir":        object.NewBuiltin("temp_dir", TempDir),
+		"try_read_file":   object.NewResultBuiltin("try_read_file", ReadFile),
 		"unsetenv":        object.NewBuiltin("unsetenv", Unsetenv),
 		"user_cache_dir":  object.NewBuiltin("user_cache_dir", UserCacheDir),
 		"user_config_dir": object.NewBuiltin("user_config_dir", UserConfigDir),
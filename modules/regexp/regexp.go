@@ -0,0 +1,121 @@
+package regexp
+
+import (
+	"context"
+
+	"github.com/risor-io/risor/internal/arg"
+	"github.com/risor-io/risor/object"
+)
+
+func Compile(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("regexp.compile", 1, args); err != nil {
+		return err
+	}
+	pattern, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	re, err := object.CompileRegexp(pattern)
+	if err != nil {
+		return err
+	}
+	return re
+}
+
+func Match(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("regexp.match", 2, args); err != nil {
+		return err
+	}
+	re, err := object.AsRegexp(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(re.Value().MatchString(s))
+}
+
+func FindAll(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("regexp.find_all", 2, 3, args); err != nil {
+		return err
+	}
+	re, err := object.AsRegexp(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	n := int64(-1)
+	if len(args) == 3 {
+		if n, err = object.AsInt(args[2]); err != nil {
+			return err
+		}
+	}
+	return re.FindAll(s, int(n))
+}
+
+func FindSubmatch(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("regexp.find_submatch", 2, args); err != nil {
+		return err
+	}
+	re, err := object.AsRegexp(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	return re.FindSubmatch(s)
+}
+
+func ReplaceAll(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("regexp.replace_all", 3, args); err != nil {
+		return err
+	}
+	re, err := object.AsRegexp(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	return re.ReplaceAll(ctx, s, args[2])
+}
+
+func Split(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("regexp.split", 2, 3, args); err != nil {
+		return err
+	}
+	re, err := object.AsRegexp(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	n := int64(-1)
+	if len(args) == 3 {
+		if n, err = object.AsInt(args[2]); err != nil {
+			return err
+		}
+	}
+	return re.Split(s, int(n))
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("regexp", map[string]object.Object{
+		"compile":       object.NewBuiltin("compile", Compile),
+		"find_all":      object.NewBuiltin("find_all", FindAll),
+		"find_submatch": object.NewBuiltin("find_submatch", FindSubmatch),
+		"match":         object.NewBuiltin("match", Match),
+		"replace_all":   object.NewBuiltin("replace_all", ReplaceAll),
+		"split":         object.NewBuiltin("split", Split),
+	})
+}
@@ -76,11 +76,52 @@ func ParseInt(ctx context.Context, args ...object.Object) object.Object {
 	return object.NewError(err)
 }
 
+func ParseBigInt(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("strconv.parse_bigint", 1, 2, args); err != nil {
+		return err
+	}
+	s, typeErr := object.AsString(args[0])
+	if typeErr != nil {
+		return typeErr
+	}
+	base := int64(10)
+	if len(args) == 2 {
+		if base, typeErr = object.AsInt(args[1]); typeErr != nil {
+			return typeErr
+		}
+	}
+	if base != 0 && (base < 2 || base > 62) {
+		return object.Errorf("value error: strconv.parse_bigint() invalid base %d", base)
+	}
+	i, err := object.ParseBigInt(s, int(base))
+	if err != nil {
+		return err
+	}
+	return i
+}
+
+func ParseDecimal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("strconv.parse_decimal", 1, args); err != nil {
+		return err
+	}
+	s, typeErr := object.AsString(args[0])
+	if typeErr != nil {
+		return typeErr
+	}
+	d, err := object.ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	return d
+}
+
 func Module() *object.Module {
 	return object.NewBuiltinsModule("strconv", map[string]object.Object{
-		"atoi":        object.NewBuiltin("atoi", Atoi),
-		"parse_bool":  object.NewBuiltin("parse_bool", ParseBool),
-		"parse_float": object.NewBuiltin("parse_float", ParseFloat),
-		"parse_int":   object.NewBuiltin("parse_int", ParseInt),
+		"atoi":          object.NewBuiltin("atoi", Atoi),
+		"parse_bigint":  object.NewBuiltin("parse_bigint", ParseBigInt),
+		"parse_bool":    object.NewBuiltin("parse_bool", ParseBool),
+		"parse_decimal": object.NewBuiltin("parse_decimal", ParseDecimal),
+		"parse_float":   object.NewBuiltin("parse_float", ParseFloat),
+		"parse_int":     object.NewBuiltin("parse_int", ParseInt),
 	})
 }
@@ -0,0 +1,229 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/risor-io/risor/op"
+)
+
+// MaxBigIntBits limits the size of the result of a BigInt operation, so that
+// a single operation such as 3 ** 100000000 can't run for an unbounded time
+// or allocate unbounded memory.
+const MaxBigIntBits = 1 << 20
+
+// BigInt wraps an arbitrary precision integer. Int arithmetic is promoted to
+// BigInt when its result would overflow an int64, and BigInt results that fit
+// in an int64 are returned as an Int.
+type BigInt struct {
+	*base
+	value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+	return b.value.String()
+}
+
+func (b *BigInt) Type() Type {
+	return BIGINT
+}
+
+// Value returns the underlying big.Int. It must not be modified.
+func (b *BigInt) Value() *big.Int {
+	return b.value
+}
+
+// IsInt64 returns true if the value can be represented as an int64.
+func (b *BigInt) IsInt64() bool {
+	return b.value.IsInt64()
+}
+
+func (b *BigInt) HashKey() HashKey {
+	// Hash the same as an Int of equal value, since the two compare equal
+	if b.value.IsInt64() {
+		return HashKey{Type: INT, IntValue: b.value.Int64()}
+	}
+	return HashKey{Type: b.Type(), StrValue: b.value.String()}
+}
+
+func (b *BigInt) Interface() interface{} {
+	return new(big.Int).Set(b.value)
+}
+
+func (b *BigInt) String() string {
+	return b.Inspect()
+}
+
+func (b *BigInt) Compare(other Object) (int, error) {
+	switch other := other.(type) {
+	case *BigInt:
+		return b.value.Cmp(other.value), nil
+	case *Int:
+		return b.value.Cmp(big.NewInt(other.value)), nil
+	case *Byte:
+		return b.value.Cmp(big.NewInt(int64(other.value))), nil
+	case *Float:
+		if math.IsNaN(other.value) {
+			return -1, nil
+		}
+		return new(big.Float).SetInt(b.value).Cmp(big.NewFloat(other.value)), nil
+	case *Decimal:
+		value, err := other.Compare(b)
+		return -value, err
+	default:
+		return CompareTypes(b, other), nil
+	}
+}
+
+func (b *BigInt) Equals(other Object) Object {
+	switch other.(type) {
+	case *BigInt, *Int, *Byte, *Float, *Decimal:
+		if value, _ := b.Compare(other); value == 0 {
+			return True
+		}
+	}
+	return False
+}
+
+func (b *BigInt) IsTruthy() bool {
+	return b.value.Sign() != 0
+}
+
+func (b *BigInt) RunOperation(opType op.BinaryOpType, right Object) Object {
+	switch right := right.(type) {
+	case *BigInt:
+		return b.runOperationBigInt(opType, right.value)
+	case *Int:
+		return b.runOperationBigInt(opType, big.NewInt(right.value))
+	case *Byte:
+		return b.runOperationBigInt(opType, big.NewInt(int64(right.value)))
+	case *Float:
+		f, _ := new(big.Float).SetInt(b.value).Float64()
+		return NewFloat(f).RunOperation(opType, right)
+	case *Decimal:
+		return NewDecimalFromBigInt(b.value).RunOperation(opType, right)
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for bigint: %v on type %s", opType, right.Type()))
+	}
+}
+
+func (b *BigInt) runOperationBigInt(opType op.BinaryOpType, right *big.Int) Object {
+	if err := checkResultBits(opType, b.value, right); err != nil {
+		return err
+	}
+	result := new(big.Int)
+	switch opType {
+	case op.Add:
+		result.Add(b.value, right)
+	case op.Subtract:
+		result.Sub(b.value, right)
+	case op.Multiply:
+		result.Mul(b.value, right)
+	case op.Divide:
+		if right.Sign() == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		result.Quo(b.value, right)
+	case op.Modulo:
+		if right.Sign() == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		result.Rem(b.value, right)
+	case op.Power:
+		if right.Sign() < 0 {
+			return Errorf("value error: negative exponent for bigint: %s", right)
+		}
+		result.Exp(b.value, right, nil)
+	case op.LShift:
+		if !right.IsUint64() {
+			return Errorf("value error: invalid shift count for bigint: %s", right)
+		}
+		result.Lsh(b.value, uint(right.Uint64()))
+	case op.RShift:
+		if !right.IsUint64() {
+			return Errorf("value error: invalid shift count for bigint: %s", right)
+		}
+		result.Rsh(b.value, uint(right.Uint64()))
+	case op.BitwiseAnd:
+		result.And(b.value, right)
+	case op.BitwiseOr:
+		result.Or(b.value, right)
+	case op.Xor:
+		result.Xor(b.value, right)
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for bigint: %v", opType))
+	}
+	if result.BitLen() > MaxBigIntBits {
+		return resultTooLarge()
+	}
+	return promoteInt(result)
+}
+
+// checkResultBits returns an error if the result of the operation would
+// exceed MaxBigIntBits. The size is estimated from the operands, before any
+// expensive work is done.
+func checkResultBits(opType op.BinaryOpType, left, right *big.Int) *Error {
+	leftBits := left.BitLen()
+	switch opType {
+	case op.Multiply:
+		if leftBits+right.BitLen() > MaxBigIntBits+1 {
+			return resultTooLarge()
+		}
+	case op.Power:
+		// Powers of 0, 1, and -1 stay small. Otherwise the result has at
+		// least (leftBits-1)*right bits.
+		if leftBits <= 1 || right.Sign() < 0 {
+			return nil
+		}
+		if !right.IsInt64() || right.Int64() > MaxBigIntBits ||
+			int64(leftBits-1)*right.Int64() > MaxBigIntBits {
+			return resultTooLarge()
+		}
+	case op.LShift:
+		if leftBits == 0 || !right.IsUint64() {
+			return nil
+		}
+		if right.Uint64() > MaxBigIntBits || uint64(leftBits)+right.Uint64() > MaxBigIntBits {
+			return resultTooLarge()
+		}
+	}
+	return nil
+}
+
+func resultTooLarge() *Error {
+	return Errorf("value error: integer result exceeds %d bits", MaxBigIntBits)
+}
+
+// Neg returns the negation of the value, as an Int if it fits.
+func (b *BigInt) Neg() Object {
+	return promoteInt(new(big.Int).Neg(b.value))
+}
+
+func (b *BigInt) MarshalJSON() ([]byte, error) {
+	// Encoded as a bare number so that no precision is lost
+	return []byte(b.value.String()), nil
+}
+
+// NewBigInt wraps the given big.Int, which must not be modified afterwards.
+func NewBigInt(value *big.Int) *BigInt {
+	return &BigInt{value: value}
+}
+
+// ParseBigInt parses a string as an integer of arbitrary size in the given
+// base. A base of 0 means the base is inferred from the string's prefix.
+func ParseBigInt(s string, base int) (*BigInt, *Error) {
+	value, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, Errorf("value error: invalid literal for bigint(): %q", s)
+	}
+	return NewBigInt(value), nil
+}
+
+// promoteInt returns an Int if the value fits in an int64, otherwise a BigInt.
+func promoteInt(value *big.Int) Object {
+	if value.IsInt64() {
+		return NewInt(value.Int64())
+	}
+	return NewBigInt(value)
+}
@@ -10,6 +10,11 @@ import (
 // BuiltinFunction holds the type of a built-in function.
 type BuiltinFunction func(ctx context.Context, args ...Object) Object
 
+// KeywordBuiltinFunction holds the type of a built-in function that receives
+// keyword arguments in addition to positional arguments. The kwargs map is
+// nil when no keyword arguments were given.
+type KeywordBuiltinFunction func(ctx context.Context, kwargs map[string]Object, args ...Object) Object
+
 // Builtin wraps func and implements Object interface.
 type Builtin struct {
 	*base
@@ -17,6 +22,10 @@ type Builtin struct {
 	// The function that this object wraps.
 	fn BuiltinFunction
 
+	// The function that this object wraps, if it accepts keyword arguments.
+	// When set, fn calls it with no keyword arguments.
+	kwfn KeywordBuiltinFunction
+
 	// The name of the function.
 	name string
 
@@ -49,6 +58,35 @@ func (b *Builtin) IsErrorHandler() bool {
 }
 
 func (b *Builtin) Call(ctx context.Context, args ...Object) Object {
+	return b.CallWithKeywords(ctx, nil, args...)
+}
+
+// AcceptsKeywords returns true if the builtin accepts keyword arguments.
+func (b *Builtin) AcceptsKeywords() bool {
+	return b.kwfn != nil
+}
+
+// WithKeywords sets the function of the builtin to one that accepts keyword
+// arguments, replacing the function it was created with, and returns the
+// builtin.
+func (b *Builtin) WithKeywords(fn KeywordBuiltinFunction) *Builtin {
+	b.kwfn = fn
+	b.fn = func(ctx context.Context, args ...Object) Object {
+		return fn(ctx, nil, args...)
+	}
+	return b
+}
+
+// CallWithKeywords calls the builtin with the given keyword and positional
+// arguments. An error is returned if keyword arguments are given to a builtin
+// that does not accept them.
+func (b *Builtin) CallWithKeywords(ctx context.Context, kwargs map[string]Object, args ...Object) Object {
+	if b.kwfn != nil {
+		return b.kwfn(ctx, kwargs, args...)
+	}
+	if len(kwargs) > 0 {
+		return Errorf("type error: %s() does not accept keyword arguments", b.Key())
+	}
 	return b.fn(ctx, args...)
 }
 
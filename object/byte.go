@@ -26,7 +26,8 @@ func (b *Byte) Inspect() string {
 }
 
 func (b *Byte) HashKey() HashKey {
-	return HashKey{Type: b.Type(), IntValue: int64(b.value)}
+	// Hash the same as an Int of equal value, since the two compare equal
+	return HashKey{Type: INT, IntValue: int64(b.value)}
 }
 
 func (b *Byte) Interface() interface{} {
@@ -114,8 +115,14 @@ func (b *Byte) runOperationByte(opType op.BinaryOpType, right byte) Object {
 	case op.Multiply:
 		return NewByte(b.value * right)
 	case op.Divide:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
 		return NewByte(b.value / right)
 	case op.Modulo:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
 		return NewByte(b.value % right)
 	case op.Xor:
 		return NewByte(b.value ^ right)
@@ -143,8 +150,14 @@ func (b *Byte) runOperationInt(opType op.BinaryOpType, right int64) Object {
 	case op.Multiply:
 		return NewInt(int64(b.value) * right)
 	case op.Divide:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
 		return NewInt(int64(b.value) / right)
 	case op.Modulo:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
 		return NewInt(int64(b.value) % right)
 	case op.Xor:
 		return NewInt(int64(b.value) ^ right)
@@ -276,6 +276,17 @@ func (b *ByteSlice) GetItem(key Object) (Object, *Error) {
 }
 
 func (b *ByteSlice) GetSlice(slice Slice) (Object, *Error) {
+	if slice.Step != nil {
+		start, step, count, err := ResolveStepSlice(slice, int64(len(b.value)))
+		if err != nil {
+			return nil, NewError(err)
+		}
+		items := make([]byte, count)
+		for i := range items {
+			items[i] = b.value[start+int64(i)*step]
+		}
+		return NewByteSlice(items), nil
+	}
 	start, stop, err := ResolveIntSlice(slice, int64(len(b.value)))
 	if err != nil {
 		return nil, NewError(err)
@@ -0,0 +1,167 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Chan is a channel used to communicate between goroutines. Channels may be
+// buffered or unbuffered, and blocking operations on a channel are cancelled
+// when the associated context is done.
+type Chan struct {
+	*base
+	ctx   context.Context
+	value chan Object
+}
+
+func (c *Chan) Type() Type {
+	return CHAN
+}
+
+func (c *Chan) Value() chan Object {
+	return c.value
+}
+
+func (c *Chan) Inspect() string {
+	return fmt.Sprintf("chan(%d)", cap(c.value))
+}
+
+func (c *Chan) String() string {
+	return c.Inspect()
+}
+
+func (c *Chan) Interface() interface{} {
+	return c.value
+}
+
+func (c *Chan) Equals(other Object) Object {
+	if other, ok := other.(*Chan); ok && c.value == other.value {
+		return True
+	}
+	return False
+}
+
+func (c *Chan) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "send":
+		return &Builtin{
+			name: "chan.send",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("chan.send", 1, len(args))
+				}
+				if err := c.Send(ctx, args[0]); err != nil {
+					return NewError(err)
+				}
+				return Nil
+			},
+		}, true
+	case "receive":
+		return &Builtin{
+			name: "chan.receive",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("chan.receive", 0, len(args))
+				}
+				value, _, err := c.Receive(ctx)
+				if err != nil {
+					return NewError(err)
+				}
+				return value
+			},
+		}, true
+	case "close":
+		return &Builtin{
+			name: "chan.close",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("chan.close", 0, len(args))
+				}
+				if err := c.Close(); err != nil {
+					return NewError(err)
+				}
+				return Nil
+			},
+		}, true
+	case "cap":
+		return &Builtin{
+			name: "chan.cap",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("chan.cap", 0, len(args))
+				}
+				return NewInt(int64(cap(c.value)))
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (c *Chan) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for chan: %v", opType))
+}
+
+func (c *Chan) Iter() Iterator {
+	return NewChanIter(c)
+}
+
+// Len returns the number of items currently buffered in the channel.
+func (c *Chan) Len() *Int {
+	return NewInt(int64(len(c.value)))
+}
+
+// Send sends a value on the channel, blocking until it is received or
+// buffered. An error is returned if the channel is closed or if the context
+// is done first.
+func (c *Chan) Send(ctx context.Context, value Object) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("exec error: send on closed channel")
+		}
+	}()
+	select {
+	case c.value <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive receives a value from the channel, blocking until one is available.
+// If the channel is closed, Nil is returned and the boolean result is false.
+// An error is returned if the context is done first.
+func (c *Chan) Receive(ctx context.Context) (Object, bool, error) {
+	select {
+	case value, ok := <-c.value:
+		if !ok {
+			return Nil, false, nil
+		}
+		return value, true, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close closes the channel. An error is returned if it was already closed.
+func (c *Chan) Close() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("exec error: close of closed channel")
+		}
+	}()
+	close(c.value)
+	return nil
+}
+
+func (c *Chan) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal chan")
+}
+
+// NewChan returns a new channel with the given buffer size. Iterating over
+// the channel stops early if the given context is done.
+func NewChan(ctx context.Context, size int) *Chan {
+	return &Chan{ctx: ctx, value: make(chan Object, size)}
+}
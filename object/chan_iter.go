@@ -0,0 +1,108 @@
+package object
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// ChanIter iterates over the values received from a channel, until the
+// channel is closed.
+type ChanIter struct {
+	*base
+	ch      *Chan
+	count   int64
+	current Object
+}
+
+func (iter *ChanIter) Type() Type {
+	return CHAN_ITER
+}
+
+func (iter *ChanIter) Inspect() string {
+	return fmt.Sprintf("chan_iter(%s)", iter.ch.Inspect())
+}
+
+func (iter *ChanIter) String() string {
+	return iter.Inspect()
+}
+
+func (iter *ChanIter) Interface() interface{} {
+	return iter.ch.value
+}
+
+func (iter *ChanIter) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *ChanIter:
+		return NewBool(iter == other)
+	default:
+		return False
+	}
+}
+
+func (iter *ChanIter) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "chan_iter.next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("chan_iter.next", 0, len(args))
+				}
+				value, ok := iter.Next()
+				if !ok {
+					return Nil
+				}
+				return value
+			},
+		}, true
+	case "entry":
+		return &Builtin{
+			name: "chan_iter.entry",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("chan_iter.entry", 0, len(args))
+				}
+				entry, ok := iter.Entry()
+				if !ok {
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (iter *ChanIter) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for chan_iter: %v", opType))
+}
+
+// Next blocks until a value is received from the channel. Iteration stops
+// when the channel is closed or when the channel's context is done.
+func (iter *ChanIter) Next() (Object, bool) {
+	value, ok, err := iter.ch.Receive(iter.ch.ctx)
+	if err != nil || !ok {
+		iter.current = nil
+		return nil, false
+	}
+	iter.count++
+	iter.current = value
+	return value, true
+}
+
+func (iter *ChanIter) Entry() (IteratorEntry, bool) {
+	if iter.current == nil {
+		return nil, false
+	}
+	return NewEntry(NewInt(iter.count-1), iter.current), true
+}
+
+func (iter *ChanIter) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal chan_iter")
+}
+
+func NewChanIter(ch *Chan) *ChanIter {
+	return &ChanIter{ch: ch}
+}
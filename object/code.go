@@ -1,7 +1,10 @@
 package object
 
 import (
+	"sort"
+
 	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/token"
 )
 
 type Loop struct {
@@ -9,14 +12,35 @@ type Loop struct {
 	BreakPos    []int
 }
 
+// ExceptionHandler is an entry in the exception table of a code object. If an
+// error is raised while executing instructions in the range [Start, End),
+// execution resumes at Target with the error object on top of the stack.
+type ExceptionHandler struct {
+	Start  int
+	End    int
+	Target int
+}
+
+// SourceLocation is an entry in the line table of a code object. It indicates
+// that the instructions beginning at Offset, up until the Offset of the next
+// entry, were compiled from source code at the given Position.
+type SourceLocation struct {
+	Offset   int
+	Position token.Position
+}
+
 type Code struct {
 	Name         string
 	IsNamed      bool
+	IsGenerator  bool
 	Parent       *Code
 	Symbols      *SymbolTable
 	Instructions []op.Code
 	Constants    []Object
 	Loops        []*Loop
+	Handlers     []*ExceptionHandler
+	Patterns     []*Pattern
+	Locations    []SourceLocation
 	Names        []string
 	Source       string
 	PipeActive   bool
@@ -27,6 +51,37 @@ func (c *Code) AddName(name string) uint16 {
 	return uint16(len(c.Names) - 1)
 }
 
+// AddLocation records that the instructions beginning at the given offset
+// were compiled from source code at the given position. Consecutive
+// instructions at the same position share a single line table entry.
+func (c *Code) AddLocation(offset int, pos token.Position) {
+	count := len(c.Locations)
+	if count > 0 {
+		last := &c.Locations[count-1]
+		if last.Position == pos {
+			return
+		}
+		if last.Offset == offset {
+			last.Position = pos
+			return
+		}
+	}
+	c.Locations = append(c.Locations, SourceLocation{Offset: offset, Position: pos})
+}
+
+// LocationAt returns the source code position of the instruction at the
+// given offset. The boolean result is false if the position is unknown.
+func (c *Code) LocationAt(offset int) (token.Position, bool) {
+	// Find the last entry with an offset less than or equal to the given one
+	idx := sort.Search(len(c.Locations), func(i int) bool {
+		return c.Locations[i].Offset > offset
+	})
+	if idx == 0 {
+		return token.Position{}, false
+	}
+	return c.Locations[idx-1].Position, true
+}
+
 func (c *Code) SymbolCount() uint16 {
 	return c.Symbols.Size()
 }
@@ -0,0 +1,589 @@
+package object
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/token"
+)
+
+// CodeFormatVersion is the version of the binary format written by
+// MarshalCode. It changes whenever the format or the meaning of any opcode
+// changes, and UnmarshalCode rejects data written with any other version.
+const CodeFormatVersion = 2
+
+// codeMagic identifies data written by MarshalCode.
+var codeMagic = []byte("RSC\x00")
+
+// Tags that identify the type of each encoded constant.
+const (
+	tagAbsent byte = iota
+	tagNil
+	tagBool
+	tagInt
+	tagFloat
+	tagString
+	tagByte
+	tagComplex
+	tagBigInt
+	tagDecimal
+	tagTuple
+	tagFunction
+)
+
+// MarshalCode encodes the code object in a versioned binary format. The
+// encoding covers the instructions, constants, names, symbol tables, source,
+// exception handlers, patterns, and line table of the code, and of the code
+// of any functions found in its constants. Values bound to global symbols,
+// such as builtins, are recorded by name only and must be supplied again to
+// UnmarshalCode.
+func MarshalCode(code *Code) ([]byte, error) {
+	enc := &codeEncoder{}
+	enc.buf.Write(codeMagic)
+	enc.writeUint(CodeFormatVersion)
+	if err := enc.writeCode(code); err != nil {
+		return nil, err
+	}
+	return enc.buf.Bytes(), nil
+}
+
+// UnmarshalCode decodes a code object that was encoded by MarshalCode. The
+// given builtins are bound to the global symbols with the same names. An
+// error is returned if the data was written by a different version of the
+// format, or if a builtin the code was compiled with is missing.
+func UnmarshalCode(data []byte, builtins map[string]Object) (*Code, error) {
+	if !bytes.HasPrefix(data, codeMagic) {
+		return nil, errors.New("decode error: invalid code header")
+	}
+	dec := &codeDecoder{data: data, pos: len(codeMagic), builtins: builtins}
+	version := dec.readUint()
+	if dec.err == nil && version != CodeFormatVersion {
+		return nil, fmt.Errorf("decode error: unsupported code version %d (expected %d)",
+			version, CodeFormatVersion)
+	}
+	code := dec.readCode(nil)
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if dec.pos != len(data) {
+		return nil, errors.New("decode error: unexpected data after code")
+	}
+	return code, nil
+}
+
+type codeEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *codeEncoder) writeUint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.buf.Write(tmp[:n])
+}
+
+func (e *codeEncoder) writeInt(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	e.buf.Write(tmp[:n])
+}
+
+func (e *codeEncoder) writeBool(v bool) {
+	if v {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *codeEncoder) writeFloat(v float64) {
+	e.writeUint(math.Float64bits(v))
+}
+
+func (e *codeEncoder) writeBytes(v []byte) {
+	e.writeUint(uint64(len(v)))
+	e.buf.Write(v)
+}
+
+func (e *codeEncoder) writeString(v string) {
+	e.writeUint(uint64(len(v)))
+	e.buf.WriteString(v)
+}
+
+func (e *codeEncoder) writeStrings(v []string) {
+	e.writeUint(uint64(len(v)))
+	for _, s := range v {
+		e.writeString(s)
+	}
+}
+
+func (e *codeEncoder) writeBigInt(v *big.Int) {
+	e.writeBool(v.Sign() < 0)
+	e.writeBytes(v.Bytes())
+}
+
+func (e *codeEncoder) writeCode(code *Code) error {
+	e.writeString(code.Name)
+	e.writeBool(code.IsNamed)
+	e.writeBool(code.IsGenerator)
+	e.writeString(code.Source)
+	e.writeStrings(code.Names)
+	e.writeUint(uint64(len(code.Instructions)))
+	for _, instr := range code.Instructions {
+		e.writeUint(uint64(instr))
+	}
+	if err := e.writeSymbols(code.Symbols); err != nil {
+		return err
+	}
+	e.writeUint(uint64(len(code.Constants)))
+	for _, constant := range code.Constants {
+		if err := e.writeConstant(constant); err != nil {
+			return err
+		}
+	}
+	e.writeUint(uint64(len(code.Handlers)))
+	for _, h := range code.Handlers {
+		e.writeUint(uint64(h.Start))
+		e.writeUint(uint64(h.End))
+		e.writeUint(uint64(h.Target))
+	}
+	e.writeUint(uint64(len(code.Patterns)))
+	for _, p := range code.Patterns {
+		if err := e.writePattern(p); err != nil {
+			return err
+		}
+	}
+	e.writeUint(uint64(len(code.Locations)))
+	for _, loc := range code.Locations {
+		e.writeUint(uint64(loc.Offset))
+		e.writePosition(loc.Position)
+	}
+	return nil
+}
+
+// writeSymbols encodes the symbols of a table in index order, along with
+// the number of values in the table. Blocks claim their indices from the
+// enclosing table, so the number of values may exceed the number of symbols.
+// Builtins shadowed by variables are included, since code compiled before
+// the variable was declared still refers to them.
+func (e *codeEncoder) writeSymbols(table *SymbolTable) error {
+	if table == nil {
+		return errors.New("encode error: code has no symbol table")
+	}
+	symbols := make([]*Symbol, 0, len(table.symbols)+len(table.shadowed))
+	for _, s := range table.symbols {
+		symbols = append(symbols, s)
+	}
+	symbols = append(symbols, table.shadowed...)
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Index < symbols[j].Index
+	})
+	e.writeUint(uint64(len(table.values)))
+	e.writeUint(uint64(len(symbols)))
+	for _, s := range symbols {
+		e.writeString(s.Name)
+		e.writeUint(uint64(s.Index))
+		e.writeBool(s.IsConstant)
+		e.writeBool(s.IsBuiltin)
+		e.writeBool(table.values[s.Index] != nil)
+	}
+	return nil
+}
+
+func (e *codeEncoder) writeConstant(obj Object) error {
+	switch obj := obj.(type) {
+	case nil:
+		e.buf.WriteByte(tagAbsent)
+	case *NilType:
+		e.buf.WriteByte(tagNil)
+	case *Bool:
+		e.buf.WriteByte(tagBool)
+		e.writeBool(obj.value)
+	case *Int:
+		e.buf.WriteByte(tagInt)
+		e.writeInt(obj.value)
+	case *Float:
+		e.buf.WriteByte(tagFloat)
+		e.writeFloat(obj.value)
+	case *String:
+		e.buf.WriteByte(tagString)
+		e.writeString(obj.value)
+	case *Byte:
+		e.buf.WriteByte(tagByte)
+		e.buf.WriteByte(obj.value)
+	case *Complex:
+		e.buf.WriteByte(tagComplex)
+		e.writeFloat(real(obj.value))
+		e.writeFloat(imag(obj.value))
+	case *BigInt:
+		e.buf.WriteByte(tagBigInt)
+		e.writeBigInt(obj.value)
+	case *Decimal:
+		e.buf.WriteByte(tagDecimal)
+		e.writeBigInt(obj.unscaled)
+		e.writeUint(uint64(obj.scale))
+	case *Tuple:
+		e.buf.WriteByte(tagTuple)
+		e.writeUint(uint64(len(obj.items)))
+		for _, item := range obj.items {
+			if err := e.writeConstant(item); err != nil {
+				return err
+			}
+		}
+	case *Function:
+		if len(obj.freeVars) > 0 {
+			return fmt.Errorf("encode error: unable to encode closure %s", obj.name)
+		}
+		e.buf.WriteByte(tagFunction)
+		e.writeString(obj.name)
+		e.writeStrings(obj.parameters)
+		e.writeUint(uint64(len(obj.defaults)))
+		for _, value := range obj.defaults {
+			if err := e.writeConstant(value); err != nil {
+				return err
+			}
+		}
+		e.writeString(obj.restParam)
+		e.writeString(obj.kwargsParam)
+		return e.writeCode(obj.code)
+	default:
+		return fmt.Errorf("encode error: unsupported constant type: %s", obj.Type())
+	}
+	return nil
+}
+
+func (e *codeEncoder) writePattern(p *Pattern) error {
+	e.buf.WriteByte(byte(p.Kind))
+	e.writeString(p.Name)
+	if err := e.writeConstant(p.Value); err != nil {
+		return err
+	}
+	e.writeString(string(p.Type))
+	e.writeStrings(p.Keys)
+	e.writeUint(uint64(len(p.Items)))
+	for _, item := range p.Items {
+		if err := e.writePattern(item); err != nil {
+			return err
+		}
+	}
+	e.writeInt(int64(p.RestIndex))
+	e.writeString(p.RestName)
+	return nil
+}
+
+func (e *codeEncoder) writePosition(pos token.Position) {
+	e.writeInt(int64(pos.Value))
+	e.writeInt(int64(pos.Char))
+	e.writeInt(int64(pos.LineStart))
+	e.writeInt(int64(pos.Line))
+	e.writeInt(int64(pos.Column))
+	e.writeString(pos.File)
+}
+
+// codeDecoder reads the format written by codeEncoder. The first error that
+// occurs is saved, after which all reads return zero values.
+type codeDecoder struct {
+	data     []byte
+	pos      int
+	err      error
+	builtins map[string]Object
+}
+
+func (d *codeDecoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("decode error: "+format, args...)
+	}
+}
+
+func (d *codeDecoder) readByte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos >= len(d.data) {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *codeDecoder) readUint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("invalid integer at offset %d", d.pos)
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *codeDecoder) readInt() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("invalid integer at offset %d", d.pos)
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// readLen reads a count of items, each of which occupies at least one byte,
+// and checks that it doesn't exceed the remaining data.
+func (d *codeDecoder) readLen() int {
+	n := d.readUint()
+	if n > uint64(len(d.data)-d.pos) {
+		d.fail("invalid length %d at offset %d", n, d.pos)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *codeDecoder) readBool() bool {
+	return d.readByte() != 0
+}
+
+func (d *codeDecoder) readFloat() float64 {
+	return math.Float64frombits(d.readUint())
+}
+
+func (d *codeDecoder) readBytes() []byte {
+	n := d.readLen()
+	if d.err != nil {
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *codeDecoder) readString() string {
+	return string(d.readBytes())
+}
+
+func (d *codeDecoder) readStrings() []string {
+	n := d.readLen()
+	if n == 0 {
+		return nil
+	}
+	items := make([]string, n)
+	for i := range items {
+		items[i] = d.readString()
+	}
+	return items
+}
+
+func (d *codeDecoder) readBigInt() *big.Int {
+	negative := d.readBool()
+	v := new(big.Int).SetBytes(d.readBytes())
+	if negative {
+		v.Neg(v)
+	}
+	return v
+}
+
+func (d *codeDecoder) readCode(parent *Code) *Code {
+	code := &Code{Parent: parent}
+	code.Name = d.readString()
+	code.IsNamed = d.readBool()
+	code.IsGenerator = d.readBool()
+	code.Source = d.readString()
+	code.Names = d.readStrings()
+	if n := d.readLen(); n > 0 {
+		code.Instructions = make([]op.Code, n)
+		for i := range code.Instructions {
+			v := d.readUint()
+			if v > math.MaxUint16 {
+				d.fail("invalid instruction %d", v)
+			}
+			code.Instructions[i] = op.Code(v)
+		}
+	}
+	if parent == nil {
+		code.Symbols = d.readSymbols(NewSymbolTable())
+	} else {
+		code.Symbols = d.readSymbols(parent.Symbols.NewChild())
+	}
+	if n := d.readLen(); n > 0 {
+		code.Constants = make([]Object, n)
+		for i := range code.Constants {
+			code.Constants[i] = d.readConstant(code)
+		}
+	}
+	if n := d.readLen(); n > 0 {
+		code.Handlers = make([]*ExceptionHandler, n)
+		for i := range code.Handlers {
+			code.Handlers[i] = &ExceptionHandler{
+				Start:  int(d.readUint()),
+				End:    int(d.readUint()),
+				Target: int(d.readUint()),
+			}
+		}
+	}
+	if n := d.readLen(); n > 0 {
+		code.Patterns = make([]*Pattern, n)
+		for i := range code.Patterns {
+			code.Patterns[i] = d.readPattern(code)
+		}
+	}
+	if n := d.readLen(); n > 0 {
+		code.Locations = make([]SourceLocation, n)
+		for i := range code.Locations {
+			code.Locations[i].Offset = int(d.readUint())
+			code.Locations[i].Position = d.readPosition()
+		}
+	}
+	return code
+}
+
+func (d *codeDecoder) readSymbols(table *SymbolTable) *SymbolTable {
+	valueCount := d.readLen()
+	symbolCount := d.readLen()
+	if d.err != nil {
+		return table
+	}
+	table.values = make([]Object, valueCount)
+	for i := 0; i < symbolCount; i++ {
+		name := d.readString()
+		index := d.readUint()
+		isConstant := d.readBool()
+		isBuiltin := d.readBool()
+		isBound := d.readBool()
+		if d.err != nil {
+			return table
+		}
+		if index >= uint64(valueCount) {
+			d.fail("invalid index %d for symbol %s", index, name)
+			return table
+		}
+		s := &Symbol{Name: name, Index: uint16(index), IsConstant: isConstant, IsBuiltin: isBuiltin}
+		if isBound {
+			value, ok := d.builtins[name]
+			if !ok {
+				d.fail("missing builtin: %s", name)
+				return table
+			}
+			s.Value = value
+			table.values[index] = value
+		}
+		// Symbols are in index order, so a shadowed builtin comes before the
+		// variable that shadows it
+		if existing, ok := table.symbols[name]; ok {
+			if !existing.IsBuiltin || isBuiltin {
+				d.fail("duplicate symbol: %s", name)
+				return table
+			}
+			table.shadowed = append(table.shadowed, existing)
+		}
+		table.symbols[name] = s
+		table.variables[name] = s
+	}
+	return table
+}
+
+func (d *codeDecoder) readConstant(code *Code) Object {
+	tag := d.readByte()
+	if d.err != nil {
+		return nil
+	}
+	switch tag {
+	case tagAbsent:
+		return nil
+	case tagNil:
+		return Nil
+	case tagBool:
+		return NewBool(d.readBool())
+	case tagInt:
+		return NewInt(d.readInt())
+	case tagFloat:
+		return NewFloat(d.readFloat())
+	case tagString:
+		return NewString(d.readString())
+	case tagByte:
+		return NewByte(d.readByte())
+	case tagComplex:
+		re := d.readFloat()
+		im := d.readFloat()
+		return NewComplex(complex(re, im))
+	case tagBigInt:
+		return NewBigInt(d.readBigInt())
+	case tagDecimal:
+		unscaled := d.readBigInt()
+		scale := d.readUint()
+		if scale > math.MaxInt32 {
+			d.fail("invalid decimal scale %d", scale)
+			return nil
+		}
+		return NewDecimal(unscaled, int(scale))
+	case tagTuple:
+		items := make([]Object, d.readLen())
+		for i := range items {
+			items[i] = d.readConstant(code)
+		}
+		return NewTuple(items)
+	case tagFunction:
+		opts := FunctionOpts{
+			Name:           d.readString(),
+			ParameterNames: d.readStrings(),
+		}
+		if n := d.readLen(); n > 0 {
+			opts.Defaults = make([]Object, n)
+			for i := range opts.Defaults {
+				opts.Defaults[i] = d.readConstant(code)
+			}
+		}
+		opts.RestParameter = d.readString()
+		opts.KwargsParameter = d.readString()
+		opts.Code = d.readCode(code)
+		fn := NewFunction(opts)
+		// Named functions hold themselves in their symbol table, which
+		// supports recursion, as set up by the compiler
+		if opts.Code.IsNamed {
+			opts.Code.Symbols.SetValue(opts.Name, fn)
+		}
+		return fn
+	}
+	d.fail("invalid constant tag %d", tag)
+	return nil
+}
+
+func (d *codeDecoder) readPattern(code *Code) *Pattern {
+	p := &Pattern{
+		Kind:  PatternKind(d.readByte()),
+		Name:  d.readString(),
+		Value: d.readConstant(code),
+		Type:  Type(d.readString()),
+		Keys:  d.readStrings(),
+	}
+	if n := d.readLen(); n > 0 {
+		p.Items = make([]*Pattern, n)
+		for i := range p.Items {
+			p.Items[i] = d.readPattern(code)
+		}
+	}
+	p.RestIndex = int(d.readInt())
+	p.RestName = d.readString()
+	return p
+}
+
+func (d *codeDecoder) readPosition() token.Position {
+	return token.Position{
+		Value:     rune(d.readInt()),
+		Char:      int(d.readInt()),
+		LineStart: int(d.readInt()),
+		Line:      int(d.readInt()),
+		Column:    int(d.readInt()),
+		File:      d.readString(),
+	}
+}
@@ -0,0 +1,164 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/risor-io/risor/op"
+	"github.com/risor-io/risor/token"
+	"github.com/stretchr/testify/require"
+)
+
+func testEncodingBuiltins() map[string]Object {
+	return map[string]Object{
+		"len": NewBuiltin("len", func(ctx context.Context, args ...Object) Object {
+			return Nil
+		}),
+	}
+}
+
+func testEncodingCode(t *testing.T) *Code {
+	builtins := testEncodingBuiltins()
+	symbols := NewSymbolTable()
+	_, err := symbols.InsertBuiltin("len", builtins["len"])
+	require.Nil(t, err)
+	_, err = symbols.InsertVariable("x")
+	require.Nil(t, err)
+
+	fnCode := &Code{
+		Name:         "add",
+		IsNamed:      true,
+		Symbols:      symbols.NewChild(),
+		Instructions: []op.Code{op.LoadFast, 0, op.LoadFast, 1, op.BinaryOp, op.Code(op.Add), op.ReturnValue},
+	}
+	_, err = fnCode.Symbols.InsertConstant("add")
+	require.Nil(t, err)
+	_, err = fnCode.Symbols.InsertVariable("a")
+	require.Nil(t, err)
+	_, err = fnCode.Symbols.InsertVariable("b")
+	require.Nil(t, err)
+	fn := NewFunction(FunctionOpts{
+		Name:           "add",
+		ParameterNames: []string{"a", "b"},
+		Defaults:       []Object{nil, NewInt(2)},
+		Code:           fnCode,
+	})
+	require.Nil(t, fnCode.Symbols.SetValue("add", fn))
+
+	return &Code{
+		Name:         "main",
+		Symbols:      symbols,
+		Instructions: []op.Code{op.LoadConst, 0, op.StoreGlobal, 1, op.Nop},
+		Constants: []Object{
+			Nil,
+			True,
+			NewInt(-42),
+			NewFloat(2.5),
+			NewString("hello"),
+			NewByte(7),
+			NewComplex(1 + 2i),
+			NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100)),
+			NewDecimal(big.NewInt(12345), 2),
+			NewTuple([]Object{NewInt(1), NewTuple([]Object{NewString("a")})}),
+			fn,
+		},
+		Handlers:  []*ExceptionHandler{{Start: 0, End: 2, Target: 4}},
+		Locations: []SourceLocation{{Offset: 0, Position: token.Position{Line: 1, Column: 3}}},
+		Names:     []string{"foo"},
+		Source:    "x := add(1)",
+	}
+}
+
+func TestCodeEncodingRoundTrip(t *testing.T) {
+	code := testEncodingCode(t)
+	data, err := MarshalCode(code)
+	require.Nil(t, err)
+
+	decoded, err := UnmarshalCode(data, testEncodingBuiltins())
+	require.Nil(t, err)
+	require.Equal(t, code.Name, decoded.Name)
+	require.Equal(t, code.Instructions, decoded.Instructions)
+	require.Equal(t, code.Names, decoded.Names)
+	require.Equal(t, code.Source, decoded.Source)
+	require.Equal(t, code.Handlers, decoded.Handlers)
+	require.Equal(t, code.Locations, decoded.Locations)
+	require.Equal(t, code.Symbols.Size(), decoded.Symbols.Size())
+	require.Len(t, decoded.Constants, len(code.Constants))
+	for i, constant := range code.Constants[:len(code.Constants)-1] {
+		require.Equal(t, constant.Inspect(), decoded.Constants[i].Inspect())
+		require.Equal(t, constant.Type(), decoded.Constants[i].Type())
+	}
+
+	lenSymbol, ok := decoded.Symbols.Get("len")
+	require.True(t, ok)
+	require.Equal(t, uint16(0), lenSymbol.Index)
+	require.Equal(t, "builtin(len)", decoded.Globals()[0].Inspect())
+
+	fn, ok := decoded.Constants[len(decoded.Constants)-1].(*Function)
+	require.True(t, ok)
+	require.Equal(t, "add", fn.Name())
+	require.Equal(t, []string{"a", "b"}, fn.Parameters())
+	require.Equal(t, []Object{nil, NewInt(2)}, fn.Defaults())
+	require.Equal(t, decoded, fn.Code().Parent)
+	require.Equal(t, uint16(3), fn.Code().Symbols.Size())
+	self, ok := fn.Code().Symbols.Get("add")
+	require.True(t, ok)
+	require.Same(t, fn, self.Value)
+}
+
+func TestCodeEncodingShadowedBuiltin(t *testing.T) {
+	builtins := testEncodingBuiltins()
+	symbols := NewSymbolTable()
+	_, err := symbols.InsertBuiltin("len", builtins["len"])
+	require.Nil(t, err)
+	_, err = symbols.InsertVariable("len")
+	require.Nil(t, err)
+	code := &Code{Name: "main", Symbols: symbols}
+
+	data, err := MarshalCode(code)
+	require.Nil(t, err)
+	decoded, err := UnmarshalCode(data, builtins)
+	require.Nil(t, err)
+
+	// Code compiled before the variable was declared still finds the builtin
+	require.Equal(t, "builtin(len)", decoded.Globals()[0].Inspect())
+	variable, ok := decoded.Symbols.Get("len")
+	require.True(t, ok)
+	require.False(t, variable.IsBuiltin)
+	require.Equal(t, uint16(1), variable.Index)
+}
+
+func TestCodeEncodingErrors(t *testing.T) {
+	data, err := MarshalCode(testEncodingCode(t))
+	require.Nil(t, err)
+
+	_, err = UnmarshalCode([]byte("nope"), testEncodingBuiltins())
+	require.NotNil(t, err)
+	require.Equal(t, "decode error: invalid code header", err.Error())
+
+	badVersion := append([]byte{}, data...)
+	badVersion[len(codeMagic)] = CodeFormatVersion + 1
+	_, err = UnmarshalCode(badVersion, testEncodingBuiltins())
+	require.NotNil(t, err)
+	require.Equal(t, fmt.Sprintf("decode error: unsupported code version %d (expected %d)",
+		CodeFormatVersion+1, CodeFormatVersion), err.Error())
+
+	_, err = UnmarshalCode(data, nil)
+	require.NotNil(t, err)
+	require.Equal(t, "decode error: missing builtin: len", err.Error())
+
+	_, err = UnmarshalCode(data[:len(data)-3], testEncodingBuiltins())
+	require.NotNil(t, err)
+
+	_, err = UnmarshalCode(append(data, 0), testEncodingBuiltins())
+	require.NotNil(t, err)
+	require.Equal(t, "decode error: unexpected data after code", err.Error())
+
+	code := testEncodingCode(t)
+	code.Constants = append(code.Constants, NewList(nil))
+	_, err = MarshalCode(code)
+	require.NotNil(t, err)
+	require.Equal(t, "encode error: unsupported constant type: list", err.Error())
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Complex wraps complex128 and implements Object and Hashable interfaces.
+type Complex struct {
+	*base
+	value complex128
+}
+
+func (c *Complex) Inspect() string {
+	return strconv.FormatComplex(c.value, 'f', -1, 128)
+}
+
+func (c *Complex) Type() Type {
+	return COMPLEX
+}
+
+func (c *Complex) Value() complex128 {
+	return c.value
+}
+
+func (c *Complex) HashKey() HashKey {
+	return HashKey{Type: c.Type(), StrValue: c.Inspect()}
+}
+
+func (c *Complex) Interface() interface{} {
+	return c.value
+}
+
+func (c *Complex) String() string {
+	return c.Inspect()
+}
+
+func (c *Complex) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "real":
+		return NewFloat(real(c.value)), true
+	case "imag":
+		return NewFloat(imag(c.value)), true
+	case "conjugate":
+		return NewBuiltin("complex.conjugate", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("complex.conjugate", 0, len(args))
+			}
+			return NewComplex(cmplx.Conj(c.value))
+		}), true
+	}
+	return nil, false
+}
+
+func (c *Complex) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *Complex:
+		if c.value == other.value {
+			return True
+		}
+	case *Int, *Float, *Byte:
+		value, _ := AsComplex(other)
+		if c.value == value {
+			return True
+		}
+	}
+	return False
+}
+
+func (c *Complex) IsTruthy() bool {
+	return c.value != 0
+}
+
+func (c *Complex) RunOperation(opType op.BinaryOpType, right Object) Object {
+	switch right := right.(type) {
+	case *Complex:
+		return c.runOperationComplex(opType, right.value)
+	case *Int, *Float, *Byte:
+		value, _ := AsComplex(right)
+		return c.runOperationComplex(opType, value)
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for complex: %v on type %s", opType, right.Type()))
+	}
+}
+
+func (c *Complex) runOperationComplex(opType op.BinaryOpType, right complex128) Object {
+	switch opType {
+	case op.Add:
+		return NewComplex(c.value + right)
+	case op.Subtract:
+		return NewComplex(c.value - right)
+	case op.Multiply:
+		return NewComplex(c.value * right)
+	case op.Divide:
+		return NewComplex(c.value / right)
+	case op.Power:
+		// Small integer exponents are computed exactly by repeated squaring,
+		// so that 1i ** 2 is exactly -1
+		if n := real(right); imag(right) == 0 && n == math.Trunc(n) && math.Abs(n) <= 1024 {
+			return NewComplex(powComplex(c.value, int64(n)))
+		}
+		return NewComplex(cmplx.Pow(c.value, right))
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for complex: %v", opType))
+	}
+}
+
+func (c *Complex) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal complex")
+}
+
+func powComplex(x complex128, n int64) complex128 {
+	if n < 0 {
+		return 1 / powComplex(x, -n)
+	}
+	result := complex128(1)
+	for n > 0 {
+		if n&1 == 1 {
+			result *= x
+		}
+		x *= x
+		n >>= 1
+	}
+	return result
+}
+
+func NewComplex(value complex128) *Complex {
+	return &Complex{value: value}
+}
@@ -0,0 +1,152 @@
+package object
+
+import (
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// ComplexSlice wraps []complex128 and implements the Container interface.
+type ComplexSlice struct {
+	*base
+	value []complex128
+}
+
+func (c *ComplexSlice) Inspect() string {
+	return fmt.Sprintf("complex_slice(%v)", c.value)
+}
+
+func (c *ComplexSlice) Type() Type {
+	return COMPLEX_SLICE
+}
+
+func (c *ComplexSlice) Value() []complex128 {
+	return c.value
+}
+
+func (c *ComplexSlice) GetAttr(name string) (Object, bool) {
+	return nil, false
+}
+
+func (c *ComplexSlice) Interface() interface{} {
+	return c.value
+}
+
+func (c *ComplexSlice) String() string {
+	return c.Inspect()
+}
+
+func (c *ComplexSlice) Compare(other Object) (int, error) {
+	return 0, fmt.Errorf("type error: cannot compare complex_slice to type %s", other.Type())
+}
+
+func (c *ComplexSlice) Equals(other Object) Object {
+	if c == other {
+		return True
+	}
+	return False
+}
+
+func (c *ComplexSlice) IsTruthy() bool {
+	return len(c.value) > 0
+}
+
+func (c *ComplexSlice) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for complex_slice: %v on type %s", opType, right.Type()))
+}
+
+func (c *ComplexSlice) Contains(item Object) *Bool {
+	value, err := AsComplex(item)
+	if err != nil {
+		return False
+	}
+	for _, v := range c.value {
+		if v == value {
+			return True
+		}
+	}
+	return False
+}
+
+func (c *ComplexSlice) GetItem(key Object) (Object, *Error) {
+	indexObj, ok := key.(*Int)
+	if !ok {
+		return nil, Errorf("index error: complex_slice index must be an int (got %s)", key.Type())
+	}
+	index, err := ResolveIndex(indexObj.value, int64(len(c.value)))
+	if err != nil {
+		return nil, NewError(err)
+	}
+	return NewComplex(c.value[index]), nil
+}
+
+func (c *ComplexSlice) GetSlice(slice Slice) (Object, *Error) {
+	if slice.Step != nil {
+		start, step, count, err := ResolveStepSlice(slice, int64(len(c.value)))
+		if err != nil {
+			return nil, NewError(err)
+		}
+		items := make([]complex128, count)
+		for i := range items {
+			items[i] = c.value[start+int64(i)*step]
+		}
+		return NewComplexSlice(items), nil
+	}
+	start, stop, err := ResolveIntSlice(slice, int64(len(c.value)))
+	if err != nil {
+		return nil, NewError(err)
+	}
+	return NewComplexSlice(c.value[start:stop]), nil
+}
+
+func (c *ComplexSlice) SetItem(key, value Object) *Error {
+	indexObj, ok := key.(*Int)
+	if !ok {
+		return Errorf("index error: index must be an int (got %s)", key.Type())
+	}
+	index, err := ResolveIndex(indexObj.value, int64(len(c.value)))
+	if err != nil {
+		return NewError(err)
+	}
+	complexVal, convErr := AsComplex(value)
+	if convErr != nil {
+		return convErr
+	}
+	c.value[index] = complexVal
+	return nil
+}
+
+func (c *ComplexSlice) DelItem(key Object) *Error {
+	return Errorf("type error: cannot delete from complex_slice")
+}
+
+func (c *ComplexSlice) Len() *Int {
+	return NewInt(int64(len(c.value)))
+}
+
+func (c *ComplexSlice) Iter() Iterator {
+	return &SliceIter{
+		s:         c.value,
+		size:      len(c.value),
+		pos:       -1,
+		converter: &Complex128Converter{},
+	}
+}
+
+func (c *ComplexSlice) Clone() *ComplexSlice {
+	value := make([]complex128, len(c.value))
+	copy(value, c.value)
+	return NewComplexSlice(value)
+}
+
+func (c *ComplexSlice) Cost() int {
+	return len(c.value)
+}
+
+func (c *ComplexSlice) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal complex_slice")
+}
+
+func NewComplexSlice(value []complex128) *ComplexSlice {
+	return &ComplexSlice{value: value}
+}
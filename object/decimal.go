@@ -0,0 +1,507 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/risor-io/risor/op"
+)
+
+// The minimum number of decimal places kept in the result of a division that
+// doesn't terminate. Exact quotients are trimmed of trailing zeros.
+const decimalDivisionScale = 16
+
+// MaxDecimalDigits limits the size of the result of a Decimal operation, so
+// that repeatedly squaring a decimal can't run for an unbounded time or
+// allocate unbounded memory. Both the number of significant digits and the
+// number of decimal places are limited.
+const MaxDecimalDigits = 1 << 18
+
+// maxDecimalBits is the bit length of an integer with MaxDecimalDigits digits.
+const maxDecimalBits = MaxDecimalDigits * 3322 / 1000
+
+// RoundingMode determines how a decimal is rounded when digits are dropped.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest neighbor, and to the even neighbor
+	// when both are equally near. This is also known as banker's rounding.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest neighbor, and away from zero when
+	// both are equally near.
+	RoundHalfUp
+	// RoundHalfDown rounds to the nearest neighbor, and towards zero when
+	// both are equally near.
+	RoundHalfDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds towards zero, which truncates the value.
+	RoundDown
+	// RoundCeiling rounds towards positive infinity.
+	RoundCeiling
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+)
+
+var roundingModeNames = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+// ParseRoundingMode returns the rounding mode with the given name. Valid names
+// are "half_even", "half_up", "half_down", "up", "down", "ceiling" and "floor".
+func ParseRoundingMode(name string) (RoundingMode, *Error) {
+	mode, ok := roundingModeNames[name]
+	if !ok {
+		return 0, Errorf("value error: invalid rounding mode: %q", name)
+	}
+	return mode, nil
+}
+
+// Decimal is a fixed precision decimal number. It holds an arbitrary precision
+// integer along with the number of digits that follow the decimal point.
+type Decimal struct {
+	*base
+	unscaled *big.Int
+	scale    int
+}
+
+func (d *Decimal) Inspect() string {
+	return fmt.Sprintf("decimal(%q)", d.String())
+}
+
+func (d *Decimal) Type() Type {
+	return DECIMAL
+}
+
+// Scale returns the number of digits that follow the decimal point.
+func (d *Decimal) Scale() int {
+	return d.scale
+}
+
+// Unscaled returns the value multiplied by 10 to the power of the scale. It
+// must not be modified.
+func (d *Decimal) Unscaled() *big.Int {
+	return d.unscaled
+}
+
+// Rat returns the value as a rational number.
+func (d *Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled, pow10(d.scale))
+}
+
+// Float64 returns the nearest float64 to the value.
+func (d *Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Integer returns the integer part of the value, truncating towards zero.
+func (d *Decimal) Integer() *big.Int {
+	return divRound(d.unscaled, pow10(d.scale), RoundDown)
+}
+
+func (d *Decimal) HashKey() HashKey {
+	// Hash the same as an integer or float of equal value, since the two
+	// compare equal
+	normalized := d.normalize()
+	if normalized.scale == 0 {
+		return NewBigInt(normalized.unscaled).HashKey()
+	}
+	if value, exact := normalized.Rat().Float64(); exact {
+		return NewFloat(value).HashKey()
+	}
+	return HashKey{Type: d.Type(), StrValue: normalized.String()}
+}
+
+func (d *Decimal) Interface() interface{} {
+	return d.String()
+}
+
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		point := len(digits) - d.scale
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func (d *Decimal) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "round":
+		return NewBuiltin("decimal.round", func(ctx context.Context, args ...Object) Object {
+			if len(args) > 2 {
+				return NewArgsRangeError("decimal.round", 0, 2, len(args))
+			}
+			var places int64
+			if len(args) > 0 {
+				var err *Error
+				if places, err = AsInt(args[0]); err != nil {
+					return err
+				}
+				if places < 0 {
+					return Errorf("value error: decimal.round() places must be non-negative (got %d)", places)
+				}
+				if places > MaxDecimalDigits {
+					return decimalTooLarge()
+				}
+			}
+			mode := RoundHalfEven
+			if len(args) > 1 {
+				name, err := AsString(args[1])
+				if err != nil {
+					return err
+				}
+				if mode, err = ParseRoundingMode(name); err != nil {
+					return err
+				}
+			}
+			return d.Round(int(places), mode)
+		}), true
+	case "scale":
+		return NewBuiltin("decimal.scale", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("decimal.scale", 0, len(args))
+			}
+			return NewInt(int64(d.scale))
+		}), true
+	}
+	return nil, false
+}
+
+func (d *Decimal) Compare(other Object) (int, error) {
+	switch other := other.(type) {
+	case *Decimal:
+		a, b := alignDecimals(d, other)
+		return a.Cmp(b), nil
+	case *Int:
+		return d.Compare(NewDecimalFromInt(other.value))
+	case *Byte:
+		return d.Compare(NewDecimalFromInt(int64(other.value)))
+	case *BigInt:
+		return d.Compare(NewDecimalFromBigInt(other.value))
+	case *Float:
+		otherRat := new(big.Rat)
+		if otherRat.SetFloat64(other.value) == nil {
+			// Infinity is greater or less than every decimal. NaN is
+			// unordered, and is treated as greater.
+			if other.value < 0 {
+				return 1, nil
+			}
+			return -1, nil
+		}
+		return d.Rat().Cmp(otherRat), nil
+	default:
+		return CompareTypes(d, other), nil
+	}
+}
+
+func (d *Decimal) Equals(other Object) Object {
+	switch other.(type) {
+	case *Decimal, *Int, *Byte, *BigInt, *Float:
+		if value, _ := d.Compare(other); value == 0 {
+			return True
+		}
+	}
+	return False
+}
+
+func (d *Decimal) IsTruthy() bool {
+	return d.unscaled.Sign() != 0
+}
+
+func (d *Decimal) RunOperation(opType op.BinaryOpType, right Object) Object {
+	switch right := right.(type) {
+	case *Decimal:
+		return d.runOperationDecimal(opType, right)
+	case *Int:
+		if opType == op.Power {
+			return d.Pow(right.value)
+		}
+		return d.runOperationDecimal(opType, NewDecimalFromInt(right.value))
+	case *Byte:
+		return d.runOperationDecimal(opType, NewDecimalFromInt(int64(right.value)))
+	case *BigInt:
+		return d.runOperationDecimal(opType, NewDecimalFromBigInt(right.value))
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for decimal: %v on type %s", opType, right.Type()))
+	}
+}
+
+func (d *Decimal) runOperationDecimal(opType op.BinaryOpType, right *Decimal) Object {
+	if d.tooLarge() || right.tooLarge() {
+		return decimalTooLarge()
+	}
+	switch opType {
+	case op.Add:
+		a, b := alignDecimals(d, right)
+		return checkDecimal(NewDecimal(new(big.Int).Add(a, b), maxInt(d.scale, right.scale)))
+	case op.Subtract:
+		a, b := alignDecimals(d, right)
+		return checkDecimal(NewDecimal(new(big.Int).Sub(a, b), maxInt(d.scale, right.scale)))
+	case op.Multiply:
+		// The product has at most the sum of the sizes of the operands
+		if d.unscaled.BitLen()+right.unscaled.BitLen() > maxDecimalBits+1 ||
+			d.scale+right.scale > MaxDecimalDigits {
+			return decimalTooLarge()
+		}
+		return checkDecimal(NewDecimal(new(big.Int).Mul(d.unscaled, right.unscaled), d.scale+right.scale))
+	case op.Divide:
+		if right.unscaled.Sign() == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		return checkDecimal(d.Quo(right))
+	case op.Modulo:
+		if right.unscaled.Sign() == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		a, b := alignDecimals(d, right)
+		return checkDecimal(NewDecimal(new(big.Int).Rem(a, b), maxInt(d.scale, right.scale)))
+	case op.Power:
+		if right.scale > 0 && !right.isInteger() {
+			return Errorf("value error: decimal exponent must be an integer (got %s)", right)
+		}
+		exponent := right.Integer()
+		if !exponent.IsInt64() {
+			return Errorf("value error: decimal exponent is too large (got %s)", right)
+		}
+		return d.Pow(exponent.Int64())
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for decimal: %v", opType))
+	}
+}
+
+// Quo returns the quotient of the two decimals. A quotient that doesn't
+// terminate is rounded half to even after at least 16 decimal places.
+func (d *Decimal) Quo(other *Decimal) *Decimal {
+	minScale := maxInt(d.scale, other.scale)
+	scale := maxInt(minScale, decimalDivisionScale)
+	// d / other = (d.unscaled / other.unscaled) * 10^(other.scale - d.scale)
+	num := new(big.Int).Mul(d.unscaled, pow10(scale+other.scale-d.scale))
+	quo, rem := new(big.Int).QuoRem(num, other.unscaled, new(big.Int))
+	if rem.Sign() != 0 {
+		return NewDecimal(divRound(num, other.unscaled, RoundHalfEven), scale)
+	}
+	return NewDecimal(quo, scale).trim(minScale)
+}
+
+// Pow returns the decimal raised to the given integer power.
+func (d *Decimal) Pow(exponent int64) Object {
+	if exponent < 0 {
+		if d.unscaled.Sign() == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		result, ok := d.Pow(-exponent).(*Decimal)
+		if !ok {
+			return result
+		}
+		return NewDecimalFromInt(1).Quo(result)
+	}
+	if d.tooLarge() {
+		return decimalTooLarge()
+	}
+	// Powers of 0, 1, and -1 stay small. Otherwise the result has at least
+	// (bits-1)*exponent bits and scale*exponent decimal places.
+	if d.scale > 0 || d.unscaled.BitLen() > 1 {
+		if exponent > MaxDecimalDigits || int64(d.scale)*exponent > MaxDecimalDigits ||
+			int64(d.unscaled.BitLen()-1)*exponent > maxDecimalBits {
+			return decimalTooLarge()
+		}
+	}
+	value := new(big.Int).Exp(d.unscaled, big.NewInt(exponent), nil)
+	return checkDecimal(NewDecimal(value, d.scale*int(exponent)))
+}
+
+// Round returns the decimal rounded to the given number of decimal places,
+// using the given rounding mode. If the decimal has fewer places, they are
+// padded with zeros so that the result always has exactly that many places.
+func (d *Decimal) Round(places int, mode RoundingMode) *Decimal {
+	if places >= d.scale {
+		value := new(big.Int).Mul(d.unscaled, pow10(places-d.scale))
+		return NewDecimal(value, places)
+	}
+	return NewDecimal(divRound(d.unscaled, pow10(d.scale-places), mode), places)
+}
+
+// Neg returns the negation of the decimal.
+func (d *Decimal) Neg() *Decimal {
+	return NewDecimal(new(big.Int).Neg(d.unscaled), d.scale)
+}
+
+func (d *Decimal) MarshalJSON() ([]byte, error) {
+	// Encoded as a bare number so that no precision is lost
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) isInteger() bool {
+	return new(big.Int).Rem(d.unscaled, pow10(d.scale)).Sign() == 0
+}
+
+// trim removes trailing zeros from the fractional digits, while keeping at
+// least minScale of them.
+func (d *Decimal) trim(minScale int) *Decimal {
+	value := new(big.Int).Set(d.unscaled)
+	scale := d.scale
+	ten := big.NewInt(10)
+	rem := new(big.Int)
+	for scale > minScale {
+		quo, r := new(big.Int).QuoRem(value, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		value = quo
+		scale--
+	}
+	return NewDecimal(value, scale)
+}
+
+// tooLarge returns true if the decimal has more than MaxDecimalDigits digits
+// or decimal places.
+func (d *Decimal) tooLarge() bool {
+	return d.scale > MaxDecimalDigits || d.unscaled.BitLen() > maxDecimalBits
+}
+
+// checkDecimal returns the given decimal, or an error if it is too large.
+func checkDecimal(d *Decimal) Object {
+	if d.tooLarge() {
+		return decimalTooLarge()
+	}
+	return d
+}
+
+func decimalTooLarge() *Error {
+	return Errorf("value error: decimal result exceeds %d digits", MaxDecimalDigits)
+}
+
+// normalize returns the decimal with all trailing zeros removed.
+func (d *Decimal) normalize() *Decimal {
+	return d.trim(0)
+}
+
+// NewDecimal returns a decimal equal to unscaled * 10^-scale. The given
+// big.Int must not be modified afterwards.
+func NewDecimal(unscaled *big.Int, scale int) *Decimal {
+	if scale < 0 {
+		unscaled = new(big.Int).Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return &Decimal{unscaled: unscaled, scale: scale}
+}
+
+// NewDecimalFromInt returns a decimal equal to the given integer.
+func NewDecimalFromInt(value int64) *Decimal {
+	return NewDecimal(big.NewInt(value), 0)
+}
+
+// NewDecimalFromBigInt returns a decimal equal to the given integer.
+func NewDecimalFromBigInt(value *big.Int) *Decimal {
+	return NewDecimal(new(big.Int).Set(value), 0)
+}
+
+// ParseDecimal parses a string such as "12.50", "-3" or "1.5e3" as a decimal.
+// The scale of the result is the number of digits following the decimal point.
+func ParseDecimal(s string) (*Decimal, *Error) {
+	invalid := Errorf("value error: invalid literal for decimal(): %q", s)
+	str := strings.ReplaceAll(strings.TrimSpace(s), "_", "")
+	exponent := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(str[i+1:])
+		if err != nil || exp > MaxDecimalDigits || exp < -MaxDecimalDigits {
+			return nil, invalid
+		}
+		exponent = exp
+		str = str[:i]
+	}
+	sign := ""
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		sign, str = str[:1], str[1:]
+	}
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return nil, invalid
+	}
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return nil, invalid
+		}
+	}
+	unscaled, ok := new(big.Int).SetString(sign+whole+frac, 10)
+	if !ok {
+		return nil, invalid
+	}
+	return NewDecimal(unscaled, len(frac)-exponent), nil
+}
+
+// divRound divides num by den, rounding the quotient with the given mode.
+func divRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+	// The sign of the exact quotient
+	sign := num.Sign() * den.Sign()
+	// Compare the remainder to half of the divisor
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(new(big.Int).Abs(den))
+	var increment bool
+	switch mode {
+	case RoundHalfEven:
+		increment = cmp > 0 || (cmp == 0 && quo.Bit(0) == 1)
+	case RoundHalfUp:
+		increment = cmp >= 0
+	case RoundHalfDown:
+		increment = cmp > 0
+	case RoundUp:
+		increment = true
+	case RoundDown:
+		increment = false
+	case RoundCeiling:
+		increment = sign > 0
+	case RoundFloor:
+		increment = sign < 0
+	}
+	if increment {
+		quo.Add(quo, big.NewInt(int64(sign)))
+	}
+	return quo
+}
+
+// alignDecimals returns the unscaled values of the two decimals, adjusted to
+// the larger of their scales.
+func alignDecimals(a, b *Decimal) (*big.Int, *big.Int) {
+	switch {
+	case a.scale > b.scale:
+		return a.unscaled, new(big.Int).Mul(b.unscaled, pow10(a.scale-b.scale))
+	case a.scale < b.scale:
+		return new(big.Int).Mul(a.unscaled, pow10(b.scale-a.scale)), b.unscaled
+	default:
+		return a.unscaled, b.unscaled
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
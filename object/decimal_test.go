@@ -0,0 +1,102 @@
+package object
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/risor-io/risor/op"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		scale    int
+	}{
+		{"0", "0", 0},
+		{"12.50", "12.50", 2},
+		{"-3", "-3", 0},
+		{"+.5", "0.5", 1},
+		{"-0.05", "-0.05", 2},
+		{"1_000.25", "1000.25", 2},
+		{"1.5e3", "1500", 0},
+		{"1.5E-3", "0.0015", 4},
+	}
+	for _, tc := range tests {
+		d, err := ParseDecimal(tc.input)
+		require.Nil(t, err, tc.input)
+		require.Equal(t, tc.expected, d.String(), tc.input)
+		require.Equal(t, tc.scale, d.Scale(), tc.input)
+	}
+	for _, input := range []string{"", ".", "-", "1.2.3", "abc", "1e", "0x10"} {
+		_, err := ParseDecimal(input)
+		require.NotNil(t, err, input)
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     RoundingMode
+		expected string
+	}{
+		{"2.5", RoundHalfEven, "2"},
+		{"3.5", RoundHalfEven, "4"},
+		{"-2.5", RoundHalfEven, "-2"},
+		{"2.5", RoundHalfUp, "3"},
+		{"-2.5", RoundHalfUp, "-3"},
+		{"2.5", RoundHalfDown, "2"},
+		{"2.6", RoundHalfDown, "3"},
+		{"2.1", RoundUp, "3"},
+		{"-2.1", RoundUp, "-3"},
+		{"2.9", RoundDown, "2"},
+		{"-2.9", RoundDown, "-2"},
+		{"2.1", RoundCeiling, "3"},
+		{"-2.9", RoundCeiling, "-2"},
+		{"2.9", RoundFloor, "2"},
+		{"-2.1", RoundFloor, "-3"},
+		{"2", RoundFloor, "2"},
+	}
+	for _, tc := range tests {
+		d, err := ParseDecimal(tc.input)
+		require.Nil(t, err)
+		require.Equal(t, tc.expected, d.Round(0, tc.mode).String(),
+			"input: %s, mode: %d", tc.input, tc.mode)
+	}
+}
+
+func TestDecimalOperations(t *testing.T) {
+	a, _ := ParseDecimal("10.25")
+	b, _ := ParseDecimal("0.5")
+
+	tests := []struct {
+		opType   op.BinaryOpType
+		right    Object
+		expected string
+	}{
+		{op.Add, b, "10.75"},
+		{op.Subtract, b, "9.75"},
+		{op.Multiply, b, "5.125"},
+		{op.Divide, b, "20.50"},
+		{op.Modulo, b, "0.25"},
+		{op.Add, NewInt(1), "11.25"},
+		{op.Multiply, NewBigInt(big.NewInt(2)), "20.50"},
+		{op.Power, NewInt(2), "105.0625"},
+	}
+	for _, tc := range tests {
+		result, ok := a.RunOperation(tc.opType, tc.right).(*Decimal)
+		require.True(t, ok, "op: %v", tc.opType)
+		require.Equal(t, tc.expected, result.String(), "op: %v", tc.opType)
+	}
+	require.True(t, IsError(a.RunOperation(op.Add, NewFloat(1))))
+}
+
+func TestDecimalHashKey(t *testing.T) {
+	a, _ := ParseDecimal("1.50")
+	b, _ := ParseDecimal("1.5")
+	c, _ := ParseDecimal("2.00")
+	require.Equal(t, a.HashKey(), b.HashKey())
+	require.Equal(t, NewInt(2).HashKey(), c.HashKey())
+	require.Equal(t, NewInt(2).HashKey(), NewBigInt(big.NewInt(2)).HashKey())
+}
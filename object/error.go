@@ -1,12 +1,19 @@
 package object
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/risor-io/risor/op"
 )
 
+// ErrDivisionByZero is the cause of the error produced when a number is
+// divided by zero. Unlike most errors produced by operators, it is raised
+// by the VM, so it can be caught with a try statement.
+var ErrDivisionByZero = errors.New("value error: division by zero")
+
 // Error wraps a Go error interface and implements Object.
 type Error struct {
 	*base
@@ -25,6 +32,32 @@ func (e *Error) String() string {
 	return fmt.Sprintf("error(%s)", e.err.Error())
 }
 
+func (e *Error) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &Builtin{
+			name: "error.message",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("error.message", 0, len(args))
+				}
+				return e.Message()
+			},
+		}, true
+	case "kind":
+		return &Builtin{
+			name: "error.kind",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("error.kind", 0, len(args))
+				}
+				return e.Kind()
+			},
+		}, true
+	}
+	return nil, false
+}
+
 func (e *Error) Value() error {
 	return e.err
 }
@@ -66,6 +99,21 @@ func (e *Error) Message() *String {
 	return NewString(e.err.Error())
 }
 
+// Kind returns the category of the error, taken from the prefix of its
+// message. For example, the kind of "value error: division by zero" is
+// "value". The kind is empty if the message has no such prefix.
+func (e *Error) Kind() *String {
+	prefix, _, found := strings.Cut(e.err.Error(), ": ")
+	if !found {
+		return NewString("")
+	}
+	kind, ok := strings.CutSuffix(prefix, " error")
+	if !ok || kind == "" || strings.Contains(kind, " ") {
+		return NewString("")
+	}
+	return NewString(kind)
+}
+
 func (e *Error) RunOperation(opType op.BinaryOpType, right Object) Object {
 	return NewError(fmt.Errorf("eval error: unsupported operation for error: %v", opType))
 }
@@ -0,0 +1,40 @@
+package object
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/risor-io/risor/op"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    string
+	}{
+		{"value error: division by zero", "value"},
+		{"type error: object is not callable (got int)", "type"},
+		{"kaboom", ""},
+		{"error: no kind", ""},
+		{"failed to read: file not found", ""},
+		{"could not parse error: bad input", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := NewError(errors.New(tt.message))
+			require.Equal(t, NewString(tt.kind), err.Kind())
+		})
+	}
+}
+
+func TestIntDivisionByZero(t *testing.T) {
+	for _, opType := range []op.BinaryOpType{op.Divide, op.Modulo} {
+		result, ok := NewInt(1).RunOperation(opType, NewInt(0)).(*Error)
+		require.True(t, ok)
+		require.ErrorIs(t, result.Value(), ErrDivisionByZero)
+		result, ok = NewByte(1).RunOperation(opType, NewByte(0)).(*Error)
+		require.True(t, ok)
+		require.ErrorIs(t, result.Value(), ErrDivisionByZero)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 
 	"github.com/risor-io/risor/op"
@@ -28,6 +29,15 @@ func (f *Float) Value() float64 {
 }
 
 func (f *Float) HashKey() HashKey {
+	// Hash whole numbers the same as integers of equal value, since the
+	// two compare equal
+	if f.value == math.Trunc(f.value) && !math.IsInf(f.value, 0) {
+		if f.value >= math.MinInt64 && f.value < math.MaxInt64 {
+			return HashKey{Type: INT, IntValue: int64(f.value)}
+		}
+		value, _ := big.NewFloat(f.value).Int(nil)
+		return NewBigInt(value).HashKey()
+	}
 	return HashKey{Type: f.Type(), FltValue: f.value}
 }
 
@@ -67,6 +77,9 @@ func (f *Float) Compare(other Object) (int, error) {
 			return 1, nil
 		}
 		return -1, nil
+	case *BigInt, *Decimal:
+		value, err := other.(Comparable).Compare(f)
+		return -value, err
 	default:
 		return CompareTypes(f, other), nil
 	}
@@ -86,6 +99,8 @@ func (f *Float) Equals(other Object) Object {
 		if f.value == float64(other.value) {
 			return True
 		}
+	case *BigInt, *Decimal, *Complex:
+		return other.Equals(f)
 	}
 	return False
 }
@@ -103,6 +118,11 @@ func (f *Float) RunOperation(opType op.BinaryOpType, right Object) Object {
 	case *Byte:
 		rightFloat := float64(right.value)
 		return f.runOperationFloat(opType, rightFloat)
+	case *BigInt:
+		rightFloat, _ := new(big.Float).SetInt(right.value).Float64()
+		return f.runOperationFloat(opType, rightFloat)
+	case *Complex:
+		return NewComplex(complex(f.value, 0)).RunOperation(opType, right)
 	default:
 		return NewError(fmt.Errorf("eval error: unsupported operation for float: %v on type %s", opType, right.Type()))
 	}
@@ -81,6 +81,17 @@ func (f *FloatSlice) GetItem(key Object) (Object, *Error) {
 }
 
 func (f *FloatSlice) GetSlice(slice Slice) (Object, *Error) {
+	if slice.Step != nil {
+		start, step, count, err := ResolveStepSlice(slice, int64(len(f.value)))
+		if err != nil {
+			return nil, NewError(err)
+		}
+		items := make([]float64, count)
+		for i := range items {
+			items[i] = f.value[start+int64(i)*step]
+		}
+		return NewFloatSlice(items), nil
+	}
 	start, stop, err := ResolveIntSlice(slice, int64(len(f.value)))
 	if err != nil {
 		return nil, NewError(err)
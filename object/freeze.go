@@ -0,0 +1,59 @@
+package object
+
+// Freeze returns a read-only copy of the given object. Lists, maps and sets
+// are copied into frozen variants that return an error when modified, and
+// their contents are frozen recursively. Tuples are copied so that their
+// items are frozen. All other objects are returned unchanged.
+func Freeze(obj Object) Object {
+	return freeze(obj, map[Object]Object{})
+}
+
+// freeze implements Freeze. The seen map holds the frozen copy of each
+// container visited so far, so that self-referencing containers terminate.
+func freeze(obj Object, seen map[Object]Object) Object {
+	if frozen, ok := seen[obj]; ok {
+		return frozen
+	}
+	switch obj := obj.(type) {
+	case *List:
+		if obj.frozen {
+			return obj
+		}
+		result := &List{items: make([]Object, len(obj.items)), frozen: true}
+		seen[obj] = result
+		for i, item := range obj.items {
+			result.items[i] = freeze(item, seen)
+		}
+		return result
+	case *Map:
+		if obj.frozen {
+			return obj
+		}
+		result := NewMap(nil)
+		seen[obj] = result
+		for _, entry := range obj.entries {
+			if entry != nil {
+				result.set(entry.key.(Hashable).HashKey(), freeze(entry.key, seen), freeze(entry.value, seen))
+			}
+		}
+		result.frozen = true
+		return result
+	case *Set:
+		if obj.frozen {
+			return obj
+		}
+		result := &Set{items: make(map[HashKey]Object, len(obj.items)), frozen: true}
+		seen[obj] = result
+		for k, v := range obj.items {
+			result.items[k] = freeze(v, seen)
+		}
+		return result
+	case *Tuple:
+		items := make([]Object, len(obj.items))
+		for i, item := range obj.items {
+			items[i] = freeze(item, seen)
+		}
+		return NewTuple(items)
+	}
+	return obj
+}
@@ -15,6 +15,8 @@ type Function struct {
 	parameters    []string
 	defaults      []Object
 	defaultsCount int
+	restParam     string
+	kwargsParam   string
 	code          *Code
 	freeVars      []*Cell
 }
@@ -36,6 +38,12 @@ func (f *Function) Inspect() string {
 		}
 		parameters = append(parameters, name)
 	}
+	if f.restParam != "" {
+		parameters = append(parameters, "*"+f.restParam)
+	}
+	if f.kwargsParam != "" {
+		parameters = append(parameters, "**"+f.kwargsParam)
+	}
 	out.WriteString("func")
 	if f.name != "" {
 		out.WriteString(" " + f.name)
@@ -99,6 +107,24 @@ func (f *Function) Defaults() []Object {
 	return f.defaults
 }
 
+// RestParameter returns the name of the parameter that receives any extra
+// positional arguments, or an empty string if there is none.
+func (f *Function) RestParameter() string {
+	return f.restParam
+}
+
+// KwargsParameter returns the name of the parameter that receives any extra
+// keyword arguments, or an empty string if there is none.
+func (f *Function) KwargsParameter() string {
+	return f.kwargsParam
+}
+
+// IsVariadic returns true if the function accepts extra positional or keyword
+// arguments.
+func (f *Function) IsVariadic() bool {
+	return f.restParam != "" || f.kwargsParam != ""
+}
+
 func (f *Function) RequiredArgsCount() int {
 	return len(f.parameters) - f.defaultsCount
 }
@@ -112,10 +138,12 @@ func (f *Function) MarshalJSON() ([]byte, error) {
 }
 
 type FunctionOpts struct {
-	Name           string
-	ParameterNames []string
-	Defaults       []Object
-	Code           *Code
+	Name            string
+	ParameterNames  []string
+	Defaults        []Object
+	RestParameter   string
+	KwargsParameter string
+	Code            *Code
 }
 
 func NewFunction(opts FunctionOpts) *Function {
@@ -130,6 +158,8 @@ func NewFunction(opts FunctionOpts) *Function {
 		parameters:    opts.ParameterNames,
 		defaults:      opts.Defaults,
 		defaultsCount: defaultsCount,
+		restParam:     opts.RestParameter,
+		kwargsParam:   opts.KwargsParameter,
 		code:          opts.Code,
 	}
 }
@@ -144,6 +174,8 @@ func NewClosure(
 		parameters:    fn.parameters,
 		defaults:      fn.defaults,
 		defaultsCount: fn.defaultsCount,
+		restParam:     fn.restParam,
+		kwargsParam:   fn.kwargsParam,
 		code:          code,
 		freeVars:      freeVars,
 	}
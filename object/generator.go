@@ -0,0 +1,151 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// ResumeFunc resumes a suspended generator function and runs it until it
+// yields a value or returns. The boolean result is false once the function
+// has returned.
+type ResumeFunc func() (Object, bool, error)
+
+// Generator iterates over the values yielded by a call to a generator
+// function. The function runs lazily: each call to Next resumes it until it
+// yields the next value, and iteration stops when the function returns.
+type Generator struct {
+	*base
+	fn      *Function
+	resume  ResumeFunc
+	running bool
+	done    bool
+	err     error
+	count   int64
+	current Object
+}
+
+func (g *Generator) Type() Type {
+	return GENERATOR
+}
+
+func (g *Generator) Inspect() string {
+	return fmt.Sprintf("generator(%s)", g.fn.Name())
+}
+
+func (g *Generator) String() string {
+	return g.Inspect()
+}
+
+func (g *Generator) Interface() interface{} {
+	return nil
+}
+
+func (g *Generator) Equals(other Object) Object {
+	return NewBool(g == other)
+}
+
+func (g *Generator) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "generator.next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("generator.next", 0, len(args))
+				}
+				value, ok := g.Next()
+				if !ok {
+					if g.err != nil {
+						return NewError(g.err)
+					}
+					return Nil
+				}
+				return value
+			},
+		}, true
+	case "entry":
+		return &Builtin{
+			name: "generator.entry",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("generator.entry", 0, len(args))
+				}
+				entry, ok := g.Entry()
+				if !ok {
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (g *Generator) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for generator: %v", opType))
+}
+
+// Next resumes the generator function and returns the next value it yields.
+// Iteration stops when the function returns or raises an error, in which case
+// the error is available from Err.
+func (g *Generator) Next() (Object, bool) {
+	if g.done {
+		return nil, false
+	}
+	if g.running {
+		g.stop(errors.New("exec error: generator is already running"))
+		return nil, false
+	}
+	g.running = true
+	value, ok, err := g.resume()
+	g.running = false
+	if err != nil {
+		g.stop(err)
+		return nil, false
+	}
+	if !ok {
+		g.stop(nil)
+		return nil, false
+	}
+	g.count++
+	g.current = value
+	return value, true
+}
+
+func (g *Generator) stop(err error) {
+	g.done = true
+	g.err = err
+	g.current = nil
+	g.resume = nil
+}
+
+func (g *Generator) Entry() (IteratorEntry, bool) {
+	if g.current == nil {
+		return nil, false
+	}
+	return NewEntry(NewInt(g.count-1), g.current), true
+}
+
+// Err returns the error raised by the generator function, if any.
+func (g *Generator) Err() error {
+	return g.err
+}
+
+// Function returns the generator function that was called to create this
+// generator.
+func (g *Generator) Function() *Function {
+	return g.fn
+}
+
+func (g *Generator) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal generator")
+}
+
+// NewGenerator returns a generator for a call to the given generator
+// function. The resume function is called to produce each value.
+func NewGenerator(fn *Function, resume ResumeFunc) *Generator {
+	return &Generator{fn: fn, resume: resume}
+}
@@ -22,6 +22,7 @@ type GoType struct {
 	converter      TypeConverter
 	isPointerType  bool
 	isDirectMethod map[string]bool
+	operators      map[string]*GoMethod
 }
 
 func (t *GoType) Type() Type {
@@ -129,6 +130,48 @@ func (t *GoType) HasDirectMethod(name string) bool {
 	return t.isDirectMethod[name]
 }
 
+// Operator returns the Go method that implements the named operator, such as
+// "__add__", if there is one.
+func (t *GoType) Operator(name string) (*GoMethod, bool) {
+	method, ok := t.operators[name]
+	return method, ok
+}
+
+// SetOperator registers the named Go method as the implementation of the
+// named operator, such as "__add__", for both this type and its indirect
+// type. By convention, methods like Add, Equal, Compare, and Len are
+// registered automatically. This should be called before the type is used.
+func (t *GoType) SetOperator(name, methodName string) error {
+	goTypeMutex.Lock()
+	defer goTypeMutex.Unlock()
+
+	spec, ok := goOperatorMethods[name]
+	if !ok {
+		return fmt.Errorf("type error: unknown operator method %s", name)
+	}
+	found := false
+	for _, goType := range []*GoType{t, t.indirectType} {
+		attr, ok := goType.attributes[methodName]
+		if !ok {
+			continue
+		}
+		method, ok := attr.(*GoMethod)
+		if !ok {
+			return fmt.Errorf("type error: %s.%s is not a method", t.Name(), methodName)
+		}
+		if method.NumIn()-1 != spec.args {
+			return fmt.Errorf("type error: %s.%s must accept %d arguments to implement %s",
+				t.Name(), methodName, spec.args, name)
+		}
+		goType.operators[name] = method
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("type error: %s has no method %s", t.Name(), methodName)
+	}
+	return nil
+}
+
 func (t *GoType) GetConverter() (TypeConverter, error) {
 	if t.converter != nil {
 		return t.converter, nil
@@ -194,6 +237,7 @@ func newGoType(typ reflect.Type) (*GoType, error) {
 		packagePath:    NewString(typ.PkgPath()),
 		isPointerType:  isPointer,
 		isDirectMethod: map[string]bool{},
+		operators:      map[string]*GoMethod{},
 	}
 
 	// Add the new type to the registry before calling newGoType recursively
@@ -244,6 +288,14 @@ func newGoType(typ reflect.Type) (*GoType, error) {
 		goType.isDirectMethod[name] = true
 	}
 
+	// Register the methods that implement operators by naming convention
+	for name, spec := range goOperatorMethods {
+		method, ok := goType.attributes[spec.name].(*GoMethod)
+		if ok && method.NumIn()-1 == spec.args {
+			goType.operators[name] = method
+		}
+	}
+
 	// Now that all attributes have been discovered, create a sorted list of
 	// attribute names for use in the proxy.
 	for attrName := range goType.attributes {
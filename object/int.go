@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 
 	"github.com/risor-io/risor/op"
 )
@@ -65,6 +66,9 @@ func (i *Int) Compare(other Object) (int, error) {
 			return 1, nil
 		}
 		return -1, nil
+	case *BigInt, *Decimal:
+		value, err := other.(Comparable).Compare(i)
+		return -value, err
 	default:
 		return CompareTypes(i, other), nil
 	}
@@ -84,6 +88,8 @@ func (i *Int) Equals(other Object) Object {
 		if i.value == int64(other.value) {
 			return True
 		}
+	case *BigInt, *Decimal, *Complex:
+		return other.Equals(i)
 	}
 	return False
 }
@@ -101,6 +107,12 @@ func (i *Int) RunOperation(opType op.BinaryOpType, right Object) Object {
 	case *Byte:
 		rightInt := int64(right.value)
 		return i.runOperationInt(opType, rightInt)
+	case *BigInt:
+		return NewBigInt(big.NewInt(i.value)).RunOperation(opType, right)
+	case *Decimal:
+		return NewDecimalFromInt(i.value).RunOperation(opType, right)
+	case *Complex:
+		return NewComplex(complex(float64(i.value), 0)).RunOperation(opType, right)
 	default:
 		return NewError(fmt.Errorf("eval error: unsupported operation for int: %v on type %s", opType, right.Type()))
 	}
@@ -109,21 +121,63 @@ func (i *Int) RunOperation(opType op.BinaryOpType, right Object) Object {
 func (i *Int) runOperationInt(opType op.BinaryOpType, right int64) Object {
 	switch opType {
 	case op.Add:
-		return NewInt(i.value + right)
+		result := i.value + right
+		if (result > i.value) != (right > 0) {
+			return i.runOperationBigInt(opType, right)
+		}
+		return NewInt(result)
 	case op.Subtract:
-		return NewInt(i.value - right)
+		result := i.value - right
+		if (result < i.value) != (right > 0) {
+			return i.runOperationBigInt(opType, right)
+		}
+		return NewInt(result)
 	case op.Multiply:
-		return NewInt(i.value * right)
+		if i.value == 0 || right == 0 {
+			return NewInt(0)
+		}
+		result := i.value * right
+		if result/right != i.value || (i.value == -1 && right == math.MinInt64) ||
+			(right == -1 && i.value == math.MinInt64) {
+			return i.runOperationBigInt(opType, right)
+		}
+		return NewInt(result)
 	case op.Divide:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
+		if i.value == math.MinInt64 && right == -1 {
+			return i.runOperationBigInt(opType, right)
+		}
 		return NewInt(i.value / right)
 	case op.Modulo:
+		if right == 0 {
+			return NewError(ErrDivisionByZero)
+		}
 		return NewInt(i.value % right)
 	case op.Xor:
 		return NewInt(i.value ^ right)
 	case op.Power:
+		if right >= 0 {
+			return i.runOperationBigInt(opType, right)
+		}
 		return NewInt(int64(math.Pow(float64(i.value), float64(right))))
 	case op.LShift:
-		return NewInt(i.value << uint(right))
+		// Shifts that would overflow are made with arbitrary precision
+		if right < 0 {
+			return Errorf("value error: negative shift count: %d", right)
+		}
+		if right >= 64 {
+			if i.value == 0 {
+				return NewInt(0)
+			}
+			return i.runOperationBigInt(opType, right)
+		}
+		result := i.value << uint(right)
+		if result>>uint(right) != i.value {
+			return i.runOperationBigInt(opType, right)
+		}
+		return NewInt(result)
 	case op.RShift:
 		return NewInt(i.value >> uint(right))
 	case op.BitwiseAnd:
@@ -135,6 +189,13 @@ func (i *Int) runOperationInt(opType op.BinaryOpType, right int64) Object {
 	}
 }
 
+// runOperationBigInt performs the operation with arbitrary precision. This is
+// used when the int64 result would overflow. The result is demoted to an Int
+// if it fits.
+func (i *Int) runOperationBigInt(opType op.BinaryOpType, right int64) Object {
+	return NewBigInt(big.NewInt(i.value)).runOperationBigInt(opType, big.NewInt(right))
+}
+
 func (i *Int) runOperationFloat(opType op.BinaryOpType, right float64) Object {
 	iValue := float64(i.value)
 	switch opType {
@@ -17,6 +17,9 @@ type List struct {
 	// items holds the list of objects
 	items []Object
 
+	// frozen is set on lists created by Freeze, which may not be modified.
+	frozen bool
+
 	// Used to avoid the possibility of infinite recursion when inspecting.
 	// Similar to the usage of Py_ReprEnter in CPython.
 	inspectActive bool
@@ -51,170 +54,28 @@ func (ls *List) Inspect() string {
 }
 
 func (ls *List) GetAttr(name string) (Object, bool) {
-	switch name {
-	case "append":
-		return &Builtin{
-			name: "list.append",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.append", 1, len(args))
-				}
-				ls.Append(args[0])
-				return ls
-			},
-		}, true
-	case "clear":
-		return &Builtin{
-			name: "list.clear",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("list.clear", 0, len(args))
-				}
-				ls.Clear()
-				return ls
-			},
-		}, true
-	case "copy":
-		return &Builtin{
-			name: "list.copy",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("list.copy", 0, len(args))
-				}
-				return ls.Copy()
-			},
-		}, true
-	case "count":
-		return &Builtin{
-			name: "list.count",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.count", 1, len(args))
-				}
-				return NewInt(ls.Count(args[0]))
-			},
-		}, true
-	case "extend":
-		return &Builtin{
-			name: "list.extend",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.extend", 1, len(args))
-				}
-				other, err := AsList(args[0])
-				if err != nil {
-					return err
-				}
-				ls.Extend(other)
-				return ls
-			},
-		}, true
-	case "index":
-		return &Builtin{
-			name: "list.index",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.index", 1, len(args))
-				}
-				return NewInt(ls.Index(args[0]))
-			},
-		}, true
-	case "insert":
-		return &Builtin{
-			name: "list.insert",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 2 {
-					return NewArgsError("list.insert", 2, len(args))
-				}
-				index, err := AsInt(args[0])
-				if err != nil {
-					return err
-				}
-				ls.Insert(index, args[1])
-				return ls
-			},
-		}, true
-	case "pop":
-		return &Builtin{
-			name: "list.pop",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.pop", 1, len(args))
-				}
-				index, err := AsInt(args[0])
-				if err != nil {
-					return err
-				}
-				return ls.Pop(index)
-			},
-		}, true
-	case "remove":
-		return &Builtin{
-			name: "list.remove",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.remove", 1, len(args))
-				}
-				ls.Remove(args[0])
-				return ls
-			},
-		}, true
-	case "reverse":
-		return &Builtin{
-			name: "list.reverse",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("list.reverse", 0, len(args))
-				}
-				ls.Reverse()
-				return ls
-			},
-		}, true
-	case "sort":
-		return &Builtin{
-			name: "list.sort",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("list.sort", 0, len(args))
-				}
-				if err := Sort(ls.items); err != nil {
-					return err
-				}
-				return ls
-			},
-		}, true
-	case "map":
-		return &Builtin{
-			name: "list.map",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.map", 1, len(args))
-				}
-				return ls.Map(ctx, args[0])
-			},
-		}, true
-	case "filter":
-		return &Builtin{
-			name: "list.filter",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.filter", 1, len(args))
-				}
-				return ls.Filter(ctx, args[0])
-			},
-		}, true
-	case "each":
-		return &Builtin{
-			name: "list.each",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("list.each", 1, len(args))
-				}
-				return ls.Each(ctx, args[0])
-			},
-		}, true
+	method, ok := listMethods[name]
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	return method(ls), true
+}
+
+// AttrCacheKey returns the list type, since all lists have the same methods.
+func (ls *List) AttrCacheKey() interface{} {
+	return LIST
+}
+
+// ResolveAttrLoader resolves the named method. The loader binds the method
+// to the list it is given.
+func (ls *List) ResolveAttrLoader(name string) (AttrLoader, bool) {
+	method, ok := listMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return func(obj Object) Object {
+		return method(obj.(*List))
+	}, true
 }
 
 func (ls *List) Map(ctx context.Context, fn Object) Object {
@@ -251,7 +112,7 @@ func (ls *List) Map(ctx context.Context, fn Object) Object {
 			outputValue, err = callFunc(ctx, compiledFunc, mapArgs)
 		}
 		if err != nil {
-			return Errorf(err.Error())
+			return NewError(err)
 		}
 		if IsError(outputValue) {
 			return outputValue
@@ -278,7 +139,7 @@ func (ls *List) Filter(ctx context.Context, fn Object) Object {
 		filterArgs[0] = value
 		decision, err := callFunc(ctx, fn.(*Function), filterArgs)
 		if err != nil {
-			return Errorf(err.Error())
+			return NewError(err)
 		}
 		if IsError(decision) {
 			return decision
@@ -306,7 +167,7 @@ func (ls *List) Each(ctx context.Context, fn Object) Object {
 		eachArgs[0] = value
 		result, err := callFunc(ctx, fn.(*Function), eachArgs)
 		if err != nil {
-			return Errorf(err.Error())
+			return NewError(err)
 		}
 		if IsError(result) {
 			return result
@@ -315,6 +176,18 @@ func (ls *List) Each(ctx context.Context, fn Object) Object {
 	return Nil
 }
 
+// IsFrozen returns true if the list may not be modified.
+func (ls *List) IsFrozen() bool {
+	return ls.frozen
+}
+
+func (ls *List) checkMutable() *Error {
+	if ls.frozen {
+		return Errorf("type error: cannot modify a frozen list")
+	}
+	return nil
+}
+
 // Append adds an item at the end of the list.
 func (ls *List) Append(obj Object) {
 	ls.items = append(ls.items, obj)
@@ -497,8 +370,19 @@ func (ls *List) GetItem(key Object) (Object, *Error) {
 	return ls.items[idx], nil
 }
 
-// GetSlice implements the [start:stop] operator for a container type.
+// GetSlice implements the [start:stop:step] operator for a container type.
 func (ls *List) GetSlice(s Slice) (Object, *Error) {
+	if s.Step != nil {
+		start, step, count, err := ResolveStepSlice(s, int64(len(ls.items)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		items := make([]Object, count)
+		for i := range items {
+			items[i] = ls.items[start+int64(i)*step]
+		}
+		return NewList(items), nil
+	}
 	start, stop, err := ResolveIntSlice(s, int64(len(ls.items)))
 	if err != nil {
 		return nil, Errorf(err.Error())
@@ -511,6 +395,9 @@ func (ls *List) GetSlice(s Slice) (Object, *Error) {
 
 // SetItem implements the [key] = value operator for a container type.
 func (ls *List) SetItem(key, value Object) *Error {
+	if err := ls.checkMutable(); err != nil {
+		return err
+	}
 	indexObj, ok := key.(*Int)
 	if !ok {
 		return Errorf("type error: list index must be an int (got %s)", key.Type())
@@ -525,6 +412,9 @@ func (ls *List) SetItem(key, value Object) *Error {
 
 // DelItem implements the del [key] operator for a container type.
 func (ls *List) DelItem(key Object) *Error {
+	if err := ls.checkMutable(); err != nil {
+		return err
+	}
 	indexObj, ok := key.(*Int)
 	if !ok {
 		return Errorf("type error: list index must be an int (got %s)", key.Type())
@@ -674,3 +564,276 @@ func ResolveIntSlice(slice Slice, size int64) (start int64, stop int64, err erro
 	}
 	return start, stop, nil
 }
+
+// ResolveStepSlice resolves a slice that may have a step, returning the index
+// of the first item, the step, and the number of items selected. With a
+// positive step, the start and stop indices are checked as in ResolveIntSlice.
+// With a negative step, the items are selected in reverse, the start index
+// defaults to the last item, and the stop index defaults to before the first.
+func ResolveStepSlice(slice Slice, size int64) (start, step, count int64, err error) {
+	step = 1
+	if slice.Step != nil {
+		stepObj, ok := slice.Step.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice step must be an int (got %s)", slice.Step.Type())
+			return
+		}
+		step = stepObj.value
+	}
+	if step == 0 {
+		err = fmt.Errorf("slice error: slice step cannot be zero")
+		return
+	}
+	if step > 0 {
+		var stop int64
+		start, stop, err = ResolveIntSlice(Slice{Start: slice.Start, Stop: slice.Stop}, size)
+		if err != nil {
+			return
+		}
+		return start, step, rangeLen(start, stop, step), nil
+	}
+	start, stop := size-1, int64(-1)
+	if slice.Start != nil {
+		startObj, ok := slice.Start.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice start index must be an int (got %s)", slice.Start.Type())
+			return
+		}
+		if start, err = resolveSliceIndex(startObj.value, size); err != nil {
+			err = fmt.Errorf("slice error: start index is out of range")
+			return
+		}
+	}
+	if slice.Stop != nil {
+		stopObj, ok := slice.Stop.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice stop index must be an int (got %s)", slice.Stop.Type())
+			return
+		}
+		if stop, err = resolveSliceIndex(stopObj.value, size); err != nil {
+			err = fmt.Errorf("slice error: stop index is out of range")
+			return
+		}
+	}
+	if start < stop {
+		err = fmt.Errorf("slice error: start index is less than stop index")
+		return
+	}
+	return start, step, rangeLen(start, stop, step), nil
+}
+
+// resolveSliceIndex transforms a negative slice index into the corresponding
+// positive index, and checks that it refers to an item.
+func resolveSliceIndex(idx, size int64) (int64, error) {
+	if idx < 0 {
+		idx += size
+	}
+	if idx < 0 || idx >= size {
+		return 0, fmt.Errorf("index out of range")
+	}
+	return idx, nil
+}
+
+// listMethods holds the methods of lists, by name. Each function returns the method
+// bound to the given list.
+var listMethods = map[string]func(ls *List) *Builtin{
+	"append": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.append",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.append", 1, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				ls.Append(args[0])
+				return ls
+			},
+		}
+	},
+	"clear": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.clear",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("list.clear", 0, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				ls.Clear()
+				return ls
+			},
+		}
+	},
+	"copy": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.copy",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("list.copy", 0, len(args))
+				}
+				return ls.Copy()
+			},
+		}
+	},
+	"count": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.count",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.count", 1, len(args))
+				}
+				return NewInt(ls.Count(args[0]))
+			},
+		}
+	},
+	"extend": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.extend",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.extend", 1, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsList(args[0])
+				if err != nil {
+					return err
+				}
+				ls.Extend(other)
+				return ls
+			},
+		}
+	},
+	"index": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.index",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.index", 1, len(args))
+				}
+				return NewInt(ls.Index(args[0]))
+			},
+		}
+	},
+	"insert": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.insert",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 2 {
+					return NewArgsError("list.insert", 2, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				index, err := AsInt(args[0])
+				if err != nil {
+					return err
+				}
+				ls.Insert(index, args[1])
+				return ls
+			},
+		}
+	},
+	"pop": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.pop",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.pop", 1, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				index, err := AsInt(args[0])
+				if err != nil {
+					return err
+				}
+				return ls.Pop(index)
+			},
+		}
+	},
+	"remove": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.remove",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.remove", 1, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				ls.Remove(args[0])
+				return ls
+			},
+		}
+	},
+	"reverse": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.reverse",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("list.reverse", 0, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				ls.Reverse()
+				return ls
+			},
+		}
+	},
+	"sort": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.sort",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("list.sort", 0, len(args))
+				}
+				if err := ls.checkMutable(); err != nil {
+					return err
+				}
+				if err := SortContext(ctx, ls.items); err != nil {
+					return err
+				}
+				return ls
+			},
+		}
+	},
+	"map": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.map",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.map", 1, len(args))
+				}
+				return ls.Map(ctx, args[0])
+			},
+		}
+	},
+	"filter": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.filter",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.filter", 1, len(args))
+				}
+				return ls.Filter(ctx, args[0])
+			},
+		}
+	},
+	"each": func(ls *List) *Builtin {
+		return &Builtin{
+			name: "list.each",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("list.each", 1, len(args))
+				}
+				return ls.Each(ctx, args[0])
+			},
+		}
+	},
+}
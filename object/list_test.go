@@ -45,3 +45,42 @@ func TestListPop(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, "index error: index out of range: 1", err.Message().Value())
 }
+
+func TestListGetSliceStep(t *testing.T) {
+	list := NewList([]Object{NewInt(0), NewInt(1), NewInt(2), NewInt(3), NewInt(4)})
+	tests := []struct {
+		slice    Slice
+		expected []Object
+	}{
+		{Slice{Step: NewInt(2)}, []Object{NewInt(0), NewInt(2), NewInt(4)}},
+		{Slice{Step: NewInt(-1)}, []Object{NewInt(4), NewInt(3), NewInt(2), NewInt(1), NewInt(0)}},
+		{Slice{Start: NewInt(-2), Step: NewInt(-2)}, []Object{NewInt(3), NewInt(1)}},
+		{Slice{Start: NewInt(4), Stop: NewInt(1), Step: NewInt(-1)}, []Object{NewInt(4), NewInt(3), NewInt(2)}},
+		{Slice{Start: NewInt(1), Stop: NewInt(4), Step: NewInt(2)}, []Object{NewInt(1), NewInt(3)}},
+		{Slice{Start: NewInt(2), Stop: NewInt(2), Step: NewInt(-1)}, []Object{}},
+	}
+	for _, tt := range tests {
+		result, err := list.GetSlice(tt.slice)
+		require.Nil(t, err)
+		require.Equal(t, NewList(tt.expected), result)
+	}
+}
+
+func TestResolveStepSliceErrors(t *testing.T) {
+	tests := []struct {
+		slice    Slice
+		expected string
+	}{
+		{Slice{Step: NewInt(0)}, "slice error: slice step cannot be zero"},
+		{Slice{Step: NewString("a")}, "type error: slice step must be an int (got string)"},
+		{Slice{Start: NewInt(5), Step: NewInt(-1)}, "slice error: start index is out of range"},
+		{Slice{Stop: NewInt(-6), Step: NewInt(-1)}, "slice error: stop index is out of range"},
+		{Slice{Start: NewInt(1), Stop: NewInt(3), Step: NewInt(-1)}, "slice error: start index is less than stop index"},
+		{Slice{Start: NewInt(3), Stop: NewInt(1), Step: NewInt(1)}, "slice error: start index is greater than stop index"},
+	}
+	for _, tt := range tests {
+		_, _, _, err := ResolveStepSlice(tt.slice, 5)
+		require.NotNil(t, err)
+		require.Equal(t, tt.expected, err.Error())
+	}
+}
@@ -11,10 +11,29 @@ import (
 	"github.com/risor-io/risor/op"
 )
 
+// mapEntry is a key and value pair held by a map.
+type mapEntry struct {
+	key   Object
+	value Object
+}
+
+// Map holds key-value pairs. Keys may be any hashable object, and the order
+// in which keys are inserted is preserved when iterating over the map.
 type Map struct {
 	*base
 
-	items map[string]Object
+	// The entries of the map in insertion order. Deleted entries are set
+	// to nil until the slice is compacted.
+	entries []*mapEntry
+
+	// The position of each key's entry in the entries slice.
+	index map[HashKey]int
+
+	// The number of deleted entries in the entries slice.
+	deleted int
+
+	// frozen is set on maps created by Freeze, which may not be modified.
+	frozen bool
 
 	// Used to avoid the possibility of infinite recursion when inspecting.
 	// Similar to the usage of Py_ReprEnter in CPython.
@@ -35,10 +54,11 @@ func (m *Map) Inspect() string {
 	defer func() { m.inspectActive = false }()
 
 	var out bytes.Buffer
-	pairs := make([]string, 0)
-	for _, k := range m.SortedKeys() {
-		v := m.items[k]
-		pairs = append(pairs, fmt.Sprintf("%q: %s", k, v.Inspect()))
+	pairs := make([]string, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", entry.key.Inspect(), entry.value.Inspect()))
+		}
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -50,161 +70,99 @@ func (m *Map) String() string {
 	return m.Inspect()
 }
 
+// Value returns a Go map holding the items of this map. Keys that aren't
+// strings are converted to strings.
+//
+// A new Go map is built on each call, so changes to the result do not affect
+// this map. Use Range to visit the items without copying them, and Set or
+// SetItem to modify the map.
+//
+// The conversion is lossy when distinct keys have the same string form, such
+// as the int 1 and the string "1". Only the item inserted last is kept for
+// such keys.
 func (m *Map) Value() map[string]Object {
-	return m.items
+	result := make(map[string]Object, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			result[keyString(entry.key)] = entry.value
+		}
+	}
+	return result
 }
 
 func (m *Map) GetAttr(name string) (Object, bool) {
-	switch name {
-	case "keys":
-		return &Builtin{
-			name: "map.keys",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("map.keys", 0, len(args))
-				}
-				return m.Keys()
-			},
-		}, true
-	case "values":
-		return &Builtin{
-			name: "map.values",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("map.values", 0, len(args))
-				}
-				return m.Values()
-			},
-		}, true
-	case "get":
-		return &Builtin{
-			name: "map.get",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) < 1 || len(args) > 2 {
-					return NewArgsRangeError("map.get", 1, 2, len(args))
-				}
-				key, err := AsString(args[0])
-				if err != nil {
-					return err
-				}
-				value, found := m.items[key]
-				if !found {
-					if len(args) == 2 {
-						return args[1]
-					}
-					return Nil
-				}
-				return value
-			},
-		}, true
-	case "clear":
-		return &Builtin{
-			name: "map.clear",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("map.clear", 0, len(args))
-				}
-				m.Clear()
-				return m
-			},
-		}, true
-	case "copy":
-		return &Builtin{
-			name: "map.copy",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("map.copy", 0, len(args))
-				}
-				return m.Copy()
-			},
-		}, true
-	case "items":
-		return &Builtin{
-			name: "map.items",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("map.items", 0, len(args))
-				}
-				return m.ListItems()
-			},
-		}, true
-	case "pop":
-		return &Builtin{
-			name: "map.pop",
-			fn: func(ctx context.Context, args ...Object) Object {
-				nArgs := len(args)
-				if nArgs < 1 || nArgs > 2 {
-					return NewArgsRangeError("map.pop", 1, 2, len(args))
-				}
-				key, err := AsString(args[0])
-				if err != nil {
-					return err
-				}
-				var def Object
-				if nArgs == 2 {
-					def = args[1]
-				}
-				return m.Pop(key, def)
-			},
-		}, true
-	case "setdefault":
-		return &Builtin{
-			name: "map.setdefault",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 2 {
-					return NewArgsError("map.setdefault", 2, len(args))
-				}
-				key, err := AsString(args[0])
-				if err != nil {
-					return err
-				}
-				return m.SetDefault(key, args[1])
-			},
-		}, true
-	case "update":
-		return &Builtin{
-			name: "map.update",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("map.update", 1, len(args))
-				}
-				other, err := AsMap(args[0])
-				if err != nil {
-					return err
-				}
-				m.Update(other)
-				return m
-			},
-		}, true
+	method, ok := mapMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return method(m), true
+}
+
+// AttrCacheKey returns the map type, since all maps have the same methods.
+func (m *Map) AttrCacheKey() interface{} {
+	return MAP
+}
+
+// ResolveAttrLoader resolves the named method. The loader binds the method
+// to the map it is given.
+func (m *Map) ResolveAttrLoader(name string) (AttrLoader, bool) {
+	method, ok := mapMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return func(obj Object) Object {
+		return method(obj.(*Map))
+	}, true
+}
+
+// Range calls fn for each key and value in the map, in insertion order, until
+// fn returns false. The map must not be modified by fn.
+func (m *Map) Range(fn func(key, value Object) bool) {
+	for _, entry := range m.entries {
+		if entry != nil && !fn(entry.key, entry.value) {
+			return
+		}
 	}
-	return nil, false
 }
 
 func (m *Map) ListItems() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, NewList([]Object{NewString(k), m.items[k]}))
+	items := make([]Object, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			items = append(items, NewList([]Object{entry.key, entry.value}))
+		}
 	}
 	return NewList(items)
 }
 
 func (m *Map) Clear() {
-	m.items = map[string]Object{}
+	m.entries = nil
+	m.index = map[HashKey]int{}
+	m.deleted = 0
 }
 
 func (m *Map) Copy() *Map {
-	items := make(map[string]Object, len(m.items))
-	for k, v := range m.items {
-		items[k] = v
+	result := &Map{
+		entries: make([]*mapEntry, 0, m.Size()),
+		index:   make(map[HashKey]int, m.Size()),
 	}
-	return &Map{items: items}
+	for _, entry := range m.entries {
+		if entry != nil {
+			result.set(entry.key.(Hashable).HashKey(), entry.key, entry.value)
+		}
+	}
+	return result
 }
 
-func (m *Map) Pop(key string, def Object) Object {
-	value, found := m.items[key]
-	if found {
-		delete(m.items, key)
-		return value
+// Pop removes the given key from the map and returns its value. If the key
+// isn't found, the default is returned if one is given, otherwise nil.
+func (m *Map) Pop(key Object, def Object) Object {
+	hk, err := hashKey(key)
+	if err != nil {
+		return err
+	}
+	if entry, found := m.remove(hk); found {
+		return entry.value
 	}
 	if def != nil {
 		return def
@@ -212,46 +170,57 @@ func (m *Map) Pop(key string, def Object) Object {
 	return Nil
 }
 
-func (m *Map) SetDefault(key string, value Object) Object {
-	if _, found := m.items[key]; !found {
-		m.items[key] = value
+// SetDefault sets the given key to the value if the key isn't already in the
+// map. The value of the key is returned.
+func (m *Map) SetDefault(key Object, value Object) Object {
+	hk, err := hashKey(key)
+	if err != nil {
+		return err
+	}
+	if existing, found := m.lookup(hk); found {
+		return existing
 	}
-	return m.items[key]
+	m.set(hk, key, value)
+	return value
 }
 
 func (m *Map) Update(other *Map) {
-	for k, v := range other.items {
-		m.items[k] = v
+	for _, entry := range other.entries {
+		if entry != nil {
+			m.set(entry.key.(Hashable).HashKey(), entry.key, entry.value)
+		}
 	}
 }
 
+// SortedKeys returns the keys of the map as sorted strings.
 func (m *Map) SortedKeys() []string {
-	keys := make([]string, 0, len(m.items))
-	for k := range m.items {
-		keys = append(keys, k)
-	}
+	keys := m.StringKeys()
 	sort.Strings(keys)
 	return keys
 }
 
 func (m *Map) Keys() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, NewString(k))
+	items := make([]Object, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			items = append(items, entry.key)
+		}
 	}
 	return &List{items: items}
 }
 
 func (m *Map) Values() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, m.items[k])
+	items := make([]Object, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			items = append(items, entry.value)
+		}
 	}
 	return &List{items: items}
 }
 
 func (m *Map) GetWithObject(key *String) Object {
-	value, found := m.items[key.value]
+	value, found := m.lookup(key.HashKey())
 	if !found {
 		return Nil
 	}
@@ -259,7 +228,7 @@ func (m *Map) GetWithObject(key *String) Object {
 }
 
 func (m *Map) Get(key string) Object {
-	value, found := m.items[key]
+	value, found := m.lookup(stringHashKey(key))
 	if !found {
 		return Nil
 	}
@@ -267,7 +236,7 @@ func (m *Map) Get(key string) Object {
 }
 
 func (m *Map) GetWithDefault(key string, defaultValue Object) Object {
-	value, found := m.items[key]
+	value, found := m.lookup(stringHashKey(key))
 	if !found {
 		return defaultValue
 	}
@@ -275,22 +244,24 @@ func (m *Map) GetWithDefault(key string, defaultValue Object) Object {
 }
 
 func (m *Map) Delete(key string) Object {
-	delete(m.items, key)
+	m.remove(stringHashKey(key))
 	return Nil
 }
 
 func (m *Map) Set(key string, value Object) {
-	m.items[key] = value
+	m.set(stringHashKey(key), NewString(key), value)
 }
 
 func (m *Map) Size() int {
-	return len(m.items)
+	return len(m.index)
 }
 
 func (m *Map) Interface() interface{} {
-	result := make(map[string]any, len(m.items))
-	for k, v := range m.items {
-		result[k] = v.Interface()
+	result := make(map[string]any, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			result[keyString(entry.key)] = entry.value.Interface()
+		}
 	}
 	return result
 }
@@ -300,15 +271,15 @@ func (m *Map) Equals(other Object) Object {
 		return False
 	}
 	otherMap := other.(*Map)
-	if len(m.items) != len(otherMap.items) {
+	if m.Size() != otherMap.Size() {
 		return False
 	}
-	for k, v := range m.items {
-		otherValue, found := otherMap.items[k]
+	for hk, pos := range m.index {
+		otherValue, found := otherMap.lookup(hk)
 		if !found {
 			return False
 		}
-		if !v.Equals(otherValue).(*Bool).value {
+		if !m.entries[pos].value.Equals(otherValue).(*Bool).value {
 			return False
 		}
 	}
@@ -316,21 +287,45 @@ func (m *Map) Equals(other Object) Object {
 }
 
 func (m *Map) RunOperation(opType op.BinaryOpType, right Object) Object {
-	return NewError(fmt.Errorf("eval error: unsupported operation for map: %v", opType))
+	other, ok := right.(*Map)
+	if !ok || opType != op.BitwiseOr {
+		return NewError(fmt.Errorf("eval error: unsupported operation for map: %v on type %s",
+			opType, right.Type()))
+	}
+	// The merged map holds the keys of this map followed by any new keys from
+	// the other map. Values from the other map take precedence.
+	result := m.Copy()
+	result.Update(other)
+	return result
 }
 
 func (m *Map) GetItem(key Object) (Object, *Error) {
-	strObj, ok := key.(*String)
-	if !ok {
-		return nil, Errorf("key error: map key must be a string (got %s)", key.Type())
+	hk, err := hashKey(key)
+	if err != nil {
+		return nil, err
 	}
-	value, found := m.items[strObj.value]
+	value, found := m.lookup(hk)
 	if !found {
-		return nil, Errorf("key error: %q", strObj.Value())
+		if str, ok := key.(*String); ok {
+			return nil, Errorf("key error: %q", str.value)
+		}
+		return nil, Errorf("key error: %s", key.Inspect())
 	}
 	return value, nil
 }
 
+// IsFrozen returns true if the map may not be modified.
+func (m *Map) IsFrozen() bool {
+	return m.frozen
+}
+
+func (m *Map) checkMutable() *Error {
+	if m.frozen {
+		return Errorf("type error: cannot modify a frozen map")
+	}
+	return nil
+}
+
 // GetSlice implements the [start:stop] operator for a container type.
 func (m *Map) GetSlice(s Slice) (Object, *Error) {
 	return nil, Errorf("map does not support slice operations")
@@ -338,51 +333,60 @@ func (m *Map) GetSlice(s Slice) (Object, *Error) {
 
 // SetItem assigns a value to the given key in the map.
 func (m *Map) SetItem(key, value Object) *Error {
-	strObj, ok := key.(*String)
-	if !ok {
-		return Errorf("key error: map key must be a string (got %s)", key.Type())
+	if err := m.checkMutable(); err != nil {
+		return err
 	}
-	m.items[strObj.value] = value
+	hk, err := hashKey(key)
+	if err != nil {
+		return err
+	}
+	m.set(hk, key, value)
 	return nil
 }
 
 // DelItem deletes the item with the given key from the map.
 func (m *Map) DelItem(key Object) *Error {
-	strObj, ok := key.(*String)
-	if !ok {
-		return Errorf("key error: map key must be a string (got %s)", key.Type())
+	if err := m.checkMutable(); err != nil {
+		return err
+	}
+	hk, err := hashKey(key)
+	if err != nil {
+		return err
 	}
-	delete(m.items, strObj.value)
+	m.remove(hk)
 	return nil
 }
 
 // Contains returns true if the given item is found in this container.
 func (m *Map) Contains(key Object) *Bool {
-	strObj, ok := key.(*String)
-	if !ok {
+	hk, err := hashKey(key)
+	if err != nil {
 		return False
 	}
-	_, found := m.items[strObj.value]
+	_, found := m.lookup(hk)
 	return NewBool(found)
 }
 
 func (m *Map) IsTruthy() bool {
-	return len(m.items) > 0
+	return m.Size() > 0
 }
 
 // Len returns the number of items in this container.
 func (m *Map) Len() *Int {
-	return NewInt(int64(len(m.items)))
+	return NewInt(int64(m.Size()))
 }
 
 func (m *Map) Iter() Iterator {
 	return NewMapIter(m)
 }
 
+// StringKeys returns the keys of the map as strings, in insertion order.
 func (m *Map) StringKeys() []string {
-	keys := make([]string, 0, len(m.items))
-	for k := range m.items {
-		keys = append(keys, k)
+	keys := make([]string, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			keys = append(keys, keyString(entry.key))
+		}
 	}
 	return keys
 }
@@ -390,16 +394,286 @@ func (m *Map) StringKeys() []string {
 func (m *Map) Cost() int {
 	// It would be possible to recurse and compute the cost of each item, but
 	// let's avoid that since it would be an expensive op itself.
-	return len(m.items) * 8
+	return m.Size() * 8
 }
 
 func (m *Map) MarshalJSON() ([]byte, error) {
-	return json.Marshal(m.items)
+	// Written out by hand so that keys are kept in insertion order
+	var out bytes.Buffer
+	out.WriteByte('{')
+	first := true
+	seen := make(map[string]Object, m.Size())
+	for _, entry := range m.entries {
+		if entry == nil {
+			continue
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		str := keyString(entry.key)
+		if other, found := seen[str]; found {
+			return nil, fmt.Errorf("value error: map keys %s and %s have the same json key %q",
+				other.Inspect(), entry.key.Inspect(), str)
+		}
+		seen[str] = entry.key
+		key, err := json.Marshal(str)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func (m *Map) lookup(key HashKey) (Object, bool) {
+	pos, found := m.index[key]
+	if !found {
+		return nil, false
+	}
+	return m.entries[pos].value, true
+}
+
+// set assigns the value to the key. A new key is added to the end of the map,
+// while an existing key keeps its position.
+func (m *Map) set(hk HashKey, key, value Object) {
+	if pos, found := m.index[hk]; found {
+		m.entries[pos].value = value
+		return
+	}
+	m.index[hk] = len(m.entries)
+	m.entries = append(m.entries, &mapEntry{key: key, value: value})
+}
+
+func (m *Map) remove(hk HashKey) (*mapEntry, bool) {
+	pos, found := m.index[hk]
+	if !found {
+		return nil, false
+	}
+	entry := m.entries[pos]
+	m.entries[pos] = nil
+	delete(m.index, hk)
+	m.deleted++
+	// Compact the entries once deletions make up most of the slice
+	if m.deleted > 8 && m.deleted > len(m.entries)/2 {
+		m.compact()
+	}
+	return entry, true
+}
+
+func (m *Map) compact() {
+	entries := make([]*mapEntry, 0, len(m.index))
+	for _, entry := range m.entries {
+		if entry != nil {
+			m.index[entry.key.(Hashable).HashKey()] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+	m.entries = entries
+	m.deleted = 0
+}
+
+// entryKeys returns the keys of the map in insertion order.
+func (m *Map) entryKeys() []Object {
+	keys := make([]Object, 0, m.Size())
+	for _, entry := range m.entries {
+		if entry != nil {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
 }
 
+// hashKey returns the hash key of the given object, or an error if the object
+// can't be used as a map key or set member.
+func hashKey(key Object) (HashKey, *Error) {
+	hashable, ok := key.(Hashable)
+	if !ok {
+		return HashKey{}, Errorf("type error: %s object is unhashable", key.Type())
+	}
+	if tuple, ok := key.(*Tuple); ok {
+		if err := tuple.checkHashable(); err != nil {
+			return HashKey{}, err
+		}
+	}
+	return hashable.HashKey(), nil
+}
+
+func stringHashKey(key string) HashKey {
+	return HashKey{Type: STRING, StrValue: key}
+}
+
+// keyString returns the string form of a map key, which is used when a map
+// is converted to a Go map or to JSON.
+func keyString(key Object) string {
+	if str, ok := key.(*String); ok {
+		return str.value
+	}
+	return key.Inspect()
+}
+
+// NewMap returns a map holding the given items. Since Go maps are unordered,
+// the items are inserted in sorted key order.
+//
+// The items are copied into the new map, so later changes to the given Go
+// map are not reflected in the returned map, and vice versa.
 func NewMap(m map[string]Object) *Map {
-	if m == nil {
-		m = map[string]Object{}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	return &Map{items: m}
+	sort.Strings(keys)
+	result := &Map{
+		entries: make([]*mapEntry, 0, len(keys)),
+		index:   make(map[HashKey]int, len(keys)),
+	}
+	for _, k := range keys {
+		result.set(stringHashKey(k), NewString(k), m[k])
+	}
+	return result
+}
+
+// mapMethods holds the methods of maps, by name. Each function returns the method
+// bound to the given map.
+var mapMethods = map[string]func(m *Map) *Builtin{
+	"keys": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.keys",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("map.keys", 0, len(args))
+				}
+				return m.Keys()
+			},
+		}
+	},
+	"values": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.values",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("map.values", 0, len(args))
+				}
+				return m.Values()
+			},
+		}
+	},
+	"get": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.get",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) < 1 || len(args) > 2 {
+					return NewArgsRangeError("map.get", 1, 2, len(args))
+				}
+				key, err := hashKey(args[0])
+				if err != nil {
+					return err
+				}
+				value, found := m.lookup(key)
+				if !found {
+					if len(args) == 2 {
+						return args[1]
+					}
+					return Nil
+				}
+				return value
+			},
+		}
+	},
+	"clear": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.clear",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("map.clear", 0, len(args))
+				}
+				if err := m.checkMutable(); err != nil {
+					return err
+				}
+				m.Clear()
+				return m
+			},
+		}
+	},
+	"copy": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.copy",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("map.copy", 0, len(args))
+				}
+				return m.Copy()
+			},
+		}
+	},
+	"items": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.items",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("map.items", 0, len(args))
+				}
+				return m.ListItems()
+			},
+		}
+	},
+	"pop": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.pop",
+			fn: func(ctx context.Context, args ...Object) Object {
+				nArgs := len(args)
+				if nArgs < 1 || nArgs > 2 {
+					return NewArgsRangeError("map.pop", 1, 2, len(args))
+				}
+				if err := m.checkMutable(); err != nil {
+					return err
+				}
+				var def Object
+				if nArgs == 2 {
+					def = args[1]
+				}
+				return m.Pop(args[0], def)
+			},
+		}
+	},
+	"setdefault": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.setdefault",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 2 {
+					return NewArgsError("map.setdefault", 2, len(args))
+				}
+				if err := m.checkMutable(); err != nil {
+					return err
+				}
+				return m.SetDefault(args[0], args[1])
+			},
+		}
+	},
+	"update": func(m *Map) *Builtin {
+		return &Builtin{
+			name: "map.update",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("map.update", 1, len(args))
+				}
+				if err := m.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsMap(args[0])
+				if err != nil {
+					return err
+				}
+				m.Update(other)
+				return m
+			},
+		}
+	},
 }
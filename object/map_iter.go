@@ -10,9 +10,9 @@ import (
 type MapIter struct {
 	*base
 	m       *Map
-	keys    []string
+	keys    []Object
 	pos     int64
-	current *String
+	current Object
 }
 
 func (iter *MapIter) Type() Type {
@@ -97,7 +97,7 @@ func (iter *MapIter) Next() (Object, bool) {
 		return nil, false
 	}
 	iter.pos++
-	iter.current = NewString(keys[iter.pos])
+	iter.current = keys[iter.pos]
 	return iter.current, true
 }
 
@@ -105,7 +105,7 @@ func (iter *MapIter) Entry() (IteratorEntry, bool) {
 	if iter.current == nil {
 		return nil, false
 	}
-	value, ok := iter.m.items[iter.current.value]
+	value, ok := iter.m.lookup(iter.current.(Hashable).HashKey())
 	if !ok {
 		iter.current = nil
 		return nil, false
@@ -118,5 +118,5 @@ func (iter *MapIter) MarshalJSON() ([]byte, error) {
 }
 
 func NewMapIter(m *Map) *MapIter {
-	return &MapIter{m: m, keys: m.SortedKeys(), pos: -1}
+	return &MapIter{m: m, keys: m.entryKeys(), pos: -1}
 }
@@ -0,0 +1,88 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapInsertionOrder(t *testing.T) {
+	m := NewMap(nil)
+	require.Nil(t, m.SetItem(NewString("z"), NewInt(1)))
+	require.Nil(t, m.SetItem(NewInt(3), NewInt(2)))
+	require.Nil(t, m.SetItem(NewFloat(1.5), NewInt(3)))
+	require.Nil(t, m.SetItem(True, NewInt(4)))
+	require.Nil(t, m.SetItem(NewString("z"), NewInt(5)))
+
+	require.Equal(t, NewList([]Object{NewString("z"), NewInt(3), NewFloat(1.5), True}), m.Keys())
+	require.Equal(t, NewList([]Object{NewInt(5), NewInt(2), NewInt(3), NewInt(4)}), m.Values())
+	require.Equal(t, `{"z": 5, 3: 2, 1.5: 3, true: 4}`, m.Inspect())
+
+	data, err := m.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `{"z":5,"3":2,"1.5":3,"true":4}`, string(data))
+}
+
+func TestMapUnhashableKey(t *testing.T) {
+	m := NewMap(nil)
+	err := m.SetItem(NewList(nil), NewInt(1))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: list object is unhashable", err.Message().Value())
+}
+
+func TestMapCompaction(t *testing.T) {
+	m := NewMap(nil)
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("k%d", i), NewInt(int64(i)))
+	}
+	for i := 0; i < 90; i++ {
+		m.Delete(fmt.Sprintf("k%d", i))
+	}
+	require.Less(t, len(m.entries), 100)
+	require.Equal(t, 10, m.Size())
+	require.Equal(t, []string{"k90", "k91", "k92", "k93", "k94", "k95", "k96", "k97", "k98", "k99"}, m.StringKeys())
+	for i := 90; i < 100; i++ {
+		require.Equal(t, NewInt(int64(i)), m.Get(fmt.Sprintf("k%d", i)))
+	}
+}
+
+func TestMapRange(t *testing.T) {
+	m := NewMap(nil)
+	m.Set("a", NewInt(1))
+	require.Nil(t, m.SetItem(NewInt(2), NewInt(2)))
+	m.Set("c", NewInt(3))
+
+	var keys []Object
+	m.Range(func(key, value Object) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	require.Equal(t, []Object{NewString("a"), NewInt(2)}, keys)
+}
+
+func TestMapGoValueCopies(t *testing.T) {
+	items := map[string]Object{"a": NewInt(1)}
+	m := NewMap(items)
+	items["b"] = NewInt(2)
+	require.Equal(t, 1, m.Size())
+
+	value := m.Value()
+	value["c"] = NewInt(3)
+	require.Equal(t, 1, m.Size())
+	require.Equal(t, map[string]Object{"a": NewInt(1)}, m.Value())
+}
+
+func TestMapMixedKeyCollision(t *testing.T) {
+	m := NewMap(nil)
+	require.Nil(t, m.SetItem(NewInt(1), NewString("a")))
+	require.Nil(t, m.SetItem(NewString("1"), NewString("b")))
+	require.Equal(t, 2, m.Size())
+
+	_, err := m.MarshalJSON()
+	require.NotNil(t, err)
+	require.Equal(t, `value error: map keys 1 and "1" have the same json key "1"`, err.Error())
+
+	// Value keeps only the item inserted last
+	require.Equal(t, map[string]Object{"1": NewString("b")}, m.Value())
+}
@@ -0,0 +1,63 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// BoundMethod is a struct method bound to an instance. When called, the
+// instance is passed as the first argument to the method.
+type BoundMethod struct {
+	*base
+	receiver Object
+	fn       *Function
+}
+
+// Receiver returns the object the method is bound to.
+func (m *BoundMethod) Receiver() Object {
+	return m.receiver
+}
+
+// Function returns the underlying method function.
+func (m *BoundMethod) Function() *Function {
+	return m.fn
+}
+
+func (m *BoundMethod) Type() Type {
+	return METHOD
+}
+
+func (m *BoundMethod) Inspect() string {
+	return fmt.Sprintf("method(%s)", m.fn.Name())
+}
+
+func (m *BoundMethod) String() string {
+	return m.Inspect()
+}
+
+func (m *BoundMethod) Interface() interface{} {
+	return m.fn
+}
+
+func (m *BoundMethod) Equals(other Object) Object {
+	otherMethod, ok := other.(*BoundMethod)
+	if ok && m.fn == otherMethod.fn && m.receiver == otherMethod.receiver {
+		return True
+	}
+	return False
+}
+
+func (m *BoundMethod) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for method: %v", opType))
+}
+
+func (m *BoundMethod) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("type error: unable to marshal method")
+}
+
+// NewBoundMethod returns the method bound to the given receiver.
+func NewBoundMethod(receiver Object, fn *Function) *BoundMethod {
+	return &BoundMethod{receiver: receiver, fn: fn}
+}
@@ -38,6 +38,28 @@ func (m *Module) GetAttr(name string) (Object, bool) {
 	}
 }
 
+// AttrCacheKey returns the module itself, since each module has its own
+// set of attributes.
+func (m *Module) AttrCacheKey() interface{} {
+	return m
+}
+
+// ResolveAttrLoader resolves the named global variable of the module to its
+// index in the module globals.
+func (m *Module) ResolveAttrLoader(name string) (AttrLoader, bool) {
+	if name == "__name__" {
+		return nil, false
+	}
+	resolution, found := m.code.Symbols.Lookup(name)
+	if !found || resolution.Scope != ScopeGlobal {
+		return nil, false
+	}
+	index := resolution.Symbol.Index
+	return func(obj Object) Object {
+		return obj.(*Module).code.Globals()[index]
+	}, true
+}
+
 func (m *Module) Interface() interface{} {
 	return nil
 }
@@ -27,15 +27,19 @@ type Type string
 
 // Type constants
 const (
+	BIGINT        Type = "bigint"
 	BOOL          Type = "bool"
 	BUFFER        Type = "buffer"
 	BUILTIN       Type = "builtin"
 	BYTE_SLICE    Type = "byte_slice"
 	BYTE          Type = "byte"
 	CELL          Type = "cell"
+	CHAN          Type = "chan"
+	CHAN_ITER     Type = "chan_iter"
 	COLOR         Type = "color"
 	COMPLEX       Type = "complex"
 	COMPLEX_SLICE Type = "complex_slice"
+	DECIMAL       Type = "decimal"
 	DIR_ENTRY     Type = "dir_entry"
 	DYNAMIC_ATTR  Type = "dynamic_attr"
 	DURATION      Type = "duration"
@@ -47,6 +51,7 @@ const (
 	FLOAT         Type = "float"
 	FLOAT_SLICE   Type = "float_slice"
 	FUNCTION      Type = "function"
+	GENERATOR     Type = "generator"
 	GO_TYPE       Type = "go_type"
 	GO_FIELD      Type = "go_field"
 	GO_METHOD     Type = "go_method"
@@ -57,10 +62,13 @@ const (
 	LIST_ITER     Type = "list_iter"
 	MAP           Type = "map"
 	MAP_ITER      Type = "map_iter"
+	METHOD        Type = "method"
 	MODULE        Type = "module"
 	NIL           Type = "nil"
 	PARTIAL       Type = "partial"
 	PROXY         Type = "proxy"
+	RANGE         Type = "range"
+	RANGE_ITER    Type = "range_iter"
 	REGEXP        Type = "regexp"
 	RESULT        Type = "result"
 	SET           Type = "set"
@@ -68,7 +76,10 @@ const (
 	SLICE_ITER    Type = "slice_iter"
 	STRING        Type = "string"
 	STRING_ITER   Type = "string_iter"
+	STRUCT        Type = "struct"
 	TIME          Type = "time"
+	TUPLE         Type = "tuple"
+	TUPLE_ITER    Type = "tuple_iter"
 )
 
 var (
@@ -109,10 +120,12 @@ type Object interface {
 	Cost() int
 }
 
-// Slice is used to specify a range or slice of items in a container.
+// Slice is used to specify a range or slice of items in a container. A nil
+// Start, Stop, or Step means the value was not given.
 type Slice struct {
 	Start Object
 	Stop  Object
+	Step  Object
 }
 
 // IteratorEntry is a single item returned by an iterator.
@@ -137,6 +150,24 @@ type Iterator interface {
 	Entry() (IteratorEntry, bool)
 }
 
+// FallibleIterator is an Iterator that may stop early because of an error,
+// such as a generator whose function raised an error.
+type FallibleIterator interface {
+	Iterator
+
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+// IteratorError returns the error that stopped the given iterator early, or
+// nil if the iterator is not a FallibleIterator or did not fail.
+func IteratorError(iter Iterator) error {
+	if iter, ok := iter.(FallibleIterator); ok {
+		return iter.Err()
+	}
+	return nil
+}
+
 // Iterable is an interface that exposes an iterator for an Object.
 type Iterable interface {
 	Iter() Iterator
@@ -148,7 +179,7 @@ type Container interface {
 	// GetItem implements the [key] operator for a container type.
 	GetItem(key Object) (Object, *Error)
 
-	// GetSlice implements the [start:stop] operator for a container type.
+	// GetSlice implements the [start:stop:step] operator for a container type.
 	GetSlice(s Slice) (Object, *Error)
 
 	// SetItem implements the [key] = value operator for a container type.
@@ -210,3 +241,24 @@ type AttrResolver interface {
 }
 
 type ResolveAttrFunc func(ctx context.Context, name string) (Object, error)
+
+// AttrLoader loads an attribute that was resolved in advance from an object.
+// It may only be given objects with the same attribute cache key as the
+// object that resolved the attribute.
+type AttrLoader func(obj Object) Object
+
+// AttrCacher is implemented by objects whose attribute names may be resolved
+// once and then loaded from other objects of the same kind. The VM uses this
+// to cache the attribute lookups made by each instruction. It is implemented
+// by proxies, structs, modules, strings, lists and maps. Attributes of other
+// objects are loaded through GetAttr each time.
+type AttrCacher interface {
+	// AttrCacheKey identifies the attributes of the object. Objects with the
+	// same key resolve each attribute name in the same way.
+	AttrCacheKey() interface{}
+
+	// ResolveAttrLoader resolves the named attribute and returns a function
+	// that loads it. The boolean result is false if the attribute can't be
+	// cached, in which case GetAttr should be used.
+	ResolveAttrLoader(name string) (AttrLoader, bool)
+}
@@ -25,7 +25,12 @@ func Compare(opType op.CompareOpType, a, b Object) Object {
 	if err != nil {
 		return NewError(err)
 	}
+	return compareResult(opType, value)
+}
 
+// compareResult converts the result of comparing two objects, which is a
+// negative int, zero, or a positive int, into the result of the operator.
+func compareResult(opType op.CompareOpType, value int) Object {
 	switch opType {
 	case op.LessThan:
 		return NewBool(value < 0)
@@ -35,6 +40,10 @@ func Compare(opType op.CompareOpType, a, b Object) Object {
 		return NewBool(value > 0)
 	case op.GreaterThanOrEqual:
 		return NewBool(value >= 0)
+	case op.Equal:
+		return NewBool(value == 0)
+	case op.NotEqual:
+		return NewBool(value != 0)
 	default:
 		panic(fmt.Errorf("unknown object comparison operator: %d", opType))
 	}
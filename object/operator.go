@@ -0,0 +1,184 @@
+package object
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Names of the special methods that implement operators. A struct type
+// declares these as methods, as in `func (v Vec) __add__(other) { ... }`.
+// Proxies of Go types map them to Go methods; see GoType.SetOperator.
+const (
+	AddMethod     = "__add__"
+	SubMethod     = "__sub__"
+	MulMethod     = "__mul__"
+	DivMethod     = "__div__"
+	ModMethod     = "__mod__"
+	PowMethod     = "__pow__"
+	AndMethod     = "__and__"
+	OrMethod      = "__or__"
+	XorMethod     = "__xor__"
+	LShiftMethod  = "__lshift__"
+	RShiftMethod  = "__rshift__"
+	EqualsMethod  = "__eq__"
+	CompareMethod = "__cmp__"
+	GetItemMethod = "__getitem__"
+	LenMethod     = "__len__"
+)
+
+// goOperatorMethods holds the names of the Go methods that implement each
+// operator by convention, along with the number of arguments they accept.
+var goOperatorMethods = map[string]struct {
+	name string
+	args int
+}{
+	AddMethod:     {"Add", 1},
+	SubMethod:     {"Sub", 1},
+	MulMethod:     {"Mul", 1},
+	DivMethod:     {"Div", 1},
+	ModMethod:     {"Mod", 1},
+	PowMethod:     {"Pow", 1},
+	AndMethod:     {"And", 1},
+	OrMethod:      {"Or", 1},
+	XorMethod:     {"Xor", 1},
+	LShiftMethod:  {"Lsh", 1},
+	RShiftMethod:  {"Rsh", 1},
+	EqualsMethod:  {"Equal", 1},
+	CompareMethod: {"Compare", 1},
+	GetItemMethod: {"Get", 1},
+	LenMethod:     {"Len", 0},
+}
+
+// Overloadable is implemented by objects whose operators may be implemented
+// by methods, such as struct instances and proxies of Go types.
+type Overloadable interface {
+	// OperatorMethod returns the method that implements the named operator,
+	// bound to this object, if there is one.
+	OperatorMethod(name string) (Object, bool)
+}
+
+// BinaryOpMethod returns the name of the special method that implements the
+// given binary operator. The logical "&&" and "||" operators can't be
+// overloaded.
+func BinaryOpMethod(opType op.BinaryOpType) (string, bool) {
+	switch opType {
+	case op.Add:
+		return AddMethod, true
+	case op.Subtract:
+		return SubMethod, true
+	case op.Multiply:
+		return MulMethod, true
+	case op.Divide:
+		return DivMethod, true
+	case op.Modulo:
+		return ModMethod, true
+	case op.Power:
+		return PowMethod, true
+	case op.BitwiseAnd:
+		return AndMethod, true
+	case op.BitwiseOr:
+		return OrMethod, true
+	case op.Xor:
+		return XorMethod, true
+	case op.LShift:
+		return LShiftMethod, true
+	case op.RShift:
+		return RShiftMethod, true
+	}
+	return "", false
+}
+
+// CallOperator calls the method that implements the named operator on the
+// given object. Returns false if the object doesn't overload the operator.
+// Risor methods are called using the CallFunc found in the context.
+func CallOperator(ctx context.Context, obj Object, name string, args ...Object) (Object, bool, error) {
+	overloadable, ok := obj.(Overloadable)
+	if !ok {
+		return nil, false, nil
+	}
+	method, ok := overloadable.OperatorMethod(name)
+	if !ok {
+		return nil, false, nil
+	}
+	switch method := method.(type) {
+	case *BoundMethod:
+		callFunc, found := GetCallFunc(ctx)
+		if !found {
+			return nil, true, fmt.Errorf("eval error: context did not contain a call function")
+		}
+		methodArgs := make([]Object, 0, len(args)+1)
+		methodArgs = append(methodArgs, method.Receiver())
+		methodArgs = append(methodArgs, args...)
+		result, err := callFunc(ctx, method.Function(), methodArgs)
+		return result, true, err
+	case *Builtin:
+		result := method.Call(ctx, args...)
+		if err, ok := result.(*Error); ok {
+			return nil, true, err.Value()
+		}
+		return result, true, nil
+	}
+	return nil, true, fmt.Errorf("type error: %s operator method is not callable (got %s)",
+		name, method.Type())
+}
+
+// BinaryOperator runs a binary operation using the special method of the left
+// operand. Returns false if the left operand doesn't overload the operator.
+func BinaryOperator(ctx context.Context, opType op.BinaryOpType, a, b Object) (Object, bool, error) {
+	if _, ok := a.(Overloadable); !ok {
+		return nil, false, nil
+	}
+	name, ok := BinaryOpMethod(opType)
+	if !ok {
+		return nil, false, nil
+	}
+	return CallOperator(ctx, a, name, b)
+}
+
+// CompareOperator runs a comparison using the special methods of the left
+// operand. The "==" and "!=" operators use the __eq__ method, which returns
+// whether the operands are equal. The ordering operators use the __cmp__
+// method, which returns a negative int, zero, or a positive int as in Go.
+// Returns false if the left operand doesn't overload the comparison.
+func CompareOperator(ctx context.Context, opType op.CompareOpType, a, b Object) (Object, bool, error) {
+	if _, ok := a.(Overloadable); !ok {
+		return nil, false, nil
+	}
+	switch opType {
+	case op.Equal, op.NotEqual:
+		result, found, err := CallOperator(ctx, a, EqualsMethod, b)
+		if !found || err != nil {
+			return nil, found, err
+		}
+		if opType == op.NotEqual {
+			return NewBool(!result.IsTruthy()), true, nil
+		}
+		return NewBool(result.IsTruthy()), true, nil
+	}
+	result, found, err := CallOperator(ctx, a, CompareMethod, b)
+	if !found || err != nil {
+		return nil, found, err
+	}
+	value, ok := result.(*Int)
+	if !ok {
+		return nil, true, fmt.Errorf("type error: %s method must return an int (got %s)",
+			CompareMethod, result.Type())
+	}
+	return compareResult(opType, int(value.value)), true, nil
+}
+
+// LenOperator returns the length of the object using its __len__ method.
+// Returns false if the object doesn't overload the length.
+func LenOperator(ctx context.Context, obj Object) (Object, bool, error) {
+	result, found, err := CallOperator(ctx, obj, LenMethod)
+	if !found || err != nil {
+		return nil, found, err
+	}
+	if _, ok := result.(*Int); !ok {
+		return nil, true, fmt.Errorf("type error: %s method must return an int (got %s)",
+			LenMethod, result.Type())
+	}
+	return result, true, nil
+}
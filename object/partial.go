@@ -3,6 +3,7 @@ package object
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/risor-io/risor/op"
@@ -11,8 +12,9 @@ import (
 // Partial is a partially applied function
 type Partial struct {
 	*base
-	fn   Object
-	args []Object
+	fn     Object
+	args   []Object
+	kwargs map[string]Object
 }
 
 func (p *Partial) Function() Object {
@@ -23,6 +25,11 @@ func (p *Partial) Args() []Object {
 	return p.args
 }
 
+// Kwargs returns the keyword arguments of the partial, which may be nil.
+func (p *Partial) Kwargs() map[string]Object {
+	return p.kwargs
+}
+
 func (p *Partial) Type() Type {
 	return PARTIAL
 }
@@ -32,6 +39,14 @@ func (p *Partial) Inspect() string {
 	for _, arg := range p.args {
 		args = append(args, arg.Inspect())
 	}
+	names := make([]string, 0, len(p.kwargs))
+	for name := range p.kwargs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, name+"="+p.kwargs[name].Inspect())
+	}
 	return fmt.Sprintf("partial(%s, %s)", p.fn.Inspect(), strings.Join(args, ", "))
 }
 
@@ -60,3 +75,13 @@ func NewPartial(fn Object, args []Object) *Partial {
 		args: args,
 	}
 }
+
+// NewPartialWithKwargs returns a partial that passes both the given positional
+// arguments and keyword arguments to the function when called.
+func NewPartialWithKwargs(fn Object, args []Object, kwargs map[string]Object) *Partial {
+	return &Partial{
+		fn:     fn,
+		args:   args,
+		kwargs: kwargs,
+	}
+}
@@ -0,0 +1,266 @@
+package object
+
+import "fmt"
+
+// PatternKind identifies the kind of a Pattern.
+type PatternKind uint8
+
+const (
+	// PatternWildcard matches any value
+	PatternWildcard PatternKind = iota
+	// PatternCapture matches any value and captures it
+	PatternCapture
+	// PatternValue matches values equal to the pattern's Value
+	PatternValue
+	// PatternList matches lists and tuples whose items match the pattern's Items
+	PatternList
+	// PatternMap matches maps with the pattern's Keys, where the value of
+	// each key matches the corresponding pattern in Items
+	PatternMap
+	// PatternType matches values of the pattern's Type that also match the
+	// single pattern in Items
+	PatternType
+)
+
+// Pattern describes the structure of a value, as given in a switch case that
+// uses structural pattern matching. Matching a value against the pattern
+// captures the parts of the value that correspond to capture patterns.
+type Pattern struct {
+	Kind PatternKind
+
+	// Name of the variable that receives a captured value
+	Name string
+
+	// Value to compare with, for value patterns
+	Value Object
+
+	// Type to match, for type patterns
+	Type Type
+
+	// Keys to look up, for map patterns
+	Keys []string
+
+	// Nested patterns for list items, map values, or the value of a type
+	Items []*Pattern
+
+	// Position in a list pattern where any additional items are accepted,
+	// or -1 if the list must have exactly as many items as the pattern
+	RestIndex int
+
+	// Name of the variable that receives the additional items of a list,
+	// if they are captured
+	RestName string
+}
+
+// Names returns the names of the variables that receive captured values, in
+// the order that Match appends the captured values.
+func (p *Pattern) Names() []string {
+	var names []string
+	p.walkNames(func(name string) {
+		names = append(names, name)
+	})
+	return names
+}
+
+func (p *Pattern) walkNames(fn func(name string)) {
+	switch p.Kind {
+	case PatternCapture:
+		fn(p.Name)
+	case PatternList:
+		for i, item := range p.Items {
+			if i == p.RestIndex && p.RestName != "" {
+				fn(p.RestName)
+			}
+			item.walkNames(fn)
+		}
+		if p.RestIndex == len(p.Items) && p.RestName != "" {
+			fn(p.RestName)
+		}
+	case PatternMap, PatternType:
+		for _, item := range p.Items {
+			item.walkNames(fn)
+		}
+	}
+}
+
+// Match reports whether the given object matches the pattern. The captured
+// values are appended to the given slice, in the same order as the names
+// returned by Names, and the extended slice is returned.
+func (p *Pattern) Match(obj Object, captures []Object) ([]Object, bool) {
+	switch p.Kind {
+	case PatternWildcard:
+		return captures, true
+	case PatternCapture:
+		return append(captures, obj), true
+	case PatternValue:
+		return captures, p.Value.Equals(obj) == True
+	case PatternType:
+		if !p.matchType(obj) {
+			return captures, false
+		}
+		return p.Items[0].Match(obj, captures)
+	case PatternList:
+		switch obj := obj.(type) {
+		case *List:
+			return p.matchItems(obj.Value(), captures)
+		case *Tuple:
+			return p.matchItems(obj.Value(), captures)
+		}
+		return captures, false
+	case PatternMap:
+		m, ok := obj.(*Map)
+		if !ok {
+			return captures, false
+		}
+		for i, key := range p.Keys {
+			value, found := m.lookup(stringHashKey(key))
+			if !found {
+				return captures, false
+			}
+			if captures, ok = p.Items[i].Match(value, captures); !ok {
+				return captures, false
+			}
+		}
+		return captures, true
+	}
+	return captures, false
+}
+
+// matchType reports whether the object has the type of a type pattern.
+func (p *Pattern) matchType(obj Object) bool {
+	switch p.Type {
+	case INT:
+		// Ints that are promoted to BigInts on overflow are still ints
+		t := obj.Type()
+		return t == INT || t == BIGINT
+	case STRUCT:
+		// The type of a struct instance is the name of its struct type
+		_, ok := obj.(*Struct)
+		return ok
+	}
+	return obj.Type() == p.Type
+}
+
+func (p *Pattern) matchItems(items []Object, captures []Object) ([]Object, bool) {
+	var ok bool
+	count := len(p.Items)
+	if p.RestIndex < 0 {
+		if len(items) != count {
+			return captures, false
+		}
+		for i, item := range p.Items {
+			if captures, ok = item.Match(items[i], captures); !ok {
+				return captures, false
+			}
+		}
+		return captures, true
+	}
+	if len(items) < count {
+		return captures, false
+	}
+	// Items after the rest position are matched against the end of the list
+	restCount := len(items) - count
+	for i, item := range p.Items {
+		if i == p.RestIndex && p.RestName != "" {
+			captures = append(captures, restList(items[i:i+restCount]))
+		}
+		index := i
+		if i >= p.RestIndex {
+			index += restCount
+		}
+		if captures, ok = item.Match(items[index], captures); !ok {
+			return captures, false
+		}
+	}
+	if p.RestIndex == count && p.RestName != "" {
+		captures = append(captures, restList(items[count:]))
+	}
+	return captures, true
+}
+
+// Unpack destructures the given object according to the pattern, as in the
+// declaration "a, [b, *c] := x". The values are appended to the given slice in
+// the same order as the names returned by Names. Unlike Match, an object that
+// doesn't fit the pattern is an error, and list patterns accept any container.
+// Value and type patterns are not supported here.
+func (p *Pattern) Unpack(obj Object, values []Object) ([]Object, error) {
+	switch p.Kind {
+	case PatternCapture:
+		return append(values, obj), nil
+	case PatternList:
+		container, ok := obj.(Container)
+		if !ok {
+			return values, fmt.Errorf("type error: object is not a container (got %s)", obj.Type())
+		}
+		var items []Object
+		iter := container.Iter()
+		for {
+			item, ok := iter.Next()
+			if !ok {
+				break
+			}
+			items = append(items, item)
+		}
+		return p.unpackItems(items, values)
+	case PatternMap:
+		m, ok := obj.(*Map)
+		if !ok {
+			return values, fmt.Errorf("type error: object is not a map (got %s)", obj.Type())
+		}
+		for i, key := range p.Keys {
+			value, found := m.lookup(stringHashKey(key))
+			if !found {
+				return values, fmt.Errorf("key error: %q", key)
+			}
+			var err error
+			if values, err = p.Items[i].Unpack(value, values); err != nil {
+				return values, err
+			}
+		}
+		return values, nil
+	}
+	return values, nil
+}
+
+func (p *Pattern) unpackItems(items []Object, values []Object) ([]Object, error) {
+	var err error
+	count := len(p.Items)
+	if p.RestIndex < 0 {
+		if len(items) != count {
+			return values, fmt.Errorf("exec error: unpack count mismatch: %d != %d", len(items), count)
+		}
+		for i, item := range p.Items {
+			if values, err = item.Unpack(items[i], values); err != nil {
+				return values, err
+			}
+		}
+		return values, nil
+	}
+	if len(items) < count {
+		return values, fmt.Errorf("exec error: not enough values to unpack (expected at least %d, got %d)",
+			count, len(items))
+	}
+	restCount := len(items) - count
+	for i, item := range p.Items {
+		if i == p.RestIndex && p.RestName != "" {
+			values = append(values, restList(items[i:i+restCount]))
+		}
+		index := i
+		if i >= p.RestIndex {
+			index += restCount
+		}
+		if values, err = item.Unpack(items[index], values); err != nil {
+			return values, err
+		}
+	}
+	if p.RestIndex == count && p.RestName != "" {
+		values = append(values, restList(items[count:]))
+	}
+	return values, nil
+}
+
+func restList(items []Object) *List {
+	rest := make([]Object, len(items))
+	copy(rest, items)
+	return NewList(rest)
+}
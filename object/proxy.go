@@ -64,8 +64,26 @@ func (p *Proxy) GoType() *GoType {
 }
 
 func (p *Proxy) GetAttr(name string) (Object, bool) {
+	loader, found := p.ResolveAttrLoader(name)
+	if !found {
+		return nil, false
+	}
+	return loader(p), true
+}
+
+// AttrCacheKey returns the Go type of the proxied object, since all proxies
+// of the same Go type have the same attributes.
+func (p *Proxy) AttrCacheKey() interface{} {
+	return p.typ
+}
+
+// ResolveAttrLoader resolves the named field or method of the Go type. The
+// returned function loads the attribute from any proxy of the same type
+// without looking up the attribute by name again.
+func (p *Proxy) ResolveAttrLoader(name string) (AttrLoader, bool) {
 	if name == "__type__" {
-		return p.typ, true
+		typ := p.typ
+		return func(obj Object) Object { return typ }, true
 	}
 	attr, found := p.typ.GetAttribute(name)
 	if !found {
@@ -75,25 +93,32 @@ func (p *Proxy) GetAttr(name string) (Object, bool) {
 	case *GoField:
 		conv, ok := attr.Converter()
 		if !ok {
-			return Errorf("type error: no converter for field %s", name), true
+			err := Errorf("type error: no converter for field %s", name)
+			return func(obj Object) Object { return err }, true
 		}
-		var value interface{}
-		if p.typ.IsPointerType() {
-			value = reflect.ValueOf(p.obj).Elem().FieldByName(name).Interface()
-		} else {
-			value = reflect.ValueOf(p.obj).FieldByName(name).Interface()
-		}
-		result, err := conv.From(value)
-		if err != nil {
-			return NewError(err), true
-		}
-		return result, true
+		index := attr.field.Index
+		isPointer := p.typ.IsPointerType()
+		return func(obj Object) Object {
+			value := reflect.ValueOf(obj.(*Proxy).obj)
+			if isPointer {
+				value = value.Elem()
+			}
+			result, err := conv.From(value.FieldByIndex(index).Interface())
+			if err != nil {
+				return NewError(err)
+			}
+			return result
+		}, true
 	case *GoMethod:
-		return &Builtin{
-			name: fmt.Sprintf("%s.%s", p.typ.Name(), name),
-			fn: func(ctx context.Context, args ...Object) Object {
-				return p.call(ctx, attr, args...)
-			},
+		methodName := fmt.Sprintf("%s.%s", p.typ.Name(), name)
+		return func(obj Object) Object {
+			proxy := obj.(*Proxy)
+			return &Builtin{
+				name: methodName,
+				fn: func(ctx context.Context, args ...Object) Object {
+					return proxy.call(ctx, attr, args...)
+				},
+			}
 		}, true
 	}
 	return nil, false
@@ -132,14 +157,58 @@ func (p *Proxy) SetAttr(name string, value Object) error {
 	return fmt.Errorf("attribute error: unknown attribute type")
 }
 
+// OperatorMethod returns the Go method that implements the named operator,
+// bound to the proxied object, if the Go type has one.
+func (p *Proxy) OperatorMethod(name string) (Object, bool) {
+	method, ok := p.typ.Operator(name)
+	if !ok {
+		return nil, false
+	}
+	return &Builtin{
+		name: fmt.Sprintf("%s.%s", p.typ.Name(), method.Name()),
+		fn: func(ctx context.Context, args ...Object) Object {
+			return p.call(ctx, method, args...)
+		},
+	}, true
+}
+
 func (p *Proxy) Equals(other Object) Object {
 	if p == other {
 		return True
 	}
+	// Use the Go type's Equal method if it has one
+	if method, ok := p.typ.Operator(EqualsMethod); ok {
+		result := p.call(context.Background(), method, other)
+		if IsError(result) {
+			return False
+		}
+		return NewBool(result.IsTruthy())
+	}
 	return False
 }
 
+func (p *Proxy) Compare(other Object) (int, error) {
+	method, ok := p.typ.Operator(CompareMethod)
+	if !ok {
+		return 0, fmt.Errorf("type error: expected a comparable object (got %s)", p.Type())
+	}
+	result := p.call(context.Background(), method, other)
+	switch result := result.(type) {
+	case *Error:
+		return 0, result.Value()
+	case *Int:
+		return int(result.value), nil
+	}
+	return 0, fmt.Errorf("type error: %s.%s must return an int (got %s)",
+		p.typ.Name(), method.Name(), result.Type())
+}
+
 func (p *Proxy) RunOperation(opType op.BinaryOpType, right Object) Object {
+	if name, ok := BinaryOpMethod(opType); ok {
+		if method, ok := p.typ.Operator(name); ok {
+			return p.call(context.Background(), method, right)
+		}
+	}
 	return NewError(fmt.Errorf("eval error: unsupported operation for proxy: %v", opType))
 }
 
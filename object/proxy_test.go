@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/op"
 	"github.com/stretchr/testify/require"
 )
 
@@ -228,6 +229,37 @@ func TestProxySetGetAttr(t *testing.T) {
 
 }
 
+func TestProxyAttrLoader(t *testing.T) {
+	proxy1, err := object.NewProxy(&proxyTestType2{A: 1})
+	require.Nil(t, err)
+	proxy2, err := object.NewProxy(&proxyTestType2{A: 2})
+	require.Nil(t, err)
+	require.Equal(t, proxy1.AttrCacheKey(), proxy2.AttrCacheKey())
+
+	// The loader resolved with one proxy works for another of the same type
+	loader, ok := proxy1.ResolveAttrLoader("A")
+	require.True(t, ok)
+	require.Equal(t, object.NewInt(1), loader(proxy1))
+	require.Equal(t, object.NewInt(2), loader(proxy2))
+	require.Nil(t, proxy2.SetAttr("A", object.NewInt(3)))
+	require.Equal(t, object.NewInt(3), loader(proxy2))
+
+	loader, ok = proxy1.ResolveAttrLoader("D")
+	require.True(t, ok)
+	method, ok := loader(proxy2).(*object.Builtin)
+	require.True(t, ok)
+	require.Equal(t, "*object_test.proxyTestType2.D", method.Name())
+	require.Equal(t, object.NewInt(3), method.Call(context.Background(),
+		object.NewInt(1), object.NewFloat(2.0)))
+
+	_, ok = proxy1.ResolveAttrLoader("missing")
+	require.False(t, ok)
+
+	value, err := object.NewProxy(proxyTestType2{})
+	require.Nil(t, err)
+	require.NotEqual(t, proxy1.AttrCacheKey(), value.AttrCacheKey())
+}
+
 func TestProxyOnStructValue(t *testing.T) {
 	p, err := object.NewProxy(proxyTestType2{A: 99})
 	require.NoError(t, err)
@@ -331,3 +363,66 @@ func TestProxyHasher(t *testing.T) {
 
 	require.Equal(t, expected, byte_slice.Value())
 }
+
+type proxyTestVec struct {
+	X, Y int
+}
+
+func (v proxyTestVec) Add(other proxyTestVec) proxyTestVec {
+	return proxyTestVec{X: v.X + other.X, Y: v.Y + other.Y}
+}
+
+func (v proxyTestVec) Dot(other proxyTestVec) int {
+	return v.X*other.X + v.Y*other.Y
+}
+
+func (v proxyTestVec) Compare(other proxyTestVec) int {
+	return v.X - other.X
+}
+
+func (v proxyTestVec) Norm() int {
+	return v.X*v.X + v.Y*v.Y
+}
+
+func TestProxyOperators(t *testing.T) {
+	ctx := context.Background()
+	a, err := object.NewProxy(proxyTestVec{X: 1, Y: 2})
+	require.Nil(t, err)
+	b, err := object.NewProxy(proxyTestVec{X: 3, Y: 4})
+	require.Nil(t, err)
+
+	// Add and Compare are registered by convention
+	_, ok := a.GoType().Operator(object.AddMethod)
+	require.True(t, ok)
+	_, ok = a.GoType().Operator(object.MulMethod)
+	require.False(t, ok)
+
+	result := a.RunOperation(op.Add, b)
+	sum, ok := result.(*object.Proxy)
+	require.True(t, ok)
+	require.Equal(t, proxyTestVec{X: 4, Y: 6}, sum.Interface())
+
+	cmp, err := a.Compare(b)
+	require.Nil(t, err)
+	require.Equal(t, -2, cmp)
+	require.Equal(t, object.False, a.Equals(b))
+
+	// Other methods may be registered explicitly
+	require.Nil(t, a.GoType().SetOperator(object.MulMethod, "Dot"))
+	result, found, err := object.BinaryOperator(ctx, op.Multiply, a, b)
+	require.Nil(t, err)
+	require.True(t, found)
+	require.Equal(t, object.NewInt(11), result)
+
+	err = a.GoType().SetOperator(object.MulMethod, "Norm")
+	require.NotNil(t, err)
+	require.Equal(t, "type error: proxyTestVec.Norm must accept 1 arguments to implement __mul__", err.Error())
+
+	err = a.GoType().SetOperator(object.MulMethod, "Cross")
+	require.NotNil(t, err)
+	require.Equal(t, "type error: proxyTestVec has no method Cross", err.Error())
+
+	err = a.GoType().SetOperator("__neg__", "Norm")
+	require.NotNil(t, err)
+	require.Equal(t, "type error: unknown operator method __neg__", err.Error())
+}
@@ -0,0 +1,247 @@
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/risor-io/risor/op"
+)
+
+// maxRangeItems limits the number of items of a range that is converted to a
+// Go slice or to JSON, since the items must be materialized to do so.
+const maxRangeItems = 1 << 20
+
+// Range is a lazy sequence of evenly spaced integers, as created by the
+// range() builtin. Items are computed on demand, so a range of any length
+// uses a constant amount of memory.
+type Range struct {
+	*base
+	start int64
+	stop  int64
+	step  int64
+}
+
+func (r *Range) Type() Type {
+	return RANGE
+}
+
+// Start returns the first value of the range.
+func (r *Range) Start() int64 {
+	return r.start
+}
+
+// Stop returns the value the range stops before.
+func (r *Range) Stop() int64 {
+	return r.stop
+}
+
+// Step returns the difference between consecutive values of the range.
+func (r *Range) Step() int64 {
+	return r.step
+}
+
+// Size returns the number of items in the range.
+func (r *Range) Size() int64 {
+	return rangeLen(r.start, r.stop, r.step)
+}
+
+func (r *Range) Inspect() string {
+	if r.step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.start, r.stop)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.start, r.stop, r.step)
+}
+
+func (r *Range) String() string {
+	return r.Inspect()
+}
+
+func (r *Range) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "start":
+		return NewInt(r.start), true
+	case "stop":
+		return NewInt(r.stop), true
+	case "step":
+		return NewInt(r.step), true
+	}
+	return nil, false
+}
+
+// Interface returns the items of the range as a []int64, or nil if the range
+// has more than maxRangeItems items.
+func (r *Range) Interface() interface{} {
+	size := r.Size()
+	if size > maxRangeItems {
+		return nil
+	}
+	items := make([]int64, 0, size)
+	for i := int64(0); i < size; i++ {
+		items = append(items, r.start+i*r.step)
+	}
+	return items
+}
+
+// Equals returns True if the other object is a range holding the same
+// sequence of integers, e.g. range(0) == range(2, 2).
+func (r *Range) Equals(other Object) Object {
+	otherRange, ok := other.(*Range)
+	if !ok {
+		return False
+	}
+	size := r.Size()
+	if size != otherRange.Size() {
+		return False
+	}
+	if size == 0 {
+		return True
+	}
+	if r.start != otherRange.start {
+		return False
+	}
+	return NewBool(size == 1 || r.step == otherRange.step)
+}
+
+func (r *Range) IsTruthy() bool {
+	return r.Size() > 0
+}
+
+// GetItem implements the [key] operator for a container type.
+func (r *Range) GetItem(key Object) (Object, *Error) {
+	indexObj, ok := key.(*Int)
+	if !ok {
+		return nil, Errorf("type error: range index must be an int (got %s)", key.Type())
+	}
+	idx, err := ResolveIndex(indexObj.value, r.Size())
+	if err != nil {
+		return nil, NewError(err)
+	}
+	return NewInt(r.start + idx*r.step), nil
+}
+
+// GetSlice implements the [start:stop:step] operator for a container type.
+// Slicing a range returns another range.
+func (r *Range) GetSlice(s Slice) (Object, *Error) {
+	start, step, count, err := ResolveStepSlice(s, r.Size())
+	if err != nil {
+		return nil, NewError(err)
+	}
+	first := r.start + start*r.step
+	step *= r.step
+	if count == 0 {
+		return NewRange(first, first, step), nil
+	}
+	// The stop value follows the last item, which must not overflow. Any
+	// value between the last item and the next one will do.
+	last := first + (count-1)*step
+	stop := last + step
+	if (step > 0) != (stop > last) {
+		if step > 0 {
+			stop = last + 1
+		} else {
+			stop = last - 1
+		}
+		if (step > 0) != (stop > last) {
+			return nil, Errorf("value error: range slice exceeds the bounds of an int")
+		}
+	}
+	return NewRange(first, stop, step), nil
+}
+
+// SetItem implements the [key] = value operator for a container type.
+func (r *Range) SetItem(key, value Object) *Error {
+	return Errorf("type error: range does not support item assignment")
+}
+
+// DelItem implements the del [key] operator for a container type.
+func (r *Range) DelItem(key Object) *Error {
+	return Errorf("type error: range does not support item deletion")
+}
+
+// Contains returns true if the given int is one of the values in the range.
+func (r *Range) Contains(item Object) *Bool {
+	value, ok := item.(*Int)
+	if !ok {
+		return False
+	}
+	v := value.value
+	if r.step > 0 {
+		if v < r.start || v >= r.stop {
+			return False
+		}
+		return NewBool((uint64(v)-uint64(r.start))%uint64(r.step) == 0)
+	}
+	if v > r.start || v <= r.stop {
+		return False
+	}
+	return NewBool((uint64(r.start)-uint64(v))%uint64(-r.step) == 0)
+}
+
+// Len returns the number of items in this container.
+func (r *Range) Len() *Int {
+	return NewInt(r.Size())
+}
+
+func (r *Range) Iter() Iterator {
+	return NewRangeIter(r)
+}
+
+func (r *Range) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for range: %v", opType))
+}
+
+// Cost reflects the size of the range once its items are materialized, for
+// example by list(range(n)).
+func (r *Range) Cost() int {
+	size := r.Size()
+	if size > math.MaxInt/8 {
+		return math.MaxInt
+	}
+	return int(size) * 8
+}
+
+func (r *Range) MarshalJSON() ([]byte, error) {
+	if r.Size() > maxRangeItems {
+		return nil, fmt.Errorf("value error: unable to marshal range with more than %d items", maxRangeItems)
+	}
+	return json.Marshal(r.Interface())
+}
+
+// NewRange returns a range from start up to, but not including, stop. The
+// arguments must be valid according to CheckRange.
+func NewRange(start, stop, step int64) *Range {
+	return &Range{start: start, stop: stop, step: step}
+}
+
+// CheckRange returns an error if a range can't be created from the given
+// arguments, because the step is zero or because the range has more items
+// than fit in an int.
+func CheckRange(start, stop, step int64) *Error {
+	if step == 0 {
+		return Errorf("value error: range() step must not be zero")
+	}
+	if rangeCount(start, stop, step) > math.MaxInt64 {
+		return Errorf("value error: range() has too many items")
+	}
+	return nil
+}
+
+// rangeLen returns the number of values from start up to, but not including,
+// stop when counting by step. The count must fit in an int64.
+func rangeLen(start, stop, step int64) int64 {
+	return int64(rangeCount(start, stop, step))
+}
+
+// rangeCount returns the number of values from start up to, but not
+// including, stop when counting by step. Unsigned math is used since the
+// distance between start and stop may not fit in an int64.
+func rangeCount(start, stop, step int64) uint64 {
+	if step > 0 && start < stop {
+		return (uint64(stop)-uint64(start)-1)/uint64(step) + 1
+	}
+	if step < 0 && start > stop {
+		return (uint64(start)-uint64(stop)-1)/uint64(-step) + 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package object
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+type RangeIter struct {
+	*base
+	r       *Range
+	pos     int64
+	current Object
+}
+
+func (iter *RangeIter) Type() Type {
+	return RANGE_ITER
+}
+
+func (iter *RangeIter) Inspect() string {
+	return fmt.Sprintf("range_iter(%s)", iter.r.Inspect())
+}
+
+func (iter *RangeIter) String() string {
+	return iter.Inspect()
+}
+
+func (iter *RangeIter) Interface() interface{} {
+	var entries []map[string]interface{}
+	for {
+		entry, ok := iter.Next()
+		if !ok {
+			break
+		}
+		entries = append(entries, entry.Interface().(map[string]interface{}))
+	}
+	return entries
+}
+
+func (iter *RangeIter) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *RangeIter:
+		return NewBool(iter == other)
+	default:
+		return False
+	}
+}
+
+func (iter *RangeIter) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "range_iter.next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("range_iter.next", 0, len(args))
+				}
+				value, ok := iter.Next()
+				if !ok {
+					return Nil
+				}
+				return value
+			},
+		}, true
+	case "entry":
+		return &Builtin{
+			name: "range_iter.entry",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("range_iter.entry", 0, len(args))
+				}
+				entry, ok := iter.Entry()
+				if !ok {
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (iter *RangeIter) IsTruthy() bool {
+	return iter.pos < iter.r.Size()
+}
+
+func (iter *RangeIter) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for range_iter: %v", opType))
+}
+
+func (iter *RangeIter) Next() (Object, bool) {
+	if iter.pos >= iter.r.Size()-1 {
+		iter.current = nil
+		return nil, false
+	}
+	iter.pos++
+	iter.current = NewInt(iter.r.start + iter.pos*iter.r.step)
+	return iter.current, true
+}
+
+func (iter *RangeIter) Entry() (IteratorEntry, bool) {
+	if iter.current == nil {
+		return nil, false
+	}
+	return NewEntry(NewInt(iter.pos), iter.current), true
+}
+
+func (iter *RangeIter) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal range_iter")
+}
+
+func NewRangeIter(r *Range) *RangeIter {
+	return &RangeIter{r: r, pos: -1}
+}
@@ -0,0 +1,95 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected int64
+	}{
+		{NewRange(0, 10, 1), 10},
+		{NewRange(0, 10, 3), 4},
+		{NewRange(10, 0, -3), 4},
+		{NewRange(5, 5, 1), 0},
+		{NewRange(5, 0, 1), 0},
+		{NewRange(0, 5, -1), 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, NewInt(tt.expected), tt.r.Len(), tt.r.Inspect())
+	}
+}
+
+func TestRangeContainer(t *testing.T) {
+	r := NewRange(1, 10, 3) // 1, 4, 7
+
+	item, err := r.GetItem(NewInt(-1))
+	require.Nil(t, err)
+	require.Equal(t, NewInt(7), item)
+	_, err = r.GetItem(NewInt(3))
+	require.NotNil(t, err)
+	require.Equal(t, "index error: index out of range: 3", err.Message().Value())
+
+	require.Equal(t, True, r.Contains(NewInt(4)))
+	require.Equal(t, False, r.Contains(NewInt(5)))
+	require.Equal(t, False, r.Contains(NewInt(10)))
+	require.Equal(t, False, r.Contains(NewString("4")))
+	require.Equal(t, True, NewRange(10, 0, -2).Contains(NewInt(2)))
+	require.Equal(t, False, NewRange(10, 0, -2).Contains(NewInt(0)))
+
+	sliced, err := r.GetSlice(Slice{Step: NewInt(-1)})
+	require.Nil(t, err)
+	require.Equal(t, "range(7, -2, -3)", sliced.Inspect())
+	require.Equal(t, []int64{7, 4, 1}, sliced.Interface())
+
+	require.Equal(t, True, NewRange(0, 3, 1).Equals(NewRange(0, 3, 1)))
+	require.Equal(t, True, NewRange(0, 0, 1).Equals(NewRange(5, 1, 2)))
+	require.Equal(t, False, NewRange(0, 3, 1).Equals(NewRange(0, 3, 2)))
+}
+
+func TestRangeIter(t *testing.T) {
+	iter := NewRange(3, 0, -1).Iter()
+	var values []Object
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		entry, ok := iter.Entry()
+		require.True(t, ok)
+		require.Equal(t, NewInt(int64(len(values))), entry.Key())
+		values = append(values, value)
+	}
+	require.Equal(t, []Object{NewInt(3), NewInt(2), NewInt(1)}, values)
+}
+
+func TestRangeOverflow(t *testing.T) {
+	require.NotNil(t, CheckRange(math.MinInt64, math.MaxInt64, 2))
+	r := NewRange(math.MinInt64+1, math.MaxInt64, 2)
+	require.Nil(t, CheckRange(r.start, r.stop, r.step))
+	require.Equal(t, int64(math.MaxInt64), r.Size())
+	require.Equal(t, True, r.Contains(NewInt(math.MaxInt64-2)))
+	require.Equal(t, False, r.Contains(NewInt(math.MaxInt64-1)))
+	require.Equal(t, math.MaxInt, r.Cost())
+	require.Nil(t, r.Interface())
+	_, err := r.MarshalJSON()
+	require.NotNil(t, err)
+
+	r = NewRange(math.MaxInt64, math.MinInt64, -3)
+	require.Equal(t, int64(6148914691236517205), r.Size())
+	require.Equal(t, True, r.Contains(NewInt(math.MaxInt64-3)))
+	require.Equal(t, False, r.Contains(NewInt(math.MinInt64)))
+
+	// Reversing a range whose stop value would overflow
+	sliced, sliceErr := NewRange(math.MinInt64+1, 0, 2).GetSlice(Slice{Step: NewInt(-1)})
+	require.Nil(t, sliceErr)
+	require.Equal(t, fmt.Sprintf("range(-1, %d, -2)", math.MinInt64), sliced.Inspect())
+	require.Equal(t, NewInt(1<<62), sliced.(*Range).Len())
+	_, sliceErr = NewRange(math.MinInt64, 0, 2).GetSlice(Slice{Step: NewInt(-1)})
+	require.NotNil(t, sliceErr)
+}
@@ -0,0 +1,280 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Compiled regular expressions are cached by pattern, so that patterns given
+// as strings aren't recompiled on every call. The cache is cleared when full.
+const regexpCacheSize = 256
+
+var (
+	regexpCacheMutex sync.Mutex
+	regexpCache      = map[string]*regexp.Regexp{}
+)
+
+// Regexp wraps a compiled regular expression.
+type Regexp struct {
+	*base
+	value *regexp.Regexp
+}
+
+func (r *Regexp) Type() Type {
+	return REGEXP
+}
+
+func (r *Regexp) Value() *regexp.Regexp {
+	return r.value
+}
+
+func (r *Regexp) Inspect() string {
+	return fmt.Sprintf("regexp(%q)", r.value.String())
+}
+
+func (r *Regexp) String() string {
+	return r.value.String()
+}
+
+func (r *Regexp) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "pattern":
+		return NewString(r.value.String()), true
+	case "match":
+		return NewBuiltin("regexp.match", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("regexp.match", 1, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			return NewBool(r.value.MatchString(s))
+		}), true
+	case "find":
+		return NewBuiltin("regexp.find", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("regexp.find", 1, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			return r.Find(s)
+		}), true
+	case "find_all":
+		return NewBuiltin("regexp.find_all", func(ctx context.Context, args ...Object) Object {
+			if len(args) < 1 || len(args) > 2 {
+				return NewArgsRangeError("regexp.find_all", 1, 2, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			n := int64(-1)
+			if len(args) == 2 {
+				if n, err = AsInt(args[1]); err != nil {
+					return err
+				}
+			}
+			return r.FindAll(s, int(n))
+		}), true
+	case "find_submatch":
+		return NewBuiltin("regexp.find_submatch", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("regexp.find_submatch", 1, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			return r.FindSubmatch(s)
+		}), true
+	case "replace_all":
+		return NewBuiltin("regexp.replace_all", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 2 {
+				return NewArgsError("regexp.replace_all", 2, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			return r.ReplaceAll(ctx, s, args[1])
+		}), true
+	case "split":
+		return NewBuiltin("regexp.split", func(ctx context.Context, args ...Object) Object {
+			if len(args) < 1 || len(args) > 2 {
+				return NewArgsRangeError("regexp.split", 1, 2, len(args))
+			}
+			s, err := AsString(args[0])
+			if err != nil {
+				return err
+			}
+			n := int64(-1)
+			if len(args) == 2 {
+				if n, err = AsInt(args[1]); err != nil {
+					return err
+				}
+			}
+			return r.Split(s, int(n))
+		}), true
+	}
+	return nil, false
+}
+
+func (r *Regexp) Interface() interface{} {
+	return r.value
+}
+
+func (r *Regexp) Equals(other Object) Object {
+	if other, ok := other.(*Regexp); ok && r.value.String() == other.value.String() {
+		return True
+	}
+	return False
+}
+
+func (r *Regexp) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for regexp: %v", opType))
+}
+
+func (r *Regexp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.value.String())
+}
+
+// Find returns the leftmost match in the string, or nil if there is none.
+func (r *Regexp) Find(s string) Object {
+	loc := r.value.FindStringIndex(s)
+	if loc == nil {
+		return Nil
+	}
+	return NewString(s[loc[0]:loc[1]])
+}
+
+// FindAll returns a list of up to n successive matches in the string. All
+// matches are returned if n is negative.
+func (r *Regexp) FindAll(s string, n int) Object {
+	matches := r.value.FindAllString(s, n)
+	items := make([]Object, 0, len(matches))
+	for _, match := range matches {
+		items = append(items, NewString(match))
+	}
+	return NewList(items)
+}
+
+// FindSubmatch returns the groups of the leftmost match in the string, or nil
+// if there is none. If the expression has named groups, a map from each name
+// to the text it matched is returned. Otherwise, the result is a list holding
+// the text of the whole match followed by the text of each group.
+func (r *Regexp) FindSubmatch(s string) Object {
+	matches := r.value.FindStringSubmatch(s)
+	if matches == nil {
+		return Nil
+	}
+	names := r.value.SubexpNames()
+	named := map[string]Object{}
+	for i, name := range names {
+		if name != "" {
+			named[name] = NewString(matches[i])
+		}
+	}
+	if len(named) > 0 {
+		return NewMap(named)
+	}
+	items := make([]Object, 0, len(matches))
+	for _, match := range matches {
+		items = append(items, NewString(match))
+	}
+	return NewList(items)
+}
+
+// ReplaceAll replaces each match in the string. The replacement may be a
+// string, in which "$1" or "${name}" expand to the text of a group, or a
+// function that is called with each match and returns its replacement.
+func (r *Regexp) ReplaceAll(ctx context.Context, s string, repl Object) Object {
+	switch repl := repl.(type) {
+	case *String:
+		return NewString(r.value.ReplaceAllString(s, repl.value))
+	case *Function, *Builtin:
+		var replErr *Error
+		result := r.value.ReplaceAllStringFunc(s, func(match string) string {
+			if replErr != nil {
+				return match
+			}
+			value := callRegexpFunc(ctx, repl, NewString(match))
+			if err, ok := value.(*Error); ok {
+				replErr = err
+				return match
+			}
+			str, ok := value.(*String)
+			if !ok {
+				replErr = Errorf("type error: regexp.replace_all() function must return a string (%s returned)", value.Type())
+				return match
+			}
+			return str.value
+		})
+		if replErr != nil {
+			return replErr
+		}
+		return NewString(result)
+	default:
+		return Errorf("type error: regexp.replace_all() expected a string or function (%s given)", repl.Type())
+	}
+}
+
+// Split slices the string into substrings separated by the expression. At
+// most n substrings are returned, or all of them if n is negative.
+func (r *Regexp) Split(s string, n int) Object {
+	parts := r.value.Split(s, n)
+	items := make([]Object, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, NewString(part))
+	}
+	return NewList(items)
+}
+
+func callRegexpFunc(ctx context.Context, fn Object, arg Object) Object {
+	switch fn := fn.(type) {
+	case *Builtin:
+		return fn.Call(ctx, arg)
+	case *Function:
+		callFunc, found := GetCallFunc(ctx)
+		if !found {
+			return Errorf("eval error: regexp.replace_all() context did not contain a call function")
+		}
+		result, err := callFunc(ctx, fn, []Object{arg})
+		if err != nil {
+			return NewError(err)
+		}
+		return result
+	}
+	return Errorf("type error: regexp.replace_all() expected a function (%s given)", fn.Type())
+}
+
+// NewRegexp wraps the given compiled regular expression.
+func NewRegexp(value *regexp.Regexp) *Regexp {
+	return &Regexp{value: value}
+}
+
+// CompileRegexp compiles the given pattern, reusing the result of a previous
+// compilation of the same pattern if possible.
+func CompileRegexp(pattern string) (*Regexp, *Error) {
+	regexpCacheMutex.Lock()
+	defer regexpCacheMutex.Unlock()
+	if value, found := regexpCache[pattern]; found {
+		return NewRegexp(value), nil
+	}
+	value, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, Errorf("value error: %s", err)
+	}
+	if len(regexpCache) >= regexpCacheSize {
+		regexpCache = map[string]*regexp.Regexp{}
+	}
+	regexpCache[pattern] = value
+	return NewRegexp(value), nil
+}
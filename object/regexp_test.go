@@ -0,0 +1,29 @@
+package object
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexpBasics(t *testing.T) {
+	value := NewRegexp(regexp.MustCompile("a+b"))
+	require.Equal(t, REGEXP, value.Type())
+	require.Equal(t, "a+b", value.String())
+	require.Equal(t, `regexp("a+b")`, value.Inspect())
+	require.True(t, value.IsTruthy())
+	require.Equal(t, True, value.Equals(NewRegexp(regexp.MustCompile("a+b"))))
+	require.Equal(t, False, value.Equals(NewString("a+b")))
+}
+
+func TestCompileRegexpCache(t *testing.T) {
+	first, err := CompileRegexp("x(y)")
+	require.Nil(t, err)
+	second, err := CompileRegexp("x(y)")
+	require.Nil(t, err)
+	require.Same(t, first.Value(), second.Value())
+
+	_, err = CompileRegexp("x(")
+	require.NotNil(t, err)
+}
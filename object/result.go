@@ -0,0 +1,202 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Result holds either an ok value or an error. Results allow failures to be
+// handled as values, rather than stopping evaluation like raised errors do.
+type Result struct {
+	*base
+	ok  Object
+	err *Error
+}
+
+func (r *Result) Type() Type {
+	return RESULT
+}
+
+func (r *Result) Inspect() string {
+	if r.err != nil {
+		return fmt.Sprintf("err(%q)", r.err.Message().Value())
+	}
+	return fmt.Sprintf("ok(%s)", r.ok.Inspect())
+}
+
+func (r *Result) String() string {
+	return r.Inspect()
+}
+
+func (r *Result) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "is_ok":
+		return NewBuiltin("result.is_ok", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("result.is_ok", 0, len(args))
+			}
+			return NewBool(r.IsOk())
+		}), true
+	case "is_err":
+		return NewBuiltin("result.is_err", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("result.is_err", 0, len(args))
+			}
+			return NewBool(r.IsErr())
+		}), true
+	case "unwrap":
+		return NewBuiltin("result.unwrap", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("result.unwrap", 0, len(args))
+			}
+			return r.Unwrap()
+		}), true
+	case "unwrap_or":
+		return NewBuiltin("result.unwrap_or", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("result.unwrap_or", 1, len(args))
+			}
+			return r.UnwrapOr(args[0])
+		}), true
+	case "map":
+		return NewBuiltin("result.map", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("result.map", 1, len(args))
+			}
+			return r.Map(ctx, args[0])
+		}), true
+	case "err_msg":
+		return NewBuiltin("result.err_msg", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("result.err_msg", 0, len(args))
+			}
+			if r.err == nil {
+				return Nil
+			}
+			return r.err.Message()
+		}), true
+	}
+	// Attributes of an ok value are available through the result
+	if r.err == nil {
+		return r.ok.GetAttr(name)
+	}
+	return nil, false
+}
+
+func (r *Result) Interface() interface{} {
+	if r.err != nil {
+		return r.err.Value()
+	}
+	return r.ok.Interface()
+}
+
+func (r *Result) Equals(other Object) Object {
+	otherResult, ok := other.(*Result)
+	if !ok || r.IsOk() != otherResult.IsOk() {
+		return False
+	}
+	if r.err != nil {
+		return r.err.Equals(otherResult.err)
+	}
+	return r.ok.Equals(otherResult.ok)
+}
+
+func (r *Result) IsTruthy() bool {
+	return r.err == nil
+}
+
+func (r *Result) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for result: %v", opType))
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err.Value()
+	}
+	return json.Marshal(r.ok)
+}
+
+// IsOk returns true if the result holds an ok value.
+func (r *Result) IsOk() bool {
+	return r.err == nil
+}
+
+// IsErr returns true if the result holds an error.
+func (r *Result) IsErr() bool {
+	return r.err != nil
+}
+
+// Err returns the error held by the result, or nil if it holds an ok value.
+func (r *Result) Err() *Error {
+	return r.err
+}
+
+// Unwrap returns the ok value, or an error if the result holds an error.
+func (r *Result) Unwrap() Object {
+	if r.err != nil {
+		return Errorf("result error: unwrap() called on an error: %s", r.err.Inspect())
+	}
+	return r.ok
+}
+
+// UnwrapOr returns the ok value, or the fallback if the result holds an error.
+func (r *Result) UnwrapOr(fallback Object) Object {
+	if r.err != nil {
+		return fallback
+	}
+	return r.ok
+}
+
+// Map returns an ok result holding the value returned by calling the function
+// with the ok value. If the result holds an error, it is returned unchanged.
+func (r *Result) Map(ctx context.Context, fn Object) Object {
+	if r.err != nil {
+		return r
+	}
+	var value Object
+	switch fn := fn.(type) {
+	case *Builtin:
+		value = fn.Call(ctx, r.ok)
+	case *Function:
+		callFunc, found := GetCallFunc(ctx)
+		if !found {
+			return Errorf("eval error: result.map() context did not contain a call function")
+		}
+		var err error
+		if value, err = callFunc(ctx, fn, []Object{r.ok}); err != nil {
+			return NewError(err)
+		}
+	default:
+		return Errorf("type error: result.map() expected a function (%s given)", fn.Type())
+	}
+	if IsError(value) {
+		return value
+	}
+	return NewOkResult(value)
+}
+
+// NewOkResult returns a result holding the given ok value.
+func NewOkResult(value Object) *Result {
+	return &Result{ok: value}
+}
+
+// NewErrResult returns a result holding the given error.
+func NewErrResult(err *Error) *Result {
+	return &Result{err: err}
+}
+
+// NewResultBuiltin creates a builtin that calls the given function and wraps
+// its return value in a result. An error returned by the function becomes an
+// err result, rather than being raised.
+func NewResultBuiltin(name string, fn BuiltinFunction, module ...*Module) *Builtin {
+	return NewBuiltin(name, func(ctx context.Context, args ...Object) Object {
+		value := fn(ctx, args...)
+		if err, ok := value.(*Error); ok {
+			return NewErrResult(err)
+		}
+		return NewOkResult(value)
+	}, module...)
+}
@@ -13,6 +13,9 @@ import (
 type Set struct {
 	*base
 	items map[HashKey]Object
+
+	// frozen is set on sets created by Freeze, which may not be modified.
+	frozen bool
 }
 
 func (s *Set) Type() Type {
@@ -48,6 +51,9 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 				if len(args) != 1 {
 					return NewArgsError("set.add", 1, len(args))
 				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
 				return s.Add(args[0])
 			},
 		}, true
@@ -58,6 +64,9 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 				if len(args) != 0 {
 					return NewArgsError("set.clear", 0, len(args))
 				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
 				s.Clear()
 				return s
 			},
@@ -69,6 +78,9 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 				if len(args) != 1 {
 					return NewArgsError("set.remove", 1, len(args))
 				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
 				return s.Remove(args[0])
 			},
 		}, true
@@ -100,6 +112,148 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 				return s.Intersection(other)
 			},
 		}, true
+	case "difference":
+		return &Builtin{
+			name: "set.difference",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.difference", 1, len(args))
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				return s.Difference(other)
+			},
+		}, true
+	case "symmetric_difference":
+		return &Builtin{
+			name: "set.symmetric_difference",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.symmetric_difference", 1, len(args))
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				return s.SymmetricDifference(other)
+			},
+		}, true
+	case "is_subset":
+		return &Builtin{
+			name: "set.is_subset",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.is_subset", 1, len(args))
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				return NewBool(s.IsSubset(other))
+			},
+		}, true
+	case "is_superset":
+		return &Builtin{
+			name: "set.is_superset",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.is_superset", 1, len(args))
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				return NewBool(other.IsSubset(s))
+			},
+		}, true
+	case "is_disjoint":
+		return &Builtin{
+			name: "set.is_disjoint",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.is_disjoint", 1, len(args))
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				return NewBool(s.IsDisjoint(other))
+			},
+		}, true
+	case "update":
+		return &Builtin{
+			name: "set.update",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.update", 1, len(args))
+				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				s.Update(other)
+				return s
+			},
+		}, true
+	case "intersection_update":
+		return &Builtin{
+			name: "set.intersection_update",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.intersection_update", 1, len(args))
+				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				s.IntersectionUpdate(other)
+				return s
+			},
+		}, true
+	case "difference_update":
+		return &Builtin{
+			name: "set.difference_update",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.difference_update", 1, len(args))
+				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				s.DifferenceUpdate(other)
+				return s
+			},
+		}, true
+	case "symmetric_difference_update":
+		return &Builtin{
+			name: "set.symmetric_difference_update",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("set.symmetric_difference_update", 1, len(args))
+				}
+				if err := s.checkMutable(); err != nil {
+					return err
+				}
+				other, err := AsSet(args[0])
+				if err != nil {
+					return err
+				}
+				s.SymmetricDifferenceUpdate(other)
+				return s
+			},
+		}, true
 	}
 	return nil, false
 }
@@ -125,24 +279,36 @@ func (s *Set) SortedItems() []Object {
 	return items
 }
 
+// IsFrozen returns true if the set may not be modified.
+func (s *Set) IsFrozen() bool {
+	return s.frozen
+}
+
+func (s *Set) checkMutable() *Error {
+	if s.frozen {
+		return Errorf("type error: cannot modify a frozen set")
+	}
+	return nil
+}
+
 func (s *Set) Add(items ...Object) Object {
 	for _, item := range items {
-		hashable, ok := item.(Hashable)
-		if !ok {
-			return Errorf("type error: %s object is unhashable", item.Type())
+		hk, err := hashKey(item)
+		if err != nil {
+			return err
 		}
-		s.items[hashable.HashKey()] = item
+		s.items[hk] = item
 	}
 	return s
 }
 
 func (s *Set) Remove(items ...Object) Object {
 	for _, item := range items {
-		hashable, ok := item.(Hashable)
-		if !ok {
-			return Errorf("type error: %s object is unhashable", item.Type())
+		hk, err := hashKey(item)
+		if err != nil {
+			return err
 		}
-		delete(s.items, hashable.HashKey())
+		delete(s.items, hk)
 	}
 	return s
 }
@@ -185,6 +351,81 @@ func (s *Set) Difference(other *Set) *Set {
 	return difference
 }
 
+// SymmetricDifference returns a new set holding the items that are in exactly
+// one of the two sets.
+func (s *Set) SymmetricDifference(other *Set) *Set {
+	result := s.Difference(other)
+	for k, v := range other.items {
+		if _, ok := s.items[k]; !ok {
+			result.items[k] = v
+		}
+	}
+	return result
+}
+
+// IsSubset returns true if every item in this set is also in the other set.
+func (s *Set) IsSubset(other *Set) bool {
+	if len(s.items) > len(other.items) {
+		return false
+	}
+	for k := range s.items {
+		if _, ok := other.items[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDisjoint returns true if the two sets have no items in common.
+func (s *Set) IsDisjoint(other *Set) bool {
+	small, large := s, other
+	if len(small.items) > len(large.items) {
+		small, large = large, small
+	}
+	for k := range small.items {
+		if _, ok := large.items[k]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Update adds all items from the other set to this set.
+func (s *Set) Update(other *Set) {
+	for k, v := range other.items {
+		s.items[k] = v
+	}
+}
+
+// IntersectionUpdate removes all items from this set that are not in the
+// other set.
+func (s *Set) IntersectionUpdate(other *Set) {
+	for k := range s.items {
+		if _, ok := other.items[k]; !ok {
+			delete(s.items, k)
+		}
+	}
+}
+
+// DifferenceUpdate removes all items from this set that are in the other set.
+func (s *Set) DifferenceUpdate(other *Set) {
+	for k := range other.items {
+		delete(s.items, k)
+	}
+}
+
+// SymmetricDifferenceUpdate updates this set to hold the items that are in
+// exactly one of the two sets.
+func (s *Set) SymmetricDifferenceUpdate(other *Set) {
+	for k, v := range other.items {
+		if _, ok := s.items[k]; ok {
+			delete(s.items, k)
+		} else {
+			s.items[k] = v
+		}
+	}
+}
+
 func (s *Set) List() *List {
 	return &List{items: s.SortedItems()}
 }
@@ -206,11 +447,11 @@ func (s *Set) Equals(other Object) Object {
 }
 
 func (s *Set) GetItem(key Object) (Object, *Error) {
-	hashable, ok := key.(Hashable)
-	if !ok {
-		return nil, Errorf("type error: %s object is unhashable", key.Type())
+	hk, err := hashKey(key)
+	if err != nil {
+		return nil, err
 	}
-	if _, ok := s.items[hashable.HashKey()]; ok {
+	if _, ok := s.items[hk]; ok {
 		return True, nil
 	}
 	return False, nil
@@ -228,21 +469,24 @@ func (s *Set) SetItem(key, value Object) *Error {
 
 // DelItem deletes the item with the given key from the map.
 func (s *Set) DelItem(key Object) *Error {
-	hashable, ok := key.(Hashable)
-	if !ok {
-		return Errorf("type error: %s object is unhashable", key.Type())
+	if err := s.checkMutable(); err != nil {
+		return err
+	}
+	hk, err := hashKey(key)
+	if err != nil {
+		return err
 	}
-	delete(s.items, hashable.HashKey())
+	delete(s.items, hk)
 	return nil
 }
 
 // Contains returns true if the given item is found in this container.
 func (s *Set) Contains(key Object) *Bool {
-	hashable, ok := key.(Hashable)
-	if !ok {
+	hk, err := hashKey(key)
+	if err != nil {
 		return False
 	}
-	_, ok = s.items[hashable.HashKey()]
+	_, ok := s.items[hk]
 	return NewBool(ok)
 }
 
@@ -251,7 +495,23 @@ func (s *Set) IsTruthy() bool {
 }
 
 func (s *Set) RunOperation(opType op.BinaryOpType, right Object) Object {
-	return NewError(fmt.Errorf("eval error: unsupported operation for set: %v", opType))
+	other, ok := right.(*Set)
+	if !ok {
+		return NewError(fmt.Errorf("eval error: unsupported operation for set: %v on type %s",
+			opType, right.Type()))
+	}
+	switch opType {
+	case op.BitwiseOr:
+		return s.Union(other)
+	case op.BitwiseAnd:
+		return s.Intersection(other)
+	case op.Subtract:
+		return s.Difference(other)
+	case op.Xor:
+		return s.SymmetricDifference(other)
+	default:
+		return NewError(fmt.Errorf("eval error: unsupported operation for set: %v", opType))
+	}
 }
 
 // Len returns the number of items in this container.
@@ -1,34 +1,71 @@
 package object
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
 
 // Sort a list in place. If the list contains a non-comparable object, an error
-// is returned.
+// is returned. Objects that overload comparisons with a __cmp__ method are
+// only supported by SortContext.
 func Sort(items []Object) *Error {
-	var comparableErr string
+	return SortContext(context.Background(), items)
+}
+
+// SortContext sorts a list in place, like Sort. Objects that overload
+// comparisons are compared by calling their __cmp__ method, which for Risor
+// methods requires the CallFunc found in the context.
+func SortContext(ctx context.Context, items []Object) *Error {
+	var sortErr error
 	sort.SliceStable(items, func(a, b int) bool {
-		itemA := items[a]
-		itemB := items[b]
-		compA, ok := itemA.(Comparable)
-		if !ok {
-			comparableErr = fmt.Sprintf(
-				"type error: sorted() encountered a non-comparable item (%s)", itemA.Type())
+		// Stop calling comparison methods once one has failed
+		if sortErr != nil {
+			return false
 		}
-		if _, ok := itemB.(Comparable); !ok {
-			comparableErr = fmt.Sprintf(
-				"type error: sorted() encountered a non-comparable item (%s)", itemB.Type())
-		}
-		result, err := compA.Compare(itemB)
+		result, err := compareItems(ctx, items[a], items[b])
 		if err != nil {
-			comparableErr = err.Error()
+			sortErr = err
+			return false
 		}
-		return result == -1
+		return result < 0
 	})
-	if comparableErr != "" {
-		return Errorf(comparableErr)
+	if sortErr != nil {
+		return NewError(sortErr)
 	}
 	return nil
 }
+
+// compareItems compares two items being sorted. If either item has a __cmp__
+// method, the method is used to compare them.
+func compareItems(ctx context.Context, a, b Object) (int, error) {
+	if result, found, err := compareMethod(ctx, a, b); found || err != nil {
+		return result, err
+	}
+	if result, found, err := compareMethod(ctx, b, a); found || err != nil {
+		return -result, err
+	}
+	comparable, ok := a.(Comparable)
+	if !ok {
+		return 0, fmt.Errorf("type error: sorted() encountered a non-comparable item (%s)", a.Type())
+	}
+	if _, ok := b.(Comparable); !ok {
+		return 0, fmt.Errorf("type error: sorted() encountered a non-comparable item (%s)", b.Type())
+	}
+	return comparable.Compare(b)
+}
+
+// compareMethod compares a to b by calling the __cmp__ method of a. Returns
+// false if a has no such method.
+func compareMethod(ctx context.Context, a, b Object) (int, bool, error) {
+	result, found, err := CallOperator(ctx, a, CompareMethod, b)
+	if !found || err != nil {
+		return 0, found, err
+	}
+	value, ok := result.(*Int)
+	if !ok {
+		return 0, true, fmt.Errorf("type error: %s method must return an int (got %s)",
+			CompareMethod, result.Type())
+	}
+	return int(value.value), true, nil
+}
@@ -43,169 +43,28 @@ func (s *String) HashKey() HashKey {
 }
 
 func (s *String) GetAttr(name string) (Object, bool) {
-	switch name {
-	case "contains":
-		return &Builtin{
-			name: "string.contains",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.contains", 1, len(args))
-				}
-				return s.Contains(args[0])
-			},
-		}, true
-	case "has_prefix":
-		return &Builtin{
-			name: "string.has_prefix",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.has_prefix", 1, len(args))
-				}
-				return s.HasPrefix(args[0])
-			},
-		}, true
-	case "has_suffix":
-		return &Builtin{
-			name: "string.has_suffix",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.has_suffix", 1, len(args))
-				}
-				return s.HasSuffix(args[0])
-			},
-		}, true
-	case "count":
-		return &Builtin{
-			name: "string.count",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.count", 1, len(args))
-				}
-				return s.Count(args[0])
-			},
-		}, true
-	case "join":
-		return &Builtin{
-			name: "string.join",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.join", 1, len(args))
-				}
-				return s.Join(args[0])
-			},
-		}, true
-	case "split":
-		return &Builtin{
-			name: "string.split",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.split", 1, len(args))
-				}
-				return s.Split(args[0])
-			},
-		}, true
-	case "fields":
-		return &Builtin{
-			name: "string.fields",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("string.fields", 0, len(args))
-				}
-				return s.Fields()
-			},
-		}, true
-	case "index":
-		return &Builtin{
-			name: "string.index",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.index", 1, len(args))
-				}
-				return s.Index(args[0])
-			},
-		}, true
-	case "last_index":
-		return &Builtin{
-			name: "string.last_index",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.last_index", 1, len(args))
-				}
-				return s.LastIndex(args[0])
-			},
-		}, true
-	case "replace_all":
-		return &Builtin{
-			name: "string.replace_all",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 2 {
-					return NewArgsError("string.replace_all", 2, len(args))
-				}
-				return s.ReplaceAll(args[0], args[1])
-			},
-		}, true
-	case "to_lower":
-		return &Builtin{
-			name: "string.to_lower",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("string.to_lower", 0, len(args))
-				}
-				return s.ToLower()
-			},
-		}, true
-	case "to_upper":
-		return &Builtin{
-			name: "string.to_upper",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("string.to_upper", 0, len(args))
-				}
-				return s.ToUpper()
-			},
-		}, true
-	case "trim":
-		return &Builtin{
-			name: "string.trim",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.trim", 1, len(args))
-				}
-				return s.Trim(args[0])
-			},
-		}, true
-	case "trim_prefix":
-		return &Builtin{
-			name: "string.trim_prefix",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.trim_prefix", 1, len(args))
-				}
-				return s.TrimPrefix(args[0])
-			},
-		}, true
-	case "trim_space":
-		return &Builtin{
-			name: "string.trim_space",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 0 {
-					return NewArgsError("string.trim_space", 0, len(args))
-				}
-				return s.TrimSpace()
-			},
-		}, true
-	case "trim_suffix":
-		return &Builtin{
-			name: "string.trim_suffix",
-			fn: func(ctx context.Context, args ...Object) Object {
-				if len(args) != 1 {
-					return NewArgsError("string.trim_suffix", 1, len(args))
-				}
-				return s.TrimSuffix(args[0])
-			},
-		}, true
+	method, ok := stringMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return method(s), true
+}
+
+// AttrCacheKey returns the string type, since all strings have the same methods.
+func (s *String) AttrCacheKey() interface{} {
+	return STRING
+}
+
+// ResolveAttrLoader resolves the named method. The loader binds the method
+// to the string it is given.
+func (s *String) ResolveAttrLoader(name string) (AttrLoader, bool) {
+	method, ok := stringMethods[name]
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	return func(obj Object) Object {
+		return method(obj.(*String))
+	}, true
 }
 
 func (s *String) Interface() interface{} {
@@ -279,6 +138,17 @@ func (s *String) GetItem(key Object) (Object, *Error) {
 
 func (s *String) GetSlice(slice Slice) (Object, *Error) {
 	runes := []rune(s.value)
+	if slice.Step != nil {
+		start, step, count, err := ResolveStepSlice(slice, int64(len(runes)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		resultRunes := make([]rune, count)
+		for i := range resultRunes {
+			resultRunes[i] = runes[start+int64(i)*step]
+		}
+		return NewString(string(resultRunes)), nil
+	}
 	start, stop, err := ResolveIntSlice(slice, int64(len(runes)))
 	if err != nil {
 		return nil, Errorf(err.Error())
@@ -383,6 +253,22 @@ func (s *String) ReplaceAll(old, new Object) Object {
 	return NewString(strings.ReplaceAll(s.value, oldStr, newStr))
 }
 
+func (s *String) Matches(obj Object) Object {
+	re, err := AsRegexp(obj)
+	if err != nil {
+		return err
+	}
+	return NewBool(re.value.MatchString(s.value))
+}
+
+func (s *String) ReplaceRe(ctx context.Context, obj, repl Object) Object {
+	re, err := AsRegexp(obj)
+	if err != nil {
+		return err
+	}
+	return re.ReplaceAll(ctx, s.value, repl)
+}
+
 func (s *String) ToLower() Object {
 	return NewString(strings.ToLower(s.value))
 }
@@ -453,3 +339,206 @@ func (s *String) MarshalJSON() ([]byte, error) {
 func NewString(s string) *String {
 	return &String{value: s}
 }
+
+// stringMethods holds the methods of strings, by name. Each function returns the method
+// bound to the given string.
+var stringMethods = map[string]func(s *String) *Builtin{
+	"contains": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.contains",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.contains", 1, len(args))
+				}
+				return s.Contains(args[0])
+			},
+		}
+	},
+	"has_prefix": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.has_prefix",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.has_prefix", 1, len(args))
+				}
+				return s.HasPrefix(args[0])
+			},
+		}
+	},
+	"has_suffix": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.has_suffix",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.has_suffix", 1, len(args))
+				}
+				return s.HasSuffix(args[0])
+			},
+		}
+	},
+	"count": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.count",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.count", 1, len(args))
+				}
+				return s.Count(args[0])
+			},
+		}
+	},
+	"join": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.join",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.join", 1, len(args))
+				}
+				return s.Join(args[0])
+			},
+		}
+	},
+	"split": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.split",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.split", 1, len(args))
+				}
+				return s.Split(args[0])
+			},
+		}
+	},
+	"fields": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.fields",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("string.fields", 0, len(args))
+				}
+				return s.Fields()
+			},
+		}
+	},
+	"index": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.index",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.index", 1, len(args))
+				}
+				return s.Index(args[0])
+			},
+		}
+	},
+	"last_index": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.last_index",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.last_index", 1, len(args))
+				}
+				return s.LastIndex(args[0])
+			},
+		}
+	},
+	"replace_all": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.replace_all",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 2 {
+					return NewArgsError("string.replace_all", 2, len(args))
+				}
+				return s.ReplaceAll(args[0], args[1])
+			},
+		}
+	},
+	"matches": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.matches",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.matches", 1, len(args))
+				}
+				return s.Matches(args[0])
+			},
+		}
+	},
+	"replace_re": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.replace_re",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 2 {
+					return NewArgsError("string.replace_re", 2, len(args))
+				}
+				return s.ReplaceRe(ctx, args[0], args[1])
+			},
+		}
+	},
+	"to_lower": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.to_lower",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("string.to_lower", 0, len(args))
+				}
+				return s.ToLower()
+			},
+		}
+	},
+	"to_upper": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.to_upper",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("string.to_upper", 0, len(args))
+				}
+				return s.ToUpper()
+			},
+		}
+	},
+	"trim": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.trim",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.trim", 1, len(args))
+				}
+				return s.Trim(args[0])
+			},
+		}
+	},
+	"trim_prefix": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.trim_prefix",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.trim_prefix", 1, len(args))
+				}
+				return s.TrimPrefix(args[0])
+			},
+		}
+	},
+	"trim_space": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.trim_space",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("string.trim_space", 0, len(args))
+				}
+				return s.TrimSpace()
+			},
+		}
+	},
+	"trim_suffix": func(s *String) *Builtin {
+		return &Builtin{
+			name: "string.trim_suffix",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 1 {
+					return NewArgsError("string.trim_suffix", 1, len(args))
+				}
+				return s.TrimSuffix(args[0])
+			},
+		}
+	},
+}
@@ -0,0 +1,305 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/risor-io/risor/op"
+)
+
+// StructType is a user-defined type declared with a struct statement. It
+// holds the names and default values of the fields along with the methods
+// declared for the type. Calling a StructType creates a new Struct instance.
+type StructType struct {
+	*base
+	name     string
+	fields   []string
+	defaults []Object
+	index    map[string]int
+	methods  map[string]*Function
+}
+
+func (st *StructType) Type() Type {
+	return STRUCT
+}
+
+// Name returns the name of the struct type.
+func (st *StructType) Name() string {
+	return st.name
+}
+
+// Fields returns the field names of the struct type in declaration order.
+// The slice must not be modified.
+func (st *StructType) Fields() []string {
+	return st.fields
+}
+
+// Method returns the method with the given name, if it exists.
+func (st *StructType) Method(name string) (*Function, bool) {
+	fn, ok := st.methods[name]
+	return fn, ok
+}
+
+func (st *StructType) Inspect() string {
+	return fmt.Sprintf("struct(%s)", st.name)
+}
+
+func (st *StructType) String() string {
+	return st.Inspect()
+}
+
+func (st *StructType) GetAttr(name string) (Object, bool) {
+	if fn, ok := st.methods[name]; ok {
+		return fn, true
+	}
+	return nil, false
+}
+
+// SetAttr adds a method to the struct type. Methods receive the instance as
+// their first argument.
+func (st *StructType) SetAttr(name string, value Object) error {
+	fn, ok := value.(*Function)
+	if !ok {
+		return fmt.Errorf("type error: struct %s method %s must be a function (got %s)",
+			st.name, name, value.Type())
+	}
+	if _, ok := st.index[name]; ok {
+		return fmt.Errorf("type error: struct %s already has a field named %s", st.name, name)
+	}
+	if len(fn.parameters) == 0 {
+		return fmt.Errorf("type error: struct %s method %s must accept a receiver", st.name, name)
+	}
+	st.methods[name] = fn
+	return nil
+}
+
+func (st *StructType) Interface() interface{} {
+	return st
+}
+
+func (st *StructType) Equals(other Object) Object {
+	if st == other {
+		return True
+	}
+	return False
+}
+
+func (st *StructType) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for struct: %v", opType))
+}
+
+func (st *StructType) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("type error: unable to marshal struct type")
+}
+
+// New creates an instance of the struct type. Positional arguments are
+// assigned to the fields in declaration order, followed by the keyword
+// arguments. Fields that are not given take their default value.
+func (st *StructType) New(args []Object, kwargs map[string]Object) (*Struct, error) {
+	if len(args) > len(st.fields) {
+		return nil, fmt.Errorf("type error: struct %s has %d fields (%d given)",
+			st.name, len(st.fields), len(args))
+	}
+	fields := make([]Object, len(st.fields))
+	copy(fields, args)
+	for name, value := range kwargs {
+		i, ok := st.index[name]
+		if !ok {
+			return nil, fmt.Errorf("type error: struct %s has no field %s", st.name, name)
+		}
+		if i < len(args) {
+			return nil, fmt.Errorf("type error: struct %s field %s given more than once", st.name, name)
+		}
+		fields[i] = value
+	}
+	for i := len(args); i < len(fields); i++ {
+		if fields[i] == nil {
+			fields[i] = st.defaults[i]
+		}
+	}
+	return &Struct{typ: st, fields: fields}, nil
+}
+
+// NewStructType returns a struct type with the given fields. The defaults
+// slice holds the default value of each field, where nil means the field
+// defaults to Nil.
+func NewStructType(name string, fields []string, defaults []Object) (*StructType, error) {
+	if len(defaults) != len(fields) {
+		return nil, fmt.Errorf("type error: struct %s has %d fields but %d defaults",
+			name, len(fields), len(defaults))
+	}
+	st := &StructType{
+		name:     name,
+		fields:   fields,
+		defaults: make([]Object, len(fields)),
+		index:    make(map[string]int, len(fields)),
+		methods:  map[string]*Function{},
+	}
+	for i, field := range fields {
+		if _, ok := st.index[field]; ok {
+			return nil, fmt.Errorf("type error: struct %s has duplicate field %s", name, field)
+		}
+		st.index[field] = i
+		if defaults[i] == nil {
+			st.defaults[i] = Nil
+		} else {
+			st.defaults[i] = defaults[i]
+		}
+	}
+	return st, nil
+}
+
+// Struct is an instance of a user-defined StructType. Its fields are fixed
+// by the type, while their values may be changed.
+type Struct struct {
+	*base
+	typ    *StructType
+	fields []Object
+}
+
+// Type returns the name of the struct type, e.g. "Point".
+func (s *Struct) Type() Type {
+	return Type(s.typ.name)
+}
+
+// StructType returns the type of this struct.
+func (s *Struct) StructType() *StructType {
+	return s.typ
+}
+
+// Get returns the value of the named field, if it exists.
+func (s *Struct) Get(name string) (Object, bool) {
+	i, ok := s.typ.index[name]
+	if !ok {
+		return nil, false
+	}
+	return s.fields[i], true
+}
+
+func (s *Struct) Inspect() string {
+	var out strings.Builder
+	out.WriteString(s.typ.name)
+	out.WriteString("{")
+	for i, name := range s.typ.fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(name)
+		out.WriteString(": ")
+		out.WriteString(s.fields[i].Inspect())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func (s *Struct) String() string {
+	return s.Inspect()
+}
+
+// GetAttr returns the named field, or if there is no such field, the named
+// method bound to this instance.
+func (s *Struct) GetAttr(name string) (Object, bool) {
+	if value, ok := s.Get(name); ok {
+		return value, true
+	}
+	if fn, ok := s.typ.methods[name]; ok {
+		return NewBoundMethod(s, fn), true
+	}
+	return nil, false
+}
+
+// AttrCacheKey returns the struct type, since all instances of the same type
+// have the same fields.
+func (s *Struct) AttrCacheKey() interface{} {
+	return s.typ
+}
+
+// ResolveAttrLoader resolves the named field to its position in the struct.
+// Methods aren't cached, since they may be replaced on the struct type.
+func (s *Struct) ResolveAttrLoader(name string) (AttrLoader, bool) {
+	i, ok := s.typ.index[name]
+	if !ok {
+		return nil, false
+	}
+	return func(obj Object) Object {
+		return obj.(*Struct).fields[i]
+	}, true
+}
+
+// OperatorMethod returns the special method that implements the named
+// operator, bound to this instance, if the struct type declares it.
+func (s *Struct) OperatorMethod(name string) (Object, bool) {
+	fn, ok := s.typ.methods[name]
+	if !ok {
+		return nil, false
+	}
+	return NewBoundMethod(s, fn), true
+}
+
+// SetAttr sets the value of the named field. Only fields declared by the
+// struct type may be set.
+func (s *Struct) SetAttr(name string, value Object) error {
+	i, ok := s.typ.index[name]
+	if !ok {
+		return fmt.Errorf("type error: struct %s has no field %s", s.typ.name, name)
+	}
+	s.fields[i] = value
+	return nil
+}
+
+func (s *Struct) Interface() interface{} {
+	result := make(map[string]interface{}, len(s.fields))
+	for i, name := range s.typ.fields {
+		result[name] = s.fields[i].Interface()
+	}
+	return result
+}
+
+func (s *Struct) Equals(other Object) Object {
+	otherStruct, ok := other.(*Struct)
+	if !ok || s.typ != otherStruct.typ {
+		return False
+	}
+	for i, value := range s.fields {
+		if !Equals(value, otherStruct.fields[i]) {
+			return False
+		}
+	}
+	return True
+}
+
+func (s *Struct) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for %s: %v on type %s",
+		s.typ.name, opType, right.Type()))
+}
+
+func (s *Struct) Cost() int {
+	return len(s.fields) * 8
+}
+
+func (s *Struct) MarshalJSON() ([]byte, error) {
+	// Written out by hand so that fields are kept in declaration order
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, name := range s.typ.fields {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(s.fields[i])
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
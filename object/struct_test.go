@@ -0,0 +1,86 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructNew(t *testing.T) {
+	st, err := NewStructType("Point", []string{"x", "y"}, []Object{nil, NewInt(0)})
+	require.Nil(t, err)
+	require.Equal(t, "struct(Point)", st.Inspect())
+
+	p, err := st.New([]Object{NewInt(1)}, nil)
+	require.Nil(t, err)
+	require.Equal(t, Type("Point"), p.Type())
+	require.Equal(t, "Point{x: 1, y: 0}", p.Inspect())
+
+	p, err = st.New(nil, map[string]Object{"y": NewInt(2)})
+	require.Nil(t, err)
+	require.Equal(t, "Point{x: nil, y: 2}", p.Inspect())
+
+	_, err = st.New(nil, map[string]Object{"z": NewInt(2)})
+	require.NotNil(t, err)
+	require.Equal(t, "type error: struct Point has no field z", err.Error())
+
+	_, err = NewStructType("Point", []string{"x", "x"}, []Object{nil, nil})
+	require.NotNil(t, err)
+	require.Equal(t, "type error: struct Point has duplicate field x", err.Error())
+}
+
+func TestStructAttrs(t *testing.T) {
+	st, err := NewStructType("Point", []string{"x"}, []Object{nil})
+	require.Nil(t, err)
+	fn := NewFunction(FunctionOpts{Name: "Point.get", ParameterNames: []string{"p"}})
+	require.Nil(t, st.SetAttr("get", fn))
+	require.NotNil(t, st.SetAttr("x", fn))
+	require.NotNil(t, st.SetAttr("other", NewInt(1)))
+
+	p, err := st.New([]Object{NewInt(1)}, nil)
+	require.Nil(t, err)
+	require.Nil(t, p.SetAttr("x", NewInt(2)))
+	require.NotNil(t, p.SetAttr("y", NewInt(2)))
+	value, ok := p.GetAttr("x")
+	require.True(t, ok)
+	require.Equal(t, NewInt(2), value)
+
+	method, ok := p.GetAttr("get")
+	require.True(t, ok)
+	bound, ok := method.(*BoundMethod)
+	require.True(t, ok)
+	require.Same(t, p, bound.Receiver())
+	require.Same(t, fn, bound.Function())
+	require.Equal(t, "method(Point.get)", bound.Inspect())
+}
+
+func TestStructAttrLoader(t *testing.T) {
+	st, err := NewStructType("Point", []string{"x", "y"}, []Object{nil, nil})
+	require.Nil(t, err)
+	require.Nil(t, st.SetAttr("get", NewFunction(FunctionOpts{ParameterNames: []string{"p"}})))
+	p1, err := st.New([]Object{NewInt(1), NewInt(2)}, nil)
+	require.Nil(t, err)
+	p2, err := st.New([]Object{NewInt(3), NewInt(4)}, nil)
+	require.Nil(t, err)
+	require.Equal(t, p1.AttrCacheKey(), p2.AttrCacheKey())
+
+	loader, ok := p1.ResolveAttrLoader("y")
+	require.True(t, ok)
+	require.Equal(t, NewInt(2), loader(p1))
+	require.Equal(t, NewInt(4), loader(p2))
+
+	// Methods may be replaced, so they aren't cached
+	_, ok = p1.ResolveAttrLoader("get")
+	require.False(t, ok)
+}
+
+func TestStructJSON(t *testing.T) {
+	st, err := NewStructType("Point", []string{"y", "x"}, []Object{nil, nil})
+	require.Nil(t, err)
+	p, err := st.New([]Object{NewInt(1), NewString("a")}, nil)
+	require.Nil(t, err)
+	data, err := p.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `{"y":1,"x":"a"}`, string(data))
+	require.Equal(t, map[string]interface{}{"x": "a", "y": int64(1)}, p.Interface())
+}
@@ -20,6 +20,7 @@ type Symbol struct {
 	Index      uint16
 	Value      Object
 	IsConstant bool
+	IsBuiltin  bool
 }
 
 type Resolution struct {
@@ -43,6 +44,9 @@ type SymbolTable struct {
 	values    []Object
 	isBlock   bool
 	freeCount int
+
+	// builtins that were shadowed by variables of the same name
+	shadowed []*Symbol
 }
 
 func (t *SymbolTable) NewChild() *SymbolTable {
@@ -83,8 +87,12 @@ func (t *SymbolTable) InsertConstant(name string, value ...Object) (*Symbol, err
 	return sym, nil
 }
 
+// InsertVariable adds a variable to the table. A variable may shadow a
+// builtin of the same name, which then remains available only to code that
+// was compiled before the variable was declared.
 func (t *SymbolTable) InsertVariable(name string, value ...Object) (*Symbol, error) {
-	if _, ok := t.symbols[name]; ok {
+	existing, exists := t.symbols[name]
+	if exists && !existing.IsBuiltin {
 		return nil, fmt.Errorf("symbol %q already exists", name)
 	}
 	var obj Object
@@ -98,6 +106,9 @@ func (t *SymbolTable) InsertVariable(name string, value ...Object) (*Symbol, err
 	if err != nil {
 		return nil, err
 	}
+	if exists {
+		t.shadowed = append(t.shadowed, existing)
+	}
 	s := &Symbol{Name: name, Index: index, Value: obj}
 	t.symbols[name] = s
 	t.variables[name] = s
@@ -108,7 +119,15 @@ func (t *SymbolTable) InsertBuiltin(name string, value ...Object) (*Symbol, erro
 	if t.parent != nil {
 		return nil, errors.New("cannot insert builtin in child table")
 	}
-	return t.InsertVariable(name, value...)
+	if _, ok := t.symbols[name]; ok {
+		return nil, fmt.Errorf("symbol %q already exists", name)
+	}
+	sym, err := t.InsertVariable(name, value...)
+	if err != nil {
+		return nil, err
+	}
+	sym.IsBuiltin = true
+	return sym, nil
 }
 
 func (t *SymbolTable) SetValue(name string, value Object) error {
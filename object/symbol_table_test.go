@@ -45,6 +45,29 @@ func TestTable(t *testing.T) {
 	// require.Len(t, table.Builtins(), 1)
 }
 
+func TestShadowBuiltin(t *testing.T) {
+	table := NewSymbolTable()
+	builtin, err := table.InsertBuiltin("len", NewInt(1))
+	require.Nil(t, err)
+	require.True(t, builtin.IsBuiltin)
+
+	_, err = table.InsertBuiltin("len")
+	require.NotNil(t, err)
+
+	variable, err := table.InsertVariable("len")
+	require.Nil(t, err)
+	require.False(t, variable.IsBuiltin)
+	require.Equal(t, uint16(1), variable.Index)
+	sym, ok := table.Get("len")
+	require.True(t, ok)
+	require.Same(t, variable, sym)
+
+	// Once shadowed, the name can't be declared again
+	_, err = table.InsertVariable("len")
+	require.NotNil(t, err)
+	require.Equal(t, "symbol \"len\" already exists", err.Error())
+}
+
 func TestBlock(t *testing.T) {
 	table := NewSymbolTable()
 	block := table.NewBlock()
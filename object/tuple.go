@@ -0,0 +1,275 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/risor-io/risor/op"
+)
+
+// Tuple is an immutable sequence of objects. A tuple is hashable, and so may
+// be used as a map key or set member, as long as all its items are hashable.
+type Tuple struct {
+	*base
+	items []Object
+}
+
+func (t *Tuple) Type() Type {
+	return TUPLE
+}
+
+// Value returns the items of the tuple. The slice must not be modified.
+func (t *Tuple) Value() []Object {
+	return t.items
+}
+
+func (t *Tuple) Inspect() string {
+	items := make([]string, 0, len(t.items))
+	for _, item := range t.items {
+		items = append(items, item.Inspect())
+	}
+	// A tuple with one item needs a trailing comma to differentiate it from
+	// a parenthesized expression
+	if len(items) == 1 {
+		return "(" + items[0] + ",)"
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+func (t *Tuple) String() string {
+	return t.Inspect()
+}
+
+func (t *Tuple) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "count":
+		return NewBuiltin("tuple.count", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("tuple.count", 1, len(args))
+			}
+			return NewInt(t.Count(args[0]))
+		}), true
+	case "index":
+		return NewBuiltin("tuple.index", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 1 {
+				return NewArgsError("tuple.index", 1, len(args))
+			}
+			return NewInt(t.Index(args[0]))
+		}), true
+	}
+	return nil, false
+}
+
+// Count returns the number of items with the specified value.
+func (t *Tuple) Count(obj Object) int64 {
+	count := int64(0)
+	for _, item := range t.items {
+		if Equals(obj, item) {
+			count++
+		}
+	}
+	return count
+}
+
+// Index returns the index of the first item with the specified value, or -1
+// if no such item exists.
+func (t *Tuple) Index(obj Object) int64 {
+	for i, item := range t.items {
+		if Equals(obj, item) {
+			return int64(i)
+		}
+	}
+	return int64(-1)
+}
+
+func (t *Tuple) HashKey() HashKey {
+	var out strings.Builder
+	out.WriteString("(")
+	for i, item := range t.items {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		hashable, ok := item.(Hashable)
+		if !ok {
+			out.WriteString(item.Inspect())
+			continue
+		}
+		key := hashable.HashKey()
+		out.WriteString(string(key.Type))
+		out.WriteString(":")
+		switch {
+		case key.StrValue != "":
+			out.WriteString(strconv.Quote(key.StrValue))
+		case key.FltValue != 0:
+			out.WriteString(strconv.FormatFloat(key.FltValue, 'g', -1, 64))
+		default:
+			out.WriteString(strconv.FormatInt(key.IntValue, 10))
+		}
+	}
+	out.WriteString(")")
+	return HashKey{Type: t.Type(), StrValue: out.String()}
+}
+
+// checkHashable returns an error if any item in the tuple is unhashable.
+func (t *Tuple) checkHashable() *Error {
+	for _, item := range t.items {
+		switch item := item.(type) {
+		case *Tuple:
+			if err := item.checkHashable(); err != nil {
+				return err
+			}
+		case Hashable:
+		default:
+			return Errorf("type error: %s object is unhashable", item.Type())
+		}
+	}
+	return nil
+}
+
+func (t *Tuple) Interface() interface{} {
+	items := make([]interface{}, 0, len(t.items))
+	for _, item := range t.items {
+		items = append(items, item.Interface())
+	}
+	return items
+}
+
+func (t *Tuple) Compare(other Object) (int, error) {
+	typeComp := CompareTypes(t, other)
+	if typeComp != 0 {
+		return typeComp, nil
+	}
+	otherTuple := other.(*Tuple)
+	// Tuples are compared item by item, with a shorter tuple ordered first
+	// when it is a prefix of the other
+	for i := 0; i < len(t.items) && i < len(otherTuple.items); i++ {
+		comparable, ok := t.items[i].(Comparable)
+		if !ok {
+			return 0, fmt.Errorf("type error: %s object is not comparable",
+				t.items[i].Type())
+		}
+		comp, err := comparable.Compare(otherTuple.items[i])
+		if err != nil {
+			return 0, err
+		}
+		if comp != 0 {
+			return comp, nil
+		}
+	}
+	switch {
+	case len(t.items) > len(otherTuple.items):
+		return 1, nil
+	case len(t.items) < len(otherTuple.items):
+		return -1, nil
+	}
+	return 0, nil
+}
+
+func (t *Tuple) Equals(other Object) Object {
+	otherTuple, ok := other.(*Tuple)
+	if !ok || len(t.items) != len(otherTuple.items) {
+		return False
+	}
+	for i, v := range t.items {
+		if !Equals(v, otherTuple.items[i]) {
+			return False
+		}
+	}
+	return True
+}
+
+func (t *Tuple) IsTruthy() bool {
+	return len(t.items) > 0
+}
+
+func (t *Tuple) GetItem(key Object) (Object, *Error) {
+	indexObj, ok := key.(*Int)
+	if !ok {
+		return nil, Errorf("type error: tuple index must be an int (got %s)", key.Type())
+	}
+	idx, err := ResolveIndex(indexObj.value, int64(len(t.items)))
+	if err != nil {
+		return nil, Errorf(err.Error())
+	}
+	return t.items[idx], nil
+}
+
+// GetSlice implements the [start:stop:step] operator for a container type.
+func (t *Tuple) GetSlice(s Slice) (Object, *Error) {
+	if s.Step != nil {
+		start, step, count, err := ResolveStepSlice(s, int64(len(t.items)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		items := make([]Object, count)
+		for i := range items {
+			items[i] = t.items[start+int64(i)*step]
+		}
+		return NewTuple(items), nil
+	}
+	start, stop, err := ResolveIntSlice(s, int64(len(t.items)))
+	if err != nil {
+		return nil, Errorf(err.Error())
+	}
+	items := make([]Object, stop-start)
+	copy(items, t.items[start:stop])
+	return NewTuple(items), nil
+}
+
+// SetItem implements the [key] = value operator for a container type.
+func (t *Tuple) SetItem(key, value Object) *Error {
+	return Errorf("type error: tuple does not support item assignment")
+}
+
+// DelItem implements the del [key] operator for a container type.
+func (t *Tuple) DelItem(key Object) *Error {
+	return Errorf("type error: tuple does not support item deletion")
+}
+
+// Contains returns true if the given item is found in this container.
+func (t *Tuple) Contains(item Object) *Bool {
+	for _, v := range t.items {
+		if Equals(v, item) {
+			return True
+		}
+	}
+	return False
+}
+
+// Len returns the number of items in this container.
+func (t *Tuple) Len() *Int {
+	return NewInt(int64(len(t.items)))
+}
+
+func (t *Tuple) Iter() Iterator {
+	return NewTupleIter(t)
+}
+
+func (t *Tuple) RunOperation(opType op.BinaryOpType, right Object) Object {
+	rightTuple, ok := right.(*Tuple)
+	if !ok || opType != op.Add {
+		return NewError(fmt.Errorf("eval error: unsupported operation for tuple: %v on type %s",
+			opType, right.Type()))
+	}
+	combined := make([]Object, len(t.items)+len(rightTuple.items))
+	copy(combined, t.items)
+	copy(combined[len(t.items):], rightTuple.items)
+	return NewTuple(combined)
+}
+
+func (t *Tuple) Cost() int {
+	return len(t.items) * 8
+}
+
+func (t *Tuple) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.items)
+}
+
+// NewTuple returns a tuple holding the given items. The slice must not be
+// modified afterwards.
+func NewTuple(items []Object) *Tuple {
+	return &Tuple{items: items}
+}
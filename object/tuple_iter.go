@@ -0,0 +1,116 @@
+package object
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/risor-io/risor/op"
+)
+
+type TupleIter struct {
+	*base
+	t       *Tuple
+	pos     int64
+	current Object
+}
+
+func (iter *TupleIter) Type() Type {
+	return TUPLE_ITER
+}
+
+func (iter *TupleIter) Inspect() string {
+	return fmt.Sprintf("tuple_iter(%s)", iter.t.Inspect())
+}
+
+func (iter *TupleIter) String() string {
+	return iter.Inspect()
+}
+
+func (iter *TupleIter) Interface() interface{} {
+	var entries []map[string]interface{}
+	for {
+		entry, ok := iter.Next()
+		if !ok {
+			break
+		}
+		entries = append(entries, entry.Interface().(map[string]interface{}))
+	}
+	return entries
+}
+
+func (iter *TupleIter) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *TupleIter:
+		return NewBool(iter == other)
+	default:
+		return False
+	}
+}
+
+func (iter *TupleIter) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "tuple_iter.next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("tuple_iter.next", 0, len(args))
+				}
+				value, ok := iter.Next()
+				if !ok {
+					return Nil
+				}
+				return value
+			},
+		}, true
+	case "entry":
+		return &Builtin{
+			name: "tuple_iter.entry",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("tuple_iter.entry", 0, len(args))
+				}
+				entry, ok := iter.Entry()
+				if !ok {
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (iter *TupleIter) IsTruthy() bool {
+	return iter.pos < int64(len(iter.t.items))
+}
+
+func (iter *TupleIter) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return NewError(fmt.Errorf("eval error: unsupported operation for tuple_iter: %v", opType))
+}
+
+func (iter *TupleIter) Next() (Object, bool) {
+	items := iter.t.items
+	if iter.pos >= int64(len(items)-1) {
+		iter.current = nil
+		return nil, false
+	}
+	iter.pos++
+	iter.current = items[iter.pos]
+	return iter.current, true
+}
+
+func (iter *TupleIter) Entry() (IteratorEntry, bool) {
+	if iter.current == nil {
+		return nil, false
+	}
+	return NewEntry(NewInt(iter.pos), iter.current), true
+}
+
+func (iter *TupleIter) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("type error: unable to marshal tuple_iter")
+}
+
+func NewTupleIter(t *Tuple) *TupleIter {
+	return &TupleIter{t: t, pos: -1}
+}
@@ -0,0 +1,54 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleHashKey(t *testing.T) {
+	a := NewTuple([]Object{NewInt(1), NewString("a")})
+	b := NewTuple([]Object{NewInt(1), NewString("a")})
+	c := NewTuple([]Object{NewString("1"), NewString("a")})
+	d := NewTuple([]Object{NewTuple([]Object{NewInt(1)}), NewFloat(2.5)})
+	e := NewTuple([]Object{NewTuple([]Object{NewInt(1)}), NewFloat(2.5)})
+	require.Equal(t, a.HashKey(), b.HashKey())
+	require.NotEqual(t, a.HashKey(), c.HashKey())
+	require.Equal(t, d.HashKey(), e.HashKey())
+	require.NotEqual(t, NewTuple(nil).HashKey(), NewTuple([]Object{NewString("")}).HashKey())
+
+	_, err := hashKey(NewTuple([]Object{NewInt(1), NewList(nil)}))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: list object is unhashable", err.Message().Value())
+}
+
+func TestTupleInspect(t *testing.T) {
+	require.Equal(t, "()", NewTuple(nil).Inspect())
+	require.Equal(t, "(1,)", NewTuple([]Object{NewInt(1)}).Inspect())
+	require.Equal(t, `(1, "a")`, NewTuple([]Object{NewInt(1), NewString("a")}).Inspect())
+}
+
+func TestFreeze(t *testing.T) {
+	inner := NewList([]Object{NewInt(1)})
+	m := NewMap(map[string]Object{"a": inner})
+	frozen, ok := Freeze(m).(*Map)
+	require.True(t, ok)
+	require.True(t, frozen.IsFrozen())
+	require.False(t, m.IsFrozen())
+
+	frozenInner, ok := frozen.Get("a").(*List)
+	require.True(t, ok)
+	require.True(t, frozenInner.IsFrozen())
+	require.False(t, inner.IsFrozen())
+	require.NotNil(t, frozenInner.SetItem(NewInt(0), NewInt(2)))
+	require.NotNil(t, frozen.SetItem(NewString("b"), NewInt(2)))
+
+	// Freezing a frozen object returns it unchanged
+	require.Same(t, frozen, Freeze(frozen))
+
+	set := NewSet([]Object{NewInt(1)}).(*Set)
+	frozenSet := Freeze(set).(*Set)
+	require.True(t, frozenSet.IsFrozen())
+	require.NotNil(t, frozenSet.DelItem(NewInt(1)))
+	require.Nil(t, set.DelItem(NewInt(1)))
+}
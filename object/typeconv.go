@@ -12,20 +12,22 @@ import (
 )
 
 var kindConverters = map[reflect.Kind]TypeConverter{
-	reflect.Bool:    &BoolConverter{},
-	reflect.Int:     &IntConverter{},
-	reflect.Int8:    &Int8Converter{},
-	reflect.Int16:   &Int16Converter{},
-	reflect.Int32:   &Int32Converter{},
-	reflect.Int64:   &Int64Converter{},
-	reflect.Uint:    &UintConverter{},
-	reflect.Uint8:   &Uint8Converter{},
-	reflect.Uint16:  &Uint16Converter{},
-	reflect.Uint32:  &Uint32Converter{},
-	reflect.Uint64:  &Uint64Converter{},
-	reflect.Float32: &Float32Converter{},
-	reflect.Float64: &Float64Converter{},
-	reflect.String:  &StringConverter{},
+	reflect.Bool:       &BoolConverter{},
+	reflect.Int:        &IntConverter{},
+	reflect.Int8:       &Int8Converter{},
+	reflect.Int16:      &Int16Converter{},
+	reflect.Int32:      &Int32Converter{},
+	reflect.Int64:      &Int64Converter{},
+	reflect.Uint:       &UintConverter{},
+	reflect.Uint8:      &Uint8Converter{},
+	reflect.Uint16:     &Uint16Converter{},
+	reflect.Uint32:     &Uint32Converter{},
+	reflect.Uint64:     &Uint64Converter{},
+	reflect.Float32:    &Float32Converter{},
+	reflect.Float64:    &Float64Converter{},
+	reflect.Complex64:  &Complex64Converter{},
+	reflect.Complex128: &Complex128Converter{},
+	reflect.String:     &StringConverter{},
 }
 
 var typeConverters = map[reflect.Type]TypeConverter{
@@ -34,12 +36,11 @@ var typeConverters = map[reflect.Type]TypeConverter{
 	reflect.TypeOf(bytes.NewBuffer(nil)): &BufferConverter{},
 	reflect.TypeOf([]byte{}):             &ByteSliceConverter{},
 	reflect.TypeOf([]float64{}):          &FloatSliceConverter{},
+	reflect.TypeOf([]complex128{}):       &ComplexSliceConverter{},
 }
 
 // Kinds do NOT intend to handle for now:
 // * Chan
-// * Complex64
-// * Complex128
 // * UnsafePointer
 
 // *****************************************************************************
@@ -104,6 +105,21 @@ func AsFloat(obj Object) (float64, *Error) {
 	}
 }
 
+func AsComplex(obj Object) (complex128, *Error) {
+	switch obj := obj.(type) {
+	case *Int:
+		return complex(float64(obj.value), 0), nil
+	case *Byte:
+		return complex(float64(obj.value), 0), nil
+	case *Float:
+		return complex(obj.value, 0), nil
+	case *Complex:
+		return obj.value, nil
+	default:
+		return 0, Errorf("type error: expected a number (%s given)", obj.Type())
+	}
+}
+
 func AsList(obj Object) (*List, *Error) {
 	list, ok := obj.(*List)
 	if !ok {
@@ -136,6 +152,18 @@ func AsSet(obj Object) (*Set, *Error) {
 	return set, nil
 }
 
+// AsRegexp returns the given regexp, or compiles the given string pattern.
+func AsRegexp(obj Object) (*Regexp, *Error) {
+	switch obj := obj.(type) {
+	case *Regexp:
+		return obj, nil
+	case *String:
+		return CompileRegexp(obj.value)
+	default:
+		return nil, Errorf("type error: expected a regexp or string (%s given)", obj.Type())
+	}
+}
+
 func AsBytes(obj Object) ([]byte, *Error) {
 	switch obj := obj.(type) {
 	case *ByteSlice:
@@ -211,6 +239,12 @@ func FromGoType(obj interface{}) Object {
 		return NewFloat(float64(obj))
 	case float64:
 		return NewFloat(obj)
+	case complex64:
+		return NewComplex(complex128(obj))
+	case complex128:
+		return NewComplex(obj)
+	case []complex128:
+		return NewComplexSlice(obj)
 	case string:
 		return NewString(obj)
 	case byte:
@@ -661,6 +695,36 @@ func (c *Float64Converter) From(obj interface{}) (Object, error) {
 	return NewFloat(obj.(float64)), nil
 }
 
+// Complex64Converter converts between complex64 and *Complex.
+type Complex64Converter struct{}
+
+func (c *Complex64Converter) To(obj Object) (interface{}, error) {
+	value, err := AsComplex(obj)
+	if err != nil {
+		return nil, fmt.Errorf("type error: expected complex (%s given)", obj.Type())
+	}
+	return complex64(value), nil
+}
+
+func (c *Complex64Converter) From(obj interface{}) (Object, error) {
+	return NewComplex(complex128(obj.(complex64))), nil
+}
+
+// Complex128Converter converts between complex128 and *Complex.
+type Complex128Converter struct{}
+
+func (c *Complex128Converter) To(obj Object) (interface{}, error) {
+	value, err := AsComplex(obj)
+	if err != nil {
+		return nil, fmt.Errorf("type error: expected complex (%s given)", obj.Type())
+	}
+	return value, nil
+}
+
+func (c *Complex128Converter) From(obj interface{}) (Object, error) {
+	return NewComplex(obj.(complex128)), nil
+}
+
 // StringConverter converts between string and *String.
 type StringConverter struct{}
 
@@ -717,6 +781,22 @@ func (c *FloatSliceConverter) From(obj interface{}) (Object, error) {
 	return NewFloatSlice(obj.([]float64)), nil
 }
 
+// ComplexSliceConverter converts between []complex128 and *ComplexSlice.
+type ComplexSliceConverter struct{}
+
+func (c *ComplexSliceConverter) To(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *ComplexSlice:
+		return obj.value, nil
+	default:
+		return nil, fmt.Errorf("type error: expected complex_slice (%s given)", obj.Type())
+	}
+}
+
+func (c *ComplexSliceConverter) From(obj interface{}) (Object, error) {
+	return NewComplexSlice(obj.([]complex128)), nil
+}
+
 // TimeConverter converts between time.Time and *Time.
 type TimeConverter struct{}
 
@@ -784,7 +864,7 @@ func (c *MapConverter) To(obj Object) (interface{}, error) {
 	keyType := reflect.TypeOf("")
 	mapType := reflect.MapOf(keyType, c.valueType)
 	gMap := reflect.MakeMapWithSize(mapType, tMap.Size())
-	for k, v := range tMap.items {
+	for k, v := range tMap.Value() {
 		conv, err := c.valueConverter.To(v)
 		if err != nil {
 			return nil, err
@@ -836,7 +916,7 @@ func (c *StructConverter) To(obj Object) (interface{}, error) {
 		value := c.goType.New()
 		// Get the underlying struct so that we can set its fields.
 		structValue := value.Elem()
-		for k, value := range obj.items {
+		for k, value := range obj.Value() {
 			// If the struct has a field with the same name as a key, set it.
 			if f := structValue.FieldByName(k); f.CanSet() {
 				if attr, ok := c.goType.GetAttribute(k); ok {
@@ -24,6 +24,40 @@ func TestFloat64Converter(t *testing.T) {
 	require.Equal(t, 3.0, v)
 }
 
+func TestComplex128Converter(t *testing.T) {
+	c := Complex128Converter{}
+
+	v, err := c.From(complex(1, 2))
+	require.Nil(t, err)
+	require.Equal(t, NewComplex(complex(1, 2)), v)
+
+	result, err := c.To(NewComplex(complex(3, 4)))
+	require.Nil(t, err)
+	require.Equal(t, complex(3, 4), result)
+
+	result, err = c.To(NewFloat(2.5))
+	require.Nil(t, err)
+	require.Equal(t, complex(2.5, 0), result)
+
+	_, err = c.To(NewString("x"))
+	require.NotNil(t, err)
+}
+
+func TestComplexSliceConverter(t *testing.T) {
+	conv, err := NewTypeConverter(reflect.TypeOf([]complex128{}))
+	require.Nil(t, err)
+
+	v, err := conv.From([]complex128{1, 2i})
+	require.Nil(t, err)
+	require.Equal(t, NewComplexSlice([]complex128{1, 2i}), v)
+
+	result, err := conv.To(NewComplexSlice([]complex128{3i}))
+	require.Nil(t, err)
+	require.Equal(t, []complex128{3i}, result)
+
+	require.Equal(t, NewComplex(2i), FromGoType(complex64(2i)))
+}
+
 func TestMapStringConverter(t *testing.T) {
 	c, err := newMapConverter(reflect.TypeOf(""))
 	require.Nil(t, err)
@@ -11,20 +11,28 @@ const (
 	BuildMap
 	BuildSet
 	BuildString
+	BuildStruct
+	BuildTuple
 	Call
+	CallEx
 	CompareOp
 	ContainsOp
 	Copy
+	Defer
 	DeleteSubscr
 	False
 	ForIter
 	GetIter
+	Go
 	Halt
 	Import
 	JumpBackward
 	JumpForward
 	Length
+	ListAppend
+	ListExtend
 	LoadAttr
+	LoadAttrOrNil
 	LoadClosure
 	LoadConst
 	LoadFast
@@ -32,17 +40,29 @@ const (
 	LoadGlobal
 	LoadName
 	MakeCell
+	MapAdd
+	MatchPattern
+	NewStruct
 	Nil
 	Partial
+	PartialEx
 	PopJumpBackwardIfFalse
 	PopJumpBackwardIfTrue
 	PopJumpForwardIfFalse
+	PopJumpForwardIfNil
+	PopJumpForwardIfNotNil
 	PopJumpForwardIfTrue
 	PopTop
+	PopTry
 	Print
 	PushNil
 	Range
+	Receive
 	ReturnValue
+	Select
+	Send
+	SetAdd
+	SetupTry
 	Slice
 	StoreAttr
 	StoreFast
@@ -51,14 +71,34 @@ const (
 	StoreName
 	StoreSubscr
 	Swap
+	Throw
 	True
 	UnaryInvert
 	UnaryNegative
 	UnaryNot
 	UnaryPositive
 	Unpack
+	UnpackPattern
+	UnwrapResult
+	Yield
+
+	// Superinstructions combine common sequences of instructions into one,
+	// reducing the number of dispatches. These are only emitted when the
+	// compiler optimizes code.
+	BinaryOpConst
+	ComparePopJumpForwardIfFalse
+	LoadFastLoadFast
+
+	// TailCall is a call whose result is returned by the calling function.
+	// The call reuses the frame of the calling function when possible.
+	TailCall
 )
 
+// ForIterPrimary may be given as the name count operand of ForIter to push
+// only the primary value of each iterator entry. This is the item of a list
+// or set entry and the key of a map entry.
+const ForIterPrimary = 0xFFFF
+
 type BinaryOpType uint16
 
 const (
@@ -110,19 +150,27 @@ func init() {
 		{BuildMap, "BUILD_MAP", 1, []int{2}},
 		{BuildSet, "BUILD_SET", 1, []int{2}},
 		{BuildString, "BUILD_STRING", 1, []int{2}},
+		{BuildStruct, "BUILD_STRUCT", 1, []int{2}},
+		{BuildTuple, "BUILD_TUPLE", 1, []int{2}},
 		{Call, "CALL", 1, []int{2}},
+		{CallEx, "CALL_EX", 0, nil},
 		{CompareOp, "COMPARE_OP", 1, []int{2}},
 		{ContainsOp, "CONTAINS_OP", 1, []int{2}},
 		{Copy, "COPY", 1, []int{2}},
+		{Defer, "DEFER", 1, []int{2}},
 		{DeleteSubscr, "DELETE_SUBSCR", 0, nil},
 		{False, "FALSE", 0, nil},
 		{GetIter, "GET_ITER", 0, nil},
+		{Go, "GO", 1, []int{2}},
 		{Halt, "HALT", 0, nil},
 		{Import, "IMPORT", 0, nil},
 		{JumpBackward, "JUMP_BACKWARD", 1, []int{2}},
 		{JumpForward, "JUMP_FORWARD", 1, []int{2}},
 		{Length, "LENGTH", 0, nil},
+		{ListAppend, "LIST_APPEND", 1, []int{2}},
+		{ListExtend, "LIST_EXTEND", 0, nil},
 		{LoadAttr, "LOAD_ATTR", 1, []int{2}},
+		{LoadAttrOrNil, "LOAD_ATTR_OR_NIL", 1, []int{2}},
 		{LoadClosure, "LOAD_CLOSURE", 2, []int{2, 2}},
 		{LoadConst, "LOAD_CONST", 1, []int{2}},
 		{LoadFast, "LOAD_FAST", 1, []int{2}},
@@ -130,18 +178,30 @@ func init() {
 		{LoadGlobal, "LOAD_GLOBAL", 1, []int{2}},
 		{LoadName, "LOAD_NAME", 1, []int{2}},
 		{MakeCell, "MAKE_CELL", 2, []int{2, 1}},
+		{MapAdd, "MAP_ADD", 1, []int{2}},
+		{MatchPattern, "MATCH_PATTERN", 1, []int{2}},
+		{NewStruct, "NEW_STRUCT", 1, []int{2}},
 		{Nil, "NIL", 0, nil},
 		{Nop, "NOP", 0, nil},
 		{Partial, "PARTIAL", 1, []int{2}},
+		{PartialEx, "PARTIAL_EX", 0, nil},
 		{PopJumpBackwardIfFalse, "POP_JUMP_BACKWARD_IF_FALSE", 1, []int{2}},
 		{PopJumpBackwardIfTrue, "POP_JUMP_BACKWARD_IF_TRUE", 1, []int{2}},
 		{PopJumpForwardIfFalse, "POP_JUMP_FORWARD_IF_FALSE", 1, []int{2}},
+		{PopJumpForwardIfNil, "POP_JUMP_FORWARD_IF_NIL", 1, []int{2}},
+		{PopJumpForwardIfNotNil, "POP_JUMP_FORWARD_IF_NOT_NIL", 1, []int{2}},
 		{PopJumpForwardIfTrue, "POP_JUMP_FORWARD_IF_TRUE", 1, []int{2}},
 		{PopTop, "POP_TOP", 0, nil},
+		{PopTry, "POP_TRY", 0, nil},
 		{Print, "PRINT", 0, nil},
 		{Range, "RANGE", 0, nil},
+		{Receive, "RECEIVE", 0, nil},
 		{ReturnValue, "RETURN_VALUE", 0, nil},
-		{Slice, "SLICE", 0, nil},
+		{Select, "SELECT", 2, []int{2, 2}},
+		{Send, "SEND", 0, nil},
+		{SetAdd, "SET_ADD", 1, []int{2}},
+		{SetupTry, "SETUP_TRY", 1, []int{2}},
+		{Slice, "SLICE", 1, []int{2}},
 		{StoreAttr, "STORE_ATTR", 1, []int{2}},
 		{StoreFast, "STORE_FAST", 1, []int{2}},
 		{StoreFree, "STORE_FREE", 1, []int{2}},
@@ -149,12 +209,20 @@ func init() {
 		{StoreName, "STORE_NAME", 1, []int{2}},
 		{StoreSubscr, "STORE_SUBSCR", 0, nil},
 		{Swap, "SWAP", 1, []int{2}},
+		{Throw, "THROW", 0, nil},
 		{True, "TRUE", 0, nil},
 		{UnaryNegative, "UNARY_NEGATIVE", 0, nil},
 		{UnaryNot, "UNARY_NOT", 0, nil},
 		{UnaryPositive, "UNARY_POSITIVE", 0, nil},
 		{Unpack, "UNPACK", 1, []int{2}},
+		{UnpackPattern, "UNPACK_PATTERN", 1, []int{2}},
+		{UnwrapResult, "UNWRAP_RESULT", 1, []int{2}},
+		{Yield, "YIELD", 0, nil},
 		{ForIter, "FOR_ITER", 2, []int{2, 2}},
+		{BinaryOpConst, "BINARY_OP_CONST", 2, []int{2, 2}},
+		{ComparePopJumpForwardIfFalse, "COMPARE_POP_JUMP_FORWARD_IF_FALSE", 2, []int{2, 2}},
+		{LoadFastLoadFast, "LOAD_FAST_LOAD_FAST", 2, []int{2, 2}},
+		{TailCall, "TAIL_CALL", 1, []int{2}},
 	}
 	for _, o := range ops {
 		OperandCount[o.op] = Info{
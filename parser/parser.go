@@ -85,6 +85,12 @@ type Parser struct {
 
 	// The filename of the input
 	filename string
+
+	// are we inside the header of an if, for, or switch statement?
+	//
+	// A "{" after a name begins the body of the statement there, rather than
+	// a struct literal, unless the literal is enclosed in parentheses.
+	noStructLiterals bool
 }
 
 // New returns a Parser for the program provided by the given Lexer.
@@ -106,11 +112,13 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	p.nextToken() // makes curToken=token[0], peekToken=token[1]
 
 	// Register prefix-functions
+	p.registerPrefix(token.ARROW, p.parseReceive)
 	p.registerPrefix(token.BACKTICK, p.parseString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpr)
 	p.registerPrefix(token.EOF, p.illegalToken)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.FLOAT, p.parseFloat)
+	p.registerPrefix(token.IMAGINARY, p.parseImaginary)
 	p.registerPrefix(token.FOR, p.parseFor)
 	p.registerPrefix(token.FSTRING, p.parseString)
 	p.registerPrefix(token.FUNC, p.parseFunc)
@@ -127,11 +135,14 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	p.registerPrefix(token.NIL, p.parseNil)
 	p.registerPrefix(token.PIPE, p.parsePrefixExpr)
 	p.registerPrefix(token.RANGE, p.parseRange)
+	p.registerPrefix(token.SELECT, p.parseSelect)
 	p.registerPrefix(token.STRING, p.parseString)
 	p.registerPrefix(token.SWITCH, p.parseSwitch)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.registerPrefix(token.TRY, p.parseTry)
 
 	// Register infix functions
+	p.registerInfix(token.ARROW, p.parseSend)
 	p.registerInfix(token.ASSIGN, p.parseAssign)
 	p.registerInfix(token.ASTERISK_EQUALS, p.parseAssign)
 	p.registerInfix(token.MINUS_EQUALS, p.parseAssign)
@@ -142,9 +153,13 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	p.registerInfix(token.LPAREN, p.parseCall)
 	p.registerInfix(token.PERIOD, p.parseGetAttr)
 	p.registerInfix(token.PIPE, p.parsePipe)
-	p.registerInfix(token.QUESTION, p.parseTernary)
+	p.registerInfix(token.QUESTION, p.parseQuestion)
+	p.registerInfix(token.QUESTION_PERIOD, p.parseOptionalChain)
+	p.registerInfix(token.QUESTION_QUESTION, p.parseInfixExpr)
 	p.registerInfix(token.AND, p.parseInfixExpr)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpr)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpr)
+	p.registerInfix(token.CARET, p.parseInfixExpr)
 	p.registerInfix(token.EQ, p.parseInfixExpr)
 	p.registerInfix(token.GT_EQUALS, p.parseInfixExpr)
 	p.registerInfix(token.GT, p.parseInfixExpr)
@@ -281,12 +296,26 @@ func (p *Parser) parseStatement() ast.Node {
 		return p.parseVar()
 	case token.CONST:
 		return p.parseConst()
+	case token.STRUCT:
+		return p.parseStruct()
+	case token.FUNC:
+		if p.isMethod() {
+			return p.parseMethod()
+		}
 	case token.RETURN:
 		return p.parseReturn()
 	case token.BREAK:
 		return p.parseBreak()
 	case token.CONTINUE:
 		return p.parseContinue()
+	case token.THROW:
+		return p.parseThrow()
+	case token.YIELD:
+		return p.parseYield()
+	case token.DEFER:
+		return p.parseDefer()
+	case token.GO:
+		return p.parseGo()
 	case token.NEWLINE:
 		return nil
 	case token.IDENT:
@@ -294,6 +323,14 @@ func (p *Parser) parseStatement() ast.Node {
 			return p.parseDeclaration()
 		}
 		// intentional fallthrough!
+	case token.ASTERISK:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseDeclaration()
+		}
+	case token.LBRACKET, token.LBRACE:
+		if p.isDestructuring() {
+			return p.parseDeclaration()
+		}
 	}
 	return p.parseExpressionStatement()
 }
@@ -327,13 +364,9 @@ func (p *Parser) parseVar() ast.Node {
 
 func (p *Parser) parseDeclaration() ast.Node {
 	tok := p.curToken
-	idents := []*ast.Ident{ast.NewIdent(p.curToken)}
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		if !p.expectPeek("declaration statement", token.IDENT) {
-			return nil
-		}
-		idents = append(idents, ast.NewIdent(p.curToken))
+	targets, rest, restIndex, ok := p.parseDeclarationTargets()
+	if !ok {
+		return nil
 	}
 	if !p.expectPeek("declaration statement", token.DECLARE) {
 		return nil
@@ -343,10 +376,156 @@ func (p *Parser) parseDeclaration() ast.Node {
 	if value == nil {
 		return nil
 	}
-	if len(idents) > 1 {
-		return ast.NewMultiVar(tok, idents, value, true)
+	// Declarations that only assign names don't need patterns
+	if rest == nil {
+		idents := make([]*ast.Ident, 0, len(targets))
+		for _, target := range targets {
+			capture, ok := target.(*ast.CapturePattern)
+			if !ok {
+				break
+			}
+			idents = append(idents, ast.NewIdent(capture.Token()))
+		}
+		if len(idents) == 1 && len(targets) == 1 {
+			return ast.NewDeclaration(tok, idents[0], value)
+		}
+		if len(idents) == len(targets) {
+			return ast.NewMultiVar(tok, idents, value, true)
+		}
+	}
+	return ast.NewDestructure(tok, targets, rest, restIndex, value)
+}
+
+// parseDeclarationTargets parses the comma separated names and patterns on the
+// left side of a declaration, including an optional rest name like "*tail".
+// The current token is left on the end of the last target. Returns false if
+// an error occurred.
+func (p *Parser) parseDeclarationTargets() ([]ast.Expression, *ast.Ident, int, bool) {
+	var targets []ast.Expression
+	var rest *ast.Ident
+	restIndex := -1
+	for {
+		switch p.curToken.Type {
+		case token.IDENT:
+			targets = append(targets, ast.NewCapturePattern(p.curToken))
+		case token.LBRACKET, token.LBRACE:
+			target := p.parsePattern()
+			if target == nil {
+				return nil, nil, 0, false
+			}
+			targets = append(targets, target)
+		case token.ASTERISK:
+			if rest != nil {
+				p.setTokenError(p.curToken, "declaration has multiple rest names")
+				return nil, nil, 0, false
+			}
+			if !p.expectPeek("declaration statement", token.IDENT) {
+				return nil, nil, 0, false
+			}
+			rest = ast.NewIdent(p.curToken)
+			restIndex = len(targets)
+		default:
+			p.peekError("declaration statement", token.IDENT, p.curToken)
+			return nil, nil, 0, false
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			return targets, rest, restIndex, true
+		}
+		p.nextToken() // move to the comma
+		p.nextToken() // move to the next target
+	}
+}
+
+// isDestructuring reports whether the statement beginning at the current token
+// is a declaration that starts with a list or map pattern, like "[a, b] := x",
+// rather than an expression. The parser is rewound after looking ahead.
+func (p *Parser) isDestructuring() bool {
+	if p.err != nil {
+		return false
+	}
+	state := p.saveState()
+	defer p.restoreState(state)
+	_, _, _, ok := p.parseDeclarationTargets()
+	return ok && p.err == nil && p.peekTokenIs(token.DECLARE)
+}
+
+// parserState is a snapshot of the parser position, used to look ahead.
+type parserState struct {
+	lexer     lexer.Lexer
+	prevToken token.Token
+	curToken  token.Token
+	peekToken token.Token
+	err       ParserError
+}
+
+func (p *Parser) saveState() parserState {
+	return parserState{
+		lexer:     *p.l,
+		prevToken: p.prevToken,
+		curToken:  p.curToken,
+		peekToken: p.peekToken,
+		err:       p.err,
+	}
+}
+
+func (p *Parser) restoreState(state parserState) {
+	*p.l = state.lexer
+	p.prevToken = state.prevToken
+	p.curToken = state.curToken
+	p.peekToken = state.peekToken
+	p.err = state.err
+}
+
+func (p *Parser) parseStruct() ast.Node {
+	tok := p.curToken
+	if !p.expectPeek("struct statement", token.IDENT) {
+		return nil
+	}
+	name := ast.NewIdent(p.curToken)
+	if !p.expectPeek("struct statement", token.LBRACE) {
+		return nil
+	}
+	var fields []*ast.Ident
+	defaults := map[string]ast.Expression{}
+	seen := map[string]bool{}
+	for !p.peekPastNewlinesIs(token.RBRACE) {
+		if !p.expectPeek("struct statement", token.IDENT) {
+			return nil
+		}
+		field := ast.NewIdent(p.curToken)
+		if seen[field.Literal()] {
+			p.setTokenError(p.curToken, "duplicate struct field: %s", field.Literal())
+			return nil
+		}
+		seen[field.Literal()] = true
+		fields = append(fields, field)
+		// If there is "=expr" after the name then expr is a default value
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken() // move to the "="
+			p.nextToken() // move to the default value
+			value := p.parseExpression(LOWEST)
+			if value == nil {
+				return nil
+			}
+			defaults[field.Literal()] = value
+		}
+		// Fields are separated by commas or newlines
+		switch p.peekToken.Type {
+		case token.COMMA, token.SEMICOLON, token.NEWLINE:
+			p.nextToken()
+		case token.RBRACE:
+		default:
+			p.setTokenError(p.peekToken, "unexpected token after struct field: %s", p.peekToken.Literal)
+			return nil
+		}
+	}
+	p.nextToken() // move to the "}"
+	for p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
+		if err := p.nextToken(); err != nil {
+			return nil
+		}
 	}
-	return ast.NewDeclaration(tok, idents[0], value)
+	return ast.NewStruct(tok, name, fields, defaults)
 }
 
 func (p *Parser) parseConst() *ast.Const {
@@ -432,6 +611,50 @@ func (p *Parser) parseReturn() *ast.Control {
 	}
 }
 
+func (p *Parser) parseThrow() *ast.Throw {
+	throwToken := p.curToken
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		p.setTokenError(throwToken, "throw statement is missing a value")
+		return nil
+	}
+	switch p.peekToken.Type {
+	case token.SEMICOLON, token.NEWLINE, token.EOF:
+		p.nextToken()
+	case token.RBRACE:
+	default:
+		p.setTokenError(p.peekToken, "unexpected token %s following throw value", p.peekToken.Literal)
+		return nil
+	}
+	return ast.NewThrow(throwToken, value)
+}
+
+func (p *Parser) parseYield() *ast.Yield {
+	yieldToken := p.curToken
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) || p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		return ast.NewYield(yieldToken, nil)
+	}
+	if p.peekTokenIs(token.RBRACE) {
+		return ast.NewYield(yieldToken, nil)
+	}
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+	switch p.peekToken.Type {
+	case token.SEMICOLON, token.NEWLINE, token.EOF:
+		p.nextToken()
+	case token.RBRACE:
+	default:
+		p.setTokenError(p.peekToken, "unexpected token %s following yield value", p.peekToken.Literal)
+		return nil
+	}
+	return ast.NewYield(yieldToken, value)
+}
+
 func (p *Parser) parseBreak() *ast.Control {
 	stmt := ast.NewControl(p.curToken, nil)
 	for p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
@@ -541,6 +764,9 @@ func (p *Parser) parseIdent() ast.Node {
 		p.setTokenError(p.curToken, "invalid identifier")
 		return nil
 	}
+	if p.isStructLiteral() {
+		return p.parseStructLiteral(ast.NewIdent(p.curToken))
+	}
 	return ast.NewIdent(p.curToken)
 }
 
@@ -586,10 +812,29 @@ func (p *Parser) parseFloat() ast.Node {
 	return ast.NewFloat(tok, value)
 }
 
+func (p *Parser) parseImaginary() ast.Node {
+	tok, lit := p.curToken, p.curToken.Literal
+	value, err := strconv.ParseFloat(strings.TrimSuffix(lit, "i"), 64)
+	if err != nil {
+		p.setError(NewParserError(ErrorOpts{
+			ErrType:       "parse error",
+			Message:       fmt.Sprintf("invalid imaginary number: %s", lit),
+			File:          p.l.Filename(),
+			StartPosition: p.curToken.StartPosition,
+			EndPosition:   p.curToken.EndPosition,
+			SourceCode:    p.l.GetLineText(p.curToken),
+		}))
+		return nil
+	}
+	return ast.NewImaginary(tok, value)
+}
+
 func (p *Parser) parseSwitch() ast.Node {
 	switchToken := p.curToken
 	p.nextToken()
+	restore := p.allowStructLiterals(false)
 	switchValue := p.parseExpression(LOWEST)
+	restore()
 	if switchValue == nil {
 		return nil
 	}
@@ -614,15 +859,28 @@ func (p *Parser) parseSwitch() ast.Node {
 		caseToken := p.curToken
 		var isDefaultCase bool
 		var caseExprs []ast.Expression
+		var guard ast.Expression
 		if p.curTokenIs(token.DEFAULT) {
 			isDefaultCase = true
 		} else if p.curTokenIs(token.CASE) {
 			p.nextToken() // move to the token following "case"
-			caseExprs = append(caseExprs, p.parseExpression(LOWEST))
+			caseExprs = append(caseExprs, p.parseCaseValue())
 			for p.peekTokenIs(token.COMMA) {
 				p.nextToken() // move to the comma
 				p.nextToken() // move to the following expression
-				caseExprs = append(caseExprs, p.parseExpression(LOWEST))
+				caseExprs = append(caseExprs, p.parseCaseValue())
+			}
+			for _, expr := range caseExprs {
+				if expr == nil {
+					return nil
+				}
+			}
+			if p.peekTokenIs(token.IF) {
+				p.nextToken() // move to the "if"
+				p.nextToken() // move to the guard expression
+				if guard = p.parseExpression(LOWEST); guard == nil {
+					return nil
+				}
 			}
 		} else {
 			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
@@ -632,60 +890,336 @@ func (p *Parser) parseSwitch() ast.Node {
 			return nil
 		}
 		// Now we are at the block of code to be executed for this case
-		p.nextToken()
-		p.eatNewlines()
-		// An empty case statement is valid
-		if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
-			if isDefaultCase {
-				defaultCaseCount++
-				if defaultCaseCount > 1 {
-					p.setTokenError(caseToken, "switch statement has multiple default blocks")
-					return nil
-				}
-				cases = append(cases, ast.NewDefaultCase(caseToken, nil))
-			} else {
-				cases = append(cases, ast.NewCase(caseToken, caseExprs, nil))
+		block, ok := p.parseCaseBlock()
+		if !ok {
+			return nil
+		}
+		if isDefaultCase {
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+				return nil
 			}
-			continue
+			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewGuardedCase(caseToken, caseExprs, guard, block))
 		}
-		blockFirstToken := p.curToken
-		var blockStatements []ast.Node
-		for {
-			// Skip over newlines and semicolons
-			for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
-				if err := p.nextToken(); err != nil {
-					return nil
-				}
+	}
+	return ast.NewSwitch(switchToken, switchValue, cases)
+}
+
+// patternTypes are the type names that may be used in type patterns, as in
+// "case int(n):". Each matches values having the type of the same name.
+var patternTypes = map[string]bool{
+	"bigint":      true,
+	"bool":        true,
+	"buffer":      true,
+	"byte":        true,
+	"byte_slice":  true,
+	"chan":        true,
+	"complex":     true,
+	"decimal":     true,
+	"error":       true,
+	"float":       true,
+	"float_slice": true,
+	"int":         true,
+	"list":        true,
+	"map":         true,
+	"range":       true,
+	"regexp":      true,
+	"result":      true,
+	"set":         true,
+	"string":      true,
+	"struct":      true,
+	"tuple":       true,
+}
+
+// isTypePattern returns true if the current token begins a type pattern. The
+// type may be a keyword, as in "range(r)" or "struct(s)".
+func (p *Parser) isTypePattern() bool {
+	return patternTypes[p.curToken.Literal] && p.peekTokenIs(token.LPAREN)
+}
+
+// parseCaseValue parses one of the values given in a switch case. List and
+// map literals, type conversions like "int(n)", the "_" wildcard, and a name
+// followed by a guard are parsed as patterns. Anything else is an expression
+// that is compared with the switch value.
+func (p *Parser) parseCaseValue() ast.Expression {
+	switch {
+	case p.curTokenIs(token.LBRACKET), p.curTokenIs(token.LBRACE), p.isTypePattern():
+		return p.parsePattern()
+	case p.curTokenIs(token.IDENT):
+		if p.curToken.Literal == "_" || p.peekTokenIs(token.IF) {
+			return p.parsePattern()
+		}
+	}
+	return p.parseExpression(LOWEST)
+}
+
+// parsePattern parses a pattern beginning at the current token.
+func (p *Parser) parsePattern() ast.Expression {
+	if p.isTypePattern() {
+		return p.parseTypePattern()
+	}
+	switch p.curToken.Type {
+	case token.LBRACKET:
+		return p.parseListPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	case token.IDENT:
+		return ast.NewCapturePattern(p.curToken)
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NIL, token.MINUS:
+		tok := p.curToken
+		value := p.parseExpression(PREFIX)
+		if value == nil {
+			return nil
+		}
+		switch value := value.(type) {
+		case *ast.Int, *ast.Float, *ast.Bool, *ast.Nil:
+			return value
+		case *ast.String:
+			if value.Template() == nil {
+				return value
 			}
-			// Any of these tokens indicate the end of the current case
-			if p.curTokenIs(token.CASE) ||
-				p.curTokenIs(token.DEFAULT) ||
-				p.curTokenIs(token.RBRACE) ||
-				p.curTokenIs(token.EOF) {
-				break
+		case *ast.Prefix:
+			switch value.Right().(type) {
+			case *ast.Int, *ast.Float:
+				return value
+			}
+		}
+		p.setTokenError(tok, "invalid pattern: %s", value.String())
+		return nil
+	default:
+		p.setTokenError(p.curToken, "invalid pattern: %s", p.curToken.Literal)
+		return nil
+	}
+}
+
+func (p *Parser) parseListPattern() ast.Expression {
+	tok := p.curToken
+	var items []ast.Expression
+	var rest *ast.Ident
+	restIndex := -1
+	p.nextToken()
+	p.eatNewlines()
+	for !p.curTokenIs(token.RBRACKET) {
+		if p.curTokenIs(token.ASTERISK) {
+			if rest != nil {
+				p.setTokenError(p.curToken, "list pattern has multiple rest names")
+				return nil
 			}
-			// Parse one statement
-			if s := p.parseStatement(); s != nil {
-				blockStatements = append(blockStatements, s)
+			if !p.expectPeek("list pattern", token.IDENT) {
+				return nil
 			}
-			// Move to the token just beyond the statement
-			if err := p.nextToken(); err != nil {
+			rest = ast.NewIdent(p.curToken)
+			restIndex = len(items)
+		} else {
+			item := p.parsePattern()
+			if item == nil {
 				return nil
 			}
+			items = append(items, item)
 		}
-		block := ast.NewBlock(blockFirstToken, blockStatements)
-		if isDefaultCase {
+		p.nextToken()
+		p.eatNewlines()
+		if p.curTokenIs(token.COMMA) {
+			p.nextToken()
+			p.eatNewlines()
+		} else if !p.curTokenIs(token.RBRACKET) {
+			p.setTokenError(p.curToken, "unexpected %s in list pattern", p.curToken.Literal)
+			return nil
+		}
+	}
+	return ast.NewListPattern(tok, items, rest, restIndex)
+}
+
+func (p *Parser) parseMapPattern() ast.Expression {
+	tok := p.curToken
+	var keys []string
+	var values []ast.Expression
+	p.nextToken()
+	p.eatNewlines()
+	for !p.curTokenIs(token.RBRACE) {
+		keyToken := p.curToken
+		if !p.curTokenIs(token.STRING) && !p.curTokenIs(token.IDENT) {
+			p.setTokenError(keyToken, "invalid map pattern key: %s", keyToken.Literal)
+			return nil
+		}
+		keys = append(keys, keyToken.Literal)
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken() // move to the colon
+			p.nextToken() // move to the value pattern
+			value := p.parsePattern()
+			if value == nil {
+				return nil
+			}
+			values = append(values, value)
+		} else if p.curTokenIs(token.IDENT) {
+			// The shorthand {name} binds the "name" key to the same name
+			values = append(values, ast.NewCapturePattern(keyToken))
+		} else {
+			p.setTokenError(keyToken, "map pattern key %q is missing a pattern", keyToken.Literal)
+			return nil
+		}
+		p.nextToken()
+		p.eatNewlines()
+		if p.curTokenIs(token.COMMA) {
+			p.nextToken()
+			p.eatNewlines()
+		} else if !p.curTokenIs(token.RBRACE) {
+			p.setTokenError(p.curToken, "unexpected %s in map pattern", p.curToken.Literal)
+			return nil
+		}
+	}
+	return ast.NewMapPattern(tok, keys, values)
+}
+
+func (p *Parser) parseTypePattern() ast.Expression {
+	tok := p.curToken
+	p.nextToken() // move to the "("
+	p.nextToken() // move to the inner pattern
+	value := p.parsePattern()
+	if value == nil {
+		return nil
+	}
+	if !p.expectPeek("type pattern", token.RPAREN) {
+		return nil
+	}
+	return ast.NewTypePattern(tok, value)
+}
+
+// parseCaseBlock parses the statements of a switch or select case. The current
+// token must be the colon that ends the case label. Parsing stops at the next
+// case label or closing brace, which becomes the current token. The returned
+// block is nil if the case is empty, and the bool is false on error.
+func (p *Parser) parseCaseBlock() (*ast.Block, bool) {
+	p.nextToken()
+	p.eatNewlines()
+	// An empty case statement is valid
+	if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
+		return nil, true
+	}
+	blockFirstToken := p.curToken
+	var blockStatements []ast.Node
+	for {
+		// Skip over newlines and semicolons
+		for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
+			if err := p.nextToken(); err != nil {
+				return nil, false
+			}
+		}
+		// Any of these tokens indicate the end of the current case
+		if p.curTokenIs(token.CASE) ||
+			p.curTokenIs(token.DEFAULT) ||
+			p.curTokenIs(token.RBRACE) ||
+			p.curTokenIs(token.EOF) {
+			break
+		}
+		// Parse one statement
+		if s := p.parseStatement(); s != nil {
+			blockStatements = append(blockStatements, s)
+		}
+		// Move to the token just beyond the statement
+		if err := p.nextToken(); err != nil {
+			return nil, false
+		}
+	}
+	return ast.NewBlock(blockFirstToken, blockStatements), true
+}
+
+// Parses a select expression, where each case is a channel send or receive
+// operation, e.g. "case v := <-ch:" or "case ch <- v:".
+func (p *Parser) parseSelect() ast.Node {
+	selectToken := p.curToken
+	if !p.expectPeek("select statement", token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	p.eatNewlines()
+	var cases []*ast.SelectCase
+	var defaultCaseCount int
+	// Each time through this loop we process one case statement
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.setTokenError(p.prevToken, "unterminated select statement")
+			return nil
+		}
+		caseToken := p.curToken
+		var comm ast.Expression
+		var ident *ast.Ident
+		switch p.curToken.Type {
+		case token.DEFAULT:
 			defaultCaseCount++
 			if defaultCaseCount > 1 {
-				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+				p.setTokenError(caseToken, "select statement has multiple default blocks")
 				return nil
 			}
-			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+		case token.CASE:
+			p.nextToken() // move to the token following "case"
+			if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.DECLARE) {
+				ident = ast.NewIdent(p.curToken)
+				p.nextToken() // move to the ":="
+				p.nextToken() // move to the receive expression
+			}
+			if comm = p.parseExpression(LOWEST); comm == nil {
+				return nil
+			}
+			switch comm.(type) {
+			case *ast.Receive:
+			case *ast.Send:
+				if ident != nil {
+					p.setTokenError(caseToken, "cannot assign the result of a channel send")
+					return nil
+				}
+			default:
+				p.setTokenError(caseToken, "select case must be a channel send or receive")
+				return nil
+			}
+		default:
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek("select statement", token.COLON) {
+			return nil
+		}
+		block, ok := p.parseCaseBlock()
+		if !ok {
+			return nil
+		}
+		if comm == nil {
+			cases = append(cases, ast.NewDefaultSelectCase(caseToken, block))
 		} else {
-			cases = append(cases, ast.NewCase(caseToken, caseExprs, block))
+			cases = append(cases, ast.NewSelectCase(caseToken, comm, ident, block))
 		}
 	}
-	return ast.NewSwitch(switchToken, switchValue, cases)
+	return ast.NewSelect(selectToken, cases)
+}
+
+func (p *Parser) parseReceive() ast.Node {
+	arrowToken := p.curToken
+	p.nextToken()
+	channel := p.parseExpression(PREFIX)
+	if channel == nil {
+		p.setTokenError(p.curToken, "invalid receive expression")
+		return nil
+	}
+	return ast.NewReceive(arrowToken, channel)
+}
+
+func (p *Parser) parseSend(channelNode ast.Node) ast.Node {
+	channel, ok := channelNode.(ast.Expression)
+	if !ok {
+		p.setTokenError(p.curToken, "invalid send expression")
+		return nil
+	}
+	arrowToken := p.curToken
+	p.nextToken()
+	value := p.parseExpression(ASSIGN)
+	if value == nil {
+		p.setTokenError(p.curToken, "invalid send expression")
+		return nil
+	}
+	return ast.NewSend(arrowToken, channel, value)
 }
 
 func (p *Parser) parseImport() ast.Node {
@@ -738,7 +1272,45 @@ func (p *Parser) parseInfixExpr(leftNode ast.Node) ast.Node {
 		p.setTokenError(p.curToken, "invalid expression")
 		return nil
 	}
-	return ast.NewInfix(firstToken, left, firstToken.Literal, right)
+	return ast.NewInfix(firstToken, left, firstToken.Literal, right)
+}
+
+// parseQuestion parses either a ternary expression or the postfix "?" that
+// propagates error results, depending on whether an expression follows.
+func (p *Parser) parseQuestion(leftNode ast.Node) ast.Node {
+	if !p.endsPropagate(p.peekToken) {
+		return p.parseTernary(leftNode)
+	}
+	value, ok := leftNode.(ast.Expression)
+	if !ok {
+		p.setTokenError(p.curToken, "invalid ? expression")
+		return nil
+	}
+	return ast.NewPropagate(p.curToken, value)
+}
+
+// endsPropagate returns true if the given token, which follows a "?", can't
+// begin the true branch of a ternary expression. The "?" is then a postfix
+// operator.
+func (p *Parser) endsPropagate(t token.Token) bool {
+	switch t.Type {
+	case token.NEWLINE, token.SEMICOLON, token.EOF:
+		return true
+	}
+	_, ok := p.prefixParseFns[t.Type]
+	return !ok
+}
+
+// isPeekPropagate returns true if the peek token is a postfix "?". This looks
+// one token further ahead, and then rewinds the parser.
+func (p *Parser) isPeekPropagate() bool {
+	if !p.peekTokenIs(token.QUESTION) || p.err != nil {
+		return false
+	}
+	state := p.saveState()
+	defer p.restoreState(state)
+	p.nextToken()
+	return p.err == nil && p.endsPropagate(p.peekToken)
 }
 
 func (p *Parser) parseTernary(conditionNode ast.Node) ast.Node {
@@ -772,20 +1344,72 @@ func (p *Parser) parseTernary(conditionNode ast.Node) ast.Node {
 	return ast.NewTernary(firstToken, condition, ifTrue, ifFalse)
 }
 
+// parseGroupedExpr parses a parenthesized expression or a tuple. A tuple is
+// distinguished by a comma, so that "(1, 2)" and "(1,)" are tuples while
+// "(1)" is not. The empty tuple is written as "()".
 func (p *Parser) parseGroupedExpr() ast.Node {
+	defer p.allowStructLiterals(true)()
+	paren := p.curToken
+	if p.peekPastNewlinesIs(token.RPAREN) {
+		p.nextToken()
+		return ast.NewTuple(paren, []ast.Expression{})
+	}
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.COMMA) {
+		items := p.parseExprListFrom(exp, token.RPAREN)
+		if items == nil {
+			return nil
+		}
+		return ast.NewTuple(paren, items)
+	}
 	if !p.expectPeek("grouped expression", token.RPAREN) {
 		return nil
 	}
+	// Parentheses end an optional chain, so the compiler needs to see them
+	if isOptionalChain(exp) {
+		return ast.NewGroup(paren, exp)
+	}
 	return exp
 }
 
+// isOptionalChain returns true if the expression is a chain of attribute
+// accesses, method calls, and index operations that includes a nil-safe
+// operator, as in "a?.b.c".
+func isOptionalChain(node ast.Node) bool {
+	for {
+		switch n := node.(type) {
+		case *ast.GetAttr:
+			if n.IsOptional() {
+				return true
+			}
+			node = n.Object()
+		case *ast.ObjectCall:
+			if n.IsOptional() {
+				return true
+			}
+			node = n.Object()
+		case *ast.Index:
+			if n.IsOptional() {
+				return true
+			}
+			node = n.Left()
+		default:
+			return false
+		}
+	}
+}
+
 // Parses an entire if, else if, else block. Else-ifs are handled recursively.
 func (p *Parser) parseIf() ast.Node {
 	ifToken := p.curToken
 	p.nextToken() // move past the "if"
+	restore := p.allowStructLiterals(false)
 	cond := p.parseExpression(LOWEST)
+	restore()
 	if cond == nil {
 		return nil
 	}
@@ -818,19 +1442,25 @@ func (p *Parser) parseIf() ast.Node {
 }
 
 func (p *Parser) parseFor() ast.Node {
+	defer p.allowStructLiterals(false)()
 	forToken := p.curToken
+	p.nextToken()
+	forExprToken := p.curToken
+	var firstExpr ast.Node
+	if p.curTokenIs(token.IDENT) || p.curTokenIs(token.LBRACKET) || p.curTokenIs(token.LBRACE) {
+		firstExpr = p.parseForInTargets()
+	}
 	// Check for simple form: "for { ... }"
-	if p.peekTokenIs(token.LBRACE) {
-		p.nextToken()
+	if firstExpr == nil && p.curTokenIs(token.LBRACE) {
 		consequence := p.parseBlock()
 		if consequence == nil {
 			return nil
 		}
 		return ast.NewSimpleFor(forToken, consequence)
 	}
-	p.nextToken()
-	forExprToken := p.curToken
-	firstExpr := p.parseStatement()
+	if firstExpr == nil {
+		firstExpr = p.parseStatement()
+	}
 	if firstExpr == nil {
 		p.setTokenError(forExprToken, "invalid for loop expression")
 		p.nextToken()
@@ -877,7 +1507,137 @@ func (p *Parser) parseFor() ast.Node {
 	return ast.NewFor(forToken, condition, consequence, firstExpr, postExpr)
 }
 
+// parseForInTargets parses the condition of a loop like "for [a, b] in pairs"
+// or "for k, [a, b] in m", where items are unpacked by list or map patterns
+// or assigned to two loop variables. If the loop doesn't have one of these
+// forms, nil is returned and no tokens are consumed.
+func (p *Parser) parseForInTargets() ast.Node {
+	state := p.saveState()
+	target := p.parseForInTarget()
+	if target != nil && p.err == nil && p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		second := p.parseForInTarget()
+		if second != nil {
+			target = ast.NewLoopTargets(state.curToken, []ast.Expression{target, second})
+		} else {
+			target = nil
+		}
+	}
+	if target == nil || p.err != nil || !p.peekTokenIs(token.IN) {
+		p.restoreState(state)
+		return nil
+	}
+	// A single name is parsed as an ordinary "in" expression
+	if _, ok := target.(*ast.Ident); ok {
+		p.restoreState(state)
+		return nil
+	}
+	p.nextToken() // move to the "in"
+	return p.parseIn(target)
+}
+
+// parseForInTarget parses one target of a for-in loop, which is either a
+// name or a list or map pattern.
+func (p *Parser) parseForInTarget() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return ast.NewIdent(p.curToken)
+	case token.LBRACKET, token.LBRACE:
+		return p.parsePattern()
+	}
+	return nil
+}
+
+func (p *Parser) parseDefer() *ast.Defer {
+	deferToken := p.curToken
+	call := p.parseStatementCall("defer")
+	if call == nil {
+		return nil
+	}
+	return ast.NewDefer(deferToken, call)
+}
+
+func (p *Parser) parseGo() *ast.Go {
+	goToken := p.curToken
+	call := p.parseStatementCall("go")
+	if call == nil {
+		return nil
+	}
+	return ast.NewGo(goToken, call)
+}
+
+// parseStatementCall parses the function call that follows a keyword such as
+// "defer" or "go", which must be the current token.
+func (p *Parser) parseStatementCall(keyword string) ast.Expression {
+	keywordToken := p.curToken
+	p.nextToken()
+	call := p.parseExpression(LOWEST)
+	if call == nil {
+		return nil
+	}
+	switch call.(type) {
+	case *ast.Call, *ast.ObjectCall:
+	default:
+		p.setTokenError(keywordToken, "%s statement requires a function call", keyword)
+		return nil
+	}
+	switch p.peekToken.Type {
+	case token.SEMICOLON, token.NEWLINE, token.EOF:
+		p.nextToken()
+	case token.RBRACE:
+	default:
+		p.setTokenError(p.peekToken, "unexpected token %s following %s call", p.peekToken.Literal, keyword)
+		return nil
+	}
+	return call
+}
+
+// Parses a try statement with optional catch and finally blocks. If "try" is
+// not followed by a block, it is treated as a reference to the try builtin.
+func (p *Parser) parseTry() ast.Node {
+	tryToken := p.curToken
+	if !p.peekTokenIs(token.LBRACE) {
+		return ast.NewIdent(tryToken)
+	}
+	p.nextToken() // move to the "{"
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	var catchIdent *ast.Ident
+	var catchBlock, finallyBlock *ast.Block
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken() // move to the "catch"
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken() // move to the error variable name
+			catchIdent = ast.NewIdent(p.curToken)
+		}
+		if !p.expectPeek("try statement", token.LBRACE) {
+			return nil
+		}
+		if catchBlock = p.parseBlock(); catchBlock == nil {
+			return nil
+		}
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken() // move to the "finally"
+		if !p.expectPeek("try statement", token.LBRACE) {
+			return nil
+		}
+		if finallyBlock = p.parseBlock(); finallyBlock == nil {
+			return nil
+		}
+	}
+	if catchBlock == nil && finallyBlock == nil {
+		p.setTokenError(tryToken, "try statement requires a catch or finally block")
+		return nil
+	}
+	return ast.NewTry(tryToken, body, catchIdent, catchBlock, finallyBlock)
+}
+
 func (p *Parser) parseBlock() *ast.Block {
+	defer p.allowStructLiterals(true)()
 	lbrace := p.curToken
 	var statements []ast.Node
 	p.nextToken() // move past the "{"
@@ -910,51 +1670,148 @@ func (p *Parser) parseFunc() ast.Node {
 	if !p.expectPeek("function", token.LPAREN) { // Move to the "("
 		return nil
 	}
-	defaults, params := p.parseFuncParams()
+	params := p.parseFuncParams()
+	if params == nil {
+		return nil
+	}
 	if !p.expectPeek("function", token.LBRACE) { // move to the "{"
 		return nil
 	}
-	return ast.NewFunc(funcToken, ident, params, defaults, p.parseBlock())
+	return ast.NewFunc(funcToken, ident, params.params, params.defaults,
+		params.rest, params.kwargs, p.parseBlock())
+}
+
+// isMethod returns true if the current "func" token begins a method
+// declaration like "func (p Point) norm() {}". An anonymous function has at
+// most one identifier before each comma in its parameters.
+func (p *Parser) isMethod() bool {
+	if p.err != nil || !p.peekTokenIs(token.LPAREN) {
+		return false
+	}
+	state := p.saveState()
+	defer p.restoreState(state)
+	p.nextToken() // move to the "("
+	if !p.peekTokenIs(token.IDENT) {
+		return false
+	}
+	p.nextToken() // move to the receiver name
+	return p.peekTokenIs(token.IDENT)
+}
+
+func (p *Parser) parseMethod() ast.Node {
+	funcToken := p.curToken
+	p.nextToken() // move to the "("
+	p.nextToken() // move to the receiver name
+	receiver := ast.NewIdent(p.curToken)
+	p.nextToken() // move to the struct name
+	structName := ast.NewIdent(p.curToken)
+	if !p.expectPeek("method", token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek("method", token.IDENT) { // Move to the method name
+		return nil
+	}
+	name := ast.NewIdent(p.curToken)
+	if !p.expectPeek("method", token.LPAREN) {
+		return nil
+	}
+	params := p.parseFuncParams()
+	if params == nil {
+		return nil
+	}
+	if !p.expectPeek("method", token.LBRACE) { // move to the "{"
+		return nil
+	}
+	// The receiver is passed to the method as its first parameter
+	parameters := append([]*ast.Ident{receiver}, params.params...)
+	fn := ast.NewFunc(funcToken, nil, parameters, params.defaults,
+		params.rest, params.kwargs, p.parseBlock())
+	for p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
+		if err := p.nextToken(); err != nil {
+			return nil
+		}
+	}
+	return ast.NewMethod(funcToken, structName, name, fn)
+}
+
+// funcParams holds the parsed parameters of a function literal.
+type funcParams struct {
+	params   []*ast.Ident
+	defaults map[string]ast.Expression
+	rest     *ast.Ident
+	kwargs   *ast.Ident
 }
 
-func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident) {
+func (p *Parser) parseFuncParams() *funcParams {
+	result := &funcParams{defaults: map[string]ast.Expression{}}
 	// If the next parameter is ")", then there are no parameters
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return map[string]ast.Expression{}, nil
+		return result
 	}
-	defaults := map[string]ast.Expression{}
-	params := make([]*ast.Ident, 0)
+	result.params = make([]*ast.Ident, 0)
 	p.nextToken()
 	for !p.curTokenIs(token.RPAREN) { // Keep going until we find a ")"
 		if p.curTokenIs(token.EOF) {
 			p.setTokenError(p.prevToken, "unterminated function parameters")
-			return nil, nil
+			return nil
+		}
+		if result.kwargs != nil {
+			p.setTokenError(p.curToken, "the **%s parameter must be last", result.kwargs.String())
+			return nil
+		}
+		// Variadic parameters are written as "*rest" and "**kwargs"
+		variadic := p.curToken
+		isVariadic := variadic.Type == token.ASTERISK || variadic.Type == token.POW
+		if isVariadic {
+			if err := p.nextToken(); err != nil {
+				return nil
+			}
 		}
 		if !p.curTokenIs(token.IDENT) {
 			p.setTokenError(p.curToken, "expected an identifier (got %s)", p.curToken.Literal)
-			return nil, nil
+			return nil
 		}
 		ident := ast.NewIdent(p.curToken)
-		params = append(params, ident)
+		switch variadic.Type {
+		case token.ASTERISK:
+			if result.rest != nil {
+				p.setTokenError(variadic, "only one *rest parameter is allowed")
+				return nil
+			}
+			result.rest = ident
+		case token.POW:
+			result.kwargs = ident
+		default:
+			if result.rest != nil {
+				p.setTokenError(p.curToken, "parameter %s follows the *%s parameter",
+					ident.String(), result.rest.String())
+				return nil
+			}
+			result.params = append(result.params, ident)
+		}
 		if err := p.nextToken(); err != nil {
-			return nil, nil
+			return nil
 		}
 		// If there is "=expr" after the name then expr is a default value
 		if p.curTokenIs(token.ASSIGN) {
+			if isVariadic {
+				p.setTokenError(p.curToken, "variadic parameter %s cannot have a default value", ident.String())
+				return nil
+			}
 			p.nextToken()
 			expr := p.parseExpression(LOWEST)
 			if expr == nil {
-				return nil, nil
+				return nil
 			}
-			defaults[ident.String()] = expr
+			result.defaults[ident.String()] = expr
 			p.nextToken()
 		}
 		if p.curTokenIs(token.COMMA) {
 			p.nextToken()
 		}
 	}
-	return defaults, params
+	return result
 }
 
 func (p *Parser) parseString() ast.Node {
@@ -1001,16 +1858,11 @@ func (p *Parser) parseString() ast.Node {
 }
 
 func (p *Parser) parseList() ast.Node {
+	defer p.allowStructLiterals(true)()
 	bracket := p.curToken
-	items := p.parseExprList(token.RBRACKET)
-	return ast.NewList(bracket, items)
-}
-
-func (p *Parser) parseExprList(end token.Type) []ast.Expression {
-	list := make([]ast.Expression, 0)
-	if p.peekTokenIs(end) {
+	if p.peekTokenIs(token.RBRACKET) {
 		p.nextToken()
-		return list
+		return ast.NewList(bracket, []ast.Expression{})
 	}
 	for p.peekTokenIs(token.NEWLINE) {
 		if err := p.nextToken(); err != nil {
@@ -1018,12 +1870,92 @@ func (p *Parser) parseExprList(end token.Type) []ast.Expression {
 		}
 	}
 	p.nextToken()
-	expr := p.parseExpression(LOWEST)
-	if expr == nil {
+	first := p.parseExpression(LOWEST)
+	if first == nil {
 		p.setTokenError(p.curToken, "invalid syntax in list expression")
 		return nil
 	}
-	list = append(list, expr)
+	// A "for" after the first item indicates this is a list comprehension
+	if p.peekPastNewlinesIs(token.FOR) {
+		clauses := p.parseComprehensionClauses()
+		if clauses == nil || !p.expectPeekPastNewlines("list comprehension", token.RBRACKET) {
+			return nil
+		}
+		return ast.NewListComprehension(bracket, first, clauses)
+	}
+	items := p.parseExprListFrom(first, token.RBRACKET)
+	if items == nil {
+		return nil
+	}
+	return ast.NewList(bracket, items)
+}
+
+// parseComprehensionClauses parses one or more "for names in iterable" clauses
+// of a comprehension, each with an optional "if condition". The next token
+// must be the first "for".
+func (p *Parser) parseComprehensionClauses() []*ast.ComprehensionClause {
+	var clauses []*ast.ComprehensionClause
+	for p.peekPastNewlinesIs(token.FOR) {
+		p.nextToken() // move to the "for"
+		forToken := p.curToken
+		var names []*ast.Ident
+		for {
+			if !p.expectPeek("comprehension", token.IDENT) {
+				return nil
+			}
+			names = append(names, ast.NewIdent(p.curToken))
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // move to the ","
+		}
+		if len(names) > 2 {
+			p.setTokenError(forToken, "comprehension accepts at most two loop variables")
+			return nil
+		}
+		if !p.expectPeek("comprehension", token.IN) {
+			return nil
+		}
+		p.nextToken() // move to the iterable
+		iterable := p.parseExpression(LOWEST)
+		if iterable == nil {
+			return nil
+		}
+		var condition ast.Expression
+		if p.peekPastNewlinesIs(token.IF) {
+			p.nextToken() // move to the "if"
+			p.nextToken() // move to the condition
+			if condition = p.parseExpression(LOWEST); condition == nil {
+				return nil
+			}
+		}
+		clauses = append(clauses, ast.NewComprehensionClause(forToken, names, iterable, condition))
+	}
+	return clauses
+}
+
+// peekPastNewlinesIs advances across any newlines and then checks whether the
+// next token has the given type.
+func (p *Parser) peekPastNewlinesIs(t token.Type) bool {
+	for p.peekTokenIs(token.NEWLINE) {
+		if err := p.nextToken(); err != nil {
+			return false
+		}
+	}
+	return p.peekTokenIs(t)
+}
+
+// expectPeekPastNewlines is like expectPeek, but first advances across any
+// newlines.
+func (p *Parser) expectPeekPastNewlines(context string, t token.Type) bool {
+	p.peekPastNewlinesIs(t)
+	return p.expectPeek(context, t)
+}
+
+// parseExprListFrom parses the remainder of a comma-separated expression list
+// given its first expression, up to and including the end token.
+func (p *Parser) parseExprListFrom(first ast.Expression, end token.Type) []ast.Expression {
+	list := []ast.Expression{first}
 	for p.peekTokenIs(token.COMMA) {
 		// move to the comma
 		if err := p.nextToken(); err != nil {
@@ -1051,9 +1983,14 @@ func (p *Parser) parseExprList(end token.Type) []ast.Expression {
 	return list
 }
 
-func (p *Parser) parseNodeList(end token.Type) []ast.Node {
+// parseCallArguments parses the arguments of a function call, up to and
+// including the closing ")". Arguments may be positional expressions,
+// "...expr" spreads, or "name=expr" keyword arguments. Keyword arguments must
+// follow all positional arguments.
+func (p *Parser) parseCallArguments() []ast.Node {
+	defer p.allowStructLiterals(true)()
 	list := make([]ast.Node, 0)
-	if p.peekTokenIs(end) {
+	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
 		return list
 	}
@@ -1062,14 +1999,30 @@ func (p *Parser) parseNodeList(end token.Type) []ast.Node {
 			return nil
 		}
 	}
+	keywords := map[string]bool{}
 	p.nextToken()
-	expr := p.parseNode(LOWEST)
-	if expr == nil {
-		p.setTokenError(p.curToken, "invalid syntax in list expression")
-		return nil
-	}
-	list = append(list, expr)
-	for p.peekTokenIs(token.COMMA) {
+	for {
+		arg := p.parseCallArgument()
+		if arg == nil {
+			if p.err == nil {
+				p.setTokenError(p.curToken, "invalid syntax in call arguments")
+			}
+			return nil
+		}
+		if kwarg, ok := arg.(*ast.KeywordArg); ok {
+			if keywords[kwarg.Name()] {
+				p.setTokenError(kwarg.Token(), "duplicate keyword argument: %s", kwarg.Name())
+				return nil
+			}
+			keywords[kwarg.Name()] = true
+		} else if len(keywords) > 0 {
+			p.setTokenError(arg.Token(), "positional argument follows keyword argument")
+			return nil
+		}
+		list = append(list, arg)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		// move to the comma
 		if err := p.nextToken(); err != nil {
 			return nil
@@ -1081,22 +2034,45 @@ func (p *Parser) parseNodeList(end token.Type) []ast.Node {
 			}
 		}
 		// check if the list has ended after the newlines
-		if p.peekTokenIs(end) {
+		if p.peekTokenIs(token.RPAREN) {
 			break
 		}
-		// move to the next expression
+		// move to the next argument
 		if err := p.nextToken(); err != nil {
 			return nil
 		}
-		list = append(list, p.parseNode(LOWEST))
 	}
-	if !p.expectPeek("a node list", end) {
+	if !p.expectPeek("call arguments", token.RPAREN) {
 		return nil
 	}
 	return list
 }
 
+func (p *Parser) parseCallArgument() ast.Node {
+	switch {
+	case p.curTokenIs(token.ELLIPSIS):
+		spreadToken := p.curToken
+		p.nextToken() // move past the "..."
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		return ast.NewSpread(spreadToken, value)
+	case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN):
+		name := ast.NewIdent(p.curToken)
+		p.nextToken() // move to the "="
+		p.nextToken() // move to the value
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		return ast.NewKeywordArg(name, value)
+	}
+	return p.parseNode(LOWEST)
+}
+
 func (p *Parser) parseIndex(leftNode ast.Node) ast.Node {
+	defer p.allowStructLiterals(true)()
 	left, ok := leftNode.(ast.Expression)
 	if !ok {
 		p.setTokenError(p.curToken, "invalid index expression")
@@ -1118,8 +2094,24 @@ func (p *Parser) parseIndex(leftNode ast.Node) ast.Node {
 			p.nextToken() // move to the "]"
 			return ast.NewSlice(indexToken, left, firstIndex, nil)
 		}
-		p.nextToken() // move to the second index
-		secondIndex = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			p.nextToken() // move to the second index
+			secondIndex = p.parseExpression(LOWEST)
+		}
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // move to the second ":"
+		if !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken() // move to the step
+			step := p.parseExpression(LOWEST)
+			if step == nil {
+				return nil
+			}
+			if !p.expectPeek("an index expression", token.RBRACKET) {
+				return nil
+			}
+			return ast.NewStepSlice(indexToken, left, firstIndex, secondIndex, step)
+		}
 	}
 	if !p.expectPeek("an index expression", token.RBRACKET) {
 		return nil
@@ -1131,11 +2123,22 @@ func (p *Parser) parseAssign(name ast.Node) ast.Node {
 	operator := p.curToken
 	var ident *ast.Ident
 	var index *ast.Index
+	var attr *ast.GetAttr
 	switch node := name.(type) {
 	case *ast.Ident:
 		ident = node
 	case *ast.Index:
+		if node.IsOptional() {
+			p.setTokenError(operator, "cannot assign to an optional index expression")
+			return nil
+		}
 		index = node
+	case *ast.GetAttr:
+		if node.IsOptional() {
+			p.setTokenError(operator, "cannot assign to an optional attribute expression")
+			return nil
+		}
+		attr = node
 	default:
 		p.setTokenError(operator, "unexpected token for assignment: %s", name.Literal())
 		return nil
@@ -1157,6 +2160,9 @@ func (p *Parser) parseAssign(name ast.Node) ast.Node {
 	if index != nil {
 		return ast.NewAssignIndex(operator, index, right)
 	}
+	if attr != nil {
+		return ast.NewAssignAttr(operator, attr, right)
+	}
 	return ast.NewAssign(operator, ident, right)
 }
 
@@ -1167,7 +2173,7 @@ func (p *Parser) parseCall(functionNode ast.Node) ast.Node {
 		return nil
 	}
 	callToken := p.curToken
-	arguments := p.parseNodeList(token.RPAREN)
+	arguments := p.parseCallArguments()
 	if arguments == nil {
 		return nil
 	}
@@ -1228,6 +2234,14 @@ func (p *Parser) parseIn(leftNode ast.Node) ast.Node {
 
 func (p *Parser) parseRange() ast.Node {
 	rangeToken := p.curToken
+	// "range(...)" is a call to the range builtin. With whitespace before the
+	// parenthesis, as in "range (xs)", it remains a range over a
+	// parenthesized expression.
+	if p.peekTokenIs(token.LPAREN) &&
+		p.peekToken.StartPosition.Char == rangeToken.EndPosition.Char+1 {
+		rangeToken.Type = token.IDENT
+		return ast.NewIdent(rangeToken)
+	}
 	if err := p.nextToken(); err != nil {
 		return nil
 	}
@@ -1239,7 +2253,63 @@ func (p *Parser) parseRange() ast.Node {
 	return ast.NewRange(rangeToken, container)
 }
 
+// isStructLiteral returns true if the current name is followed by a struct
+// literal like "Point{x: 1}". Any name followed by "{" begins a struct
+// literal, except in the header of an if, for, or switch statement.
+func (p *Parser) isStructLiteral() bool {
+	return p.err == nil && !p.noStructLiterals && p.peekTokenIs(token.LBRACE)
+}
+
+// allowStructLiterals sets whether a name followed by "{" begins a struct
+// literal. The returned function restores the previous setting.
+func (p *Parser) allowStructLiterals(allow bool) func() {
+	prev := p.noStructLiterals
+	p.noStructLiterals = !allow
+	return func() { p.noStructLiterals = prev }
+}
+
+// parseStructLiteral parses the fields of a struct literal of the given type.
+// The current token is the last token of the type.
+func (p *Parser) parseStructLiteral(typ ast.Expression) ast.Node {
+	defer p.allowStructLiterals(true)()
+	nameToken := p.curToken
+	p.nextToken() // move to the "{"
+	var fields []*ast.Ident
+	var values []ast.Expression
+	seen := map[string]bool{}
+	for !p.peekPastNewlinesIs(token.RBRACE) {
+		if !p.expectPeek("struct literal", token.IDENT) {
+			return nil
+		}
+		field := ast.NewIdent(p.curToken)
+		if seen[field.Literal()] {
+			p.setTokenError(p.curToken, "duplicate field in struct literal: %s", field.Literal())
+			return nil
+		}
+		seen[field.Literal()] = true
+		if !p.expectPeek("struct literal", token.COLON) {
+			return nil
+		}
+		p.nextToken() // move to the value
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		fields = append(fields, field)
+		values = append(values, value)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // move to the ","
+	}
+	if !p.expectPeekPastNewlines("struct literal", token.RBRACE) {
+		return nil
+	}
+	return ast.NewStructLiteral(nameToken, typ, fields, values)
+}
+
 func (p *Parser) parseMapOrSet() ast.Node {
+	defer p.allowStructLiterals(true)()
 	firstToken := p.curToken
 	for p.peekTokenIs(token.NEWLINE) {
 		if err := p.nextToken(); err != nil {
@@ -1249,7 +2319,7 @@ func (p *Parser) parseMapOrSet() ast.Node {
 	// Empty {} turns into an empty map (not a set)
 	if p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		return ast.NewMap(firstToken, nil)
+		return ast.NewMap(firstToken, nil, nil)
 	}
 	p.nextToken() // move to the first key
 	firstKey := p.parseExpression(LOWEST)
@@ -1257,7 +2327,18 @@ func (p *Parser) parseMapOrSet() ast.Node {
 		p.nextToken() // move to the ":"
 		p.nextToken() // move to the first value
 		firstValue := p.parseExpression(LOWEST)
-		pairs := map[ast.Expression]ast.Expression{firstKey: firstValue}
+		if firstKey == nil || firstValue == nil {
+			return nil
+		}
+		if p.peekPastNewlinesIs(token.FOR) {
+			clauses := p.parseComprehensionClauses()
+			if clauses == nil || !p.expectPeekPastNewlines("map comprehension", token.RBRACE) {
+				return nil
+			}
+			return ast.NewMapComprehension(firstToken, firstKey, firstValue, clauses)
+		}
+		keys := []ast.Expression{firstKey}
+		values := []ast.Expression{firstValue}
 		for !p.peekTokenIs(token.RBRACE) {
 			if !p.expectPeek("map", token.COMMA) {
 				return nil
@@ -1274,7 +2355,8 @@ func (p *Parser) parseMapOrSet() ast.Node {
 			if key == nil || value == nil {
 				return nil
 			}
-			pairs[key] = value
+			keys = append(keys, key)
+			values = append(values, value)
 			if !p.peekTokenIs(token.COMMA) {
 				break
 			}
@@ -1285,8 +2367,18 @@ func (p *Parser) parseMapOrSet() ast.Node {
 		if !p.expectPeek("map", token.RBRACE) {
 			return nil
 		}
-		return ast.NewMap(firstToken, pairs)
+		return ast.NewMap(firstToken, keys, values)
 	} else { // This is a set
+		if firstKey == nil {
+			return nil
+		}
+		if p.peekPastNewlinesIs(token.FOR) {
+			clauses := p.parseComprehensionClauses()
+			if clauses == nil || !p.expectPeekPastNewlines("set comprehension", token.RBRACE) {
+				return nil
+			}
+			return ast.NewSetComprehension(firstToken, firstKey, clauses)
+		}
 		items := []ast.Expression{firstKey}
 		if p.peekTokenIs(token.COMMA) {
 			p.nextToken()
@@ -1346,10 +2438,14 @@ func (p *Parser) parseGetAttr(objNode ast.Node) ast.Node {
 	p.nextToken()
 	p.eatNewlines()
 	if !p.curTokenIs(token.IDENT) {
-		p.setTokenError(p.curToken, "expected an identifier after %q", ".")
+		p.setTokenError(p.curToken, "expected an identifier after %q", period.Literal)
 		return nil
 	}
-	name := p.parseIdent().(*ast.Ident)
+	name := ast.NewIdent(p.curToken)
+	// A struct type defined in a module, as in "geo.Point{x: 1}"
+	if period.Type == token.PERIOD && p.isStructLiteral() {
+		return p.parseStructLiteral(ast.NewGetAttr(period, obj, name))
+	}
 	if p.peekTokenIs(token.LPAREN) {
 		p.nextToken()
 		callNode := p.parseCall(name)
@@ -1363,6 +2459,31 @@ func (p *Parser) parseGetAttr(objNode ast.Node) ast.Node {
 	return ast.NewGetAttr(period, obj, name)
 }
 
+// parseOptionalChain parses a nil-safe attribute access, method call, or index
+// operation, as in "a?.b", "a?.b()", or "a?.[k]". The resulting node has the
+// "?." token, which marks it as optional.
+func (p *Parser) parseOptionalChain(objNode ast.Node) ast.Node {
+	if !p.peekTokenIs(token.LBRACKET) {
+		return p.parseGetAttr(objNode)
+	}
+	left, ok := objNode.(ast.Expression)
+	if !ok {
+		p.setTokenError(p.curToken, "invalid index expression")
+		return nil
+	}
+	optionalToken := p.curToken
+	p.nextToken() // move to the "["
+	p.nextToken() // move to the index
+	index := p.parseExpression(LOWEST)
+	if index == nil {
+		return nil
+	}
+	if !p.expectPeek("an index expression", token.RBRACKET) {
+		return nil
+	}
+	return ast.NewIndex(optionalToken, left, index)
+}
+
 // curTokenIs returns true if the current token has the given type.
 func (p *Parser) curTokenIs(t token.Type) bool {
 	return p.curToken.Type == t
@@ -1386,6 +2507,10 @@ func (p *Parser) expectPeek(context string, t token.Type) bool {
 
 // peekPrecedence returns the precedence of the next token.
 func (p *Parser) peekPrecedence() int {
+	// A postfix "?" binds as tightly as a call
+	if p.isPeekPropagate() {
+		return CALL
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
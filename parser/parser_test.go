@@ -295,6 +295,9 @@ func TestOperatorPrecedence(t *testing.T) {
 		{"a + add(b*c)+d", "((a + add((b * c))) + d)"},
 		{"a*[1,2,3,4][b*c]*d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a*b[2], b[1], 2 * [1,2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"a & b ^ c", "((a & b) ^ c)"},
+		{"a + b & c - d", "((a + b) & (c - d))"},
+		{"a & b == c", "((a & b) == c)"},
 	}
 	for _, tt := range tests {
 		program, err := Parse(context.Background(), tt.input)
@@ -408,6 +411,63 @@ func TestIndex(t *testing.T) {
 	testInfixExpression(t, indexExp.Index(), 1, "+", 1)
 }
 
+func TestSliceStep(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x[1:5:2]", "(x[1:5:2])"},
+		{"x[::-1]", "(x[::(-1)])"},
+		{"x[1::2]", "(x[1::2])"},
+		{"x[:3:]", "(x[:3])"},
+		{"x[::]", "(x[])"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		require.Len(t, program.Statements(), 1)
+		slice, ok := program.First().(*ast.Slice)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, slice.String())
+	}
+	program, err := Parse(context.Background(), "x[1:5:2]")
+	require.Nil(t, err)
+	slice := program.First().(*ast.Slice)
+	testIntegerLiteral(t, slice.FromIndex(), 1)
+	testIntegerLiteral(t, slice.ToIndex(), 5)
+	testIntegerLiteral(t, slice.Step(), 2)
+}
+
+func TestRangeCall(t *testing.T) {
+	program, err := Parse(context.Background(), "range(1, 10)")
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	call, ok := program.First().(*ast.Call)
+	require.True(t, ok)
+	testIdentifier(t, call.Function(), "range")
+	require.Len(t, call.Arguments(), 2)
+
+	// A range over the result of the call
+	program, err = Parse(context.Background(), "range range(3)")
+	require.Nil(t, err)
+	rangeNode, ok := program.First().(*ast.Range)
+	require.True(t, ok)
+	require.IsType(t, &ast.Call{}, rangeNode.Container())
+
+	// With a space, the parenthesized expression is ranged over
+	program, err = Parse(context.Background(), "range (xs)")
+	require.Nil(t, err)
+	rangeNode, ok = program.First().(*ast.Range)
+	require.True(t, ok)
+	require.Equal(t, "xs", rangeNode.Container().String())
+
+	program, err = Parse(context.Background(), "range (a, b, c)")
+	require.Nil(t, err)
+	rangeNode, ok = program.First().(*ast.Range)
+	require.True(t, ok)
+	require.IsType(t, &ast.Tuple{}, rangeNode.Container())
+}
+
 func TestParsingMap(t *testing.T) {
 	input := `{"one":1, "two":2, "three":3}`
 	program, err := Parse(context.Background(), input)
@@ -540,6 +600,76 @@ func TestSwitch(t *testing.T) {
 	require.Len(t, choice2.Expressions(), 0)
 }
 
+func TestSwitchPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		guard    string
+	}{
+		{`switch v { case [first, *rest]: 1 }`, "[first, *rest]", ""},
+		{`switch v { case [*init, last]: 1 }`, "[*init, last]", ""},
+		{`switch v { case {"type": "s3", "bucket": b}: 1 }`, `{"type": "s3", "bucket": b}`, ""},
+		{`switch v { case {name, age}: 1 }`, `{"name": name, "age": age}`, ""},
+		{`switch v { case int(n): 1 }`, "int(n)", ""},
+		{`switch v { case decimal(d): 1 }`, "decimal(d)", ""},
+		{`switch v { case range(r): 1 }`, "range(r)", ""},
+		{`switch v { case struct(s): 1 }`, "struct(s)", ""},
+		{`switch v { case [range(_), struct(_)]: 1 }`, "[range(_), struct(_)]", ""},
+		{`switch v { case [1, -2.5, nil, [x, _]]: 1 }`, "[1, (-2.5), nil, [x, _]]", ""},
+		{`switch v { case x if x > 10: 1 }`, "x", "(x > 10)"},
+		{`switch v { case _: 1 }`, "_", ""},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		switchExpr, ok := program.First().(*ast.Switch)
+		require.True(t, ok)
+		choice := switchExpr.Choices()[0]
+		require.Len(t, choice.Expressions(), 1)
+		pattern, ok := choice.Expressions()[0].(ast.Pattern)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, pattern.String())
+		if tt.guard == "" {
+			require.Nil(t, choice.Guard())
+		} else {
+			require.Equal(t, tt.guard, choice.Guard().String())
+		}
+	}
+}
+
+func TestSwitchCaseExpressions(t *testing.T) {
+	// Other case values are still compared with the switch value
+	program, err := Parse(context.Background(), `switch v { case x, len(y): 1; case 2 if ok: 2 }`)
+	require.Nil(t, err)
+	switchExpr, ok := program.First().(*ast.Switch)
+	require.True(t, ok)
+	for _, choice := range switchExpr.Choices() {
+		for _, expr := range choice.Expressions() {
+			_, isPattern := expr.(ast.Pattern)
+			require.False(t, isPattern, expr.String())
+		}
+	}
+	require.Equal(t, "ok", switchExpr.Choices()[1].Guard().String())
+}
+
+func TestSwitchPatternErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`switch v { case [*a, *b]: 1 }`, "parse error: list pattern has multiple rest names"},
+		{`switch v { case [a + 1]: 1 }`, "parse error: unexpected + in list pattern"},
+		{`switch v { case {"a"}: 1 }`, `parse error: map pattern key "a" is missing a pattern`},
+		{`switch v { case [f(x)]: 1 }`, "parse error: unexpected ( in list pattern"},
+		{"switch v { case [`a{x}`]: 1 }", "parse error: invalid pattern: a{x}"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
 func TestMultiDefault(t *testing.T) {
 	input := `
 switch val {
@@ -649,10 +779,10 @@ func TestCallExpression(t *testing.T) {
 	require.Equal(t, "foo", call.Function().String())
 	args := call.Arguments()
 	require.Len(t, args, 2)
-	arg0 := args[0].(*ast.Assign)
-	require.Equal(t, "a = 1", arg0.String())
-	arg1 := args[1].(*ast.Assign)
-	require.Equal(t, "b = 2", arg1.String())
+	arg0 := args[0].(*ast.KeywordArg)
+	require.Equal(t, "a=1", arg0.String())
+	arg1 := args[1].(*ast.KeywordArg)
+	require.Equal(t, "b=2", arg1.String())
 }
 
 func TestGetAttr(t *testing.T) {
@@ -734,6 +864,60 @@ func TestMultiVar(t *testing.T) {
 	require.Equal(t, "[1, 2]", expr.String())
 }
 
+func TestDestructure(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`a, [b, c] := x`, `a, [b, c] := x`},
+		{`[a, b] := x`, `[a, b] := x`},
+		{`{name, age} := person`, `{"name": name, "age": age} := person`},
+		{`head, *tail := items`, `head, *tail := items`},
+		{`*rest, last := items`, `*rest, last := items`},
+		{`{"a": [x, *y]} := m`, `{"a": [x, *y]} := m`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		require.Len(t, program.Statements(), 1)
+		node, ok := program.First().(*ast.Destructure)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, node.String())
+	}
+}
+
+func TestDestructureExpressions(t *testing.T) {
+	// List and map literals at the start of a statement are still expressions
+	tests := []string{
+		`[a, b]`,
+		`{a, b}`,
+		`[1, 2] in x`,
+		`{a: 1}[a]`,
+	}
+	for _, input := range tests {
+		program, err := Parse(context.Background(), input)
+		require.Nil(t, err, input)
+		require.Len(t, program.Statements(), 1)
+		_, ok := program.First().(ast.Expression)
+		require.True(t, ok, input)
+	}
+}
+
+func TestDestructureErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`a, *b, *c := x`, "parse error: declaration has multiple rest names"},
+		{`a, 1 := x`, "parse error: unexpected 1 while parsing declaration statement (expected identifier)"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
 func TestIn(t *testing.T) {
 	program, err := Parse(context.Background(), "x in [1, 2]")
 	require.Nil(t, err)
@@ -966,3 +1150,544 @@ func TestNakedReturns(t *testing.T) {
 		require.Equal(t, tt.expected, result.String())
 	}
 }
+
+func TestTry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { x } catch e { y }`, "try { x } catch e { y }"},
+		{`try { x } catch { y }`, "try { x } catch { y }"},
+		{`try { x } finally { z }`, "try { x } finally { z }"},
+		{`try { x } catch e { y } finally { z }`, "try { x } catch e { y } finally { z }"},
+		{`try(x, 1)`, "try(x, 1)"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		statements := program.Statements()
+		require.Len(t, statements, 1)
+		require.Equal(t, tt.expected, statements[0].String())
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	_, err := Parse(context.Background(), `try { x }`)
+	require.NotNil(t, err)
+	require.Equal(t, "parse error: try statement requires a catch or finally block", err.Error())
+}
+
+func TestThrow(t *testing.T) {
+	program, err := Parse(context.Background(), `throw "oops"`)
+	require.Nil(t, err)
+	statements := program.Statements()
+	require.Len(t, statements, 1)
+	throw, ok := statements[0].(*ast.Throw)
+	require.True(t, ok)
+	require.Equal(t, `throw "oops"`, throw.String())
+}
+
+func TestYield(t *testing.T) {
+	program, err := Parse(context.Background(), `func f() { yield x + 1; yield }`)
+	require.Nil(t, err)
+	fn, ok := program.First().(*ast.Func)
+	require.True(t, ok)
+	statements := fn.Body().Statements()
+	require.Len(t, statements, 2)
+	first, ok := statements[0].(*ast.Yield)
+	require.True(t, ok)
+	require.Equal(t, "yield (x + 1)", first.String())
+	second, ok := statements[1].(*ast.Yield)
+	require.True(t, ok)
+	require.Nil(t, second.Value())
+}
+
+func TestForIn(t *testing.T) {
+	program, err := Parse(context.Background(), `for x in items { print(x) }`)
+	require.Nil(t, err)
+	loop, ok := program.First().(*ast.For)
+	require.True(t, ok)
+	in, ok := loop.Condition().(*ast.In)
+	require.True(t, ok)
+	require.Equal(t, "x", in.Left().String())
+	require.Equal(t, "items", in.Right().String())
+}
+
+func TestForInPattern(t *testing.T) {
+	tests := []struct {
+		input string
+		left  string
+	}{
+		{`for [a, b] in pairs { print(a) }`, `[a, b]`},
+		{`for {name} in people { print(name) }`, `{"name": name}`},
+		{`for k, [a, b] in m { print(a) }`, `k, [a, b]`},
+		{`for [k, v], {name} in m { print(name) }`, `[k, v], {"name": name}`},
+		{`for k, v in m { print(k) }`, `k, v`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		loop, ok := program.First().(*ast.For)
+		require.True(t, ok)
+		in, ok := loop.Condition().(*ast.In)
+		require.True(t, ok)
+		require.Equal(t, tt.left, in.Left().String())
+	}
+	program, err := Parse(context.Background(), `for i, [a, b] := range pairs { print(a) }`)
+	require.Nil(t, err)
+	loop, ok := program.First().(*ast.For)
+	require.True(t, ok)
+	_, ok = loop.Condition().(*ast.Destructure)
+	require.True(t, ok)
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`defer f.close()`, `defer f.close()`},
+		{`defer cleanup(x, 1)`, `defer cleanup(x, 1)`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		statements := program.Statements()
+		require.Len(t, statements, 1)
+		deferStmt, ok := statements[0].(*ast.Defer)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, deferStmt.String())
+	}
+}
+
+func TestDeferErrors(t *testing.T) {
+	_, err := Parse(context.Background(), `defer x`)
+	require.NotNil(t, err)
+	require.Equal(t, "parse error: defer statement requires a function call", err.Error())
+}
+
+func TestGo(t *testing.T) {
+	program, err := Parse(context.Background(), `go worker(ch, 1)`)
+	require.Nil(t, err)
+	statements := program.Statements()
+	require.Len(t, statements, 1)
+	goStmt, ok := statements[0].(*ast.Go)
+	require.True(t, ok)
+	require.Equal(t, `go worker(ch, 1)`, goStmt.String())
+
+	_, err = Parse(context.Background(), `go x`)
+	require.NotNil(t, err)
+	require.Equal(t, "parse error: go statement requires a function call", err.Error())
+}
+
+func TestSendReceive(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ch <- 1 + 2`, `ch <- (1 + 2)`},
+		{`<-ch`, `<-ch`},
+		{`x := <-obj.ch`, `x := <-obj.ch`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		require.Equal(t, tt.expected, program.String())
+	}
+}
+
+func TestSelect(t *testing.T) {
+	input := `select {
+	case v := <-a:
+		v
+	case b <- 1:
+	default:
+		2
+	}`
+	program, err := Parse(context.Background(), input)
+	require.Nil(t, err)
+	statements := program.Statements()
+	require.Len(t, statements, 1)
+	sel, ok := statements[0].(*ast.Select)
+	require.True(t, ok)
+	cases := sel.Cases()
+	require.Len(t, cases, 3)
+	require.Equal(t, "v", cases[0].Ident().Literal())
+	_, ok = cases[0].Comm().(*ast.Receive)
+	require.True(t, ok)
+	require.Nil(t, cases[1].Ident())
+	_, ok = cases[1].Comm().(*ast.Send)
+	require.True(t, ok)
+	require.Nil(t, cases[1].Block())
+	require.True(t, cases[2].IsDefault())
+}
+
+func TestSelectErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`select { case x: 1 }`, "parse error: select case must be a channel send or receive"},
+		{`select { case v := a <- 1: 1 }`, "parse error: cannot assign the result of a channel send"},
+		{`select { default: 1; default: 2 }`, "parse error: select statement has multiple default blocks"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestVariadicFunc(t *testing.T) {
+	program, err := Parse(context.Background(), `func f(a, b=2, *rest, **opts) { a }`)
+	require.Nil(t, err)
+	fn, ok := program.First().(*ast.Func)
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, fn.ParameterNames())
+	require.Equal(t, "rest", fn.RestParameter().Literal())
+	require.Equal(t, "opts", fn.KwargsParameter().Literal())
+	require.Equal(t, "func f(a, b, *rest, **opts) { a }", fn.String())
+}
+
+func TestVariadicFuncErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`func f(**opts, a) {}`, "parse error: the **opts parameter must be last"},
+		{`func f(*a, *b) {}`, "parse error: only one *rest parameter is allowed"},
+		{`func f(*rest, a) {}`, "parse error: parameter a follows the *rest parameter"},
+		{`func f(*rest=1) {}`, "parse error: variadic parameter rest cannot have a default value"},
+		{`func f(*) {}`, "parse error: expected an identifier (got ))"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
+func TestCallSpreadAndKeywords(t *testing.T) {
+	program, err := Parse(context.Background(), `f(1, ...args, x=y)`)
+	require.Nil(t, err)
+	call, ok := program.First().(*ast.Call)
+	require.True(t, ok)
+	args := call.Arguments()
+	require.Len(t, args, 3)
+	spread, ok := args[1].(*ast.Spread)
+	require.True(t, ok)
+	require.Equal(t, "args", spread.Value().String())
+	kwarg, ok := args[2].(*ast.KeywordArg)
+	require.True(t, ok)
+	require.Equal(t, "x", kwarg.Name())
+	require.Equal(t, "y", kwarg.Value().String())
+	require.Equal(t, "f(1, ...args, x=y)", call.String())
+}
+
+func TestCallArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`f(a=1, 2)`, "parse error: positional argument follows keyword argument"},
+		{`f(a=1, ...b)`, "parse error: positional argument follows keyword argument"},
+		{`f(a=1, a=2)`, "parse error: duplicate keyword argument: a"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
+func TestComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[x * 2 for x in items if x > 0]`, `[(x * 2) for x in items if (x > 0)]`},
+		{`[x for x in a for y in b]`, `[x for x in a for y in b]`},
+		{"[\n  x\n  for x in items\n]", `[x for x in items]`},
+		{`{k: v for k, v in m}`, `{k: v for k, v in m}`},
+		{`{x for x in xs}`, `{x for x in xs}`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		require.Equal(t, tt.expected, program.First().String())
+	}
+	program, err := Parse(context.Background(), `{k: v for k, v in m}`)
+	require.Nil(t, err)
+	comp, ok := program.First().(*ast.MapComprehension)
+	require.True(t, ok)
+	require.Len(t, comp.Clauses(), 1)
+	require.Len(t, comp.Clauses()[0].Names(), 2)
+}
+
+func TestComprehensionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`[x for a, b, c in y]`, "parse error: comprehension accepts at most two loop variables"},
+		{`[x for 1 in y]`, "parse error: unexpected 1 while parsing comprehension (expected identifier)"},
+		{`[x for x y]`, "parse error: unexpected y while parsing comprehension (expected IN)"},
+		{`[x for x in y, 1]`, "parse error: unexpected , while parsing list comprehension (expected ])"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`a?.b`, `a?.b`},
+		{`a?.b.c`, `a?.b.c`},
+		{`a?.b(1)`, `a?.b(1)`},
+		{`a?.[0]`, `(a?.[0])`},
+		{`a?.[0]?.b`, `(a?.[0])?.b`},
+		{`(a?.b).c`, `(a?.b).c`},
+		{`(a?.b()).c`, `(a?.b()).c`},
+		{`(a.b).c`, `a.b.c`},
+		{`a ?? b`, `(a ?? b)`},
+		{`a ?? b ?? c`, `((a ?? b) ?? c)`},
+		{`a?.b ?? c == d`, `(a?.b ?? (c == d))`},
+		{`a ? b : c`, `(a ? b : c)`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		require.Equal(t, tt.expected, program.First().String(), tt.input)
+	}
+	program, err := Parse(context.Background(), `a?.b()`)
+	require.Nil(t, err)
+	call, ok := program.First().(*ast.ObjectCall)
+	require.True(t, ok)
+	require.True(t, call.IsOptional())
+}
+
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`r?`, `r?`},
+		{`f(x)?`, `f(x)?`},
+		{`x := r?`, `x := r?`},
+		{`1 + r?`, `(1 + r?)`},
+		{`r? * 2`, `(r? * 2)`},
+		{`[r?, 2]`, `[r?, 2]`},
+		{`f(r?)`, `f(r?)`},
+		{`a ? b : c`, `(a ? b : c)`},
+		{`a ? -1 : 1`, `(a ? (-1) : 1)`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		require.Equal(t, tt.expected, program.First().String(), tt.input)
+	}
+	program, err := Parse(context.Background(), `r?`)
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Propagate)
+	require.True(t, ok)
+	require.Equal(t, "r", node.Value().String())
+}
+
+func TestImaginary(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{`2i`, 2},
+		{`1.5i`, 1.5},
+		{`0i`, 0},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		node, ok := program.First().(*ast.Imaginary)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, node.Value(), tt.input)
+		require.Equal(t, tt.input, node.String())
+	}
+	program, err := Parse(context.Background(), `1 + 2i`)
+	require.Nil(t, err)
+	require.Equal(t, "(1 + 2i)", program.First().String())
+}
+
+func TestTuple(t *testing.T) {
+	tests := []struct {
+		input    string
+		count    int
+		expected string
+	}{
+		{`()`, 0, "()"},
+		{`(1,)`, 1, "(1,)"},
+		{`(1, "a")`, 2, `(1, "a")`},
+		{`(1, 2,)`, 2, "(1, 2)"},
+		{"(\n1,\n2,\n)", 2, "(1, 2)"},
+		{`(a + 1, [b])`, 2, "((a + 1), [b])"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		node, ok := program.First().(*ast.Tuple)
+		require.True(t, ok, tt.input)
+		require.Len(t, node.Items(), tt.count, tt.input)
+		require.Equal(t, tt.expected, node.String(), tt.input)
+	}
+	// Parentheses without a comma only group an expression
+	program, err := Parse(context.Background(), `(1)`)
+	require.Nil(t, err)
+	_, ok := program.First().(*ast.Int)
+	require.True(t, ok)
+}
+
+func TestStruct(t *testing.T) {
+	input := `struct Point {
+		x
+		y = 0, z = "a"
+	}`
+	program, err := Parse(context.Background(), input)
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Struct)
+	require.True(t, ok)
+	require.Equal(t, "Point", node.Name().Literal())
+	require.Len(t, node.Fields(), 3)
+	require.Len(t, node.Defaults(), 2)
+	require.Equal(t, `struct Point { x, y = 0, z = "a" }`, node.String())
+}
+
+func TestStructMethod(t *testing.T) {
+	program, err := Parse(context.Background(), `func (p Point) scale(k, n=1) { p.x * k }`)
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Method)
+	require.True(t, ok)
+	require.Equal(t, "Point", node.StructName().Literal())
+	require.Equal(t, "scale", node.Name().Literal())
+	require.Equal(t, []string{"p", "k", "n"}, node.Func().ParameterNames())
+	require.Equal(t, "func (p Point) scale(k, n) { (p.x * k) }", node.String())
+
+	// A function with a single parameter is not a method
+	program, err = Parse(context.Background(), `func (p) { p }`)
+	require.Nil(t, err)
+	_, ok = program.First().(*ast.Func)
+	require.True(t, ok)
+}
+
+func TestStructLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct P { x }; P{}`, "P{}"},
+		{`struct P { x }; P{x: 1}`, "P{x: 1}"},
+		{"P{\n\tx: 1,\n\ty: a + 1,\n}", "P{x: 1, y: (a + 1)}"},
+		{`P{x: {y: 2}}`, "P{x: {y:2}}"},
+		{`P{}`, "P{}"},
+		{`f(P{}, [Q{}])`, "f(P{}, [Q{}])"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		statements := program.Statements()
+		node, ok := statements[len(statements)-1].(ast.Expression)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, node.String(), tt.input)
+	}
+}
+
+func TestStructLiteralInHeader(t *testing.T) {
+	// A name followed by "{" in the header of an if, for, or switch statement
+	// is followed by the body of the statement
+	tests := []string{
+		`if x {}`,
+		`struct P { x }; if P { 1 }`,
+		`func f() { P := true; if P { return 1 } }`,
+		`for x in P {}`,
+		`for P {}`,
+		`for i := 0; i < P; i++ {}`,
+		`switch P { default: 1 }`,
+		`if x == (P{x: 1}) { 1 }`,
+		`if f(P{}) { 1 }`,
+		`for x in [P{}] { 1 }`,
+	}
+	for _, input := range tests {
+		_, err := Parse(context.Background(), input)
+		require.Nil(t, err, input)
+	}
+	program, err := Parse(context.Background(), `if x == (P{x: 1}) { 1 }`)
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.If)
+	require.True(t, ok)
+	require.Equal(t, "(x == P{x: 1})", node.Condition().String())
+}
+
+func TestStructLiteralAttr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`mod.P{x: 1}`, "mod.P{x: 1}"},
+		{`m := {}; m.y{a: 1}`, "m.y{a: 1}"},
+		{`a.b.C{x: 1, y: 2}`, "a.b.C{x: 1, y: 2}"},
+		{`geo.Point{}`, "geo.Point{}"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err, tt.input)
+		statements := program.Statements()
+		node, ok := statements[len(statements)-1].(*ast.StructLiteral)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, node.String(), tt.input)
+		_, ok = node.Type().(*ast.GetAttr)
+		require.True(t, ok, tt.input)
+	}
+}
+
+func TestAssignAttr(t *testing.T) {
+	program, err := Parse(context.Background(), `p.x += 1`)
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Assign)
+	require.True(t, ok)
+	require.Equal(t, "x", node.Attr().Name())
+	require.Equal(t, "p.x += 1", node.String())
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`struct { x }`, "parse error: unexpected { while parsing struct statement (expected identifier)"},
+		{`struct P { x y }`, "parse error: unexpected token after struct field: y"},
+		{`struct P { x, x }`, "parse error: duplicate struct field: x"},
+		{`struct P { x }; P{x: 1, x: 2}`, "parse error: duplicate field in struct literal: x"},
+		{`a?.b = 1`, "parse error: cannot assign to an optional attribute expression"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
+func TestOptionalChainingErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`a?.[0] = 1`, "parse error: cannot assign to an optional index expression"},
+		{`a?.1`, `parse error: expected an identifier after "?."`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
@@ -13,14 +13,19 @@ import (
 	"github.com/fatih/color"
 	"github.com/risor-io/risor"
 	"github.com/risor-io/risor/compiler"
+	"github.com/risor-io/risor/disasm"
 	"github.com/risor-io/risor/internal/cfg"
 	"github.com/risor-io/risor/object"
+	"github.com/risor-io/risor/parser"
 )
 
 const (
 	clearLine   = "\033[2K\r"
 	moveBack    = "\033[%dD"
 	moveForward = "\033[%dC"
+
+	// disCommand shows the bytecode of a function or of the code that follows
+	disCommand = ":dis"
 )
 
 func Run(ctx context.Context, options []risor.Option) error {
@@ -67,7 +72,8 @@ func Run(ctx context.Context, options []risor.Option) error {
 	for _, opt := range options {
 		opt(r)
 	}
-	c, err := compiler.New(compiler.WithBuiltins(r.Builtins))
+	main := &object.Code{Name: "main", Symbols: object.NewSymbolTable()}
+	c, err := compiler.New(compiler.WithBuiltins(r.Builtins), compiler.WithCode(main))
 	if err != nil {
 		return err
 	}
@@ -77,7 +83,11 @@ func Run(ctx context.Context, options []risor.Option) error {
 		switch key.Code {
 		case keys.Enter:
 			fmt.Printf("\n")
-			execute(ctx, accumulate, c, options)
+			if strings.HasPrefix(accumulate, disCommand) {
+				disassemble(ctx, strings.TrimSpace(accumulate[len(disCommand):]), main, r.Builtins)
+			} else {
+				execute(ctx, accumulate, c, options)
+			}
 			appendToHistory(accumulate)
 			history = append(history, accumulate)
 			historyIndex = len(history)
@@ -205,3 +215,87 @@ func execute(
 	}
 	return result, nil
 }
+
+// disassemble prints the bytecode listing for a ":dis" command. If the input
+// names a function, the function body is shown. Otherwise the input is
+// compiled, without running it, and the instructions it compiled to are shown.
+func disassemble(
+	ctx context.Context,
+	input string,
+	main *object.Code,
+	builtins map[string]object.Object,
+) {
+	if input == "" {
+		color.Red("usage: %s <function name or code>", disCommand)
+		return
+	}
+	if sym, ok := main.Symbols.Get(input); ok {
+		if fn, ok := main.Globals()[sym.Index].(*object.Function); ok {
+			if err := disasm.FprintFunction(os.Stdout, fn); err != nil {
+				color.Red(err.Error())
+			}
+			return
+		}
+	}
+	code, err := compileCopy(ctx, input, main, builtins)
+	if err != nil {
+		color.Red(err.Error())
+		return
+	}
+	instructions, err := disasm.Disassemble(code)
+	if err != nil {
+		color.Red(err.Error())
+		return
+	}
+	// Skip the instructions compiled from earlier input
+	offset := len(main.Instructions)
+	start := len(instructions)
+	for i, instr := range instructions {
+		if instr.Offset >= offset {
+			start = i
+			break
+		}
+	}
+	if err := disasm.FprintInstructions(os.Stdout, code, instructions[start:]); err != nil {
+		color.Red(err.Error())
+	}
+}
+
+// compileCopy compiles the input into a copy of the main code, so that the
+// input may refer to the variables of the session without changing it.
+func compileCopy(
+	ctx context.Context,
+	input string,
+	main *object.Code,
+	builtins map[string]object.Object,
+) (*object.Code, error) {
+	data, err := object.MarshalCode(main)
+	if err != nil {
+		return nil, err
+	}
+	// The values of the global variables are bound to the copy the same way
+	// as the builtins
+	values := map[string]object.Object{}
+	for name, value := range builtins {
+		values[name] = value
+	}
+	globals := main.Globals()
+	for _, name := range main.Symbols.InsertedNames() {
+		if sym, ok := main.Symbols.Get(name); ok && globals[sym.Index] != nil {
+			values[name] = globals[sym.Index]
+		}
+	}
+	code, err := object.UnmarshalCode(data, values)
+	if err != nil {
+		return nil, err
+	}
+	ast, err := parser.Parse(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	c, err := compiler.New(compiler.WithCode(code))
+	if err != nil {
+		return nil, err
+	}
+	return c.Compile(ast)
+}
@@ -10,6 +10,7 @@ import (
 	modAws "github.com/risor-io/risor/modules/aws"
 	modBase64 "github.com/risor-io/risor/modules/base64"
 	modBytes "github.com/risor-io/risor/modules/bytes"
+	modCmath "github.com/risor-io/risor/modules/cmath"
 	modFetch "github.com/risor-io/risor/modules/fetch"
 	modFmt "github.com/risor-io/risor/modules/fmt"
 	modHash "github.com/risor-io/risor/modules/hash"
@@ -19,6 +20,7 @@ import (
 	modOs "github.com/risor-io/risor/modules/os"
 	modPgx "github.com/risor-io/risor/modules/pgx"
 	modRand "github.com/risor-io/risor/modules/rand"
+	modRegexp "github.com/risor-io/risor/modules/regexp"
 	modStrconv "github.com/risor-io/risor/modules/strconv"
 	modStrings "github.com/risor-io/risor/modules/strings"
 	modTime "github.com/risor-io/risor/modules/time"
@@ -90,6 +92,30 @@ func WithCode(c *object.Code) Option {
 	}
 }
 
+// WithOptimizations enables the compiler optimizations described by
+// compiler.WithOptimizations.
+func WithOptimizations() Option {
+	return func(r *cfg.RisorConfig) {
+		r.Optimize = true
+	}
+}
+
+// WithMaxFrameDepth sets the maximum depth of nested function calls. Calls
+// that would exceed it fail with a "recursion depth exceeded" error.
+func WithMaxFrameDepth(depth int) Option {
+	return func(r *cfg.RisorConfig) {
+		r.MaxFrameDepth = depth
+	}
+}
+
+// WithMaxStackDepth sets the maximum number of values on the VM's value
+// stack. Evaluation that would exceed it fails with a "stack overflow" error.
+func WithMaxStackDepth(depth int) Option {
+	return func(r *cfg.RisorConfig) {
+		r.MaxStackDepth = depth
+	}
+}
+
 func WithInstructionOffset(offset int) Option {
 	return func(r *cfg.RisorConfig) {
 		r.Offset = offset
@@ -124,6 +150,9 @@ func Eval(ctx context.Context, source string, options ...Option) (object.Object,
 		if r.Main != nil {
 			compilerOpts = append(compilerOpts, compiler.WithCode(r.Main))
 		}
+		if r.Optimize {
+			compilerOpts = append(compilerOpts, compiler.WithOptimizations())
+		}
 		r.Compiler, err = compiler.New(compilerOpts...)
 		if err != nil {
 			return nil, err
@@ -151,6 +180,12 @@ func Eval(ctx context.Context, source string, options ...Option) (object.Object,
 	if r.Offset != 0 {
 		vmOpts = append(vmOpts, vm.WithInstructionOffset(r.Offset))
 	}
+	if r.MaxFrameDepth > 0 {
+		vmOpts = append(vmOpts, vm.WithMaxFrameDepth(r.MaxFrameDepth))
+	}
+	if r.MaxStackDepth > 0 {
+		vmOpts = append(vmOpts, vm.WithMaxStackDepth(r.MaxStackDepth))
+	}
 	machine := vm.New(main, vmOpts...)
 	if err := machine.Run(ctx); err != nil {
 		return nil, err
@@ -161,13 +196,37 @@ func Eval(ctx context.Context, source string, options ...Option) (object.Object,
 	return object.Nil, nil
 }
 
+// Compile parses and compiles the given source code without running it. The
+// builtins given in the options are available to the compiled code, and the
+// same builtins must be supplied to object.UnmarshalCode if the compiled code
+// is encoded with object.MarshalCode.
+func Compile(ctx context.Context, source string, options ...Option) (*object.Code, error) {
+	r := &cfg.RisorConfig{
+		Builtins: map[string]object.Object{},
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	ast, err := parser.Parse(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	compilerOpts := []compiler.Option{compiler.WithBuiltins(r.Builtins)}
+	if r.Optimize {
+		compilerOpts = append(compilerOpts, compiler.WithOptimizations())
+	}
+	return compiler.Compile(ast, compilerOpts...)
+}
+
 func defaultModules() map[string]object.Object {
 	result := map[string]object.Object{
 		"math":    modMath.Module(),
+		"cmath":   modCmath.Module(),
 		"json":    modJson.Module(),
 		"strings": modStrings.Module(),
 		"time":    modTime.Module(),
 		"rand":    modRand.Module(),
+		"regexp":  modRegexp.Module(),
 		"strconv": modStrconv.Module(),
 		"pgx":     modPgx.Module(),
 		"uuid":    modUuid.Module(),
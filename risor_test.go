@@ -18,6 +18,28 @@ func TestBasicUsage(t *testing.T) {
 	require.Equal(t, object.NewInt(2), result)
 }
 
+func TestWithMaxFrameDepth(t *testing.T) {
+	source := `func depth(n) { if n == 0 { return 0 }; return 1 + depth(n - 1) }; depth(50)`
+	result, err := Eval(context.Background(), source)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(50), result)
+
+	_, err = Eval(context.Background(), source, WithMaxFrameDepth(20))
+	require.NotNil(t, err)
+	require.Equal(t, "exec error: recursion depth exceeded (max depth 20)", err.Error())
+}
+
+func TestWithMaxStackDepth(t *testing.T) {
+	source := `[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]`
+	result, err := Eval(context.Background(), source)
+	require.Nil(t, err)
+	require.Len(t, result.(*object.List).Value(), 10)
+
+	_, err = Eval(context.Background(), source, WithMaxStackDepth(5))
+	require.NotNil(t, err)
+	require.Equal(t, "exec error: stack overflow (max depth 5)", err.Error())
+}
+
 func TestConfirmNoBuiltins(t *testing.T) {
 	type testCase struct {
 		input       string
@@ -78,10 +78,12 @@ func getTestCase(name string) (TestCase, error) {
 	return testCase, err
 }
 
-func execute(ctx context.Context, input string) (object.Object, error) {
-	return risor.Eval(ctx, input,
+func execute(ctx context.Context, input string, options ...risor.Option) (object.Object, error) {
+	options = append([]risor.Option{
 		risor.WithDefaultBuiltins(),
-		risor.WithDefaultModules())
+		risor.WithDefaultModules(),
+	}, options...)
+	return risor.Eval(ctx, input, options...)
 }
 
 func listTestFiles() []string {
@@ -99,6 +101,15 @@ func listTestFiles() []string {
 }
 
 func TestFiles(t *testing.T) {
+	testFiles(t)
+}
+
+// The results must be the same when the code is optimized
+func TestFilesOptimized(t *testing.T) {
+	testFiles(t, risor.WithOptimizations())
+}
+
+func testFiles(t *testing.T, options ...risor.Option) {
 	only := "" // test-2022-12-03-08-12
 	for _, name := range listTestFiles() {
 		if !strings.HasSuffix(name, ".tm") {
@@ -111,7 +122,7 @@ func TestFiles(t *testing.T) {
 			tc, err := getTestCase(name)
 			require.Nil(t, err)
 			ctx := context.Background()
-			result, err := execute(ctx, tc.Text)
+			result, err := execute(ctx, tc.Text, options...)
 			expectedType := object.Type(tc.ExpectedType)
 
 			if tc.ExpectedValue != "" {
@@ -34,92 +34,117 @@ type Token struct {
 
 // Token types
 const (
-	AND             = "&&"
-	ASSIGN          = "="
-	ASTERISK        = "*"
-	ASTERISK_EQUALS = "*="
-	BACKTICK        = "`"
-	FSTRING         = "'"
-	BANG            = "!"
-	CASE            = "case"
-	COLON           = ":"
-	COMMA           = ","
-	CONST           = "CONST"
-	DECLARE         = ":="
-	DEFAULT         = "DEFAULT"
-	FUNC            = "FUNC"
-	ELSE            = "ELSE"
-	EOF             = "EOF"
-	EQ              = "=="
-	FALSE           = "FALSE"
-	FLOAT           = "FLOAT"
-	FOR             = "FOR"
-	GT              = ">"
-	GT_GT           = ">>"
-	GT_EQUALS       = ">="
-	IDENT           = "IDENT"
-	IF              = "IF"
-	ILLEGAL         = "ILLEGAL"
-	INT             = "INT"
-	LBRACE          = "{"
-	LBRACKET        = "["
-	LPAREN          = "("
-	LT              = "<"
-	LT_LT           = "<<"
-	LT_EQUALS       = "<="
-	MINUS           = "-"
-	MINUS_EQUALS    = "-="
-	MINUS_MINUS     = "--"
-	MOD             = "%"
-	NOT_EQ          = "!="
-	NIL             = "nil"
-	PIPE            = "|"
-	OR              = "||"
-	PERIOD          = "."
-	PLUS            = "+"
-	PLUS_EQUALS     = "+="
-	PLUS_PLUS       = "++"
-	POW             = "**"
-	QUESTION        = "?"
-	RBRACE          = "}"
-	RBRACKET        = "]"
-	RETURN          = "RETURN"
-	RPAREN          = ")"
-	SEMICOLON       = ";"
-	SLASH           = "/"
-	SLASH_EQUALS    = "/="
-	STRING          = "STRING"
-	SWITCH          = "switch"
-	TRUE            = "TRUE"
-	NEWLINE         = "EOL"
-	IMPORT          = "IMPORT"
-	BREAK           = "BREAK"
-	CONTINUE        = "CONTINUE"
-	VAR             = "VAR"
-	IN              = "IN"
-	RANGE           = "RANGE"
+	AND               = "&&"
+	ARROW             = "<-"
+	ASSIGN            = "="
+	AMPERSAND         = "&"
+	ASTERISK          = "*"
+	ASTERISK_EQUALS   = "*="
+	BACKTICK          = "`"
+	FSTRING           = "'"
+	BANG              = "!"
+	CARET             = "^"
+	CASE              = "case"
+	CATCH             = "CATCH"
+	COLON             = ":"
+	COMMA             = ","
+	CONST             = "CONST"
+	DECLARE           = ":="
+	DEFAULT           = "DEFAULT"
+	DEFER             = "DEFER"
+	FUNC              = "FUNC"
+	ELLIPSIS          = "..."
+	ELSE              = "ELSE"
+	EOF               = "EOF"
+	EQ                = "=="
+	FALSE             = "FALSE"
+	FINALLY           = "FINALLY"
+	FLOAT             = "FLOAT"
+	FOR               = "FOR"
+	GO                = "GO"
+	GT                = ">"
+	GT_GT             = ">>"
+	GT_EQUALS         = ">="
+	IDENT             = "IDENT"
+	IF                = "IF"
+	ILLEGAL           = "ILLEGAL"
+	IMAGINARY         = "IMAGINARY"
+	INT               = "INT"
+	LBRACE            = "{"
+	LBRACKET          = "["
+	LPAREN            = "("
+	LT                = "<"
+	LT_LT             = "<<"
+	LT_EQUALS         = "<="
+	MINUS             = "-"
+	MINUS_EQUALS      = "-="
+	MINUS_MINUS       = "--"
+	MOD               = "%"
+	NOT_EQ            = "!="
+	NIL               = "nil"
+	PIPE              = "|"
+	OR                = "||"
+	PERIOD            = "."
+	PLUS              = "+"
+	PLUS_EQUALS       = "+="
+	PLUS_PLUS         = "++"
+	POW               = "**"
+	QUESTION          = "?"
+	QUESTION_PERIOD   = "?."
+	QUESTION_QUESTION = "??"
+	RBRACE            = "}"
+	RBRACKET          = "]"
+	RETURN            = "RETURN"
+	RPAREN            = ")"
+	SELECT            = "SELECT"
+	SEMICOLON         = ";"
+	SLASH             = "/"
+	SLASH_EQUALS      = "/="
+	STRING            = "STRING"
+	STRUCT            = "STRUCT"
+	SWITCH            = "switch"
+	THROW             = "THROW"
+	TRUE              = "TRUE"
+	TRY               = "TRY"
+	YIELD             = "YIELD"
+	NEWLINE           = "EOL"
+	IMPORT            = "IMPORT"
+	BREAK             = "BREAK"
+	CONTINUE          = "CONTINUE"
+	VAR               = "VAR"
+	IN                = "IN"
+	RANGE             = "RANGE"
 )
 
 // Reserved keywords
 var keywords = map[string]Type{
 	"break":    BREAK,
 	"case":     CASE,
+	"catch":    CATCH,
 	"const":    CONST,
 	"continue": CONTINUE,
 	"default":  DEFAULT,
+	"defer":    DEFER,
 	"else":     ELSE,
 	"false":    FALSE,
+	"finally":  FINALLY,
 	"for":      FOR,
 	"func":     FUNC,
+	"go":       GO,
 	"if":       IF,
 	"import":   IMPORT,
 	"in":       IN,
 	"nil":      NIL,
 	"range":    RANGE,
 	"return":   RETURN,
+	"select":   SELECT,
+	"struct":   STRUCT,
 	"switch":   SWITCH,
+	"throw":    THROW,
 	"true":     TRUE,
+	"try":      TRY,
 	"var":      VAR,
+	"yield":    YIELD,
 }
 
 // LookupIdentifier used to determinate whether identifier is keyword nor not
@@ -0,0 +1,80 @@
+package vm
+
+import (
+	"github.com/risor-io/risor/object"
+)
+
+// attrCache is the inline cache of a LoadAttr instruction. It holds the
+// loader for the attribute resolved on the last kind of object the
+// instruction loaded the attribute from.
+type attrCache struct {
+	key    interface{}
+	loader object.AttrLoader
+}
+
+// codeCache holds the inline caches for the instructions of a code object,
+// along with the globals the code refers to. Caches belong to a single VM,
+// so they may be used without synchronization.
+type codeCache struct {
+	code    *object.Code
+	attrs   []attrCache
+	globals []object.Object
+}
+
+// codeCache returns the caches for the active code.
+func (vm *VirtualMachine) codeCache() *codeCache {
+	if cache := vm.cache; cache != nil && cache.code == vm.activeCode {
+		return cache
+	}
+	cache, ok := vm.caches[vm.activeCode]
+	if !ok {
+		cache = &codeCache{
+			code:    vm.activeCode,
+			globals: vm.activeCode.Globals(),
+		}
+		vm.caches[vm.activeCode] = cache
+	}
+	vm.cache = cache
+	return cache
+}
+
+// resetCaches discards all caches. Globals may be added to the code between
+// runs, for example by the REPL, so the caches are reset for each run.
+func (vm *VirtualMachine) resetCaches() {
+	vm.caches = map[*object.Code]*codeCache{}
+	vm.cache = nil
+}
+
+// globals returns the globals of the active code.
+func (vm *VirtualMachine) globals() []object.Object {
+	return vm.codeCache().globals
+}
+
+// loadAttr returns the named attribute of the object, for the LoadAttr
+// instruction at the given offset. If the object supports caching, the
+// attribute is resolved once and then loaded directly for as long as the
+// instruction sees objects with the same cache key.
+func (vm *VirtualMachine) loadAttr(obj object.Object, name string, offset int) (object.Object, bool) {
+	cacher, ok := obj.(object.AttrCacher)
+	if !ok {
+		return obj.GetAttr(name)
+	}
+	cache := vm.codeCache()
+	if offset >= len(cache.attrs) {
+		attrs := make([]attrCache, len(vm.activeCode.Instructions))
+		copy(attrs, cache.attrs)
+		cache.attrs = attrs
+	}
+	entry := &cache.attrs[offset]
+	key := cacher.AttrCacheKey()
+	if entry.loader != nil && entry.key == key {
+		return entry.loader(obj), true
+	}
+	loader, ok := cacher.ResolveAttrLoader(name)
+	if !ok {
+		return obj.GetAttr(name)
+	}
+	entry.key = key
+	entry.loader = loader
+	return loader(obj), true
+}
@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/risor-io/risor/token"
+)
+
+// StackFrame describes one entry in the Risor call stack at the point where
+// a runtime error was raised.
+type StackFrame struct {
+	// Name of the function or code object executing in this frame
+	Function string
+	// Source position of the instruction executing in this frame
+	Position token.Position
+	// Indicates whether the source position is known
+	HasPosition bool
+}
+
+// String returns the frame as "function (file:line:column)".
+func (f StackFrame) String() string {
+	if !f.HasPosition {
+		return fmt.Sprintf("%s (unknown location)", f.Function)
+	}
+	file := f.Position.File
+	if file == "" {
+		file = "<input>"
+	}
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, file,
+		f.Position.LineNumber(), f.Position.ColumnNumber())
+}
+
+// RuntimeError is an error raised while evaluating compiled Risor code. It
+// wraps the underlying error with the Risor call stack at the point where the
+// error was raised, ordered from the outermost frame to the innermost.
+type RuntimeError struct {
+	cause error
+	stack []StackFrame
+}
+
+// NewRuntimeError returns a new RuntimeError wrapping the given error.
+func NewRuntimeError(cause error, stack []StackFrame) *RuntimeError {
+	return &RuntimeError{cause: cause, stack: stack}
+}
+
+func (e *RuntimeError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *RuntimeError) FriendlyErrorMessage() string {
+	var msg bytes.Buffer
+	msg.WriteString(e.Error())
+	if len(e.stack) == 0 {
+		return msg.String()
+	}
+	msg.WriteString("\n\n")
+	if frame := e.stack[len(e.stack)-1]; frame.HasPosition {
+		pos := frame.Position
+		friendlyLoc := fmt.Sprintf("line %d, column %d",
+			pos.LineNumber(), pos.ColumnNumber())
+		if pos.File != "" {
+			msg.WriteString(fmt.Sprintf("location: %s:%d:%d (%s)\n",
+				pos.File, pos.LineNumber(), pos.ColumnNumber(), friendlyLoc))
+		} else {
+			msg.WriteString(fmt.Sprintf("location: %s\n", friendlyLoc))
+		}
+	}
+	msg.WriteString("stack (most recent call last):")
+	for i := 0; i < len(e.stack); {
+		line := e.stack[i].String()
+		// Runs of identical frames, as left by deep recursion, are collapsed
+		count := 1
+		for i+count < len(e.stack) && e.stack[i+count].String() == line {
+			count++
+		}
+		for j := 0; j < count && j < maxRepeatedFrames; j++ {
+			msg.WriteString("\n  " + line)
+		}
+		if count > maxRepeatedFrames {
+			msg.WriteString(fmt.Sprintf("\n  [previous frame repeated %d more times]",
+				count-maxRepeatedFrames))
+		}
+		i += count
+	}
+	return msg.String()
+}
+
+// maxRepeatedFrames is the number of times a frame is repeated in a friendly
+// error message before the remaining repetitions are collapsed.
+const maxRepeatedFrames = 3
+
+// Stack returns the Risor call stack at the point the error was raised.
+func (e *RuntimeError) Stack() []StackFrame {
+	return e.stack
+}
+
+// Position returns the source position where the error was raised, if known.
+func (e *RuntimeError) Position() (token.Position, bool) {
+	if len(e.stack) == 0 {
+		return token.Position{}, false
+	}
+	frame := e.stack[len(e.stack)-1]
+	return frame.Position, frame.HasPosition
+}
+
+func (e *RuntimeError) Cause() error {
+	return e.cause
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.cause
+}
@@ -6,8 +6,21 @@ import (
 
 const DefaultFrameLocals = 8
 
+// maxTailCalls limits the number of tail calls recorded by a frame, for use
+// in stack traces. Only the most recent tail calls are recorded.
+const maxTailCalls = 8
+
+// tailCall records a function that was replaced in its frame by a tail call,
+// along with the instruction pointer of the tail call.
+type tailCall struct {
+	code *object.Code
+	ip   int
+}
+
 type Frame struct {
 	returnAddr     int
+	callSite       int
+	stackBase      int
 	localsCount    uint16
 	fn             *object.Function
 	code           *object.Code
@@ -15,6 +28,8 @@ type Frame struct {
 	locals         []object.Object
 	extendedLocals []object.Object
 	capturedLocals []object.Object
+	defers         []*object.Partial
+	tailCalls      []tailCall
 }
 
 func (f *Frame) ActivateCode(code *object.Code) {
@@ -23,6 +38,8 @@ func (f *Frame) ActivateCode(code *object.Code) {
 	f.returnAddr = 0
 	f.localsCount = code.Symbols.Size()
 	f.capturedLocals = nil
+	f.defers = nil
+	f.tailCalls = f.tailCalls[:0]
 	for i := 0; i < DefaultFrameLocals; i++ {
 		f.storage[i] = nil
 	}
@@ -44,6 +61,7 @@ func (f *Frame) ActivateFunction(fn *object.Function, returnAddr int, localValue
 	f.fn = fn
 	// Save the instruction pointer of the caller
 	f.returnAddr = returnAddr
+	f.callSite = returnAddr
 	// Initialize any local variables that were provided.
 	// Note the copy builtin is slower than this loop.
 	for i := 0; i < len(localValues); i++ {
@@ -53,6 +71,44 @@ func (f *Frame) ActivateFunction(fn *object.Function, returnAddr int, localValue
 
 func (f *Frame) SetReturnAddr(addr int) {
 	f.returnAddr = addr
+	f.callSite = addr
+}
+
+// SetStackBase sets the stack pointer of the caller when the frame was
+// activated. Values above it on the stack belong to this frame.
+func (f *Frame) SetStackBase(sp int) {
+	f.stackBase = sp
+}
+
+// SetCallSite sets the instruction pointer of the caller, for use in stack
+// traces. This is needed when the frame returns to Go code via StopSignal
+// rather than to the caller's next instruction.
+func (f *Frame) SetCallSite(addr int) {
+	f.callSite = addr
+}
+
+// ActivateTailCall activates the function in place of the function running
+// in this frame, which is recorded for use in stack traces. The return
+// address of the frame is kept, so the function returns to the caller of the
+// function it replaced. The ip is the instruction pointer of the tail call.
+// The stack base of the frame is kept as well.
+func (f *Frame) ActivateTailCall(fn *object.Function, ip int, localValues []object.Object) {
+	tailCalls := f.tailCalls
+	if len(tailCalls) == maxTailCalls {
+		copy(tailCalls, tailCalls[1:])
+		tailCalls = tailCalls[:maxTailCalls-1]
+	}
+	tailCalls = append(tailCalls, tailCall{code: f.code, ip: ip})
+	returnAddr, callSite := f.returnAddr, f.callSite
+	f.ActivateFunction(fn, returnAddr, localValues)
+	f.callSite = callSite
+	f.tailCalls = tailCalls
+}
+
+// Defer adds a call to the frame's defer stack, to be made when the frame
+// exits.
+func (f *Frame) Defer(call *object.Partial) {
+	f.defers = append(f.defers, call)
 }
 
 func (f *Frame) Locals() []object.Object {
@@ -0,0 +1,174 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/risor-io/risor/object"
+)
+
+// goroutineGroup tracks the goroutines started during one run of a VM,
+// including goroutines started by other goroutines. The goroutines share the
+// global variables of the run and are stopped when the run finishes. The
+// first goroutine to fail halts the whole run.
+type goroutineGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Closed when a goroutine fails
+	failed chan struct{}
+	once   sync.Once
+	err    error
+
+	// Set to 1 when the first goroutine starts. From then on, access to
+	// global and free variables is serialized using mu.
+	active int32
+	mu     sync.RWMutex
+}
+
+func newGoroutineGroup(ctx context.Context) *goroutineGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &goroutineGroup{
+		ctx:    ctx,
+		cancel: cancel,
+		failed: make(chan struct{}),
+	}
+}
+
+// fail records the error of a goroutine and stops the run. Only the first
+// error is kept.
+func (g *goroutineGroup) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		close(g.failed)
+		g.cancel()
+	})
+}
+
+// Err returns the error of the first goroutine that failed, if any.
+func (g *goroutineGroup) Err() error {
+	select {
+	case <-g.failed:
+		return g.err
+	default:
+		return nil
+	}
+}
+
+// stop cancels the context of the goroutines in the group.
+func (g *goroutineGroup) stop() {
+	g.cancel()
+}
+
+// isActive returns true if goroutines have been started, in which case
+// variables shared between VMs must be accessed while holding mu.
+func (g *goroutineGroup) isActive() bool {
+	return atomic.LoadInt32(&g.active) == 1
+}
+
+// goroutine calls the given function on a new VM in a new goroutine. The new
+// VM shares the compiled code, globals, importer, and limits of this VM. It
+// runs until the function returns or the run that started it finishes. An
+// error that is not caught within the goroutine, including a panic, halts
+// the run and is returned by Run.
+func (vm *VirtualMachine) goroutine(fn object.Object, args []object.Object) {
+	child := New(vm.main, WithImporter(vm.importer), WithLimits(vm.limits),
+		WithMaxFrameDepth(vm.maxFrames), WithMaxStackDepth(vm.maxStack))
+	child.goroutines = vm.goroutines
+	for name, module := range vm.modules {
+		child.modules[name] = module
+	}
+	atomic.StoreInt32(&vm.goroutines.active, 1)
+	go func() {
+		group := child.goroutines
+		defer func() {
+			if r := recover(); r != nil {
+				group.fail(fmt.Errorf("panic: %v", r))
+			}
+		}()
+		ctx, finish := child.start(group.ctx)
+		defer finish()
+		if err := child.invoke(ctx, fn, args, nil); err != nil {
+			// Errors caused by stopping the run are not failures
+			if group.ctx.Err() == nil {
+				group.fail(err)
+			}
+		}
+	}()
+}
+
+// isShared returns true if the local variables of the active frame may be
+// accessed by goroutines. This is the case when they have been captured by a
+// closure while goroutines are running.
+func (vm *VirtualMachine) isShared() bool {
+	return vm.activeFrame.capturedLocals != nil && vm.goroutines.isActive()
+}
+
+// loadFast returns the local variable of the active frame at the given index.
+func (vm *VirtualMachine) loadFast(index uint16) object.Object {
+	if !vm.isShared() {
+		return vm.activeFrame.locals[index]
+	}
+	vm.goroutines.mu.RLock()
+	defer vm.goroutines.mu.RUnlock()
+	return vm.activeFrame.locals[index]
+}
+
+// storeFast sets the local variable of the active frame at the given index.
+func (vm *VirtualMachine) storeFast(index uint16, value object.Object) {
+	if !vm.isShared() {
+		vm.activeFrame.locals[index] = value
+		return
+	}
+	vm.goroutines.mu.Lock()
+	defer vm.goroutines.mu.Unlock()
+	vm.activeFrame.locals[index] = value
+}
+
+// loadGlobal returns the global variable at the given index.
+func (vm *VirtualMachine) loadGlobal(index uint16) object.Object {
+	if !vm.goroutines.isActive() {
+		return vm.globals()[index]
+	}
+	vm.goroutines.mu.RLock()
+	defer vm.goroutines.mu.RUnlock()
+	return vm.globals()[index]
+}
+
+// storeGlobal sets the global variable at the given index.
+func (vm *VirtualMachine) storeGlobal(index uint16, value object.Object) {
+	if !vm.goroutines.isActive() {
+		vm.globals()[index] = value
+		return
+	}
+	vm.goroutines.mu.Lock()
+	defer vm.goroutines.mu.Unlock()
+	vm.globals()[index] = value
+}
+
+// loadFree returns the free variable of the active function at the given
+// index.
+func (vm *VirtualMachine) loadFree(index uint16) object.Object {
+	cell := vm.activeFrame.fn.FreeVars()[index]
+	if !vm.goroutines.isActive() {
+		return cell.Value()
+	}
+	vm.goroutines.mu.RLock()
+	defer vm.goroutines.mu.RUnlock()
+	return cell.Value()
+}
+
+// storeFree sets the free variable of the active function at the given
+// index.
+func (vm *VirtualMachine) storeFree(index uint16, value object.Object) {
+	cell := vm.activeFrame.fn.FreeVars()[index]
+	if !vm.goroutines.isActive() {
+		cell.Set(value)
+		return
+	}
+	vm.goroutines.mu.Lock()
+	defer vm.goroutines.mu.Unlock()
+	cell.Set(value)
+}
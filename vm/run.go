@@ -8,10 +8,12 @@ import (
 	"github.com/risor-io/risor/compiler"
 	"github.com/risor-io/risor/importer"
 	modBytes "github.com/risor-io/risor/modules/bytes"
+	modCmath "github.com/risor-io/risor/modules/cmath"
 	modFmt "github.com/risor-io/risor/modules/fmt"
 	modJson "github.com/risor-io/risor/modules/json"
 	modMath "github.com/risor-io/risor/modules/math"
 	modRand "github.com/risor-io/risor/modules/rand"
+	modRegexp "github.com/risor-io/risor/modules/regexp"
 	modStrconv "github.com/risor-io/risor/modules/strconv"
 	modStrings "github.com/risor-io/risor/modules/strings"
 	modTime "github.com/risor-io/risor/modules/time"
@@ -20,7 +22,8 @@ import (
 )
 
 type runOpts struct {
-	Inject map[string]interface{}
+	Inject   map[string]interface{}
+	Optimize bool
 }
 
 func run(ctx context.Context, code string, opts ...runOpts) (object.Object, error) {
@@ -59,7 +62,11 @@ func run(ctx context.Context, code string, opts ...runOpts) (object.Object, erro
 	}
 
 	// Compile
-	main, err := compiler.Compile(ast, compiler.WithBuiltins(builtins))
+	compilerOpts := []compiler.Option{compiler.WithBuiltins(builtins)}
+	if len(opts) > 0 && opts[0].Optimize {
+		compilerOpts = append(compilerOpts, compiler.WithOptimizations())
+	}
+	main, err := compiler.Compile(ast, compilerOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,10 +85,12 @@ func run(ctx context.Context, code string, opts ...runOpts) (object.Object, erro
 func defaultModules() map[string]object.Object {
 	return map[string]object.Object{
 		"math":    modMath.Module(),
+		"cmath":   modCmath.Module(),
 		"json":    modJson.Module(),
 		"strings": modStrings.Module(),
 		"time":    modTime.Module(),
 		"rand":    modRand.Module(),
+		"regexp":  modRegexp.Module(),
 		"strconv": modStrconv.Module(),
 		"bytes":   modBytes.Module(),
 	}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
 	"strings"
 	"sync/atomic"
 
@@ -14,13 +17,32 @@ import (
 )
 
 const (
-	MaxArgs       = 255
+	MaxArgs = 255
+	// MaxFrameDepth is the default limit on the depth of nested calls. It
+	// may be changed with the WithMaxFrameDepth option.
 	MaxFrameDepth = 1024
-	MaxStackDepth = 1024
-	StopSignal    = -1
-	MB            = 1024 * 1024
+	// MaxStackDepth is the default limit on the number of values on the
+	// value stack. It may be changed with the WithMaxStackDepth option.
+	MaxStackDepth = 1024 * 1024
+	// InitialStackSize is the initial size of the value stack, which grows
+	// as needed up to the maximum stack depth.
+	InitialStackSize = 1024
+	StopSignal       = -1
+	MB               = 1024 * 1024
+
+	// initialFrames is the number of call frames allocated when a VM is
+	// created. More frames are allocated as calls are nested more deeply.
+	initialFrames = 16
 )
 
+// ErrRecursionDepth is wrapped by the error raised when a call would exceed
+// the maximum depth of nested calls.
+var ErrRecursionDepth = errors.New("exec error: recursion depth exceeded")
+
+// ErrStackOverflow is wrapped by the error raised when a value pushed onto
+// the stack would exceed the maximum stack depth.
+var ErrStackOverflow = errors.New("exec error: stack overflow")
+
 type Options struct {
 	Main              *object.Code
 	InstructionOffset int
@@ -31,9 +53,11 @@ type VirtualMachine struct {
 	ip          int // instruction pointer
 	sp          int // stack pointer
 	fp          int // frame pointer
-	halt        int32
-	stack       [MaxStackDepth]object.Object
-	frames      [MaxFrameDepth]Frame
+	halt        *int32
+	stack       []object.Object
+	frames      []*Frame
+	maxFrames   int
+	maxStack    int
 	tmp         [MaxArgs]object.Object
 	activeFrame *Frame
 	activeCode  *object.Code
@@ -41,6 +65,20 @@ type VirtualMachine struct {
 	importer    importer.Importer
 	modules     map[string]*object.Module
 	limits      limits.Limits
+	handlers    []handler
+	suspended   bool
+	caches      map[*object.Code]*codeCache
+	cache       *codeCache
+	goroutines  *goroutineGroup
+}
+
+// handler is an exception handler that has been activated by a SetupTry
+// instruction. It records the frame and stack depth to restore if an error
+// is caught, along with the instruction to resume execution at.
+type handler struct {
+	fp     int
+	sp     int
+	target int
 }
 
 // Option is a configuration function for a Virtual Machine.
@@ -53,6 +91,23 @@ func WithInstructionOffset(offset int) Option {
 	}
 }
 
+// WithMaxFrameDepth sets the maximum depth of nested calls. A call that
+// would exceed this depth fails with an error that wraps ErrRecursionDepth.
+func WithMaxFrameDepth(depth int) Option {
+	return func(vm *VirtualMachine) {
+		vm.maxFrames = depth
+	}
+}
+
+// WithMaxStackDepth sets the maximum number of values on the value stack.
+// Pushing a value beyond this depth fails with an error that wraps
+// ErrStackOverflow.
+func WithMaxStackDepth(depth int) Option {
+	return func(vm *VirtualMachine) {
+		vm.maxStack = depth
+	}
+}
+
 // WithImporter is used to supply an Importer to the Virtual Machine.
 func WithImporter(importer importer.Importer) Option {
 	return func(vm *VirtualMachine) {
@@ -74,14 +129,31 @@ func defaultLimits() limits.Limits {
 // New creates a new Virtual Machine.
 func New(main *object.Code, options ...Option) *VirtualMachine {
 	vm := &VirtualMachine{
-		sp:      -1,
-		ip:      0,
-		main:    main,
-		modules: map[string]*object.Module{},
+		sp:        -1,
+		ip:        0,
+		halt:      new(int32),
+		maxFrames: MaxFrameDepth,
+		maxStack:  MaxStackDepth,
+		main:      main,
+		modules:   map[string]*object.Module{},
+		caches:    map[*object.Code]*codeCache{},
 	}
+	vm.goroutines = newGoroutineGroup(context.Background())
 	for _, opt := range options {
 		opt(vm)
 	}
+	if vm.maxFrames < 1 {
+		vm.maxFrames = 1
+	}
+	if vm.maxStack < 1 {
+		vm.maxStack = 1
+	}
+	stackSize := InitialStackSize
+	if stackSize > vm.maxStack {
+		stackSize = vm.maxStack
+	}
+	vm.stack = make([]object.Object, stackSize)
+	vm.growFrames()
 	if vm.limits == nil {
 		vm.limits = defaultLimits()
 	}
@@ -97,22 +169,100 @@ func (vm *VirtualMachine) Run(ctx context.Context) (err error) {
 		}
 	}()
 
-	// Halt execution when the context is cancelled
-	go func() {
-		<-ctx.Done()
-		atomic.StoreInt32(&vm.halt, 1)
+	// Goroutines started by the code are stopped when the run finishes. If
+	// one of them fails, the run is halted and its error is returned.
+	vm.goroutines = newGoroutineGroup(ctx)
+	defer vm.goroutines.stop()
+	defer func() {
+		if gerr := vm.goroutines.Err(); gerr != nil {
+			err = gerr
+		}
 	}()
 
 	// Activate the "main" entrypoint code in frame 0 and then run it
+	ctx, finish := vm.start(ctx)
+	defer finish()
+	if err = vm.eval(ctx); err != nil {
+		return
+	}
+	// Make any calls deferred by the main code
+	if err = vm.runDefers(ctx); err != nil {
+		err = vm.runtimeError(err)
+	}
+	return
+}
+
+// start prepares the VM to run by activating the main code in frame 0. The
+// returned context should be used for evaluation, and the returned function
+// must be called once evaluation is finished. Execution halts when the given
+// context is cancelled or when a goroutine of the run fails. The returned
+// context is derived from the context of the goroutine group, so that
+// blocking operations also stop when a goroutine fails.
+func (vm *VirtualMachine) start(ctx context.Context) (context.Context, func()) {
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(vm.halt, 1)
+		case <-vm.goroutines.failed:
+			atomic.StoreInt32(vm.halt, 1)
+		case <-finished:
+		}
+	}()
 	vm.fp = 0
-	vm.activeFrame = &vm.frames[vm.fp]
+	vm.activeFrame = vm.frames[vm.fp]
 	vm.activeFrame.ActivateCode(vm.main)
 	vm.activeCode = vm.main
+	vm.resetCaches()
+	return vm.withContext(vm.goroutines.ctx), func() { close(finished) }
+}
+
+// withContext returns a context that lets Risor objects call back into this
+// VM, for example to call a function given to list.map.
+func (vm *VirtualMachine) withContext(ctx context.Context) context.Context {
 	ctx = object.WithCallFunc(ctx, vm.callFunction)
 	ctx = object.WithCodeFunc(ctx, vm.codeFunction)
 	ctx = limits.WithLimits(ctx, vm.limits)
-	err = vm.eval(ctx)
-	return
+	return ctx
+}
+
+// generator returns a generator for a call to the given generator function,
+// whose local variables have already been bound. The function runs on a new
+// VM that holds its suspended frame and stack between values. The new VM
+// shares the compiled code, globals, importer, limits, and halt flag of this
+// VM. Each time the generator is resumed, the function runs until it yields
+// a value or returns.
+func (vm *VirtualMachine) generator(ctx context.Context, fn *object.Function, locals []object.Object) (*object.Generator, error) {
+	child := New(vm.main, WithImporter(vm.importer), WithLimits(vm.limits),
+		WithMaxFrameDepth(vm.maxFrames), WithMaxStackDepth(vm.maxStack))
+	child.halt = vm.halt
+	child.goroutines = vm.goroutines
+	for name, module := range vm.modules {
+		child.modules[name] = module
+	}
+	// The function runs in frame 1 so that it returns to an empty frame 0
+	frame, err := child.pushFrame()
+	if err != nil {
+		return nil, err
+	}
+	child.activeFrame = frame
+	child.activeFrame.ActivateFunction(fn, StopSignal, locals)
+	child.activeFrame.SetStackBase(child.sp)
+	child.activeCode = fn.Code()
+	child.ip = 0
+	ctx = child.withContext(ctx)
+	return object.NewGenerator(fn, func() (object.Object, bool, error) {
+		if err := child.eval(ctx); err != nil {
+			return nil, false, err
+		}
+		value := child.pop()
+		if !child.suspended {
+			// The function returned, so its return value is discarded
+			return nil, false, nil
+		}
+		child.suspended = false
+		return value, true, nil
+	}), nil
 }
 
 // Evaluate the active code. The caller must initialize the following variables
@@ -124,12 +274,295 @@ func (vm *VirtualMachine) Run(ctx context.Context) (err error) {
 //
 // Assuming this function returns without error, the result of the evaluation
 // will be on the top of the stack.
+//
+// If an error is raised and an exception handler was activated by the code
+// evaluated here, execution resumes at that handler. Otherwise the error is
+// returned to the caller.
 func (vm *VirtualMachine) eval(ctx context.Context) error {
+	baseFrame := vm.fp
+	for {
+		err := vm.evalLoop(ctx)
+		if err == nil {
+			return nil
+		}
+		// Errors are not caught once the context is cancelled
+		if atomic.LoadInt32(vm.halt) == 1 || ctx.Err() != nil {
+			vm.unwind(ctx, baseFrame)
+			return err
+		}
+		if !vm.catch(ctx, baseFrame, err) {
+			err = vm.runtimeError(err)
+			vm.unwind(ctx, baseFrame)
+			return err
+		}
+	}
+}
+
+// runtimeError wraps the given error in a RuntimeError that captures the
+// current call stack. Errors that were already wrapped, for example by an
+// evaluation nested within a builtin function call, are returned unchanged.
+func (vm *VirtualMachine) runtimeError(err error) error {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return err
+	}
+	return NewRuntimeError(err, vm.callStack())
+}
+
+// callStack returns a description of the active call frames, ordered from
+// the outermost frame to the innermost.
+func (vm *VirtualMachine) callStack() []StackFrame {
+	stack := make([]StackFrame, 0, vm.fp+1)
+	for i := 0; i <= vm.fp; i++ {
+		frame := vm.frames[i]
+		code := frame.Code()
+		if code == nil {
+			continue
+		}
+		// Functions replaced by tail calls are listed before the function
+		// that replaced them
+		for _, call := range frame.tailCalls {
+			stack = append(stack, stackFrame(call.code, call.ip))
+		}
+		ip := vm.ip
+		if i < vm.fp {
+			ip = vm.frames[i+1].callSite
+		}
+		stack = append(stack, stackFrame(code, ip))
+	}
+	return stack
+}
+
+// stackFrame describes the code executing at the given instruction pointer.
+func stackFrame(code *object.Code, ip int) StackFrame {
+	name := code.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	// The instruction pointer has already advanced past the instruction that
+	// was executing, so look up the one before it.
+	pos, ok := code.LocationAt(ip - 1)
+	return StackFrame{
+		Function:    name,
+		Position:    pos,
+		HasPosition: ok,
+	}
+}
+
+// catch transfers control to the innermost active exception handler, as long
+// as it belongs to a frame at or above baseFrame. The frame and stack are
+// unwound to where they were when the handler was activated and the error is
+// pushed onto the stack. Returns false if no suitable handler was found.
+func (vm *VirtualMachine) catch(ctx context.Context, baseFrame int, err error) bool {
+	// Discard handlers belonging to frames that have already exited
+	vm.dropHandlers(vm.fp)
+	count := len(vm.handlers)
+	if count == 0 {
+		return false
+	}
+	h := vm.handlers[count-1]
+	if h.fp < baseFrame {
+		return false
+	}
+	vm.handlers = vm.handlers[:count-1]
+	vm.unwind(ctx, h.fp+1)
+	vm.fp = h.fp
+	vm.activeFrame = vm.frames[vm.fp]
+	vm.activeCode = vm.activeFrame.Code()
+	vm.sp = h.sp
+	vm.ip = h.target
+	vm.push(object.NewError(err))
+	return true
+}
+
+// unwind makes the deferred calls of each frame from the active frame down to
+// the given frame pointer, as these frames are being exited due to an error.
+// Errors raised by the deferred calls are discarded in favor of the original
+// error. The caller is responsible for resetting the active frame afterwards.
+func (vm *VirtualMachine) unwind(ctx context.Context, fp int) {
+	for i := vm.fp; i >= fp; i-- {
+		vm.fp = i
+		vm.activeFrame = vm.frames[i]
+		vm.activeCode = vm.activeFrame.Code()
+		vm.runDefers(ctx)
+	}
+}
+
+// runDefers makes the calls deferred by the active frame, in the reverse
+// order that they were deferred. All the calls are made even if one fails,
+// and the first error is returned.
+func (vm *VirtualMachine) runDefers(ctx context.Context) error {
+	var firstErr error
+	frame := vm.activeFrame
+	for len(frame.defers) > 0 {
+		last := len(frame.defers) - 1
+		call := frame.defers[last]
+		frame.defers = frame.defers[:last]
+		if err := vm.invoke(ctx, call.Function(), call.Args(), call.Kwargs()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// invoke calls the given function with the given arguments, discarding its
+// result. This is used for deferred calls and to start goroutines.
+func (vm *VirtualMachine) invoke(ctx context.Context, fn object.Object, args []object.Object, kwargs map[string]object.Object) error {
+	switch fn := fn.(type) {
+	case *object.Builtin:
+		if err, ok := fn.CallWithKeywords(ctx, kwargs, args...).(*object.Error); ok {
+			return err.Value()
+		}
+	case *object.Function:
+		_, err := vm.callFunctionWithKwargs(ctx, fn, args, kwargs)
+		return err
+	case *object.Partial:
+		// Arguments given to a partial precede the partial's own arguments
+		combined := make([]object.Object, 0, len(args)+len(fn.Args()))
+		combined = append(combined, args...)
+		combined = append(combined, fn.Args()...)
+		return vm.invoke(ctx, fn.Function(), combined, mergeKwargs(fn.Kwargs(), kwargs))
+	case *object.BoundMethod:
+		// The receiver is passed as the first argument to the method
+		combined := make([]object.Object, 0, len(args)+1)
+		combined = append(combined, fn.Receiver())
+		combined = append(combined, args...)
+		return vm.invoke(ctx, fn.Function(), combined, kwargs)
+	case *object.StructType:
+		_, err := fn.New(args, kwargs)
+		return err
+	default:
+		return fmt.Errorf("type error: object is not callable (got %s)", fn.Type())
+	}
+	return nil
+}
+
+// compareOp returns the result of comparing a and b with the given operator.
+func (vm *VirtualMachine) compareOp(ctx context.Context, opType op.CompareOpType, a, b object.Object) (object.Object, error) {
+	result, found, err := object.CompareOperator(ctx, opType, a, b)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		result = object.Compare(opType, a, b)
+	}
+	return result, nil
+}
+
+// binaryOp runs the binary operation on a and b, pushing the result onto the
+// stack. When the operation is a pipe into a function, the function is called
+// instead and its result is pushed once it returns.
+func (vm *VirtualMachine) binaryOp(ctx context.Context, opType op.BinaryOpType, a, b object.Object) error {
+	// The "|" operator pipes a value into a function when the right
+	// operand is callable. Otherwise it is a bitwise or, set union,
+	// or map merge, and failing that the pipe is invalid.
+	if opType == op.BitwiseOr && isCallable(b) {
+		vm.tmp[0] = a
+		return vm.call(ctx, b, 1, nil)
+	}
+	// Objects like structs may implement the operator with a method
+	result, found, err := object.BinaryOperator(ctx, opType, a, b)
+	if err != nil {
+		return err
+	}
+	if !found {
+		result = object.BinaryOp(opType, a, b)
+		if opType == op.BitwiseOr && object.IsError(result) {
+			return fmt.Errorf("type error: object is not callable (got %s)", b.Type())
+		}
+		// Division by zero is raised rather than returned as a value
+		if err, ok := result.(*object.Error); ok && errors.Is(err.Value(), object.ErrDivisionByZero) {
+			return err.Value()
+		}
+	}
+	vm.push(result)
+	return nil
+}
+
+// selectCase implements the select instruction. The operands of the given
+// number of channel operations are popped from the stack, and the VM blocks
+// until one of the operations proceeds. Then the received value (or nil) and
+// the index of the chosen case are pushed. If hasDefault is true and no
+// operation is immediately ready, the index is -1.
+func (vm *VirtualMachine) selectCase(ctx context.Context, count int, hasDefault bool) (err error) {
+	cases := make([]reflect.SelectCase, count, count+2)
+	for i := count - 1; i >= 0; i-- {
+		isSend := vm.pop()
+		value := vm.pop()
+		obj := vm.pop()
+		ch, ok := obj.(*object.Chan)
+		if !ok {
+			return fmt.Errorf("type error: select case expected a chan (got %s)", obj.Type())
+		}
+		if isSend == object.True {
+			cases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(ch.Value()),
+				Send: reflect.ValueOf(&value).Elem(),
+			}
+		} else {
+			cases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(ch.Value()),
+			}
+		}
+	}
+	// Stop waiting if the context is cancelled
+	cases = append(cases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	})
+	if hasDefault {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	// Sending on a closed channel panics
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("exec error: send on closed channel")
+		}
+	}()
+	chosen, received, ok := reflect.Select(cases)
+	switch {
+	case chosen == count:
+		return ctx.Err()
+	case chosen > count:
+		vm.push(object.Nil)
+		vm.push(object.NewInt(-1))
+	case cases[chosen].Dir == reflect.SelectRecv && ok:
+		vm.push(received.Interface().(object.Object))
+		vm.push(object.NewInt(int64(chosen)))
+	default:
+		vm.push(object.Nil)
+		vm.push(object.NewInt(int64(chosen)))
+	}
+	return nil
+}
+
+// dropHandlers discards any exception handlers that belong to frames above
+// the given frame pointer.
+func (vm *VirtualMachine) dropHandlers(fp int) {
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].fp > fp {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+func (vm *VirtualMachine) evalLoop(ctx context.Context) (err error) {
+
+	// Stack overflows are raised by push as a panic
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && errors.Is(e, ErrStackOverflow) {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
 
 	// Run to the end of the active code
 	for vm.ip < len(vm.activeCode.Instructions) {
 
-		if atomic.LoadInt32(&vm.halt) == 1 {
+		if atomic.LoadInt32(vm.halt) == 1 {
 			return ctx.Err()
 		}
 
@@ -146,11 +579,16 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 		// Dispatch the instruction
 		switch opcode {
 		case op.Nop:
-		case op.LoadAttr:
+		case op.LoadAttr, op.LoadAttrOrNil:
+			offset := vm.ip - 1
 			obj := vm.pop()
 			name := vm.activeCode.Names[vm.fetch()]
-			value, found := obj.GetAttr(name)
+			value, found := vm.loadAttr(obj, name, offset)
 			if !found {
+				if opcode == op.LoadAttrOrNil {
+					vm.push(object.Nil)
+					break
+				}
 				return fmt.Errorf("exec error: attribute %q not found on %s object",
 					name, obj.Type())
 			}
@@ -167,19 +605,20 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 		case op.LoadConst:
 			vm.push(vm.activeCode.Constants[vm.fetch()])
 		case op.LoadFast:
-			vm.push(vm.activeFrame.Locals()[vm.fetch()])
+			vm.push(vm.loadFast(vm.fetch()))
+		case op.LoadFastLoadFast:
+			vm.push(vm.loadFast(vm.fetch()))
+			vm.push(vm.loadFast(vm.fetch()))
 		case op.LoadGlobal:
-			vm.push(vm.activeCode.Globals()[vm.fetch()])
+			vm.push(vm.loadGlobal(vm.fetch()))
 		case op.LoadFree:
-			freeVars := vm.activeFrame.fn.FreeVars()
-			vm.push(freeVars[vm.fetch()].Value())
+			vm.push(vm.loadFree(vm.fetch()))
 		case op.StoreFast:
-			vm.activeFrame.Locals()[vm.fetch()] = vm.pop()
+			vm.storeFast(vm.fetch(), vm.pop())
 		case op.StoreGlobal:
-			vm.activeCode.Globals()[vm.fetch()] = vm.pop()
+			vm.storeGlobal(vm.fetch(), vm.pop())
 		case op.StoreFree:
-			freeVars := vm.activeFrame.fn.FreeVars()
-			freeVars[vm.fetch()].Set(vm.pop())
+			vm.storeFree(vm.fetch(), vm.pop())
 		case op.LoadClosure:
 			constIndex := vm.fetch()
 			freeCount := vm.fetch()
@@ -203,7 +642,7 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			if frameIndex < 0 {
 				return fmt.Errorf("exec error: no frame at depth %d", framesBack)
 			}
-			frame := &vm.frames[frameIndex]
+			frame := vm.frames[frameIndex]
 			locals := frame.CaptureLocals()
 			vm.push(object.NewCell(&locals[symbolIndex]))
 		case op.Nil:
@@ -216,19 +655,66 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			opType := op.CompareOpType(vm.fetch())
 			b := vm.pop()
 			a := vm.pop()
-			vm.push(object.Compare(opType, a, b))
+			result, err := vm.compareOp(ctx, opType, a, b)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case op.ComparePopJumpForwardIfFalse:
+			base := vm.ip - 1
+			opType := op.CompareOpType(vm.fetch())
+			delta := int(vm.fetch())
+			b := vm.pop()
+			a := vm.pop()
+			result, err := vm.compareOp(ctx, opType, a, b)
+			if err != nil {
+				return err
+			}
+			if !result.IsTruthy() {
+				vm.ip = base + delta
+			}
 		case op.BinaryOp:
 			opType := op.BinaryOpType(vm.fetch())
 			b := vm.pop()
 			a := vm.pop()
-			vm.push(object.BinaryOp(opType, a, b))
+			if err := vm.binaryOp(ctx, opType, a, b); err != nil {
+				return err
+			}
+		case op.BinaryOpConst:
+			opType := op.BinaryOpType(vm.fetch())
+			b := vm.activeCode.Constants[vm.fetch()]
+			a := vm.pop()
+			if err := vm.binaryOp(ctx, opType, a, b); err != nil {
+				return err
+			}
 		case op.Call:
 			argc := int(vm.fetch())
 			for argIndex := argc - 1; argIndex >= 0; argIndex-- {
 				vm.tmp[argIndex] = vm.pop()
 			}
 			obj := vm.pop()
-			if err := vm.call(ctx, obj, argc); err != nil {
+			if err := vm.call(ctx, obj, argc, nil); err != nil {
+				return err
+			}
+		case op.TailCall:
+			argc := int(vm.fetch())
+			for argIndex := argc - 1; argIndex >= 0; argIndex-- {
+				vm.tmp[argIndex] = vm.pop()
+			}
+			obj := vm.pop()
+			if err := vm.tailCall(ctx, obj, argc); err != nil {
+				return err
+			}
+		case op.CallEx:
+			kwargs := vm.pop().(*object.Map)
+			args := vm.pop().(*object.List).Value()
+			obj := vm.pop()
+			argc := len(args)
+			if argc > MaxArgs {
+				return fmt.Errorf("exec error: max arguments limit of %d exceeded (got %d)", MaxArgs, argc)
+			}
+			copy(vm.tmp[:argc], args)
+			if err := vm.call(ctx, obj, argc, kwargs.Value()); err != nil {
 				return err
 			}
 		case op.Partial:
@@ -240,17 +726,45 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			obj := vm.pop()
 			partial := object.NewPartial(obj, args)
 			vm.push(partial)
+		case op.PartialEx:
+			kwargs := vm.pop().(*object.Map)
+			args := vm.pop().(*object.List)
+			obj := vm.pop()
+			vm.push(object.NewPartialWithKwargs(obj, args.Value(), kwargs.Value()))
 		case op.ReturnValue:
+			if len(vm.activeFrame.defers) > 0 {
+				if err := vm.runDefers(ctx); err != nil {
+					return err
+				}
+			}
 			returnAddr := vm.frames[vm.fp].returnAddr
 			vm.fp--
-			vm.activeFrame = &vm.frames[vm.fp]
+			vm.activeFrame = vm.frames[vm.fp]
 			vm.activeCode = vm.activeFrame.Code()
 			vm.ip = returnAddr
+			vm.dropHandlers(vm.fp)
 			if returnAddr == StopSignal {
 				// If StopSignal is found as the return address, it means the
 				// current eval call should stop.
 				return nil
 			}
+		case op.Yield:
+			// Suspend the generator function running on this VM, leaving the
+			// yielded value on the top of the stack. The generator resumes
+			// evaluation from the next instruction.
+			vm.suspended = true
+			return nil
+		case op.UnwrapResult:
+			delta := int(vm.fetch()) - 2
+			result, ok := vm.stack[vm.sp].(*object.Result)
+			if !ok {
+				return fmt.Errorf("type error: ? operator expected a result (got %s)",
+					vm.stack[vm.sp].Type())
+			}
+			if result.IsOk() {
+				vm.stack[vm.sp] = result.Unwrap()
+				vm.ip += delta
+			}
 		case op.PopJumpForwardIfTrue:
 			tos := vm.pop()
 			delta := int(vm.fetch()) - 2
@@ -263,15 +777,27 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			if !tos.IsTruthy() {
 				vm.ip += delta
 			}
-		case op.PopJumpBackwardIfTrue:
+		case op.PopJumpForwardIfNil:
+			tos := vm.pop()
+			delta := int(vm.fetch()) - 2
+			if tos.Type() == object.NIL {
+				vm.ip += delta
+			}
+		case op.PopJumpForwardIfNotNil:
 			tos := vm.pop()
 			delta := int(vm.fetch()) - 2
+			if tos.Type() != object.NIL {
+				vm.ip += delta
+			}
+		case op.PopJumpBackwardIfTrue:
+			tos := vm.pop()
+			delta := int(vm.fetch()) + 2
 			if tos.IsTruthy() {
 				vm.ip -= delta
 			}
 		case op.PopJumpBackwardIfFalse:
 			tos := vm.pop()
-			delta := int(vm.fetch()) - 2
+			delta := int(vm.fetch()) + 2
 			if !tos.IsTruthy() {
 				vm.ip -= delta
 			}
@@ -290,15 +816,112 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 				items[count-1-i] = vm.pop()
 			}
 			vm.push(object.NewList(items))
+		case op.BuildTuple:
+			count := vm.fetch()
+			items := make([]object.Object, count)
+			for i := uint16(0); i < count; i++ {
+				items[count-1-i] = vm.pop()
+			}
+			vm.push(object.NewTuple(items))
+		case op.BuildStruct:
+			count := vm.fetch()
+			fields := make([]string, count)
+			defaults := make([]object.Object, count)
+			for i := int(count) - 1; i >= 0; i-- {
+				defaults[i] = vm.pop()
+				fields[i] = vm.pop().(*object.String).Value()
+			}
+			name := vm.pop().(*object.String).Value()
+			structType, err := object.NewStructType(name, fields, defaults)
+			if err != nil {
+				return err
+			}
+			vm.push(structType)
+		case op.NewStruct:
+			count := int(vm.fetch())
+			kwargs := make(map[string]object.Object, count)
+			for i := 0; i < count; i++ {
+				value := vm.pop()
+				kwargs[vm.pop().(*object.String).Value()] = value
+			}
+			typ := vm.pop()
+			structType, ok := typ.(*object.StructType)
+			if !ok {
+				return fmt.Errorf("type error: struct literal requires a struct type (got %s)", typ.Type())
+			}
+			instance, err := structType.New(nil, kwargs)
+			if err != nil {
+				return err
+			}
+			vm.push(instance)
+		case op.ListAppend:
+			// The list is found below the given number of stack items,
+			// which are the iterators of an enclosing comprehension
+			depth := int(vm.fetch())
+			value := vm.pop()
+			list := vm.stack[vm.sp-depth].(*object.List)
+			list.Append(value)
+		case op.MapAdd:
+			depth := int(vm.fetch())
+			value := vm.pop()
+			key := vm.pop()
+			m := vm.stack[vm.sp-depth].(*object.Map)
+			if err := m.SetItem(key, value); err != nil {
+				return err.Value()
+			}
+		case op.SetAdd:
+			depth := int(vm.fetch())
+			value := vm.pop()
+			set := vm.stack[vm.sp-depth].(*object.Set)
+			if err, ok := set.Add(value).(*object.Error); ok {
+				return err.Value()
+			}
+		case op.MatchPattern:
+			pattern := vm.activeCode.Patterns[vm.fetch()]
+			captures, ok := pattern.Match(vm.pop(), nil)
+			if ok {
+				for _, value := range captures {
+					vm.push(value)
+				}
+			}
+			vm.push(object.NewBool(ok))
+		case op.ListExtend:
+			obj := vm.pop()
+			list := vm.stack[vm.sp].(*object.List)
+			switch obj := obj.(type) {
+			case *object.List:
+				list.Extend(obj)
+			case object.Iterable, object.Iterator:
+				iter, _ := object.AsIterator(obj)
+				for {
+					item, ok := iter.Next()
+					if !ok {
+						break
+					}
+					list.Append(item)
+				}
+				if err := object.IteratorError(iter); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("type error: spread argument is not iterable (got %s)", obj.Type())
+			}
 		case op.BuildMap:
 			count := vm.fetch()
-			items := make(map[string]object.Object, count)
+			keys := make([]object.Object, count)
+			values := make([]object.Object, count)
 			for i := uint16(0); i < count; i++ {
-				v := vm.pop()
-				k := vm.pop()
-				items[k.(*object.String).Value()] = v
+				values[count-1-i] = vm.pop()
+				keys[count-1-i] = vm.pop()
 			}
-			vm.push(object.NewMap(items))
+			// Items are inserted in source order, which is kept by the map
+			m := object.NewMap(nil)
+			for i, k := range keys {
+				if err := m.SetItem(k, values[i]); err != nil {
+					return err.Value()
+				}
+			}
+			vm.push(m)
 		case op.BuildSet:
 			count := vm.fetch()
 			items := make([]object.Object, count)
@@ -309,15 +932,23 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 		case op.BinarySubscr:
 			idx := vm.pop()
 			lhs := vm.pop()
+			result, found, err := object.CallOperator(ctx, lhs, object.GetItemMethod, idx)
+			if err != nil {
+				return err
+			}
+			if found {
+				vm.push(result)
+				break
+			}
 			container, ok := lhs.(object.Container)
 			if !ok {
 				return fmt.Errorf("type error: object is not a container (got %s)", lhs.Type())
 			}
-			result, err := container.GetItem(idx)
-			if err != nil {
-				return err.Value()
+			item, getErr := container.GetItem(idx)
+			if getErr != nil {
+				return getErr.Value()
 			}
-			vm.push(result)
+			vm.push(item)
 		case op.StoreSubscr:
 			idx := vm.pop()
 			lhs := vm.pop()
@@ -329,13 +960,31 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			if err := container.SetItem(idx, rhs); err != nil {
 				return err.Value()
 			}
+		case op.StoreAttr:
+			obj := vm.pop()
+			value := vm.pop()
+			name := vm.activeCode.Names[vm.fetch()]
+			if err := obj.SetAttr(name, value); err != nil {
+				return err
+			}
 		case op.UnaryNegative:
 			obj := vm.pop()
 			switch obj := obj.(type) {
 			case *object.Int:
-				vm.push(object.NewInt(-obj.Value()))
+				if obj.Value() == math.MinInt64 {
+					// Negating the minimum int64 overflows
+					vm.push(object.NewInt(0).RunOperation(op.Subtract, obj))
+				} else {
+					vm.push(object.NewInt(-obj.Value()))
+				}
 			case *object.Float:
 				vm.push(object.NewFloat(-obj.Value()))
+			case *object.BigInt:
+				vm.push(obj.Neg())
+			case *object.Decimal:
+				vm.push(obj.Neg())
+			case *object.Complex:
+				vm.push(object.NewComplex(-obj.Value()))
 			default:
 				return fmt.Errorf("type error: object is not a number (got %s)", obj.Type())
 			}
@@ -387,15 +1036,22 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			}
 			vm.push(iterable.Iter())
 		case op.Slice:
-			start := vm.pop()
-			stop := vm.pop()
+			var slice object.Slice
+			if vm.fetch() == 1 {
+				// Sliced with a step, where missing indices are nil
+				slice.Step = vm.pop()
+				slice.Start = sliceIndex(vm.pop())
+				slice.Stop = sliceIndex(vm.pop())
+			} else {
+				slice.Start = vm.pop()
+				slice.Stop = vm.pop()
+			}
 			containerObj := vm.pop()
 			container, ok := containerObj.(object.Container)
 			if !ok {
 				return fmt.Errorf("type error: object is not a container (got %s)",
 					containerObj.Type())
 			}
-			slice := object.Slice{Start: start, Stop: stop}
 			result, err := container.GetSlice(slice)
 			if err != nil {
 				return err.Value()
@@ -403,6 +1059,14 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			vm.push(result)
 		case op.Length:
 			containerObj := vm.pop()
+			length, found, err := object.LenOperator(ctx, containerObj)
+			if err != nil {
+				return err
+			}
+			if found {
+				vm.push(length)
+				break
+			}
 			container, ok := containerObj.(object.Container)
 			if !ok {
 				return fmt.Errorf("type error: object is not a container (got %s)",
@@ -445,6 +1109,15 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 				}
 				vm.push(val)
 			}
+		case op.UnpackPattern:
+			pattern := vm.activeCode.Patterns[vm.fetch()]
+			values, err := pattern.Unpack(vm.pop(), nil)
+			if err != nil {
+				return err
+			}
+			for _, value := range values {
+				vm.push(value)
+			}
 		case op.GetIter:
 			obj := vm.pop()
 			switch obj := obj.(type) {
@@ -461,12 +1134,23 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			nameCount := vm.fetch()
 			iter := vm.pop().(object.Iterator)
 			if _, ok := iter.Next(); !ok {
+				if err := object.IteratorError(iter); err != nil {
+					return err
+				}
 				vm.ip = base + int(jumpAmount)
 			} else {
 				obj, _ := iter.Entry()
 				vm.push(iter)
-				if nameCount == 1 {
-					vm.push(obj.Key())
+				if nameCount == op.ForIterPrimary {
+					vm.push(obj.Primary())
+				} else if nameCount == 1 {
+					// As in Go, a single variable ranging over a channel
+					// receives the values rather than their indexes
+					if _, ok := iter.(*object.ChanIter); ok {
+						vm.push(obj.Value())
+					} else {
+						vm.push(obj.Key())
+					}
 				} else if nameCount == 2 {
 					vm.push(obj.Value())
 					vm.push(obj.Key())
@@ -474,6 +1158,71 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 					return fmt.Errorf("exec error: invalid iteration")
 				}
 			}
+		case op.SetupTry:
+			h := vm.activeCode.Handlers[vm.fetch()]
+			vm.handlers = append(vm.handlers, handler{
+				fp:     vm.fp,
+				sp:     vm.sp,
+				target: h.Target,
+			})
+		case op.PopTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case op.Defer:
+			argc := int(vm.fetch())
+			args := make([]object.Object, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = vm.pop()
+			}
+			fn := vm.pop()
+			vm.activeFrame.Defer(object.NewPartial(fn, args))
+		case op.Go:
+			argc := int(vm.fetch())
+			args := make([]object.Object, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = vm.pop()
+			}
+			fn := vm.pop()
+			if !isCallable(fn) {
+				return fmt.Errorf("type error: object is not callable (got %s)", fn.Type())
+			}
+			vm.goroutine(fn, args)
+		case op.Receive:
+			obj := vm.pop()
+			ch, ok := obj.(*object.Chan)
+			if !ok {
+				return fmt.Errorf("type error: receive from non-chan type %s", obj.Type())
+			}
+			value, _, err := ch.Receive(ctx)
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+		case op.Send:
+			value := vm.pop()
+			obj := vm.pop()
+			ch, ok := obj.(*object.Chan)
+			if !ok {
+				return fmt.Errorf("type error: send to non-chan type %s", obj.Type())
+			}
+			if err := ch.Send(ctx, value); err != nil {
+				return err
+			}
+		case op.Select:
+			count := int(vm.fetch())
+			hasDefault := vm.fetch() == 1
+			if err := vm.selectCase(ctx, count, hasDefault); err != nil {
+				return err
+			}
+		case op.Throw:
+			obj := vm.pop()
+			switch obj := obj.(type) {
+			case *object.Error:
+				return obj.Value()
+			case *object.String:
+				return errors.New(obj.Value())
+			default:
+				return fmt.Errorf("type error: throw expected an error or string (got %s)", obj.Type())
+			}
 		case op.Halt:
 			return nil
 		default:
@@ -499,28 +1248,37 @@ func (vm *VirtualMachine) loadModule(ctx context.Context, name string) (*object.
 
 	// Allocate a new frame to evaluate the module code
 	baseFrame := vm.fp
-	vm.fp++
-	frame := &vm.frames[vm.fp]
+	frame, err := vm.pushFrame()
+	if err != nil {
+		return nil, err
+	}
 	frame.ActivateCode(code)
 	frame.SetReturnAddr(vm.ip)
-	vm.activeFrame = &vm.frames[vm.fp]
+	vm.activeFrame = vm.frames[vm.fp]
 	vm.activeCode = vm.activeFrame.code
 	vm.ip = 0
 
-	// Evaluate the module code
-	if err := vm.eval(ctx); err != nil {
+	// Evaluate the module code, then make any calls it deferred
+	err = vm.eval(ctx)
+	if err == nil {
+		if err = vm.runDefers(ctx); err != nil {
+			err = vm.runtimeError(err)
+		}
+	}
+	if err != nil {
 		// Unwind the stack
 		vm.fp = baseFrame
-		vm.activeFrame = &vm.frames[vm.fp]
+		vm.activeFrame = vm.frames[vm.fp]
 		vm.activeCode = vm.activeFrame.code
 		vm.ip = frame.returnAddr
+		vm.dropHandlers(vm.fp)
 		return nil, err
 	}
 
 	// Resume the previous frame
 	vm.fp--
 	vm.ip = vm.activeFrame.returnAddr
-	vm.activeFrame = &vm.frames[vm.fp]
+	vm.activeFrame = vm.frames[vm.fp]
 	vm.activeCode = vm.activeFrame.code
 
 	// Cache the module
@@ -528,38 +1286,42 @@ func (vm *VirtualMachine) loadModule(ctx context.Context, name string) (*object.
 	return module, nil
 }
 
-func (vm *VirtualMachine) call(ctx context.Context, fn object.Object, argc int) error {
+func (vm *VirtualMachine) call(ctx context.Context, fn object.Object, argc int, kwargs map[string]object.Object) error {
 	// The arguments are understood to be stored in vm.tmp here
 	args := vm.tmp[:argc]
 	switch fn := fn.(type) {
 	case *object.Builtin:
-		result := fn.Call(ctx, args...)
+		var result object.Object
+		if kwargs != nil {
+			result = fn.CallWithKeywords(ctx, kwargs, args...)
+		} else {
+			result = fn.Call(ctx, args...)
+		}
 		if err, ok := result.(*object.Error); ok {
 			return err.Value()
 		}
 		vm.push(result)
 	case *object.Function:
-		vm.fp++
-		frame := &vm.frames[vm.fp]
-		paramsCount := len(fn.Parameters())
-		if err := checkCallArgs(fn, argc); err != nil {
+		localsCount, err := vm.bindArgs(fn, argc, kwargs)
+		if err != nil {
 			return err
 		}
-		if argc < paramsCount {
-			defaults := fn.Defaults()
-			for i := argc; i < len(defaults); i++ {
-				vm.tmp[i] = defaults[i]
+		if fn.Code().IsGenerator {
+			gen, err := vm.generator(ctx, fn, vm.tmp[:localsCount])
+			if err != nil {
+				return err
 			}
-			argc = paramsCount
+			vm.push(gen)
+			return nil
 		}
-		code := fn.Code()
-		if code.IsNamed {
-			vm.tmp[paramsCount] = fn
-			argc++
+		frame, err := vm.pushFrame()
+		if err != nil {
+			return err
 		}
-		frame.ActivateFunction(fn, vm.ip, vm.tmp[:argc])
+		frame.ActivateFunction(fn, vm.ip, vm.tmp[:localsCount])
+		frame.SetStackBase(vm.sp)
 		vm.activeFrame = frame
-		vm.activeCode = code
+		vm.activeCode = fn.Code()
 		vm.ip = 0
 	case *object.Partial:
 		// Combine the current arguments with the partial's arguments
@@ -569,13 +1331,186 @@ func (vm *VirtualMachine) call(ctx context.Context, fn object.Object, argc int)
 		}
 		// We can just append arguments from the partial into vm.tmp
 		copy(vm.tmp[argc:], fn.Args())
-		return vm.call(ctx, fn.Function(), expandedCount)
+		return vm.call(ctx, fn.Function(), expandedCount, mergeKwargs(fn.Kwargs(), kwargs))
+	case *object.BoundMethod:
+		// The receiver is passed as the first argument to the method
+		if argc+1 > MaxArgs {
+			return fmt.Errorf("exec error: max arguments limit of %d exceeded (got %d)", MaxArgs, argc+1)
+		}
+		copy(vm.tmp[1:argc+1], vm.tmp[:argc])
+		vm.tmp[0] = fn.Receiver()
+		return vm.call(ctx, fn.Function(), argc+1, kwargs)
+	case *object.StructType:
+		instance, err := fn.New(args, kwargs)
+		if err != nil {
+			return err
+		}
+		vm.push(instance)
 	default:
 		return fmt.Errorf("type error: object is not callable (got %s)", fn.Type())
 	}
 	return nil
 }
 
+// tailCall calls a function whose result is returned by the active
+// function. A compiled function runs in the frame of the active function,
+// which keeps its return address, so tail recursion runs in constant frame
+// space. Other calls are made as usual, in which case the ReturnValue
+// instruction that follows returns the result.
+func (vm *VirtualMachine) tailCall(ctx context.Context, fn object.Object, argc int) error {
+	frame := vm.activeFrame
+	target, ok := fn.(*object.Function)
+	if !ok || frame.fn == nil || len(frame.defers) > 0 || target.Code().IsGenerator {
+		return vm.call(ctx, fn, argc, nil)
+	}
+	localsCount, err := vm.bindArgs(target, argc, nil)
+	if err != nil {
+		return err
+	}
+	frame.ActivateTailCall(target, vm.ip, vm.tmp[:localsCount])
+	// Discard any values the replaced function left on the stack, such as
+	// the iterators of loops the tail call was made from
+	for vm.sp > frame.stackBase {
+		vm.stack[vm.sp] = nil
+		vm.sp--
+	}
+	vm.activeCode = target.Code()
+	vm.ip = 0
+	return nil
+}
+
+// isCallable returns true if the object may be called by the VM.
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Builtin, *object.Function, *object.Partial,
+		*object.BoundMethod, *object.StructType:
+		return true
+	}
+	return false
+}
+
+// bindArgs assembles the local variables for a call to the given function in
+// vm.tmp, where the positional arguments are already stored in vm.tmp[:argc].
+// Returns the number of local variables. The local variable order is:
+// 1. Function parameters
+// 2. Rest parameter (if the function has one)
+// 3. Kwargs parameter (if the function has one)
+// 4. Function name (if the function is named)
+// sliceIndex converts a missing slice index, passed to the Slice opcode as
+// nil, to the nil interface expected by object.Slice.
+func sliceIndex(obj object.Object) object.Object {
+	if obj == object.Nil {
+		return nil
+	}
+	return obj
+}
+
+func (vm *VirtualMachine) bindArgs(fn *object.Function, argc int, kwargs map[string]object.Object) (int, error) {
+	params := fn.Parameters()
+	paramsCount := len(params)
+	if len(kwargs) == 0 {
+		if err := checkCallArgs(fn, argc); err != nil {
+			return 0, err
+		}
+	}
+	// Collect any extra positional arguments for the rest parameter
+	var rest []object.Object
+	if argc > paramsCount {
+		if fn.RestParameter() == "" {
+			return 0, checkCallArgs(fn, argc)
+		}
+		rest = make([]object.Object, argc-paramsCount)
+		copy(rest, vm.tmp[paramsCount:argc])
+	}
+	for i := argc; i < paramsCount; i++ {
+		vm.tmp[i] = nil
+	}
+	// Match keyword arguments to parameters by name. Unmatched keyword
+	// arguments are collected for the kwargs parameter.
+	var extra map[string]object.Object
+	if fn.KwargsParameter() != "" {
+		extra = make(map[string]object.Object, len(kwargs))
+	}
+	if len(kwargs) > 0 {
+		names := make([]string, 0, len(kwargs))
+		for name := range kwargs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			index := -1
+			for i, param := range params {
+				if param == name {
+					index = i
+					break
+				}
+			}
+			switch {
+			case index < 0 && extra == nil:
+				return 0, fmt.Errorf("type error: function got an unexpected keyword argument: %s", name)
+			case index < 0:
+				extra[name] = kwargs[name]
+			case index < argc:
+				return 0, fmt.Errorf("type error: function got multiple values for argument: %s", name)
+			default:
+				vm.tmp[index] = kwargs[name]
+			}
+		}
+	}
+	// Use default values for any remaining parameters
+	defaults := fn.Defaults()
+	for i := argc; i < paramsCount; i++ {
+		if vm.tmp[i] != nil {
+			continue
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			vm.tmp[i] = defaults[i]
+			continue
+		}
+		return 0, fmt.Errorf("type error: function missing argument: %s", params[i])
+	}
+	count := paramsCount
+	if fn.RestParameter() != "" {
+		if rest == nil {
+			rest = []object.Object{}
+		}
+		vm.tmp[count] = object.NewList(rest)
+		count++
+	}
+	if extra != nil {
+		vm.tmp[count] = object.NewMap(extra)
+		count++
+	}
+	if fn.Code().IsNamed {
+		if count >= MaxArgs {
+			return 0, fmt.Errorf("exec error: max arguments limit of %d exceeded", MaxArgs)
+		}
+		vm.tmp[count] = fn
+		count++
+	}
+	return count, nil
+}
+
+// mergeKwargs combines the keyword arguments of a partial with those given
+// when the partial is called. Keyword arguments given in the call take
+// precedence.
+func mergeKwargs(partial, call map[string]object.Object) map[string]object.Object {
+	if len(partial) == 0 {
+		return call
+	}
+	if len(call) == 0 {
+		return partial
+	}
+	merged := make(map[string]object.Object, len(partial)+len(call))
+	for name, value := range partial {
+		merged[name] = value
+	}
+	for name, value := range call {
+		merged[name] = value
+	}
+	return merged
+}
+
 func (vm *VirtualMachine) TOS() (object.Object, bool) {
 	if vm.sp >= 0 {
 		return vm.stack[vm.sp], true
@@ -589,11 +1524,63 @@ func (vm *VirtualMachine) pop() object.Object {
 	return obj
 }
 
+// push pushes a value onto the stack, growing the stack if needed. If the
+// maximum stack depth would be exceeded, push panics with an error wrapping
+// ErrStackOverflow, which evalLoop recovers and returns.
 func (vm *VirtualMachine) push(obj object.Object) {
+	if vm.sp+1 == len(vm.stack) {
+		vm.growStack()
+	}
 	vm.sp++
 	vm.stack[vm.sp] = obj
 }
 
+// growStack doubles the size of the stack, up to the maximum stack depth.
+func (vm *VirtualMachine) growStack() {
+	size := len(vm.stack)
+	if size >= vm.maxStack {
+		panic(fmt.Errorf("%w (max depth %d)", ErrStackOverflow, vm.maxStack))
+	}
+	growth := size
+	if growth > vm.maxStack-size {
+		growth = vm.maxStack - size
+	}
+	vm.stack = append(vm.stack, make([]object.Object, growth)...)
+}
+
+// pushFrame advances the frame pointer to the next call frame and returns
+// it, allocating more frames if needed. The caller is responsible for
+// activating the frame. An error is returned if the maximum frame depth
+// would be exceeded.
+func (vm *VirtualMachine) pushFrame() (*Frame, error) {
+	fp := vm.fp + 1
+	if fp >= vm.maxFrames {
+		return nil, fmt.Errorf("%w (max depth %d)", ErrRecursionDepth, vm.maxFrames)
+	}
+	if fp == len(vm.frames) {
+		vm.growFrames()
+	}
+	vm.fp = fp
+	return vm.frames[fp], nil
+}
+
+// growFrames allocates more call frames, doubling the number available up
+// to the maximum frame depth. Frames are allocated in blocks and referenced
+// by pointer, so existing frames never move.
+func (vm *VirtualMachine) growFrames() {
+	count := len(vm.frames)
+	if count == 0 {
+		count = initialFrames
+	}
+	if limit := vm.maxFrames - len(vm.frames); count > limit {
+		count = limit
+	}
+	block := make([]Frame, count)
+	for i := range block {
+		vm.frames = append(vm.frames, &block[i])
+	}
+}
+
 func (vm *VirtualMachine) swap(pos int) {
 	otherIndex := vm.sp - pos
 	tos := vm.stack[vm.sp]
@@ -615,44 +1602,52 @@ func (vm *VirtualMachine) codeFunction(ctx context.Context) (*object.Code, error
 // Calls a compiled function with the given arguments. This is used internally
 // when a Risor object calls a function, e.g. [1, 2, 3].map(func(x) { x + 1 }).
 func (vm *VirtualMachine) callFunction(ctx context.Context, fn *object.Function, args []object.Object) (object.Object, error) {
+	return vm.callFunctionWithKwargs(ctx, fn, args, nil)
+}
+
+// Calls a compiled function with the given positional and keyword arguments.
+func (vm *VirtualMachine) callFunctionWithKwargs(
+	ctx context.Context,
+	fn *object.Function,
+	args []object.Object,
+	kwargs map[string]object.Object,
+) (object.Object, error) {
 	baseFrame := vm.fp
 	baseIP := vm.ip
-	// Advance to the next frame
-	vm.fp++
-	frame := &vm.frames[vm.fp]
-	// Check that the argument count is appropriate
-	paramsCount := len(fn.Parameters())
 	argc := len(args)
-	if err := checkCallArgs(fn, argc); err != nil {
-		return nil, err
+	if argc > MaxArgs {
+		return nil, fmt.Errorf("exec error: max arguments limit of %d exceeded (got %d)", MaxArgs, argc)
 	}
-	// Assemble frame local variables in vm.tmp. The local variable order is:
-	// 1. Function parameters
-	// 2. Function name (if the function is named)
+	// Assemble frame local variables in vm.tmp, checking that the arguments
+	// are appropriate for the function
 	copy(vm.tmp[:argc], args)
-	if argc < paramsCount {
-		defaults := fn.Defaults()
-		for i := argc; i < len(defaults); i++ {
-			vm.tmp[i] = defaults[i]
-		}
+	localsCount, err := vm.bindArgs(fn, argc, kwargs)
+	if err != nil {
+		return nil, err
 	}
-	code := fn.Code()
-	if code.IsNamed {
-		vm.tmp[paramsCount] = fn
-		argc++
+	if fn.Code().IsGenerator {
+		return vm.generator(ctx, fn, vm.tmp[:localsCount])
+	}
+	// Advance to the next frame
+	frame, err := vm.pushFrame()
+	if err != nil {
+		return nil, err
 	}
 	// Activate this new frame with the function code and local variables
-	frame.ActivateFunction(fn, StopSignal, vm.tmp[:argc])
+	frame.ActivateFunction(fn, StopSignal, vm.tmp[:localsCount])
+	frame.SetCallSite(baseIP)
+	frame.SetStackBase(vm.sp)
 	vm.activeFrame = frame
-	vm.activeCode = code
+	vm.activeCode = fn.Code()
 	vm.ip = 0
 	// Evaluate the function code then return the result from TOS
 	if err := vm.eval(ctx); err != nil {
 		// Unwind the stack
 		vm.fp = baseFrame
-		vm.activeFrame = &vm.frames[vm.fp]
+		vm.activeFrame = vm.frames[vm.fp]
 		vm.activeCode = vm.activeFrame.code
 		vm.ip = baseIP
+		vm.dropHandlers(vm.fp)
 		return nil, err
 	}
 	vm.ip = baseIP
@@ -668,6 +1663,17 @@ func checkCallArgs(fn *object.Function, argc int) error {
 	// Number of required args when the function is called (those without defaults)
 	requiredArgsCount := fn.RequiredArgsCount()
 
+	// A function with a rest parameter accepts any number of extra arguments
+	if fn.RestParameter() != "" {
+		if argc < requiredArgsCount {
+			if requiredArgsCount == 1 {
+				return fmt.Errorf("type error: function takes at least 1 argument (%d given)", argc)
+			}
+			return fmt.Errorf("type error: function takes at least %d arguments (%d given)", requiredArgsCount, argc)
+		}
+		return nil
+	}
+
 	// Check if too many or too few arguments were passed
 	if argc > paramsCount || argc < requiredArgsCount {
 		switch paramsCount {
@@ -2,10 +2,19 @@ package vm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/risor-io/risor/builtins"
 	"github.com/risor-io/risor/compiler"
+	"github.com/risor-io/risor/importer"
 	"github.com/risor-io/risor/object"
 	"github.com/risor-io/risor/op"
 	"github.com/risor-io/risor/parser"
@@ -505,6 +514,67 @@ func TestSet(t *testing.T) {
 	}), result)
 }
 
+func TestSetOperations(t *testing.T) {
+	ints := func(values ...int64) object.Object {
+		items := make([]object.Object, 0, len(values))
+		for _, v := range values {
+			items = append(items, object.NewInt(v))
+		}
+		return object.NewSet(items)
+	}
+	tests := []testCase{
+		{`{1, 2} | {2, 3}`, ints(1, 2, 3)},
+		{`{1, 2} & {2, 3}`, ints(2)},
+		{`{1, 2} - {2, 3}`, ints(1)},
+		{`{1, 2} ^ {2, 3}`, ints(1, 3)},
+		{`a := {1}; b := {2}; a | b | {3}`, ints(1, 2, 3)},
+		{`{1, 2, 3} - {1} & {1, 2}`, ints(2)},
+		{`{1, 2}.difference({2})`, ints(1)},
+		{`{1, 2}.symmetric_difference({2, 3})`, ints(1, 3)},
+		{`{1}.is_subset({1, 2})`, object.True},
+		{`{1, 3}.is_subset({1, 2})`, object.False},
+		{`{1, 2}.is_superset({1})`, object.True},
+		{`{1}.is_superset({1, 2})`, object.False},
+		{`{1}.is_disjoint({2})`, object.True},
+		{`{1, 2}.is_disjoint({2})`, object.False},
+		{`s := {1}; s.update({2}); s`, ints(1, 2)},
+		{`s := {1, 2}; s.intersection_update({2, 3}); s`, ints(2)},
+		{`s := {1, 2}; s.difference_update({2}); s`, ints(1)},
+		{`s := {1, 2}; s.symmetric_difference_update({2, 3}); s`, ints(1, 3)},
+		{`a := {1}; b := a | {2}; a`, ints(1)},
+		{`freeze({1}) | {2}`, ints(1, 2)},
+		{`({1} - [2]).message()`, object.NewString("eval error: unsupported operation for set: 1 on type list")},
+		{`6 & 3`, object.NewInt(2)},
+		{`6 ^ 3`, object.NewInt(5)},
+		{`6 | 3`, object.NewInt(7)},
+	}
+	runTests(t, tests)
+
+	_, err := run(context.Background(), `s := freeze({1}); s.update({2})`)
+	require.NotNil(t, err)
+	require.Equal(t, "type error: cannot modify a frozen set", err.Error())
+
+	// Since "|" is also the pipe operator, an unsupported operand is an error
+	_, err = run(context.Background(), `{1} | [2]`)
+	require.NotNil(t, err)
+	require.Equal(t, "type error: object is not callable (got list)", err.Error())
+}
+
+func TestMapMerge(t *testing.T) {
+	tests := []testCase{
+		{`{a: 1, b: 2} | {b: 3, c: 4}`, object.NewMap(map[string]object.Object{
+			"a": object.NewInt(1), "b": object.NewInt(3), "c": object.NewInt(4),
+		})},
+		{`({z: 1, a: 2} | {m: 3}).keys()`, object.NewList([]object.Object{
+			object.NewString("z"), object.NewString("a"), object.NewString("m"),
+		})},
+		{`a := {x: 1}; b := a | {y: 2}; len(a)`, object.NewInt(1)},
+		{`({1: "a"} | {1: "b"})[1]`, object.NewString("b")},
+		{`m := {a: 1} | {b: 2} | {c: 3}; len(m)`, object.NewInt(3)},
+	}
+	runTests(t, tests)
+}
+
 func TestNonLocal(t *testing.T) {
 	result, err := run(context.Background(), `
 	y := 3
@@ -551,6 +621,72 @@ func TestMapKeys(t *testing.T) {
 	}), result)
 }
 
+func TestMapHashableKeys(t *testing.T) {
+	tests := []testCase{
+		{`m := {1: "a", 2.5: "b", true: "c"}; m[1]`, object.NewString("a")},
+		{`m := {1: "a", 2.5: "b", true: "c"}; m[2.5]`, object.NewString("b")},
+		{`m := {1: "a", 2.5: "b", true: "c"}; m[true]`, object.NewString("c")},
+		{`m := {1: "a"}; m[1] = "b"; m[1]`, object.NewString("b")},
+		{`m := {1: "a"}; 1 in m`, object.True},
+		{`m := {1: "a"}; "1" in m`, object.False},
+		{`m := {1: "a"}; m.get(2, "z")`, object.NewString("z")},
+		{`m := {1: "a"}; delete(m, 1); len(m)`, object.NewInt(0)},
+		{`m := {x: 1}; m["x"]`, object.NewInt(1)},
+		{`x := 3; m := {}; m[x] = 1; m[3]`, object.NewInt(1)},
+		{`{x: x * 2 for x in [1, 2]}[2]`, object.NewInt(4)},
+		{`m := {1: "a", 2: "b"}; string(m)`, object.NewString(`{1: "a", 2: "b"}`)},
+		// Numbers that compare equal are the same key
+		{`m := {1: "a"}; [m[1.0], m[byte(1)], m[decimal("1.00")], m[bigint(1)]]`, object.NewList([]object.Object{
+			object.NewString("a"), object.NewString("a"), object.NewString("a"), object.NewString("a"),
+		})},
+		{`m := {1.0: "a"}; m[1] = "b"; len(m)`, object.NewInt(1)},
+		{`m := {0.5: "a"}; m[decimal("0.50")]`, object.NewString("a")},
+		{`m := {0.1: "a"}; decimal("0.1") in m`, object.False},
+		{`len({1, 1.0, decimal(1), byte(1), bigint(1)})`, object.NewInt(1)},
+		{`len({float(bigint(10) ** 20), bigint(10) ** 20})`, object.NewInt(1)},
+	}
+	runTests(t, tests)
+
+	errTests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`{1: "a"}[2]`, "key error: 2"},
+		{`{"a": 1}["b"]`, `key error: "b"`},
+		{`m := {}; m[[1]] = 2`, "type error: list object is unhashable"},
+		{`{[1]: 2}`, "type error: list object is unhashable"},
+	}
+	for _, tt := range errTests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestMapInsertionOrder(t *testing.T) {
+	tests := []testCase{
+		{`{z: 1, a: 2, m: 3}.keys()`, object.NewList([]object.Object{
+			object.NewString("z"), object.NewString("a"), object.NewString("m"),
+		})},
+		{`{z: 1, a: 2, m: 3}.values()`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{`m := {z: 1, a: 2}; m["b"] = 3; m["z"] = 4; m.items()`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewString("z"), object.NewInt(4)}),
+			object.NewList([]object.Object{object.NewString("a"), object.NewInt(2)}),
+			object.NewList([]object.Object{object.NewString("b"), object.NewInt(3)}),
+		})},
+		{`m := {z: 1, a: 2}; delete(m, "z"); m["z"] = 3; keys(m)`, object.NewList([]object.Object{
+			object.NewString("a"), object.NewString("z"),
+		})},
+		{`m := {z: 1, a: 2}; result := []; for k, v := range m { result.append(k) }; result`,
+			object.NewList([]object.Object{object.NewString("z"), object.NewString("a")})},
+		{`json.marshal({z: 1, a: {y: 2, b: 3}})`, object.NewString(`{"z":1,"a":{"y":2,"b":3}}`)},
+		{`string({z: 1, a: 2})`, object.NewString(`{"z": 1, "a": 2}`)},
+	}
+	runTests(t, tests)
+}
+
 func TestClosure(t *testing.T) {
 	result, err := run(context.Background(), `
 	f := func(x) { func() { x } }
@@ -604,6 +740,21 @@ func TestClosureManyVariables(t *testing.T) {
 	require.Equal(t, object.NewStringList([]string{"hello", "world", "risor", "go"}), result)
 }
 
+func TestClosureAssignments(t *testing.T) {
+	tests := []testCase{
+		{`func f() { a := 1; b := [0]; g := func() { a++; b.append(1) }; g(); [a, b] }; f()`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewList([]object.Object{object.NewInt(0), object.NewInt(1)}),
+		})},
+		{`func f() { a := 1; b := 2; g := func() { b = 5; a += 1 }; g(); [a, b] }; f()`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(5),
+		})},
+		{`func f() { a := 1; b := 2; g := func() { b--; a *= 3 }; g(); [a, b] }; f()`, object.NewList([]object.Object{
+			object.NewInt(3), object.NewInt(1),
+		})},
+	}
+	runTests(t, tests)
+}
+
 func TestRecursiveExample1(t *testing.T) {
 	result, err := run(context.Background(), `
 	func twoexp(n) {
@@ -890,206 +1041,1649 @@ func TestTry(t *testing.T) {
 	runTests(t, tests)
 }
 
-func TestMultiVarAssignment(t *testing.T) {
+func TestTryCatch(t *testing.T) {
 	tests := []testCase{
-		{`a, b := [3, 4]; a`, object.NewInt(3)},
-		{`a, b := [3, 4]; b`, object.NewInt(4)},
-		{`a, b, c := [3, 4, 5]; a`, object.NewInt(3)},
-		{`a, b, c := [3, 4, 5]; b`, object.NewInt(4)},
-		{`a, b, c := [3, 4, 5]; c`, object.NewInt(5)},
-		{`a, b := "ᛛᛥ"; a`, object.NewString("ᛛ")},
-		{`a, b := "ᛛᛥ"; b`, object.NewString("ᛥ")},
-		{`a, b := {42, 43}; a`, object.NewInt(42)},
-		{`a, b := {42, 43}; b`, object.NewInt(43)},
-		{`a, b := {foo: 1, bar: 2}; a`, object.NewString("bar")},
-		{`a, b := {foo: 1, bar: 2}; b`, object.NewString("foo")},
+		{`x := 0; try { x = 1 } catch { x = 2 }; x`, object.NewInt(1)},
+		{`x := 0; try { error("oops") } catch { x = 2 }; x`, object.NewInt(2)},
+		{`x := ""; try { error("oops") } catch e { x = e.message() }; x`, object.NewString("oops")},
+		{`x := nil; try { throw "boom" } catch e { x = e }; type(x)`, object.NewString("error")},
+		{`x := ""; try { throw error("boom") } catch e { x = e.message() }; x`, object.NewString("boom")},
+		{`x := ""; try { [1].map(func(v) { throw "inner" }) } catch e { x = e.message() }; x`, object.NewString("inner")},
+		{`func f() { throw "deep" }; func g() { f(); return 1 }
+		  x := ""; try { g() } catch e { x = e.message() }; x`, object.NewString("deep")},
+		{`func f() { try { throw "a" } catch e { return "caught " + e.message() } }; f()`, object.NewString("caught a")},
+		{`x := []; try { try { throw "a" } catch e { throw "b" } } catch e { x.append(e.message()) }; x`,
+			object.NewList([]object.Object{object.NewString("b")})},
+		{`x := 0; for i := 0; i < 3; i++ { try { throw "x" } catch { x++ } }; x`, object.NewInt(3)},
+		{`x := ""; try { 1 / 0 } catch e { x = e.message() }; x`, object.NewString("value error: division by zero")},
+		{`x := ""; try { y := 0; 5 % y } catch e { x = e.kind() }; x`, object.NewString("value")},
+		{`x := ""; try { byte(1) / byte(0) } catch e { x = e.kind() }; x`, object.NewString("value")},
+		{`x := ""; try { bigint(1) / 0 } catch e { x = e.kind() }; x`, object.NewString("value")},
+		{`x := ""; try { y := 1; y.foo } catch e { x = e.kind() }; x`, object.NewString("exec")},
+		{`x := nil; try { throw "boom" } catch e { x = e.kind() }; x`, object.NewString("")},
 	}
 	runTests(t, tests)
 }
 
-func TestFunctions(t *testing.T) {
-	tests := []testCase{
-		{`func add(x, y) { x + y }; add(3, 4)`, object.NewInt(7)},
-		{`func add(x, y) { x + y }; add(3, 4) + 5`, object.NewInt(12)},
-		{`func inc(x, amount=1) { x + amount }; inc(3)`, object.NewInt(4)},
-		{`func factorial(n) { if (n == 1) { return 1 } else { return n * factorial(n - 1) } }; factorial(5)`, object.NewInt(120)},
-		{`z := 10; y := func(x, inc=100) { x + z + inc }; y(3)`, object.NewInt(113)},
-		{`func(x="a", y="b") { x + y }()`, object.NewString("ab")},
-		{`func(x="a", y="b") { x + y + "c" }()`, object.NewString("abc")},
-		{`func(x="a", y="b") { x + y + "c" }("W")`, object.NewString("Wbc")},
-		{`func(x="a", y="b") { x + y + "c" }("W", "X")`, object.NewString("WXc")},
-		{`func(x="a", y="b") { return "X"; x + y + "c" }()`, object.NewString("X")},
-		{`x := 1; func() { y := 10; x + y }()`, object.NewInt(11)},
-		{`x := 1; func() { func() { y := 10; x + y } }()()`, object.NewInt(11)},
-	}
-	runTests(t, tests)
+func TestDivisionByZero(t *testing.T) {
+	_, err := run(context.Background(), `x := 0; 1 / x`)
+	require.NotNil(t, err)
+	require.Equal(t, "value error: division by zero", err.Error())
 }
 
-func TestContainers(t *testing.T) {
+func TestTryFinally(t *testing.T) {
 	tests := []testCase{
-		{`true`, object.True},
-		{`[1,2,3][2]`, object.NewInt(3)},
-		{`"hello"[1]`, object.NewString("e")},
-		{`{"x": 10, "y": 20}["x"]`, object.NewInt(10)},
-		{`3 in [1, 2, 3]`, object.True},
-		{`4 in [1, 2, 3]`, object.False},
-		{`{"foo": "bar"}["foo"]`, object.NewString("bar")},
-		{`{foo: "bar"}["foo"]`, object.NewString("bar")},
-		{`[1, 2, 3, 4, 5].filter(func(x) { x > 3 })`, object.NewList(
-			[]object.Object{object.NewInt(4), object.NewInt(5)})},
-		{`range [1]`, object.NewListIter(object.NewList([]object.Object{object.NewInt(1)}))},
+		{`x := []; try { x.append(1) } finally { x.append(2) }; x`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`x := []; try { throw "a" } catch { x.append(1) } finally { x.append(2) }; x`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`x := []; try { try { throw "a" } finally { x.append(1) } } catch e { x.append(e.message()) }; x`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("a")})},
+		{`x := []; try { try { throw "a" } catch { throw "b" } finally { x.append(1) } } catch e { x.append(e.message()) }; x`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")})},
+		{`x := []; func f() { try { return 1 } finally { x.append(2) } }; x.append(f()); x`,
+			object.NewList([]object.Object{object.NewInt(2), object.NewInt(1)})},
+		{`x := []; for i := range [1, 2, 3] { try { if i == 1 { break } } finally { x.append(i) } }; x`,
+			object.NewList([]object.Object{object.NewInt(0), object.NewInt(1)})},
+		{`x := []; for i := range [1, 2] { try { continue } finally { x.append(i) } }; x`,
+			object.NewList([]object.Object{object.NewInt(0), object.NewInt(1)})},
 	}
 	runTests(t, tests)
 }
 
-func TestStrings(t *testing.T) {
-	tests := []testCase{
-		{`"hello" + " " + "world"`, object.NewString("hello world")},
-		{`"hello".contains("e")`, object.True},
-		{`"hello".contains("x")`, object.False},
-		{`"hello".contains("ello")`, object.True},
-		{`"hello".contains("ellx")`, object.False},
-		{`"hello".contains("")`, object.True},
-		{`"hello"[0]`, object.NewString("h")},
-		{`"hello"[1]`, object.NewString("e")},
-		{`"hello"[-1]`, object.NewString("o")},
-		{`"hello"[-2]`, object.NewString("l")},
-		{`a := 1; b := "ok"; '{a + 1}-{b | strings.to_upper}'`, object.NewString("2-OK")},
-		{`func(a, b) { return 'A: {a} B: {b}' }("hi", "bye")`, object.NewString("A: hi B: bye")},
-	}
-	runTests(t, tests)
+func TestUncaughtThrow(t *testing.T) {
+	_, err := run(context.Background(), `try { throw "a" } finally { 1 }`)
+	require.NotNil(t, err)
+	require.Equal(t, "a", err.Error())
+
+	_, err = run(context.Background(), `throw 42`)
+	require.NotNil(t, err)
+	require.Equal(t, "type error: throw expected an error or string (got int)", err.Error())
 }
 
-func TestPipes(t *testing.T) {
+func TestRuntimeErrorStack(t *testing.T) {
+	code := `
+func inner(x) {
+	return x.foo
+}
+func outer() {
+	return inner(1)
+}
+outer()`
+	_, err := run(context.Background(), code)
+	require.NotNil(t, err)
+	require.Equal(t, "exec error: attribute \"foo\" not found on int object", err.Error())
+	runtimeErr, ok := err.(*RuntimeError)
+	require.True(t, ok)
+	stack := runtimeErr.Stack()
+	require.Len(t, stack, 3)
+	require.Equal(t, "main", stack[0].Function)
+	require.Equal(t, 8, stack[0].Position.LineNumber())
+	require.Equal(t, "outer", stack[1].Function)
+	require.Equal(t, 6, stack[1].Position.LineNumber())
+	require.Equal(t, "inner", stack[2].Function)
+	require.Equal(t, 3, stack[2].Position.LineNumber())
+	pos, ok := runtimeErr.Position()
+	require.True(t, ok)
+	require.Equal(t, 3, pos.LineNumber())
+	require.Contains(t, runtimeErr.FriendlyErrorMessage(), "location: line 3, column 10")
+}
+
+func TestRuntimeErrorStackCallback(t *testing.T) {
+	code := `
+[1, 2].map(func(x) {
+	return x.foo
+})`
+	_, err := run(context.Background(), code)
+	require.NotNil(t, err)
+	runtimeErr, ok := err.(*RuntimeError)
+	require.True(t, ok)
+	stack := runtimeErr.Stack()
+	require.Len(t, stack, 2)
+	require.Equal(t, "main", stack[0].Function)
+	require.Equal(t, 2, stack[0].Position.LineNumber())
+	require.Equal(t, "<anonymous>", stack[1].Function)
+	require.Equal(t, 3, stack[1].Position.LineNumber())
+}
+
+func TestDefer(t *testing.T) {
 	tests := []testCase{
-		{`"hello" | strings.to_upper`, object.NewString("HELLO")},
-		{`"hello" | len`, object.NewInt(5)},
-		{`func() { "hello" }() | len`, object.NewInt(5)},
-		{`["a", "b"] | strings.join(",") | strings.to_upper`, object.NewString("A,B")},
-		{`func() { "a" } | call`, object.NewString("a")},
-		{`"abc" | getattr("to_upper") | call`, object.NewString("ABC")},
-		{`"abc" | func(s) { s.to_upper() }`, object.NewString("ABC")},
-		{`[11, 12, 3] | math.max`, object.NewFloat(12)},
-		{`"42" | json.unmarshal`, object.NewFloat(42)},
+		{`out := []
+		func f() {
+			defer out.append(1)
+			defer out.append(2)
+			out.append(3)
+		}
+		f()
+		out`, object.NewList([]object.Object{
+			object.NewInt(3), object.NewInt(2), object.NewInt(1),
+		})},
+		{`out := []
+		func f() {
+			x := 1
+			defer out.append(x)
+			x = 2
+			return x
+		}
+		[f(), out]`, object.NewList([]object.Object{
+			object.NewInt(2),
+			object.NewList([]object.Object{object.NewInt(1)}),
+		})},
+		{`out := []
+		func f() {
+			defer func() { out.append("deferred") }()
+			throw "fail"
+		}
+		try { f() } catch err { out.append(err.message()) }
+		out`, object.NewList([]object.Object{
+			object.NewString("deferred"), object.NewString("fail"),
+		})},
+		{`out := []
+		func f(x) {
+			defer out.append(x)
+			return x * 2
+		}
+		[[1, 2].map(f), out]`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(2), object.NewInt(4)}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)}),
+		})},
+		{`out := []
+		func f() {
+			for i := 0; i < 3; i++ {
+				defer out.append(i)
+			}
+		}
+		f()
+		out`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(1), object.NewInt(0),
+		})},
 	}
 	runTests(t, tests)
 }
 
-func TestQuicksort(t *testing.T) {
-	result, err := run(context.Background(), `
-	func quicksort(arr) {
-		if len(arr) < 2 {
-			return arr
-		} else {
-			pivot := arr[0]
-			less := arr[1:].filter(func(x) { x <= pivot })
-			more := arr[1:].filter(func(x) { x > pivot })
-			return quicksort(less) + [pivot] + quicksort(more)
-		}
+func TestDeferUncaughtError(t *testing.T) {
+	ctx := context.Background()
+	code := `
+	out := []
+	func f() {
+		defer out.append("cleanup")
+		throw "fail"
 	}
-	quicksort([10, 5, 2, 3])
-	`)
+	f()`
+	ast, err := parser.Parse(ctx, code)
 	require.Nil(t, err)
-	require.Equal(t, object.NewList(
-		[]object.Object{
-			object.NewInt(2),
-			object.NewInt(3),
-			object.NewInt(5),
-			object.NewInt(10),
-		}), result)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+	vm := New(main)
+	err = vm.Run(ctx)
+	require.NotNil(t, err)
+	require.Equal(t, "fail", err.Error())
+	sym, ok := main.Symbols.Get("out")
+	require.True(t, ok)
+	require.Equal(t, object.NewList([]object.Object{object.NewString("cleanup")}),
+		main.Globals()[sym.Index])
 }
 
-func TestLoopBreak(t *testing.T) {
-	result, err := run(context.Background(), `
-	x := 0
-	for i := 0; i < 10; i++ {
-		if i == 3 { break }
-		x = i
+func TestDeferHalt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	ast, err := parser.Parse(ctx, `
+	out := []
+	func f() {
+		defer out.append("cleanup")
+		for {}
 	}
-	x
-	`)
+	f()`)
 	require.Nil(t, err)
-	require.Equal(t, object.NewInt(2), result)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+	err = New(main).Run(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	sym, ok := main.Symbols.Get("out")
+	require.True(t, ok)
+	require.Equal(t, object.NewList([]object.Object{object.NewString("cleanup")}),
+		main.Globals()[sym.Index])
 }
 
-func TestLoopContinue(t *testing.T) {
-	result, err := run(context.Background(), `
-	x := 0
-	for i := 0; i < 10; i++ {
-		if i > 3 { continue }
-		x = i
+func TestDeferredError(t *testing.T) {
+	_, err := run(context.Background(), `
+	func f() {
+		defer func() { throw "deferred fail" }()
+		return 1
 	}
-	x
-	`)
-	require.Nil(t, err)
-	require.Equal(t, object.NewInt(3), result)
+	f()`)
+	require.NotNil(t, err)
+	require.Equal(t, "deferred fail", err.Error())
 }
 
-func TestRangeLoopBreak(t *testing.T) {
-	result, err := run(context.Background(), `
-	x := 0
-	for i := range [0, 1, 2, 3, 4] {
-		if i == 3 { break }
-		x = i
+func TestChannels(t *testing.T) {
+	tests := []testCase{
+		{`ch := chan(1); ch <- 42; <-ch`, object.NewInt(42)},
+		{`ch := chan(2); ch <- 1; ch <- 2; len(ch)`, object.NewInt(2)},
+		{`ch := chan(2); ch.send("a"); ch.receive()`, object.NewString("a")},
+		{`ch := chan(); ch.close(); <-ch`, object.Nil},
+		{`ch := chan(3); ch <- 1; ch <- 2; ch <- 3; ch.close()
+		  total := 0
+		  for v := range ch { total += v }
+		  total`, object.NewInt(6)},
+		{`ch := chan(3); ch <- "a"; ch <- "b"; ch.close()
+		  r := []
+		  for i, v := range ch { r.append([i, v]) }
+		  r`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(0), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")}),
+		})},
+		{`ch := chan(2); ch <- 3; ch <- 4; ch.close(); [v * 2 for v in ch]`, object.NewList([]object.Object{
+			object.NewInt(6), object.NewInt(8),
+		})},
+		{`ch := chan()
+		  go func(x) { ch <- x * 2 }(21)
+		  <-ch`, object.NewInt(42)},
+		{`results := chan(10)
+		  func work(n) { results <- n * n }
+		  for i := 0; i < 10; i++ { go work(i) }
+		  total := 0
+		  for i := 0; i < 10; i++ { total += <-results }
+		  total`, object.NewInt(285)},
+		{`ch := chan()
+		  done := chan()
+		  go func() {
+			  for v := range ch { done <- v + 1 }
+		  }()
+		  ch <- 1
+		  <-done`, object.NewInt(2)},
 	}
-	x
-	`)
+	runTests(t, tests)
+}
+
+func TestGoroutineErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// An uncaught error in a goroutine halts the run and is returned
+	_, err := run(ctx, `ch := chan()
+	go func() { error("boom") }()
+	<-ch`)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "boom")
+
+	// Errors caught within the goroutine are not reported
+	result, err := run(ctx, `ch := chan()
+	go func() { try { error("boom") } catch e { ch <- e.message() } }()
+	<-ch`)
 	require.Nil(t, err)
-	require.Equal(t, object.NewInt(2), result)
+	require.Equal(t, object.NewString("boom"), result)
 }
 
-func TestRangeLoopContinue(t *testing.T) {
-	result, err := run(context.Background(), `
-	x := 0
-	for i := range [0, 1, 2, 3, 4] {
-		if i > 3 { continue }
-		x = i
+func TestGoroutinesStopWithRun(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		_, err := run(context.Background(), `go func() { for { } }(); 1`)
+		require.Nil(t, err)
 	}
-	x
-	`)
-	require.Nil(t, err)
-	require.Equal(t, object.NewInt(3), result)
+	// The goroutines halt once the runs that started them have finished
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), before)
 }
 
-func TestSimpleLoopBreak(t *testing.T) {
+func TestGoroutineGlobals(t *testing.T) {
 	result, err := run(context.Background(), `
 	x := 0
-	for {
-		x++
-		if x == 2 { break }
-		max := math.max([1, 2, 3]) // inject some extra instructions
+	done := chan(4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 100; j++ { x++ }
+			done <- true
+		}()
 	}
-	x
-	`)
+	for i := 0; i < 4; i++ { <-done }
+	x > 0`)
 	require.Nil(t, err)
-	require.Equal(t, object.NewInt(2), result)
+	require.Equal(t, object.True, result)
+
+	// Locals captured by a goroutine's closure are shared in the same way
+	result, err = run(context.Background(), `
+	func f() {
+		n := 0
+		done := chan()
+		go func() {
+			for i := 0; i < 100; i++ { n++ }
+			done <- true
+		}()
+		for i := 0; i < 100; i++ { n++ }
+		<-done
+		return n > 0
+	}
+	f()`)
+	require.Nil(t, err)
+	require.Equal(t, object.True, result)
 }
 
-func TestNestedLoops(t *testing.T) {
-	result, err := run(context.Background(), `
-	x, y, z := [0, 0, 0]
-	for {
-		x++ // This should execute 3 times total
-		if x == 3 { break }
-		// We should reach this point twice, with x as 1 then 2
-		for i := range [0, 1, 2, 3] {
-			y++ // This should execute 8 times total
-			if i > 1 { continue }
-			// We should reach this point 4 times total
-			for h := 0; h < 10; h++ {
-				z++ // This should execute 16 times total (4 times per inner loop)
-				if h == 3 { break }
-			}
-		}
+func TestSelect(t *testing.T) {
+	tests := []testCase{
+		{`a := chan(1); b := chan(1); b <- "b"
+		  select {
+		  case v := <-a:
+			  "a: " + v
+		  case v := <-b:
+			  "b: " + v
+		  }`, object.NewString("b: b")},
+		{`a := chan()
+		  select {
+		  case <-a:
+			  1
+		  default:
+			  2
+		  }`, object.NewInt(2)},
+		{`a := chan(1)
+		  select {
+		  case a <- 5:
+			  "sent"
+		  }
+		  <-a`, object.NewInt(5)},
+		{`a := chan(); a.close()
+		  select {
+		  case v := <-a:
+			  v
+		  }`, object.Nil},
+		{`a := chan()
+		  x := select { default: }
+		  x`, object.Nil},
 	}
-	[x, y, z]
-	`)
-	require.Nil(t, err)
-	require.Equal(t, object.NewList([]object.Object{
-		object.NewInt(3),
-		object.NewInt(8),
-		object.NewInt(16),
-	}), result)
+	runTests(t, tests)
+}
+
+func TestChannelErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`ch := chan(); ch.close(); ch <- 1`, "exec error: send on closed channel"},
+		{`ch := chan(); ch.close(); ch.close()`, "exec error: close of closed channel"},
+		{`<-1`, "type error: receive from non-chan type int"},
+		{`x := 1; x <- 2`, "type error: send to non-chan type int"},
+		{`chan(-1)`, "value error: chan() size must be non-negative (-1 given)"},
+		{`go 1()`, "type error: object is not callable (got int)"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error())
+	}
+}
+
+func TestChannelCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := run(ctx, `ch := chan(); <-ch`)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err = run(ctx, `ch := chan(); select { case v := <-ch: v }`)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestVariadicFunctions(t *testing.T) {
+	tests := []testCase{
+		{`func f(*rest) { rest }; f()`, object.NewList([]object.Object{})},
+		{`func f(a, *rest) { [a, rest] }; f(1, 2, 3)`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewList([]object.Object{object.NewInt(2), object.NewInt(3)}),
+		})},
+		{`func f(a, b=2, *rest) { a + b + len(rest) }; f(1)`, object.NewInt(3)},
+		{`func f(**opts) { opts }; f(x=1)`, object.NewMap(map[string]object.Object{
+			"x": object.NewInt(1),
+		})},
+		{`func f(a, **opts) { [a, opts] }; f(a=1, b=2)`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewMap(map[string]object.Object{"b": object.NewInt(2)}),
+		})},
+		{`func f(a, b=10) { a - b }; f(b=1, a=5)`, object.NewInt(4)},
+		{`func f(a, b=10, c=100) { a + b + c }; f(1, c=0)`, object.NewInt(11)},
+		{`func f(a, b, c) { [a, b, c] }; x := [1, 2]; f(...x, 3)`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{`func f(*rest) { rest }; f(0, ...[1, 2], 3, ...{4})`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1), object.NewInt(2), object.NewInt(3), object.NewInt(4),
+		})},
+		{`func f(a, b) { a - b }; f(...[], b=1, a=3)`, object.NewInt(2)},
+		{`func fact(n, *_) { n <= 1 ? 1 : n * fact(n - 1) }; fact(5, 0)`, object.NewInt(120)},
+		{`"a,b".split(...[","])`, object.NewList([]object.Object{
+			object.NewString("a"), object.NewString("b"),
+		})},
+		{"[1, 2].map(func(x, *rest) { x * 2 })", object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(4),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestKeywordArgsPartial(t *testing.T) {
+	tests := []testCase{
+		{`func sub(a, b=0) { a - b }; 5 | sub(b=2)`, object.NewInt(3)},
+		{`func f(a, *rest, **opts) { [a, rest, opts] }; 1 | f(...[2], k=3)`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewList([]object.Object{object.NewInt(2)}),
+			object.NewMap(map[string]object.Object{"k": object.NewInt(3)}),
+		})},
+		{`out := []; func f(a, b=1) { out.append(a - b) }
+		  func g() { defer f(b=10, a=12) }; g(); out`, object.NewList([]object.Object{
+			object.NewInt(2),
+		})},
+		{`ch := chan(); func f(a, *rest, **opts) { ch <- [a, rest, opts] }
+		  go f(...[1, 2], x=3); <-ch`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewList([]object.Object{object.NewInt(2)}),
+			object.NewMap(map[string]object.Object{"x": object.NewInt(3)}),
+		})},
+		{`ch := chan(size=2); ch.cap()`, object.NewInt(2)},
+		{`ch := chan(...[3]); ch.cap()`, object.NewInt(3)},
+	}
+	runTests(t, tests)
+}
+
+func TestKeywordArgsBuiltin(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `f(1, 2, x=3)`)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithBuiltins(map[string]object.Object{
+		"f": object.NewBuiltin("f", nil).WithKeywords(
+			func(ctx context.Context, kwargs map[string]object.Object, args ...object.Object) object.Object {
+				return object.NewList([]object.Object{
+					object.NewList(args),
+					object.NewMap(kwargs),
+				})
+			}),
+	}))
+	require.Nil(t, err)
+	vm := New(code)
+	require.Nil(t, vm.Run(ctx))
+	result, ok := vm.TOS()
+	require.True(t, ok)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)}),
+		object.NewMap(map[string]object.Object{"x": object.NewInt(3)}),
+	}), result)
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []testCase{
+		{`items := [1, -2, 3]; [x * 2 for x in items if x > 0]`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(6),
+		})},
+		{`[x for x in []]`, object.NewList([]object.Object{})},
+		{`[i for i, x in ["a", "b"]]`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1),
+		})},
+		{`[k for k in {b: 1, a: 2}]`, object.NewList([]object.Object{
+			object.NewString("b"), object.NewString("a"),
+		})},
+		{`[[x, y] for x in [1, 2, 3] if x != 2 for y in "ab"]`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")}),
+			object.NewList([]object.Object{object.NewInt(3), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(3), object.NewString("b")}),
+		})},
+		{`m := {a: 1, b: 2}; {k: v * 10 for k, v in m}`, object.NewMap(map[string]object.Object{
+			"a": object.NewInt(10), "b": object.NewInt(20),
+		})},
+		{`{x: true for x in ["a", "b"] if x != "a"}`, object.NewMap(map[string]object.Object{
+			"b": object.True,
+		})},
+		{`{x % 2 for x in [1, 2, 3, 4]}`, object.NewSet([]object.Object{
+			object.NewInt(0), object.NewInt(1),
+		})},
+		{`func f(xs) { n := 10; [x + n for x in xs] }; f([1, 2])`, object.NewList([]object.Object{
+			object.NewInt(11), object.NewInt(12),
+		})},
+		{`x := 5; y := [x for x in [1]]; x`, object.NewInt(5)},
+		{`len([[y for y in x] for x in [[1], [2, 3]]])`, object.NewInt(2)},
+	}
+	runTests(t, tests)
+}
+
+func TestComprehensionErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`{[x]: 1 for x in [1]}`, "type error: list object is unhashable"},
+		{`{[x] for x in [1]}`, "type error: list object is unhashable"},
+		{`[x for x in 1]`, "type error: object is not iterable (got int)"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []testCase{
+		{`x := nil; x?.foo`, object.Nil},
+		{`x := "abc"; x?.foo`, object.Nil},
+		{`x := "abc"; x?.to_upper()`, object.NewString("ABC")},
+		{`x := nil; x?.to_upper()`, object.Nil},
+		{`x := "abc"; x?.nope(1)`, object.Nil},
+		{`n := 0; func f() { n++ }; x := nil; x?.to_upper(f()); n`, object.NewInt(0)},
+		{`x := nil; x?.["a"]`, object.Nil},
+		{`x := {a: {b: 1}}; x?.["a"]?.["b"]`, object.NewInt(1)},
+		{`x := {a: 1}; x?.keys()`, object.NewList([]object.Object{object.NewString("a")})},
+		{`x := {a: nil}; x["a"]?.foo?.bar`, object.Nil},
+		// A nil receiver skips the rest of the chain
+		{`a := nil; a?.b.c`, object.Nil},
+		{`a := nil; a?.b()`, object.Nil},
+		{`a := nil; a?.b.c()`, object.Nil},
+		{`a := nil; a?.b().c["d"]`, object.Nil},
+		{`a := nil; a?.["b"].c`, object.Nil},
+		{`n := 0; func f() { n++ }; a := nil; a?.b.c(f()); n`, object.NewInt(0)},
+		{`a := {b: "x"}; a?.["b"].to_upper()`, object.NewString("X")},
+		{`a := nil; [a?.b.c, 2]`, object.NewList([]object.Object{object.Nil, object.NewInt(2)})},
+		{`a := nil; b := {c: nil}; b["c"]?.d.e ?? a?.f.g ?? 3`, object.NewInt(3)},
+		// Parentheses end the chain
+		{`a := nil; (a?.b.c)`, object.Nil},
+		{`a := nil; x := ""; try { (a?.b).c } catch e { x = e.message() }; x`,
+			object.NewString(`exec error: attribute "c" not found on nil object`)},
+		{`a := nil; x := ""; try { (a?.["b"])["c"] } catch e { x = "raised" }; x`, object.NewString("raised")},
+		{`a := "abc"; (a?.to_upper()).to_lower()`, object.NewString("abc")},
+	}
+	runTests(t, tests)
+}
+
+func TestNilCoalescing(t *testing.T) {
+	tests := []testCase{
+		{`nil ?? 3`, object.NewInt(3)},
+		{`false ?? 3`, object.False},
+		{`0 ?? 3`, object.NewInt(0)},
+		{`nil ?? nil ?? 4`, object.NewInt(4)},
+		{`n := 0; func f() { n++ }; 1 ?? f(); n`, object.NewInt(0)},
+		{`x := nil; x?.a ?? "default"`, object.NewString("default")},
+		{`x := {a: 2}; x?.["a"] ?? 0`, object.NewInt(2)},
+	}
+	runTests(t, tests)
+}
+
+func TestResults(t *testing.T) {
+	tests := []testCase{
+		{`ok(1)`, object.NewOkResult(object.NewInt(1))},
+		{`ok(1).unwrap()`, object.NewInt(1)},
+		{`ok(1).is_ok()`, object.True},
+		{`err("boom").is_err()`, object.True},
+		{`err("boom %d", 1).err_msg()`, object.NewString("boom 1")},
+		{`err("boom").unwrap_or(2)`, object.NewInt(2)},
+		{`unwrap_or(err("boom"), 2)`, object.NewInt(2)},
+		{`unwrap(ok("a"))`, object.NewString("a")},
+		{`ok(2).map(func(x) { x * 3 }).unwrap()`, object.NewInt(6)},
+		{`err("boom").map(func(x) { x * 3 }).is_err()`, object.True},
+		{`ok({a: 1}).keys()`, object.NewList([]object.Object{object.NewString("a")})},
+		{`try(ok(1), 2)`, object.NewInt(1)},
+		{`try(err("boom"), 2)`, object.NewInt(2)},
+		{`json.try_unmarshal("true").unwrap()`, object.True},
+		{`json.try_unmarshal("nope").is_err()`, object.True},
+	}
+	runTests(t, tests)
+}
+
+func TestShadowBuiltins(t *testing.T) {
+	tests := []testCase{
+		{`err := 5; err`, object.NewInt(5)},
+		{`ok := true; ok`, object.True},
+		{`x, err := [1, 2]; [x, err]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`func ok() { 3 }; ok()`, object.NewInt(3)},
+		{`r := ok(1); ok := 2; [r.unwrap(), ok]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`func f() { err("boom") }; err := 1; f().is_err()`, object.True},
+	}
+	runTests(t, tests)
+}
+
+func TestPropagate(t *testing.T) {
+	tests := []testCase{
+		{`func f(r) { v := r?; return ok(v + 1) }; f(ok(1))`, object.NewOkResult(object.NewInt(2))},
+		{`func f(r) { v := r?; return ok(v + 1) }; f(err("boom")).err_msg()`, object.NewString("boom")},
+		{`func f(r) { return ok(1 + r? * 2) }; f(ok(3)).unwrap()`, object.NewInt(7)},
+		{`n := 0; func f(r) { r?; n = 1 }; f(err("boom")); n`, object.NewInt(0)},
+		{`func f() { defer func() {}(); return json.try_unmarshal("nope")? }; f().is_err()`, object.True},
+	}
+	runTests(t, tests)
+}
+
+func TestPropagateErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`ok(1)?`, "? operator outside of function"},
+		{`func f() { return 1? }; f()`, "type error: ? operator expected a result (got int)"},
+		{`err("boom").unwrap()`, `result error: unwrap() called on an error: error("boom")`},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []testCase{
+		{`func count(n) { for i := 0; i < n; i++ { yield i } }; list(count(3))`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1), object.NewInt(2),
+		})},
+		{`func count(n) { for i := 0; i < n; i++ { yield i } }; r := []; for x in count(3) { r.append(x * 10) }; r`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(10), object.NewInt(20),
+		})},
+		{`func g() { yield "a"; yield "b" }; r := []; for i, x := range g() { r.append([i, x]) }; r`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(0), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")}),
+		})},
+		{`func g() { yield "a"; yield "b" }; r := []; for i := range g() { r.append(i) }; r`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1),
+		})},
+		{`func g() { yield 1; yield 2 }; it := iter(g()); [it.next(), it.next(), it.next()]`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.Nil,
+		})},
+		{`func nat() { i := 0; for { yield i; i++ } }; r := []; for x in nat() { if x > 2 { break }; r.append(x) }; r`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1), object.NewInt(2),
+		})},
+		{`func g(a, *rest) { for _, x := range rest { yield x + a } }; [x for x in g(10, 1, 2)]`, object.NewList([]object.Object{
+			object.NewInt(11), object.NewInt(12),
+		})},
+		{`func g() { yield 1 }; func h() { for x in g() { yield x * 2 }; yield 100 }; list(h())`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(100),
+		})},
+		{`func g() { yield; return 5 }; list(g())`, object.NewList([]object.Object{object.Nil})},
+		{`func g() { yield 1 }; func f(*args) { args }; f(...g())`, object.NewList([]object.Object{object.NewInt(1)})},
+		{`n := 0; func g() { n++; yield n }; x := g(); n`, object.NewInt(0)},
+		{`x := 5; func g() { yield x; x = 6 }; list(g()); x`, object.NewInt(6)},
+		{`func g() { yield 1 }; type(g())`, object.NewString("generator")},
+		{`func g() { yield 1 }; string(g())`, object.NewString("generator(g)")},
+		{`[x for x in func() { yield 1; yield 2 }()]`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`func g() { yield 1; throw "boom" }; list(g())`, "boom"},
+		{`func g() { yield 1; throw "boom" }; for x in g() { x }`, "boom"},
+		{`func g() { yield 1; throw "boom" }; x := g(); x.next(); x.next()`, "boom"},
+		{`gen := nil; func g() { yield gen.next() }; gen = g(); gen.next()`, "exec error: generator is already running"},
+		{`yield 1`, "yield outside of function"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestGeneratorCatch(t *testing.T) {
+	code := `
+	func g() {
+		yield 1
+		throw "boom"
+	}
+	r := []
+	try {
+		for x in g() { r.append(x) }
+	} catch e {
+		r.append(e.message())
+	}
+	r`
+	result, err := run(context.Background(), code)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(1), object.NewString("boom"),
+	}), result)
+}
+
+func TestSwitchPatterns(t *testing.T) {
+	tests := []testCase{
+		{`switch [1, 2, 3] { case [first, *rest]: [first, rest] }`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewList([]object.Object{object.NewInt(2), object.NewInt(3)}),
+		})},
+		{`switch [1, 2, 3, 4] { case [a, *mid, z]: [a, mid, z] }`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewList([]object.Object{object.NewInt(2), object.NewInt(3)}),
+			object.NewInt(4),
+		})},
+		{`switch [1] { case []: "empty"; case [a, b]: "two"; case [a]: a * 10 }`, object.NewInt(10)},
+		{`switch [1, [2, 3]] { case [a, [b, c]]: a + b + c }`, object.NewInt(6)},
+		{`switch [1, 2] { case [a, *_]: a }`, object.NewInt(1)},
+		{`cfg := {"type": "s3", "bucket": "logs", "region": "us-east-1"}
+		  switch cfg {
+		  case {"type": "gcs"}: "gcs"
+		  case {"type": "s3", "bucket": b}: b
+		  }`, object.NewString("logs")},
+		{`switch {name: "x", age: 3} { case {name, age}: name + string(age) }`, object.NewString("x3")},
+		{`switch 42 { case string(s): "str"; case int(n): n + 1 }`, object.NewInt(43)},
+		{`switch [1] { case map(m): 1; case list([x]): x }`, object.NewInt(1)},
+		{`switch 2 ** 64 { case bigint(n): "bigint" }`, object.NewString("bigint")},
+		{`switch 2 ** 64 { case int(n): n > 0 }`, object.True},
+		{`switch 42 { case bigint(n): "bigint"; case int(n): "int" }`, object.NewString("int")},
+		{`switch decimal("1.5") { case float(f): "float"; case decimal(d): string(d) }`, object.NewString("1.5")},
+		{`switch 1 + 2i { case complex(c): c.imag }`, object.NewFloat(2)},
+		{`switch regexp.compile("a+") { case regexp(r): r.match("aa") }`, object.True},
+		{`switch range(3) { case list(l): "list"; case range(r): len(r) }`, object.NewInt(3)},
+		{`struct P { x }; switch (P{x: 4}) { case struct(s): s.x }`, object.NewInt(4)},
+		{`struct P { x }; switch P { case struct(s): "instance"; default: "type" }`, object.NewString("type")},
+		{`switch 42 { case x if x > 100: "big"; case x if x > 10: "medium"; default: "small" }`, object.NewString("medium")},
+		{`switch 5 { case x if x > 100: "big"; case x if x > 10: "medium"; default: "small" }`, object.NewString("small")},
+		{`switch [1, "x"] { case [1, s] if s == "y": 1; case [1, s]: s }`, object.NewString("x")},
+		{`switch 3 { case 1, 2: "low"; case 3 if false: "no"; case 3, 4: "high" }`, object.NewString("high")},
+		{`switch -1 { case [-1]: "list"; case -1: "neg" }`, object.NewString("neg")},
+		{`switch "hi" { case _: "any" }`, object.NewString("any")},
+		{`x := 1; switch 2 { case x: "one"; case 2: "two" }`, object.NewString("two")},
+		{`func f(v) { switch v { case [n]: n; default: 0 } }; [f([7]), f(1)]`, object.NewList([]object.Object{
+			object.NewInt(7), object.NewInt(0),
+		})},
+		{`a := 1; switch [2] { case [a]: a }; a`, object.NewInt(1)},
+		{`switch [1] { case [a], [a, _]: a }`, object.NewInt(1)},
+	}
+	runTests(t, tests)
+}
+
+func TestSwitchPatternErrors(t *testing.T) {
+	_, err := run(context.Background(), `switch [1, 1] { case [a, a]: 1 }`)
+	require.NotNil(t, err)
+	require.Equal(t, "duplicate name in pattern: a", err.Error())
+}
+
+func TestKeywordArgsErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`func f(a) { a }; f(b=1)`, "type error: function got an unexpected keyword argument: b"},
+		{`func f(a) { a }; f(1, a=1)`, "type error: function got multiple values for argument: a"},
+		{`func f(a, b) { a }; f(b=1)`, "type error: function missing argument: a"},
+		{`func f(a, b, *rest) { a }; f(1)`, "type error: function takes at least 2 arguments (1 given)"},
+		{`func f(a) { a }; f(...[1, 2])`, "type error: function takes 1 argument (2 given)"},
+		{`func f(a) { a }; f(...1)`, "type error: spread argument is not iterable (got int)"},
+		{`len("a", x=1)`, "type error: len() does not accept keyword arguments"},
+		{`chan(1, size=2)`, "type error: chan() got multiple values for argument: size"},
+		{`chan(cap=2)`, "type error: chan() accepts only the size keyword argument"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestMultiVarAssignment(t *testing.T) {
+	tests := []testCase{
+		{`a, b := [3, 4]; a`, object.NewInt(3)},
+		{`a, b := [3, 4]; b`, object.NewInt(4)},
+		{`a, b, c := [3, 4, 5]; a`, object.NewInt(3)},
+		{`a, b, c := [3, 4, 5]; b`, object.NewInt(4)},
+		{`a, b, c := [3, 4, 5]; c`, object.NewInt(5)},
+		{`a, b := "ᛛᛥ"; a`, object.NewString("ᛛ")},
+		{`a, b := "ᛛᛥ"; b`, object.NewString("ᛥ")},
+		{`a, b := {42, 43}; a`, object.NewInt(42)},
+		{`a, b := {42, 43}; b`, object.NewInt(43)},
+		{`a, b := {foo: 1, bar: 2}; a`, object.NewString("foo")},
+		{`a, b := {foo: 1, bar: 2}; b`, object.NewString("bar")},
+	}
+	runTests(t, tests)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []testCase{
+		{`a, [b, c] := [1, [2, 3]]; [a, b, c]`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{`[a, b] := "xy"; a + b`, object.NewString("xy")},
+		{`{name, age} := {name: "anne", age: 30}; name`, object.NewString("anne")},
+		{`{"info": {age}} := {info: {age: 30}}; age`, object.NewInt(30)},
+		{`{"items": [x, _]} := {items: [1, 2]}; x`, object.NewInt(1)},
+		{`head, *tail := [1, 2, 3]; tail`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(3),
+		})},
+		{`*rest, last := [1, 2, 3]; rest`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2),
+		})},
+		{`first, *_, last := [1, 2, 3, 4]; first + last`, object.NewInt(5)},
+		{`head, *tail := [1]; tail`, object.NewList([]object.Object{})},
+		{`func f() { a, {b} := [1, {b: 2}]; return a + b }; f()`, object.NewInt(3)},
+		{`r := []; for i, [a, b] := range [[1, 2], [3, 4]] { r.append(i + a * b) }; r`,
+			object.NewList([]object.Object{object.NewInt(2), object.NewInt(13)})},
+		{`r := []; for _, {n} := range [{n: 1}, {n: 2}] { r.append(n) }; r`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`r := []; for [a, *b] in [[1, 2], [3]] { r.append(len(b)) }; r`,
+			object.NewList([]object.Object{object.NewInt(1), object.NewInt(0)})},
+		{`func f() { r := 0; for {n} in [{n: 1}, {n: 2}] { r += n }; return r }; f()`, object.NewInt(3)},
+		{`r := []; for k, [a, b] in {x: [1, 2], y: [3, 4]} { r.append(k + string(a * b)) }; r`,
+			object.NewList([]object.Object{object.NewString("x2"), object.NewString("y12")})},
+		{`r := []; for k, v in {x: 1, y: 2} { r.append(k + string(v)) }; r`,
+			object.NewList([]object.Object{object.NewString("x1"), object.NewString("y2")})},
+		{`func f() { r := 0; for i, {n} in [{n: 1}, {n: 2}] { r += i * n }; return r }; f()`, object.NewInt(2)},
+	}
+	runTests(t, tests)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`a, [b, c] := [1, [2]]`, "exec error: unpack count mismatch: 1 != 2"},
+		{`a, *b, c := [1]`, "exec error: not enough values to unpack (expected at least 2, got 1)"},
+		{`a, [b] := [1, 2]`, "type error: object is not a container (got int)"},
+		{`{name} := {age: 30}`, `key error: "name"`},
+		{`{name} := [1]`, "type error: object is not a map (got list)"},
+		{`[a, 1] := [1, 1]`, "invalid assignment target: 1"},
+		{`a, [a] := [1, [2]]`, "duplicate name in pattern: a"},
+		{`for a, b, [c] := range [] {}`, "invalid for loop"},
+		{`for a, [a] in {} {}`, "duplicate name in pattern: a"},
+		{`for k, [a, b] in {x: [1]} {}`, "exec error: unpack count mismatch: 1 != 2"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []testCase{
+		{`func add(x, y) { x + y }; add(3, 4)`, object.NewInt(7)},
+		{`func add(x, y) { x + y }; add(3, 4) + 5`, object.NewInt(12)},
+		{`func inc(x, amount=1) { x + amount }; inc(3)`, object.NewInt(4)},
+		{`func factorial(n) { if (n == 1) { return 1 } else { return n * factorial(n - 1) } }; factorial(5)`, object.NewInt(120)},
+		{`z := 10; y := func(x, inc=100) { x + z + inc }; y(3)`, object.NewInt(113)},
+		{`func(x="a", y="b") { x + y }()`, object.NewString("ab")},
+		{`func(x="a", y="b") { x + y + "c" }()`, object.NewString("abc")},
+		{`func(x="a", y="b") { x + y + "c" }("W")`, object.NewString("Wbc")},
+		{`func(x="a", y="b") { x + y + "c" }("W", "X")`, object.NewString("WXc")},
+		{`func(x="a", y="b") { return "X"; x + y + "c" }()`, object.NewString("X")},
+		{`x := 1; func() { y := 10; x + y }()`, object.NewInt(11)},
+		{`x := 1; func() { func() { y := 10; x + y } }()()`, object.NewInt(11)},
+	}
+	runTests(t, tests)
+}
+
+func TestContainers(t *testing.T) {
+	tests := []testCase{
+		{`true`, object.True},
+		{`[1,2,3][2]`, object.NewInt(3)},
+		{`"hello"[1]`, object.NewString("e")},
+		{`{"x": 10, "y": 20}["x"]`, object.NewInt(10)},
+		{`3 in [1, 2, 3]`, object.True},
+		{`4 in [1, 2, 3]`, object.False},
+		{`{"foo": "bar"}["foo"]`, object.NewString("bar")},
+		{`{foo: "bar"}["foo"]`, object.NewString("bar")},
+		{`[1, 2, 3, 4, 5].filter(func(x) { x > 3 })`, object.NewList(
+			[]object.Object{object.NewInt(4), object.NewInt(5)})},
+		{`range [1]`, object.NewListIter(object.NewList([]object.Object{object.NewInt(1)}))},
+	}
+	runTests(t, tests)
+}
+
+func TestStrings(t *testing.T) {
+	tests := []testCase{
+		{`"hello" + " " + "world"`, object.NewString("hello world")},
+		{`"hello".contains("e")`, object.True},
+		{`"hello".contains("x")`, object.False},
+		{`"hello".contains("ello")`, object.True},
+		{`"hello".contains("ellx")`, object.False},
+		{`"hello".contains("")`, object.True},
+		{`"hello"[0]`, object.NewString("h")},
+		{`"hello"[1]`, object.NewString("e")},
+		{`"hello"[-1]`, object.NewString("o")},
+		{`"hello"[-2]`, object.NewString("l")},
+		{`a := 1; b := "ok"; '{a + 1}-{b | strings.to_upper}'`, object.NewString("2-OK")},
+		{`func(a, b) { return 'A: {a} B: {b}' }("hi", "bye")`, object.NewString("A: hi B: bye")},
+	}
+	runTests(t, tests)
+}
+
+func TestRegexp(t *testing.T) {
+	tests := []testCase{
+		{`"hello".matches("^h.*o$")`, object.True},
+		{`"hello".matches(regexp.compile("x"))`, object.False},
+		{`"a1b22".replace_re("\\d+", "<$0>")`, object.NewString("a<1>b<22>")},
+		{`"a1b22".replace_re("\\d+", func(m) { return string(len(m)) })`, object.NewString("a1b2")},
+		{`regexp.match("b+", "abbc")`, object.True},
+		{`regexp.compile("b+").find("abbc")`, object.NewString("bb")},
+		{`regexp.compile("x").find("abbc")`, object.Nil},
+		{`regexp.find_all("\\d", "a1b2c3", 2)`, object.NewList([]object.Object{
+			object.NewString("1"), object.NewString("2"),
+		})},
+		{`regexp.find_submatch("(\\w+)@(\\w+)", "me@host")`, object.NewList([]object.Object{
+			object.NewString("me@host"), object.NewString("me"), object.NewString("host"),
+		})},
+		{`regexp.find_submatch("(?P<user>\\w+)@(?P<host>\\w+)", "me@host")`, object.NewMap(map[string]object.Object{
+			"user": object.NewString("me"), "host": object.NewString("host"),
+		})},
+		{`regexp.replace_all("[aeiou]", "banana", strings.to_upper)`, object.NewString("bAnAnA")},
+		{`regexp.split("\\s*,\\s*", "a , b,c")`, object.NewList([]object.Object{
+			object.NewString("a"), object.NewString("b"), object.NewString("c"),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestRegexpErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`regexp.compile("(")`, "value error: error parsing regexp: missing closing ): `(`"},
+		{`"a".matches(1)`, "type error: expected a regexp or string (int given)"},
+		{`regexp.replace_all("a", "a", func(m) { return 1 })`,
+			"type error: regexp.replace_all() function must return a string (int returned)"},
+		{`regexp.replace_all("a", "a", 1)`,
+			"type error: regexp.replace_all() expected a string or function (int given)"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []testCase{
+		{`type(9223372036854775807 + 1)`, object.NewString("bigint")},
+		{`string(9223372036854775807 + 1)`, object.NewString("9223372036854775808")},
+		{`string(-9223372036854775807 - 2)`, object.NewString("-9223372036854775809")},
+		{`string(3000000000 * 4000000000)`, object.NewString("12000000000000000000")},
+		{`string(2 ** 64)`, object.NewString("18446744073709551616")},
+		{`x := -9223372036854775807 - 1; string(-x)`, object.NewString("9223372036854775808")},
+		{`type(2 ** 10)`, object.NewString("int")},
+		{`2 ** 10`, object.NewInt(1024)},
+		{`string(bigint("123456789012345678901234567890") * 2)`,
+			object.NewString("246913578024691357802469135780")},
+		{`string(bigint("ff", 16) + 1)`, object.NewString("256")},
+		{`bigint(5) == 5`, object.True},
+		{`2 ** 70 > 2 ** 62`, object.True},
+		{`2 ** 70 > 1.5`, object.True},
+		{`len({bigint(5), 5})`, object.NewInt(1)},
+		{`int(bigint(42))`, object.NewInt(42)},
+		{`float(bigint(2) ** 3)`, object.NewFloat(8)},
+		{`string(int("99999999999999999999"))`, object.NewString("99999999999999999999")},
+		{`string(strconv.parse_bigint("ffffffffffffffffff", 16))`,
+			object.NewString("4722366482869645213695")},
+		{`json.marshal([2 ** 64])`, object.NewString("[18446744073709551616]")},
+		// Shifts that overflow are promoted
+		{`string(1 << 100)`, object.NewString("1267650600228229401496703205376")},
+		{`string(1 << 63)`, object.NewString("9223372036854775808")},
+		{`string(3 << 62)`, object.NewString("13835058055282163712")},
+		{`1 << 62`, object.NewInt(1 << 62)},
+		{`-1 << 63`, object.NewInt(math.MinInt64)},
+		{`0 << 100`, object.NewInt(0)},
+		{`(1 << -1).message()`, object.NewString("value error: negative shift count: -1")},
+		// Results that fit in an int64 are demoted
+		{`type(2 ** 63 - 1)`, object.NewString("int")},
+		{`2 ** 63 - 1`, object.NewInt(math.MaxInt64)},
+		{`type(-(2 ** 63))`, object.NewString("int")},
+		{`type(bigint(5) + 1)`, object.NewString("int")},
+		{`type(bigint(5))`, object.NewString("bigint")},
+		// Results are limited in size
+		{`(3 ** 100000000).message()`,
+			object.NewString("value error: integer result exceeds 1048576 bits")},
+		{`((1 << 1000000) << 100000).message()`,
+			object.NewString("value error: integer result exceeds 1048576 bits")},
+		{`x := 7 ** 300000; (x * x).message()`,
+			object.NewString("value error: integer result exceeds 1048576 bits")},
+		{`(2 ** 1048575) > 0`, object.True},
+	}
+	runTests(t, tests)
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []testCase{
+		{`string(decimal("0.1") + decimal("0.2"))`, object.NewString("0.3")},
+		{`decimal("0.1") + decimal("0.2") == decimal("0.3")`, object.True},
+		{`string(decimal("19.99") * 3)`, object.NewString("59.97")},
+		{`string(decimal("1.50") - 1)`, object.NewString("0.50")},
+		{`string(decimal(1) / 3)`, object.NewString("0.3333333333333333")},
+		{`string(decimal(10) / 4)`, object.NewString("2.5")},
+		{`string(decimal("1.00") / 4)`, object.NewString("0.25")},
+		{`string(decimal("7.5") % 2)`, object.NewString("1.5")},
+		{`string(decimal("1.5") ** 2)`, object.NewString("2.25")},
+		{`string(decimal(2) ** -2)`, object.NewString("0.25")},
+		{`string(-decimal("1.5"))`, object.NewString("-1.5")},
+		{`string(decimal(0.1))`, object.NewString("0.1")},
+		{`string(decimal("1.5e-3"))`, object.NewString("0.0015")},
+		{`string(decimal("2.675").round(2))`, object.NewString("2.68")},
+		{`string(decimal("2.665").round(2))`, object.NewString("2.66")},
+		{`string(decimal("2.665").round(2, "half_up"))`, object.NewString("2.67")},
+		{`string(decimal("-2.5").round(0, "floor"))`, object.NewString("-3")},
+		{`string(decimal("-2.5").round(0, "ceiling"))`, object.NewString("-2")},
+		{`string(decimal("2.5").round(0, "down"))`, object.NewString("2")},
+		{`string(decimal("2.1").round(0, "up"))`, object.NewString("3")},
+		{`string(decimal("1.005", 2))`, object.NewString("1.00")},
+		{`string(decimal("1.005", 2, "half_up"))`, object.NewString("1.01")},
+		{`string(decimal(3).round(2))`, object.NewString("3.00")},
+		{`decimal("1.005").scale()`, object.NewInt(3)},
+		{`decimal("1.50") == 1.5`, object.True},
+		{`decimal("3.0") == 3`, object.True},
+		{`decimal("1.5") < 2`, object.True},
+		{`len({decimal("1.50"), decimal("1.5"), decimal("2.0"), 2})`, object.NewInt(2)},
+		{`int(decimal("12.9"))`, object.NewInt(12)},
+		{`float(decimal("12.5"))`, object.NewFloat(12.5)},
+		{`json.marshal({"a": decimal("1.50")})`, object.NewString(`{"a":1.50}`)},
+		{`string(strconv.parse_decimal("12.340"))`, object.NewString("12.340")},
+		{`string(math.sum([decimal("0.1"), decimal("0.2"), 1]))`, object.NewString("1.3")},
+		{`x := ""; try { decimal(1) / 0 } catch e { x = e.message() }; x`, object.NewString("value error: division by zero")},
+		{`(decimal(2) ** decimal("0.5")).message()`,
+			object.NewString("value error: decimal exponent must be an integer (got 0.5)")},
+		// Results are limited in size
+		{`x := decimal("1.1"); for i := 0; i < 30 && type(x) == "decimal"; i++ { x = x * x }; x.message()`,
+			object.NewString("value error: decimal result exceeds 262144 digits")},
+		{`(decimal("1.1") ** 1000000).message()`,
+			object.NewString("value error: decimal result exceeds 262144 digits")},
+		{`(decimal(7) ** 400000).message()`,
+			object.NewString("value error: decimal result exceeds 262144 digits")},
+		{`(decimal("1e-262144") * decimal("0.1")).message()`,
+			object.NewString("value error: decimal result exceeds 262144 digits")},
+		{`(decimal(1) ** 1000000000) == 1`, object.True},
+		{`decimal(9) ** 100000 > 0`, object.True},
+	}
+	runTests(t, tests)
+}
+
+func TestDecimalErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`decimal("abc")`, `value error: invalid literal for decimal(): "abc"`},
+		{`decimal("1.5").round(1, "sideways")`, `value error: invalid rounding mode: "sideways"`},
+		{`bigint("12x")`, `value error: invalid literal for bigint(): "12x"`},
+		{`int(2 ** 64)`, "value error: int() argument out of range: 18446744073709551616"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestComplex(t *testing.T) {
+	tests := []testCase{
+		{`1 + 2i`, object.NewComplex(complex(1, 2))},
+		{`2 * 3i`, object.NewComplex(complex(0, 6))},
+		{`1.5 - 0.5i`, object.NewComplex(complex(1.5, -0.5))},
+		{`complex(1, 2) * complex(3, 4)`, object.NewComplex(complex(-5, 10))},
+		{`(4 + 2i) / 2`, object.NewComplex(complex(2, 1))},
+		{`1i ** 2`, object.NewComplex(complex(-1, 0))},
+		{`-(1 + 2i)`, object.NewComplex(complex(-1, -2))},
+		{`complex()`, object.NewComplex(0)},
+		{`complex(3)`, object.NewComplex(complex(3, 0))},
+		{`complex("1+2i")`, object.NewComplex(complex(1, 2))},
+		{`(1 + 2i).real`, object.NewFloat(1)},
+		{`(1 + 2i).imag`, object.NewFloat(2)},
+		{`(1 + 2i).conjugate()`, object.NewComplex(complex(1, -2))},
+		{`type(2i)`, object.NewString("complex")},
+		{`string(1 + 2i)`, object.NewString("(1+2i)")},
+		{`complex(3, 0) == 3`, object.True},
+		{`1 + 2i == complex(1, 2)`, object.True},
+		{`len({1 + 2i, complex(1, 2)})`, object.NewInt(1)},
+		{`cmath.abs(3 + 4i)`, object.NewFloat(5)},
+		{`cmath.sqrt(-1)`, object.NewComplex(complex(0, 1))},
+		{`cmath.exp(0)`, object.NewComplex(complex(1, 0))},
+		{`cmath.conj(2i)`, object.NewComplex(complex(0, -2))},
+		{`cmath.phase(-1)`, object.NewFloat(math.Pi)},
+		{`cmath.rect(2, 0)`, object.NewComplex(complex(2, 0))},
+		{`cmath.polar(2i)`, object.NewList([]object.Object{
+			object.NewFloat(2), object.NewFloat(math.Pi / 2),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestComplexSlice(t *testing.T) {
+	tests := []testCase{
+		{`complex_slice()`, object.NewComplexSlice(nil)},
+		{`complex_slice(2)`, object.NewComplexSlice([]complex128{0, 0})},
+		{`complex_slice([1, 2i, 3.5])`, object.NewComplexSlice([]complex128{1, 2i, 3.5})},
+		{`complex_slice(float_slice([1, 2]))`, object.NewComplexSlice([]complex128{1, 2})},
+		{`s := complex_slice([1, 2]); s[1] = 3i; s`, object.NewComplexSlice([]complex128{1, 3i})},
+		{`complex_slice([1, 2i])[-1]`, object.NewComplex(2i)},
+		{`complex_slice([1, 2i, 3])[1:]`, object.NewComplexSlice([]complex128{2i, 3})},
+		{`len(complex_slice([1, 2]))`, object.NewInt(2)},
+		{`2i in complex_slice([1, 2i])`, object.True},
+		{`x := []; for _, v := range complex_slice([1, 2i]) { x.append(v) }; x`, object.NewList([]object.Object{
+			object.NewComplex(1), object.NewComplex(2i),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestStepSlice(t *testing.T) {
+	tests := []testCase{
+		{`[1, 2, 3, 4, 5][::2]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(3), object.NewInt(5)})},
+		{`[1, 2, 3, 4, 5][1:4:2]`, object.NewList([]object.Object{object.NewInt(2), object.NewInt(4)})},
+		{`[1, 2, 3][::-1]`, object.NewList([]object.Object{object.NewInt(3), object.NewInt(2), object.NewInt(1)})},
+		{`[1, 2, 3, 4, 5][3::-2]`, object.NewList([]object.Object{object.NewInt(4), object.NewInt(2)})},
+		{`[1, 2, 3, 4, 5][:1:-1]`, object.NewList([]object.Object{object.NewInt(5), object.NewInt(4), object.NewInt(3)})},
+		{`[][::-1]`, object.NewList([]object.Object{})},
+		{`"hello"[::-1]`, object.NewString("olleh")},
+		{`"héllo"[::2]`, object.NewString("hlo")},
+		{`byte_slice("abc")[::-1]`, object.NewByteSlice([]byte("cba"))},
+		{`float_slice([1, 2, 3])[::-2]`, object.NewFloatSlice([]float64{3, 1})},
+		{`(1, 2, 3)[::-1]`, object.NewTuple([]object.Object{object.NewInt(3), object.NewInt(2), object.NewInt(1)})},
+		{`x := [1, 2, 3, 4]; step := 3; x[::step]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(4)})},
+	}
+	runTests(t, tests)
+}
+
+func TestRange(t *testing.T) {
+	tests := []testCase{
+		{`range(3)`, object.NewRange(0, 3, 1)},
+		{`list(range(4))`, object.NewList([]object.Object{object.NewInt(0), object.NewInt(1), object.NewInt(2), object.NewInt(3)})},
+		{`list(range(10, 0, -4))`, object.NewList([]object.Object{object.NewInt(10), object.NewInt(6), object.NewInt(2)})},
+		{`len(range(0, 10, 3))`, object.NewInt(4)},
+		{`range(2, 5)[-1]`, object.NewInt(4)},
+		{`9 in range(0, 10, 3)`, object.True},
+		{`10 in range(0, 10, 3)`, object.False},
+		{`range(10)[2:8:2]`, object.NewRange(2, 8, 2)},
+		{`list(range(4)[::-1])`, object.NewList([]object.Object{object.NewInt(3), object.NewInt(2), object.NewInt(1), object.NewInt(0)})},
+		{`range(1000000000000)[999999999999]`, object.NewInt(999999999999)},
+		{`len(range(-9223372036854775807, 9223372036854775807, 2))`, object.NewInt(math.MaxInt64)},
+		{`9223372036854775806 in range(-9223372036854775807, 9223372036854775807, 3)`, object.False},
+		{`9223372036854775805 in range(-9223372036854775807, 9223372036854775807, 3)`, object.True},
+		{`total := 0; for i := range(5) { total += i }; total`, object.NewInt(10)},
+		{`total := 0; for i, v := range range(3, 6) { total += i * v }; total`, object.NewInt(14)},
+		{`[i * i for i in range(4)]`, object.NewList([]object.Object{object.NewInt(0), object.NewInt(1), object.NewInt(4), object.NewInt(9)})},
+		{`range(3) == range(0, 3, 1)`, object.True},
+		{`bool(range(0))`, object.False},
+		{`r := range(1, 9, 2); [r.start, r.stop, r.step]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(9), object.NewInt(2)})},
+		// The parenthesized keyword form still ranges over the expression
+		{`xs := ["a", "b"]; s := ""; for i, v := range (xs) { s += string(i) + v }; s`, object.NewString("0a1b")},
+		{`total := 0; for _, v := range (5, 6, 7) { total += v }; total`, object.NewInt(18)},
+		{`a := 5; b := 6; c := 7; out := []; for _, v := range (a, b, c) { out.append(v) }; out`, object.NewList([]object.Object{object.NewInt(5), object.NewInt(6), object.NewInt(7)})},
+		{`xs := ["a", "b"]; s := ""; for i, v := range(xs) { s += string(i) + v }; s`, object.NewString("0a1b")},
+		{`list(range("ab"))`, object.NewList([]object.Object{object.NewString("a"), object.NewString("b")})},
+	}
+	runTests(t, tests)
+}
+
+func TestRangeErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`range()`, "type error: range() takes at least 1 argument (0 given)"},
+		{`range(1, 2, 3, 4)`, "type error: range() takes at most 3 arguments (4 given)"},
+		{`range(1.5)`, "type error: range() expected an int (float given)"},
+		{`range("a", 2)`, "type error: range() expected an int (string given)"},
+		{`range(0, 5, 0)`, "value error: range() step must not be zero"},
+		{`range(-9223372036854775807, 9223372036854775807)`, "value error: range() has too many items"},
+		{`range(3)[3]`, "index error: index out of range: 3"},
+		{`r := range(3); r[0] = 1`, "type error: range does not support item assignment"},
+		{`[1, 2][::0]`, "slice error: slice step cannot be zero"},
+		{`[1, 2][::"a"]`, "type error: slice step must be an int (got string)"},
+		{`[1, 2, 3][0:2:-1]`, "slice error: start index is less than stop index"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestTuple(t *testing.T) {
+	tests := []testCase{
+		{`()`, object.NewTuple([]object.Object{})},
+		{`(1,)`, object.NewTuple([]object.Object{object.NewInt(1)})},
+		{`(1)`, object.NewInt(1)},
+		{`(1, "a")`, object.NewTuple([]object.Object{object.NewInt(1), object.NewString("a")})},
+		{`type((1, 2))`, object.NewString("tuple")},
+		{`t := (1, 2, 3); t[-1]`, object.NewInt(3)},
+		{`t := (1, 2, 3); t[1:]`, object.NewTuple([]object.Object{object.NewInt(2), object.NewInt(3)})},
+		{`len((1, 2, 3))`, object.NewInt(3)},
+		{`2 in (1, 2)`, object.True},
+		{`(1, 2) == (1, 2)`, object.True},
+		{`(1, 2) == [1, 2]`, object.False},
+		{`(1, 2) < (1, 3)`, object.True},
+		{`(1, 2) < (1, 2, 0)`, object.True},
+		{`(1, 2) + (3,)`, object.NewTuple([]object.Object{object.NewInt(1), object.NewInt(2), object.NewInt(3)})},
+		{`(1, 2, 1).count(1)`, object.NewInt(2)},
+		{`(1, 2).index(2)`, object.NewInt(1)},
+		{`a, b := (1, 2); b`, object.NewInt(2)},
+		{`[x * 2 for x in (1, 2)]`, object.NewList([]object.Object{object.NewInt(2), object.NewInt(4)})},
+		{`func f(a, b) { a + b }; f(...(1, 2))`, object.NewInt(3)},
+		{`tuple([1, 2])`, object.NewTuple([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`reversed((1, 2))`, object.NewTuple([]object.Object{object.NewInt(2), object.NewInt(1)})},
+		{`sorted([(2, "a"), (1, "b"), (1, "a")])`, object.NewList([]object.Object{
+			object.NewTuple([]object.Object{object.NewInt(1), object.NewString("a")}),
+			object.NewTuple([]object.Object{object.NewInt(1), object.NewString("b")}),
+			object.NewTuple([]object.Object{object.NewInt(2), object.NewString("a")}),
+		})},
+		{`m := {}; m[(1, 2)] = "a"; m[(1, 2)]`, object.NewString("a")},
+		{`{(1, 2): "a"}[(1, 2)]`, object.NewString("a")},
+		{`len({(1, 2), (1, 2), (1, 3)})`, object.NewInt(2)},
+		{`(1, "a") in {(1, "a")}`, object.True},
+		{`json.marshal((1, "a"))`, object.NewString(`[1,"a"]`)},
+		{`switch (1, 2) { case [a, b]: a + b }`, object.NewInt(3)},
+		{`switch (1, 2) { case tuple(t): len(t) }`, object.NewInt(2)},
+	}
+	runTests(t, tests)
+}
+
+func TestTupleErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`t := (1, 2); t[0] = 3`, "type error: tuple does not support item assignment"},
+		{`t := (1, 2); delete(t, 0)`, "type error: tuple does not support item deletion"},
+		{`m := {}; m[(1, [2])] = 1`, "type error: list object is unhashable"},
+		{`(1, 2)[2]`, "index error: index out of range: 2"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []testCase{
+		{`freeze([1, 2])`, object.Freeze(object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)}))},
+		{`x := [1]; y := freeze(x); x.append(2); y`, object.Freeze(object.NewList([]object.Object{object.NewInt(1)}))},
+		{`x := [1]; y := freeze(x); x.append(2); x`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`l := freeze([1, 2]); [v * 2 for v in l]`, object.NewList([]object.Object{object.NewInt(2), object.NewInt(4)})},
+		{`m := freeze({a: 1}); m["a"]`, object.NewInt(1)},
+		{`freeze(1)`, object.NewInt(1)},
+		{`l := [1]; l.append(l); len(freeze(l)[1])`, object.NewInt(2)},
+	}
+	runTests(t, tests)
+
+	errTests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`l := freeze([1]); l.append(2)`, "type error: cannot modify a frozen list"},
+		{`l := freeze([1]); l[0] = 2`, "type error: cannot modify a frozen list"},
+		{`l := freeze([1]); l.sort()`, "type error: cannot modify a frozen list"},
+		{`l := freeze([1, [2]]); l[1].append(3)`, "type error: cannot modify a frozen list"},
+		{`m := freeze({a: 1}); m["b"] = 2`, "type error: cannot modify a frozen map"},
+		{`m := freeze({a: 1}); delete(m, "a")`, "type error: cannot modify a frozen map"},
+		{`m := freeze({a: 1}); m.update({b: 2})`, "type error: cannot modify a frozen map"},
+		{`m := freeze({a: {b: 1}}); m["a"]["c"] = 2`, "type error: cannot modify a frozen map"},
+		{`s := freeze({1, 2}); s.add(3)`, "type error: cannot modify a frozen set"},
+		{`s := freeze({1, 2}); delete(s, 1)`, "type error: cannot modify a frozen set"},
+		{`t := freeze((1, [2])); t[1].append(3)`, "type error: cannot modify a frozen list"},
+	}
+	for _, tt := range errTests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []testCase{
+		{`struct Point { x; y = 0 }
+		p := Point{x: 1}
+		[p.x, p.y]`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(0)})},
+		{`struct Point { x, y }
+		p := Point{x: 1, y: 2}
+		p.x = 5
+		p.y += 3
+		p.x * 10 + p.y`, object.NewInt(55)},
+		{`struct Point { x, y }; Point(1, y=2).y`, object.NewInt(2)},
+		{`struct Point { x, y }; Point{}.x`, object.Nil},
+		{`struct Point { x, y }; string(Point{x: 1, y: 2})`, object.NewString("Point{x: 1, y: 2}")},
+		{`struct Point { x, y }; Point{x: 1} == Point{x: 1}`, object.True},
+		{`struct Point { x, y }; Point{x: 1} == Point{x: 2}`, object.False},
+		{`struct A { x }; struct B { x }; A{x: 1} == B{x: 1}`, object.False},
+		{`struct Point { x, y }; type(Point{})`, object.NewString("Point")},
+		{`struct Point { x, y }; json.marshal(Point{x: 1, y: [true]})`, object.NewString(`{"x":1,"y":[true]}`)},
+		{`func f() { struct Q { v }; return Q{v: 2}.v }; f()`, object.NewInt(2)},
+		{`struct P { x }; func f() { P := true; if P { return 1 }; return 2 }; f()`, object.NewInt(1)},
+		{`struct P { x }; if (P{x: 1}).x == 1 { 3 } else { 4 }`, object.NewInt(3)},
+	}
+	runTests(t, tests)
+}
+
+func TestStructLiteralModule(t *testing.T) {
+	point, err := object.NewStructType("Point", []string{"x", "y"}, []object.Object{nil, object.NewInt(0)})
+	require.Nil(t, err)
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `p := geo.Point{x: 3}; [p.x, p.y, type(p), geo.Point{}.y]`)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithBuiltins(map[string]object.Object{
+		"geo":  object.NewBuiltinsModule("geo", map[string]object.Object{"Point": point}),
+		"type": builtins.Builtins()["type"],
+	}))
+	require.Nil(t, err)
+	vm := New(code)
+	require.Nil(t, vm.Run(ctx))
+	result, ok := vm.TOS()
+	require.True(t, ok)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(3),
+		object.NewInt(0),
+		object.NewString("Point"),
+		object.NewInt(0),
+	}), result)
+}
+
+func TestStructMethods(t *testing.T) {
+	tests := []testCase{
+		{`struct Point { x, y }
+		func (p Point) norm() { return p.x * p.x + p.y * p.y }
+		Point{x: 3, y: 4}.norm()`, object.NewInt(25)},
+		{`struct Point { x, y }
+		func (p Point) scale(k) {
+			p.x *= k
+			p.y *= k
+		}
+		p := Point{x: 1, y: 2}
+		p.scale(3)
+		[p.x, p.y]`, object.NewList([]object.Object{object.NewInt(3), object.NewInt(6)})},
+		{`struct Counter { n = 0 }
+		func (c Counter) add(k=1) { c.n += k; return c }
+		Counter{}.add().add(k=5).n`, object.NewInt(6)},
+		{`struct Box { v }
+		func (b Box) get() { return b.v }
+		m := Box{v: "a"}.get
+		m()`, object.NewString("a")},
+		{`struct Box { v }
+		func (b Box) get() { return b.v }
+		Box{v: 2} | Box.get`, object.NewInt(2)},
+		{`struct Box { v }
+		func (b Box) add(*rest) { return b.v + len(rest) }
+		Box{v: 1}.add(1, 2, 3)`, object.NewInt(4)},
+		{`struct Worker { id }
+		func (w Worker) run(c) { c <- w.id * 10 }
+		c := chan()
+		w := Worker{id: 4}
+		go w.run(c)
+		<-c`, object.NewInt(40)},
+		{`struct Log { items }
+		func (l Log) add(x) { l.items.append(x) }
+		l := Log{items: []}
+		func f() {
+			defer l.add("done")
+			l.add("body")
+		}
+		f()
+		l.items`, object.NewList([]object.Object{object.NewString("body"), object.NewString("done")})},
+		{`struct Box { v }
+		func (b Box) get(c) { c <- b.v }
+		c := chan(1)
+		m := Box{v: 7}.get
+		go m(c)
+		<-c`, object.NewInt(7)},
+	}
+	runTests(t, tests)
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`struct P { x }; P{y: 1}`, "type error: struct P has no field y"},
+		{`struct P { x }; P(1, 2)`, "type error: struct P has 1 fields (2 given)"},
+		{`struct P { x }; P(1, x=2)`, "type error: struct P field x given more than once"},
+		{`struct P { x }; p := P{}; p.y = 1`, "type error: struct P has no field y"},
+		{`struct P { x }; P{}.y`, `exec error: attribute "y" not found on P object`},
+		{`struct P { x }; func (p P) x() {}`, "type error: struct P already has a field named x"},
+		{`struct P { x = [] }`, "unsupported default value: []"},
+		{`func (p P) x() {}`, "undefined variable: P"},
+		{`struct P { x }; P = 1`, "cannot assign to constant: P"},
+		{`func f(x) { return x }; f{x: 3}`, "type error: struct literal requires a struct type (got function)"},
+		{`m := {}; m{}`, "type error: struct literal requires a struct type (got map)"},
+		{`p := P{}; struct P { x }`, "undefined variable: P"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vec := `struct Vec { x, y }
+	func (v Vec) __add__(o) { return Vec{x: v.x + o.x, y: v.y + o.y} }
+	func (v Vec) __mul__(k) { return Vec{x: v.x * k, y: v.y * k} }
+	func (v Vec) __eq__(o) { return v.x == o.x }
+	func (v Vec) __cmp__(o) { return v.x - o.x }
+	func (v Vec) __getitem__(i) { return [v.x, v.y][i] }
+	func (v Vec) __len__() { return 2 }
+	`
+	tests := []testCase{
+		{vec + `string(Vec{x: 1, y: 2} + Vec{x: 3, y: 4})`, object.NewString("Vec{x: 4, y: 6}")},
+		{vec + `(Vec{x: 1, y: 2} * 3).y`, object.NewInt(6)},
+		{vec + `v := Vec{x: 1, y: 2}; v += Vec{x: 1, y: 1}; v.x`, object.NewInt(2)},
+		{vec + `Vec{x: 1, y: 2} == Vec{x: 1, y: 5}`, object.True},
+		{vec + `Vec{x: 1, y: 2} != Vec{x: 1, y: 5}`, object.False},
+		{vec + `Vec{x: 1} < Vec{x: 2}`, object.True},
+		{vec + `Vec{x: 1} >= Vec{x: 2}`, object.False},
+		{vec + `Vec{x: 1, y: 2}[1]`, object.NewInt(2)},
+		{vec + `len(Vec{})`, object.NewInt(2)},
+		{vec + `[v.x for v in sorted([Vec{x: 3}, Vec{x: 1}, Vec{x: 2}])]`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{`struct N { n }
+		func (a N) __cmp__(b) { return a.n - b }
+		l := [3, N{n: 2}, 1]; l.sort(); [l[0], l[1].n, l[2]]`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{`struct B { v }
+		func (b B) __or__(o) { return b.v + o }
+		B{v: 40} | 2`, object.NewInt(42)},
+		{`struct B { v }
+		func (b B) __or__(o) { return b.v + o }
+		B{v: 40} | func(b) { b.v + 1 }`, object.NewInt(41)},
+	}
+	runTests(t, tests)
+}
+
+func TestOperatorOverloadingErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{`struct S { }; func (s S) __len__() { return "a" }; len(S{})`,
+			"type error: __len__ method must return an int (got string)"},
+		{`struct S { }; func (s S) __cmp__(o) { return nil }; S{} < S{}`,
+			"type error: __cmp__ method must return an int (got nil)"},
+		{`struct S { }; func (s S) __add__(o) { error("boom") }; S{} + 1`, "boom"},
+		{`struct S { }; sorted([S{}, S{}])`,
+			"type error: sorted() encountered a non-comparable item (S)"},
+		{`struct S { }; func (s S) __cmp__(o) { return "a" }; sorted([S{}, S{}])`,
+			"type error: __cmp__ method must return an int (got string)"},
+		{`struct S { }; func (s S) __cmp__(o) { error("boom") }; [S{}, S{}].sort()`, "boom"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err, tt.input)
+		require.Equal(t, tt.expectedErr, err.Error(), tt.input)
+	}
+}
+
+type testMoney struct {
+	Cents int
+}
+
+func (m testMoney) Add(other testMoney) testMoney {
+	return testMoney{Cents: m.Cents + other.Cents}
+}
+
+func (m testMoney) Equal(other testMoney) bool {
+	return m.Cents == other.Cents
+}
+
+func (m testMoney) Compare(other testMoney) int {
+	return m.Cents - other.Cents
+}
+
+func (m testMoney) Len() int {
+	return m.Cents
+}
+
+func TestProxyOperators(t *testing.T) {
+	opts := runOpts{
+		Inject: map[string]interface{}{
+			"a": testMoney{Cents: 1},
+			"b": testMoney{Cents: 2},
+			"c": testMoney{Cents: 1},
+		},
+	}
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{`(a + b).Cents`, object.NewInt(3)},
+		{`a == c`, object.True},
+		{`a != b`, object.True},
+		{`a < b`, object.True},
+		{`a >= b`, object.False},
+		{`len(b)`, object.NewInt(2)},
+	}
+	for _, tt := range tests {
+		result, err := run(context.Background(), tt.input, opts)
+		require.Nil(t, err, tt.input)
+		require.Equal(t, tt.expected, result, tt.input)
+	}
+}
+
+func TestPipes(t *testing.T) {
+	tests := []testCase{
+		{`"hello" | strings.to_upper`, object.NewString("HELLO")},
+		{`"hello" | len`, object.NewInt(5)},
+		{`func() { "hello" }() | len`, object.NewInt(5)},
+		{`["a", "b"] | strings.join(",") | strings.to_upper`, object.NewString("A,B")},
+		{`func() { "a" } | call`, object.NewString("a")},
+		{`"abc" | getattr("to_upper") | call`, object.NewString("ABC")},
+		{`"abc" | func(s) { s.to_upper() }`, object.NewString("ABC")},
+		{`[11, 12, 3] | math.max`, object.NewFloat(12)},
+		{`"42" | json.unmarshal`, object.NewFloat(42)},
+	}
+	runTests(t, tests)
+}
+
+func TestQuicksort(t *testing.T) {
+	result, err := run(context.Background(), `
+	func quicksort(arr) {
+		if len(arr) < 2 {
+			return arr
+		} else {
+			pivot := arr[0]
+			less := arr[1:].filter(func(x) { x <= pivot })
+			more := arr[1:].filter(func(x) { x > pivot })
+			return quicksort(less) + [pivot] + quicksort(more)
+		}
+	}
+	quicksort([10, 5, 2, 3])
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList(
+		[]object.Object{
+			object.NewInt(2),
+			object.NewInt(3),
+			object.NewInt(5),
+			object.NewInt(10),
+		}), result)
+}
+
+func TestLoopBreak(t *testing.T) {
+	result, err := run(context.Background(), `
+	x := 0
+	for i := 0; i < 10; i++ {
+		if i == 3 { break }
+		x = i
+	}
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2), result)
+}
+
+func TestLoopContinue(t *testing.T) {
+	result, err := run(context.Background(), `
+	x := 0
+	for i := 0; i < 10; i++ {
+		if i > 3 { continue }
+		x = i
+	}
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(3), result)
+}
+
+func TestRangeLoopBreak(t *testing.T) {
+	result, err := run(context.Background(), `
+	x := 0
+	for i := range [0, 1, 2, 3, 4] {
+		if i == 3 { break }
+		x = i
+	}
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2), result)
+}
+
+func TestRangeLoopContinue(t *testing.T) {
+	result, err := run(context.Background(), `
+	x := 0
+	for i := range [0, 1, 2, 3, 4] {
+		if i > 3 { continue }
+		x = i
+	}
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(3), result)
+}
+
+func TestSimpleLoopBreak(t *testing.T) {
+	result, err := run(context.Background(), `
+	x := 0
+	for {
+		x++
+		if x == 2 { break }
+		max := math.max([1, 2, 3]) // inject some extra instructions
+	}
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2), result)
+}
+
+func TestNestedLoops(t *testing.T) {
+	result, err := run(context.Background(), `
+	x, y, z := [0, 0, 0]
+	for {
+		x++ // This should execute 3 times total
+		if x == 3 { break }
+		// We should reach this point twice, with x as 1 then 2
+		for i := range [0, 1, 2, 3] {
+			y++ // This should execute 8 times total
+			if i > 1 { continue }
+			// We should reach this point 4 times total
+			for h := 0; h < 10; h++ {
+				z++ // This should execute 16 times total (4 times per inner loop)
+				if h == 3 { break }
+			}
+		}
+	}
+	[x, y, z]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(3),
+		object.NewInt(8),
+		object.NewInt(16),
+	}), result)
 }
 
 func TestSimpleLoopContinue(t *testing.T) {
@@ -1231,6 +2825,423 @@ func TestImports(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestCodeEncoding(t *testing.T) {
+	ctx := context.Background()
+	builtins := builtins.Builtins()
+	for k, v := range defaultModules() {
+		builtins[k] = v
+	}
+	tests := []testCase{
+		{`x := 1 + 2; x * 3`, object.NewInt(9)},
+		{`func f(a, b=2, *rest, **kw) { a + b + len(rest) + len(kw) }; f(1, 3, 4, 5, z=1)`, object.NewInt(7)},
+		{`func fib(n) { if n < 2 { return n }; return fib(n - 1) + fib(n - 2) }; fib(10)`, object.NewInt(55)},
+		{`func g() { yield 1; yield 2 }; list(g())`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`switch [1, 2] { case [a, b]: a + b }`, object.NewInt(3)},
+		{`struct P { x, y = 2 }; func (p P) sum() { p.x + p.y }; P{x: 1}.sum()`, object.NewInt(3)},
+		{`(1, 2.5)`, object.NewTuple([]object.Object{object.NewInt(1), object.NewFloat(2.5)})},
+		{`strings.to_upper("x")`, object.NewString("X")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ast, err := parser.Parse(ctx, tt.input)
+			require.Nil(t, err)
+			main, err := compiler.Compile(ast, compiler.WithBuiltins(builtins))
+			require.Nil(t, err)
+			data, err := object.MarshalCode(main)
+			require.Nil(t, err)
+			decoded, err := object.UnmarshalCode(data, builtins)
+			require.Nil(t, err)
+			machine := New(decoded)
+			require.Nil(t, machine.Run(ctx))
+			result, exists := machine.TOS()
+			require.True(t, exists)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCompiledImport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	source := `func double(x) { x * 2 }`
+	modPath := filepath.Join(dir, "mod.risor")
+	require.Nil(t, os.WriteFile(modPath, []byte(source), 0o644))
+
+	builtins := builtins.Builtins()
+	newImporter := func() *importer.LocalImporter {
+		return importer.NewLocalImporter(importer.LocalImporterOptions{
+			SourceDir:  dir,
+			Extensions: []string{".risor"},
+			Builtins:   builtins,
+			WriteCache: true,
+		})
+	}
+	_, err := newImporter().Import(ctx, "mod")
+	require.Nil(t, err)
+	compiledPath := filepath.Join(dir, "mod.rsc")
+	_, err = importer.ReadCompiled(compiledPath, source, builtins)
+	require.Nil(t, err)
+
+	// Changing the source makes the compiled file stale
+	_, err = importer.ReadCompiled(compiledPath, source+"\n", builtins)
+	require.Equal(t, importer.ErrStaleCompiled, err)
+
+	ast, err := parser.Parse(ctx, `import mod; mod.double(21)`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast, compiler.WithBuiltins(builtins))
+	require.Nil(t, err)
+	machine := New(main, WithImporter(newImporter()))
+	require.Nil(t, machine.Run(ctx))
+	result, exists := machine.TOS()
+	require.True(t, exists)
+	require.Equal(t, object.NewInt(42), result)
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []testCase{
+		// Tail recursion runs in constant frame space
+		{`func sum(n, acc) {
+			if n == 0 { return acc }
+			return sum(n - 1, acc + n)
+		}
+		sum(100000, 0)`, object.NewInt(5000050000)},
+		{`odd := nil
+		func even(n) { if n == 0 { return true }; return odd(n - 1) }
+		odd = func(n) { if n == 0 { return false }; return even(n - 1) }
+		[even(10001), odd(10001)]`, object.NewList([]object.Object{object.False, object.True})},
+		// Calls to builtins, and calls that can't reuse the frame
+		{`func f(x) { return len(x) }; f([1, 2])`, object.NewInt(2)},
+		{`func f(n) {
+			defer func() {}()
+			if n == 0 { return "done" }
+			return f(n - 1)
+		}
+		f(10)`, object.NewString("done")},
+		{`func g(n) { error("fail") }
+		func f(n) {
+			try { return g(n) } catch e { return -1 }
+		}
+		f(1)`, object.NewInt(-1)},
+		{`func gen() { yield 1; yield 2 }
+		func f() { return gen() }
+		list(f())`, object.NewList([]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`func f(x, y=2) { return x + y }; func g(a) { return f(a) }; g(1)`, object.NewInt(3)},
+	}
+	runTests(t, tests)
+}
+
+func TestTailCallInLoop(t *testing.T) {
+	// Loop iterators left on the stack by the replaced function are discarded
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+	func f(n) {
+		for _, v := range [1] {
+			if n > 0 { return f(n - 1) }
+		}
+		return n
+	}
+	f(100000)`)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast)
+	require.Nil(t, err)
+	vm := New(code)
+	require.Nil(t, vm.Run(ctx))
+	result, ok := vm.TOS()
+	require.True(t, ok)
+	require.Equal(t, object.NewInt(0), result)
+	require.Equal(t, InitialStackSize, len(vm.stack))
+}
+
+func TestTailCallStack(t *testing.T) {
+	_, err := run(context.Background(), `
+	func count(n) {
+		if n == 0 { return error("done") }
+		return count(n - 1)
+	}
+	count(100)`)
+	require.NotNil(t, err)
+	runtimeErr, ok := err.(*RuntimeError)
+	require.True(t, ok)
+	// Only the most recent tail calls are included in the stack
+	stack := runtimeErr.Stack()
+	require.Len(t, stack, 10)
+	require.Equal(t, "main", stack[0].Function)
+	for _, frame := range stack[1:] {
+		require.Equal(t, "count", frame.Function)
+	}
+	require.Equal(t, 4, stack[1].Position.LineNumber())
+	require.Equal(t, 3, stack[9].Position.LineNumber())
+}
+
+func TestRecursionDepth(t *testing.T) {
+	ctx := context.Background()
+	// Frames are allocated as needed
+	result, err := run(ctx, `
+	func depth(n) { if n == 0 { return 0 }; return 1 + depth(n - 1) }
+	depth(1000)`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(1000), result)
+
+	_, err = run(ctx, `
+	func depth(n) { return 1 + depth(n + 1) }
+	depth(0)`)
+	require.NotNil(t, err)
+	require.True(t, errors.Is(err, ErrRecursionDepth))
+	require.Equal(t, "exec error: recursion depth exceeded (max depth 1024)", err.Error())
+	runtimeErr, ok := err.(*RuntimeError)
+	require.True(t, ok)
+	require.Len(t, runtimeErr.Stack(), MaxFrameDepth)
+	require.Contains(t, runtimeErr.FriendlyErrorMessage(), `stack (most recent call last):
+  main (<input>:3:7)
+  depth (<input>:2:34)
+  depth (<input>:2:34)
+  depth (<input>:2:34)
+  [previous frame repeated 1020 more times]`)
+}
+
+func TestMaxFrameDepth(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+	func depth(n) { if n == 0 { return 0 }; return 1 + depth(n - 1) }
+	result := nil
+	try { depth(20) } catch e { result = e.message() }
+	[depth(5), result]`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast, compiler.WithBuiltins(builtins.Builtins()))
+	require.Nil(t, err)
+	machine := New(main, WithMaxFrameDepth(10))
+	require.Nil(t, machine.Run(ctx))
+	result, exists := machine.TOS()
+	require.True(t, exists)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(5),
+		object.NewString("exec error: recursion depth exceeded (max depth 10)"),
+	}), result)
+}
+
+func TestMaxStackDepth(t *testing.T) {
+	items := make([]string, 5000)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+	}
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+	result := nil
+	try { [`+strings.Join(items, ", ")+`] } catch e { result = e.message() }
+	result`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+	machine := New(main, WithMaxStackDepth(1000))
+	require.Nil(t, machine.Run(ctx))
+	result, exists := machine.TOS()
+	require.True(t, exists)
+	require.Equal(t, object.NewString("exec error: stack overflow (max depth 1000)"), result)
+	require.Equal(t, 1000, len(machine.stack))
+
+	ast, err = parser.Parse(ctx, "["+strings.Join(items, ", ")+"]")
+	require.Nil(t, err)
+	main, err = compiler.Compile(ast)
+	require.Nil(t, err)
+	err = New(main, WithMaxStackDepth(100)).Run(ctx)
+	require.NotNil(t, err)
+	require.True(t, errors.Is(err, ErrStackOverflow))
+}
+
+func TestStackGrowth(t *testing.T) {
+	items := make([]string, 5000)
+	expected := make([]object.Object, 5000)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+		expected[i] = object.NewInt(int64(i))
+	}
+	result, err := run(context.Background(), "["+strings.Join(items, ", ")+"]")
+	require.Nil(t, err)
+	require.Equal(t, object.NewList(expected), result)
+}
+
+func TestAttrCache(t *testing.T) {
+	type other struct {
+		Count string
+	}
+	opts := runOpts{
+		Inject: map[string]interface{}{
+			"items": []interface{}{
+				&testData{Count: 1},
+				&testData{Count: 2},
+				&other{Count: "3"},
+				&testData{Count: 4},
+			},
+		},
+	}
+	// The same instruction loads attributes from objects of different types
+	result, err := run(context.Background(), `
+	struct A { Count }
+	struct B { id, Count }
+	func count(obj) { return obj.Count }
+	values := []
+	for _, item := range items {
+		values.append(count(item))
+	}
+	for _, item := range [A{Count: 5}, B{id: 0, Count: 6}, A{Count: 7}] {
+		values.append(count(item))
+	}
+	values`, opts)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(1),
+		object.NewInt(2),
+		object.NewString("3"),
+		object.NewInt(4),
+		object.NewInt(5),
+		object.NewInt(6),
+		object.NewInt(7),
+	}), result)
+}
+
+func TestAttrCacheUpdates(t *testing.T) {
+	data := &testData{Count: 1}
+	opts := runOpts{Inject: map[string]interface{}{"data": data}}
+	// Cached attributes still see changes to the object
+	result, err := run(context.Background(), `
+	struct P { x }
+	p := P{x: 1}
+	func get(obj) { return obj.x }
+	counts := []
+	for i := 0; i < 3; i++ {
+		data.Increment()
+		counts.append(data.Count)
+		p.x = get(p) * 10
+	}
+	[counts, p.x, strings.to_upper("ok")]`, opts)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewList([]object.Object{object.NewInt(2), object.NewInt(3), object.NewInt(4)}),
+		object.NewInt(1000),
+		object.NewString("OK"),
+	}), result)
+}
+
+func TestAttrCacheBuiltinTypes(t *testing.T) {
+	// Cached methods of built-in types are bound to the object they are
+	// loaded from, and the cache follows changes in the type of the object
+	result, err := run(context.Background(), `
+	func first(obj) { return obj.copy }
+	values := []
+	for _, item := range ["a", "b"] {
+		values.append(item.to_upper())
+	}
+	for _, obj := range [[1], {"k": 2}, [3], {"k": 4}] {
+		values.append(first(obj)())
+	}
+	func index(obj, item) { return obj.index(item) }
+	values.extend([index("x", "y"), index([1], 1), index("y", "y")])
+	values`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewString("A"),
+		object.NewString("B"),
+		object.NewList([]object.Object{object.NewInt(1)}),
+		object.NewMap(map[string]object.Object{"k": object.NewInt(2)}),
+		object.NewList([]object.Object{object.NewInt(3)}),
+		object.NewMap(map[string]object.Object{"k": object.NewInt(4)}),
+		object.NewInt(-1),
+		object.NewInt(0),
+		object.NewInt(0),
+	}), result)
+}
+
+// uncached hides the object's support for attribute caching, which is used
+// as a baseline in benchmarks.
+type uncached struct {
+	object.Object
+}
+
+func benchmarkCode(b *testing.B, code string, globals map[string]object.Object) {
+	ctx := context.Background()
+	builtins := builtins.Builtins()
+	for k, v := range defaultModules() {
+		builtins[k] = v
+	}
+	for k, v := range globals {
+		builtins[k] = v
+	}
+	ast, err := parser.Parse(ctx, code)
+	require.Nil(b, err)
+	main, err := compiler.Compile(ast, compiler.WithBuiltins(builtins))
+	require.Nil(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		require.Nil(b, New(main).Run(ctx))
+	}
+}
+
+const attrBenchmarkCode = `
+func sum(obj) {
+	total := 0
+	for i := 0; i < 1000; i++ {
+		total += obj.Count
+	}
+	return total
+}
+sum(data)`
+
+func BenchmarkLoadAttrProxy(b *testing.B) {
+	proxy, err := object.NewProxy(&testData{Count: 1})
+	require.Nil(b, err)
+	benchmarkCode(b, attrBenchmarkCode, map[string]object.Object{"data": proxy})
+}
+
+func BenchmarkLoadAttrProxyUncached(b *testing.B) {
+	proxy, err := object.NewProxy(&testData{Count: 1})
+	require.Nil(b, err)
+	benchmarkCode(b, attrBenchmarkCode, map[string]object.Object{"data": uncached{proxy}})
+}
+
+func BenchmarkLoadAttrMethod(b *testing.B) {
+	proxy, err := object.NewProxy(&testData{Count: 1})
+	require.Nil(b, err)
+	benchmarkCode(b, `for i := 0; i < 1000; i++ { data.GetCount }`,
+		map[string]object.Object{"data": proxy})
+}
+
+func BenchmarkLoadAttrMethodUncached(b *testing.B) {
+	proxy, err := object.NewProxy(&testData{Count: 1})
+	require.Nil(b, err)
+	benchmarkCode(b, `for i := 0; i < 1000; i++ { data.GetCount }`,
+		map[string]object.Object{"data": uncached{proxy}})
+}
+
+func BenchmarkLoadAttrString(b *testing.B) {
+	benchmarkCode(b, `for i := 0; i < 1000; i++ { data.to_upper }`,
+		map[string]object.Object{"data": object.NewString("abc")})
+}
+
+func BenchmarkLoadAttrStringUncached(b *testing.B) {
+	benchmarkCode(b, `for i := 0; i < 1000; i++ { data.to_upper }`,
+		map[string]object.Object{"data": uncached{object.NewString("abc")}})
+}
+
+func BenchmarkLoadAttrModule(b *testing.B) {
+	benchmarkCode(b, `for i := 0; i < 1000; i++ { strings.to_upper }`, nil)
+}
+
+func BenchmarkLoadAttrStruct(b *testing.B) {
+	benchmarkCode(b, `
+	struct P { x, y, z }
+	p := P{x: 1, y: 2, z: 3}
+	for i := 0; i < 1000; i++ { p.z }`, nil)
+}
+
+func BenchmarkLoadGlobal(b *testing.B) {
+	benchmarkCode(b, `
+	x := 1
+	func f() {
+		for i := 0; i < 1000; i++ { x }
+	}
+	f()`, nil)
+}
+
 type testCase struct {
 	input    string
 	expected object.Object
@@ -1246,6 +3257,10 @@ func runTests(t *testing.T, tests []testCase) {
 			require.Nil(t, err)
 			require.NotNil(t, result)
 			require.Equal(t, tt.expected, result)
+			// The optimized code must produce the same result
+			result, err = run(ctx, tt.input, runOpts{Optimize: true})
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, result)
 		})
 	}
 }